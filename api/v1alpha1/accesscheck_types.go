@@ -0,0 +1,189 @@
+package v1alpha1
+
+import (
+	"github.com/platform-mesh/subroutines/conditions"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SelfAccessCheckSignatureAnnotation holds the signature the admission
+// webhook stamps the user of a SelfAccessCheck with. SelfAccessChecks without
+// a valid signature are not answered.
+const SelfAccessCheckSignatureAnnotation = "core.platform-mesh.io/user-signature"
+
+// AccessCheckAttributes describe what an AccessCheck or SelfAccessCheck
+// checks.
+type AccessCheckAttributes struct {
+	// Relation is checked between the user and the object, e.g. "member" or
+	// "get".
+	// +kubebuilder:validation:MinLength=1
+	Relation string `json:"relation"`
+	// Object is the object the relation is checked on. Accounts can be
+	// referenced by their workspace path as "account:<path>", all other
+	// objects are passed to OpenFGA as they are. Only the account of the
+	// workspace of the check, its child accounts and objects in the workspace
+	// can be checked.
+	// +kubebuilder:validation:MinLength=1
+	Object string `json:"object"`
+	// Expand additionally reports the tree of users having the relation on
+	// the object.
+	// +optional
+	Expand bool `json:"expand,omitempty"`
+}
+
+// AccessCheckSpec defines the desired state of AccessCheck.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type AccessCheckSpec struct {
+	// User is the name of the Kubernetes user the relation is checked for.
+	// +kubebuilder:validation:MinLength=1
+	User string `json:"user"`
+
+	AccessCheckAttributes `json:",inline"`
+}
+
+// SelfAccessCheckSpec defines the desired state of SelfAccessCheck.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type SelfAccessCheckSpec struct {
+	// User is set to the name of the user creating the SelfAccessCheck on
+	// admission, which signs it with the
+	// core.platform-mesh.io/user-signature annotation.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	AccessCheckAttributes `json:",inline"`
+}
+
+// AccessCheckStatus defines the observed state of AccessCheck and
+// SelfAccessCheck.
+type AccessCheckStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Allowed is whether the user has the relation on the object.
+	// +optional
+	Allowed bool `json:"allowed,omitempty"`
+	// Object is the object the relation was checked on in OpenFGA.
+	// +optional
+	Object string `json:"object,omitempty"`
+	// Expansion is the userset tree of the relation on the object as returned
+	// by OpenFGA. It is only set if spec.expand is.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	Expansion *runtime.RawExtension `json:"expansion,omitempty"`
+	// CompletionTime is the time the check was made at. Completed checks are
+	// deleted after a while.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="User",type=string,JSONPath=`.spec.user`
+// +kubebuilder:printcolumn:name="Relation",type=string,JSONPath=`.spec.relation`
+// +kubebuilder:printcolumn:name="Object",type=string,JSONPath=`.spec.object`
+// +kubebuilder:printcolumn:name="Allowed",type=boolean,JSONPath=`.status.allowed`
+
+// AccessCheck checks whether a user has a relation on an object in the store
+// of the organization of its workspace.
+type AccessCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AccessCheckSpec   `json:"spec"`
+	Status AccessCheckStatus `json:"status,omitempty"`
+}
+
+// GetUser returns the name of the user the relation is checked for.
+func (in *AccessCheck) GetUser() string {
+	return in.Spec.User
+}
+
+// GetAttributes returns what is checked.
+func (in *AccessCheck) GetAttributes() AccessCheckAttributes {
+	return in.Spec.AccessCheckAttributes
+}
+
+// GetAccessCheckStatus returns the status of the check.
+func (in *AccessCheck) GetAccessCheckStatus() *AccessCheckStatus {
+	return &in.Status
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *AccessCheck) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *AccessCheck) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// AccessCheckList contains a list of AccessCheck.
+type AccessCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccessCheck `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Relation",type=string,JSONPath=`.spec.relation`
+// +kubebuilder:printcolumn:name="Object",type=string,JSONPath=`.spec.object`
+// +kubebuilder:printcolumn:name="Allowed",type=boolean,JSONPath=`.status.allowed`
+
+// SelfAccessCheck checks whether the user creating it has a relation on an
+// object in the store of the organization of its workspace.
+type SelfAccessCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SelfAccessCheckSpec `json:"spec"`
+	Status AccessCheckStatus   `json:"status,omitempty"`
+}
+
+// GetUser returns the name of the user the relation is checked for.
+func (in *SelfAccessCheck) GetUser() string {
+	return in.Spec.User
+}
+
+// GetAttributes returns what is checked.
+func (in *SelfAccessCheck) GetAttributes() AccessCheckAttributes {
+	return in.Spec.AccessCheckAttributes
+}
+
+// GetAccessCheckStatus returns the status of the check.
+func (in *SelfAccessCheck) GetAccessCheckStatus() *AccessCheckStatus {
+	return &in.Status
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *SelfAccessCheck) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *SelfAccessCheck) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// SelfAccessCheckList contains a list of SelfAccessCheck.
+type SelfAccessCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SelfAccessCheck `json:"items"`
+}
+
+var (
+	_ conditions.ConditionAccessor = &AccessCheck{}
+	_ conditions.ConditionAccessor = &SelfAccessCheck{}
+)
+
+func init() {
+	SchemeBuilder.Register(&AccessCheck{}, &AccessCheckList{}, &SelfAccessCheck{}, &SelfAccessCheckList{})
+}
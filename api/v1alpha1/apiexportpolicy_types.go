@@ -22,6 +22,9 @@ type APIExportPolicySpec struct {
 type APIExportPolicyStatus struct {
 	Conditions              []metav1.Condition `json:"conditions,omitempty"`
 	ManagedAllowExpressions []string           `json:"managedAllowExpressions,omitempty"`
+	// LastTupleChange summarizes the last reconciliation that changed tuples.
+	// +optional
+	LastTupleChange *TupleChangeSummary `json:"lastTupleChange,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -6,6 +6,27 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// PermissionProfileRelationsAnnotation overrides the relations granting
+	// the Kubernetes verbs on the resource of an APIResourceSchema, e.g.
+	// "update=owner,delete=owner".
+	PermissionProfileRelationsAnnotation = "core.platform-mesh.io/permission-relations"
+
+	// PermissionProfileVerbsAnnotation adds custom verbs to the resource of an
+	// APIResourceSchema together with the relation granting them, e.g.
+	// "approve=owner".
+	PermissionProfileVerbsAnnotation = "core.platform-mesh.io/permission-verbs"
+
+	// PermissionProfileParentAnnotation replaces the type the resources of an
+	// APIResourceSchema are created in, which is the account or namespace by
+	// default. It must be a type defined in the model of the org.
+	PermissionProfileParentAnnotation = "core.platform-mesh.io/permission-parent"
+
+	// PermissionProfileCondition reports whether the permission profile of
+	// the APIResourceSchema an AuthorizationModel is generated from is valid.
+	PermissionProfileCondition = "PermissionProfileValid"
+)
+
 type WorkspaceStoreRef struct {
 	Name    string `json:"name"`
 	Cluster string `json:"cluster"`
@@ -20,12 +41,46 @@ type AuthorizationModelSpec struct {
 	StoreRef WorkspaceStoreRef `json:"storeRef"`
 	Model    string            `json:"model"`
 	Tuples   []Tuple           `json:"tuples,omitempty"`
+	// TuplesFrom adds the tuples held by ConfigMaps and Secrets to Tuples.
+	// Changes to them are picked up with the next reconciliation.
+	// +optional
+	TuplesFrom []TupleSource `json:"tuplesFrom,omitempty"`
+	// TupleOwnership optionally selects the tuples in the store that are
+	// exclusively managed through this AuthorizationModel.
+	// +optional
+	TupleOwnership *TupleOwnership `json:"tupleOwnership,omitempty"`
+	// Tests are evaluated together with the tests of the Store before a
+	// changed authorization model is written to it.
+	// +optional
+	Tests []ModelTest `json:"tests,omitempty"`
 }
 
 // AuthorizationModelStatus defines the observed state of AuthorizationModel.
 type AuthorizationModelStatus struct {
-	Conditions    []metav1.Condition `json:"conditions,omitempty"`
-	ManagedTuples []Tuple            `json:"managedTuples,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ManagedTuples is deprecated and only read to migrate to
+	// ManagedTupleSet.
+	// +optional
+	ManagedTuples []Tuple `json:"managedTuples,omitempty"`
+	// ManagedTupleSet records the tuples written to the store.
+	// +optional
+	ManagedTupleSet *ManagedTupleSet `json:"managedTupleSet,omitempty"`
+	// SharedTuples lists managed tuples that are managed by other Stores or
+	// AuthorizationModels as well, up to MaxReportedSharedTuples. It is
+	// refreshed whenever the managed tuples change and on drift checks, so
+	// changes of other objects are only picked up with drift detection on.
+	// +optional
+	SharedTuples []SharedTuple `json:"sharedTuples,omitempty"`
+	// LastTupleChange summarizes the last reconciliation that changed tuples.
+	// +optional
+	LastTupleChange *TupleChangeSummary `json:"lastTupleChange,omitempty"`
+	// NextTupleExpiry is the earliest expiry of all managed tuples.
+	// +optional
+	NextTupleExpiry *metav1.Time `json:"nextTupleExpiry,omitempty"`
+	// LastDriftCheckTime is when the tuples were last read back from OpenFGA
+	// to correct drift.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,53 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ModelTemplateSpec defines the desired state of ModelTemplate.
+// +kubebuilder:validation:XValidation:rule="has(self.apiExportName) != has(self.apiExportSelector)",message="exactly one of apiExportName and apiExportSelector must be set"
+type ModelTemplateSpec struct {
+	// APIExportName selects the APIExport of this name in the workspace of
+	// the template. It takes precedence over templates selecting the
+	// APIExport by label.
+	// +optional
+	APIExportName string `json:"apiExportName,omitempty"`
+	// APIExportSelector selects the APIExports in the workspace of the
+	// template by label. If several templates select an APIExport, the first
+	// one by name is used.
+	// +optional
+	APIExportSelector *metav1.LabelSelector `json:"apiExportSelector,omitempty"`
+	// Template is a Go text/template rendering the OpenFGA module of every
+	// resource of the selected APIExports. It is given the Name, Group,
+	// Singular and Scope of the resource, its full APIResourceSchema as
+	// Schema and the Parent, Relations and CustomVerbs of its permission
+	// profile.
+	// +kubebuilder:validation:MinLength=1
+	Template string `json:"template"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="APIExport",type=string,JSONPath=".spec.apiExportName"
+
+// ModelTemplate replaces the built-in template the authorization models of
+// the resources of an APIExport are generated from.
+type ModelTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ModelTemplateSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// ModelTemplateList contains a list of ModelTemplate.
+type ModelTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ModelTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ModelTemplate{}, &ModelTemplateList{})
+}
@@ -0,0 +1,38 @@
+package v1alpha1
+
+const (
+	// ModelTestsCondition reports whether the model tests of a Store and its
+	// AuthorizationModels passed against the last authorization model that was
+	// about to be written.
+	ModelTestsCondition = "ModelTestsPassed"
+)
+
+// ModelTest is an assertion on the authorization model in the format of
+// `fga model test`. The tuples are written to a scratch store with the new
+// model before the assertions are evaluated against it.
+type ModelTest struct {
+	Name string `json:"name"`
+	// +optional
+	Tuples []Tuple `json:"tuples,omitempty"`
+	// +optional
+	Check []CheckAssertion `json:"check,omitempty"`
+	// +optional
+	ListObjects []ListObjectsAssertion `json:"listObjects,omitempty"`
+}
+
+// CheckAssertion asserts the check results of a user on an object.
+type CheckAssertion struct {
+	User   string `json:"user"`
+	Object string `json:"object"`
+	// Assertions maps relations to whether the user is expected to have them.
+	Assertions map[string]bool `json:"assertions"`
+}
+
+// ListObjectsAssertion asserts the objects of a type a user has relations to.
+type ListObjectsAssertion struct {
+	User string `json:"user"`
+	Type string `json:"type"`
+	// Assertions maps relations to the objects the user is expected to have
+	// them on, in any order.
+	Assertions map[string][]string `json:"assertions"`
+}
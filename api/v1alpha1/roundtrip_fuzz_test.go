@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	"encoding/json"
 	"testing"
+	"unicode/utf8"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 )
@@ -10,6 +11,8 @@ import (
 func FuzzStoreRoundTrip(f *testing.F) {
 	f.Add([]byte(`{"spec":{"coreModule":"module","tuples":[{"object":"doc:1","relation":"viewer","user":"user:anne"}]}}`))
 	f.Add([]byte(`{"status":{"storeID":"s1","authorizationModelID":"am1","managedTuples":[{"object":"o","relation":"r","user":"u"}]}}`))
+	f.Add([]byte(`{"spec":{"coreModule":"module","tuples":[{"object":"doc:1","relation":"viewer","user":"user:anne","condition":{"name":"in_network","context":{"cidr":"10.0.0.0/8"}}}]}}`))
+	f.Add([]byte(`{"spec":{"tuplesFrom":[{"configMapRef":{"namespace":"default","name":"tuples","key":"tuples.yaml"}}]},"status":{"managedTupleSet":{"hash":"abc","count":1,"data":"H4sIAAAAAAAA/w=="}}}`))
 	f.Add([]byte(`{}`))
 
 	f.Fuzz(func(t *testing.T, data []byte) {
@@ -81,3 +84,64 @@ func fuzzRoundTrip[T any](t *testing.T, data []byte, obj *T, obj2 *T) {
 		t.Errorf("roundtrip mismatch for %T", obj)
 	}
 }
+
+func FuzzParseTuple(f *testing.F) {
+	f.Add("doc:1#viewer@user:anne")
+	f.Add("doc:1#viewer@group:admins#member")
+	f.Add("doc:1#viewer")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		tuple, err := ParseTuple(s)
+		if err != nil {
+			return
+		}
+		reparsed, err := ParseTuple(tuple.String())
+		if err != nil {
+			t.Fatalf("parsing %q again: %v", tuple.String(), err)
+		}
+		if !reparsed.Equal(tuple) {
+			t.Fatalf("tuple changed on round trip: %s != %s", reparsed, tuple)
+		}
+	})
+}
+
+func FuzzManagedTupleSetRoundTrip(f *testing.F) {
+	f.Add("doc:1", "viewer", "user:anne", "doc:2", "owner", "user:bob")
+
+	f.Fuzz(func(t *testing.T, object1, relation1, user1, object2, relation2, user2 string) {
+		// Objects stored by the API server are valid UTF-8.
+		for _, s := range []string{object1, relation1, user1, object2, relation2, user2} {
+			if !utf8.ValidString(s) {
+				return
+			}
+		}
+		tuples := []Tuple{
+			{Object: object1, Relation: relation1, User: user1},
+			{Object: object2, Relation: relation2, User: user2},
+		}
+		set, err := NewManagedTupleSet(tuples)
+		if err != nil {
+			t.Fatalf("recording tuples: %v", err)
+		}
+		if set.Hash != HashTuples([]Tuple{tuples[1], tuples[0]}) {
+			t.Fatal("hash depends on the order of the tuples")
+		}
+
+		recorded, err := set.Tuples()
+		if err != nil {
+			t.Fatalf("reading tuples: %v", err)
+		}
+		if len(recorded) != len(tuples) || set.Count != len(tuples) {
+			t.Fatalf("recorded %d tuples with count %d, want %d", len(recorded), set.Count, len(tuples))
+		}
+		for _, tuple := range tuples {
+			found := false
+			for _, r := range recorded {
+				found = found || r.Equal(tuple)
+			}
+			if !found {
+				t.Fatalf("tuple %s was not recorded", tuple)
+			}
+		}
+	})
+}
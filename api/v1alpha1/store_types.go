@@ -1,45 +1,270 @@
 package v1alpha1
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/platform-mesh/subroutines/conditions"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
 	StoreRefLabelKey = "core.platform-mesh.io/store-name"
+
+	// DriftedCondition reports whether the tuples in OpenFGA diverged from the
+	// managed tuples of a Store or AuthorizationModel at the last drift check.
+	DriftedCondition = "Drifted"
+
+	// TuplesSharedCondition reports whether managed tuples of a Store or
+	// AuthorizationModel are managed by other objects of the same store as
+	// well. Like SharedTuples, it lags behind changes of other objects while
+	// drift detection is disabled.
+	TuplesSharedCondition = "TuplesShared"
+
+	// AuthorizationModelPinnedCondition reports whether a Store uses a pinned
+	// authorization model instead of the latest one written.
+	AuthorizationModelPinnedCondition = "AuthorizationModelPinned"
+)
+
+// StoreDeletionPolicy decides what happens to the OpenFGA store of a Store
+// once the Store is deleted. Unless it is Delete, the tuples the Store wrote
+// are kept in the OpenFGA store as well. AuthorizationModels always delete
+// the tuples they wrote once they are deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type StoreDeletionPolicy string
+
+const (
+	// StoreDeletionPolicyDelete deletes the OpenFGA store once no
+	// AuthorizationModel references the Store anymore.
+	StoreDeletionPolicyDelete StoreDeletionPolicy = "Delete"
+	// StoreDeletionPolicyRetain keeps the OpenFGA store, but still waits until
+	// no AuthorizationModel references the Store anymore.
+	StoreDeletionPolicyRetain StoreDeletionPolicy = "Retain"
+	// StoreDeletionPolicyOrphan keeps the OpenFGA store and releases it right
+	// away, even if AuthorizationModels still reference the Store.
+	StoreDeletionPolicyOrphan StoreDeletionPolicy = "Orphan"
 )
 
 type Tuple struct {
 	Object   string `json:"object"`
 	Relation string `json:"relation"`
 	User     string `json:"user"`
+	// Condition optionally restricts the tuple to a condition defined in the
+	// authorization model.
+	// +optional
+	Condition *TupleCondition `json:"condition,omitempty"`
+	// ExpiresAt is the point in time after which the tuple is removed from
+	// OpenFGA again, e.g. for temporary access grants.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
 }
 
+// TupleCondition references a condition of the authorization model and the
+// context parameters it is evaluated with.
+type TupleCondition struct {
+	Name string `json:"name"`
+	// Context holds the condition parameters that are known at write time, e.g.
+	// an allowed CIDR range or the end of a time window.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	Context *runtime.RawExtension `json:"context,omitempty"`
+}
+
+// TupleOwnership selects the tuples in OpenFGA an object claims ownership of.
+// Owned tuples that are not managed by the object are deleted on drift
+// detection.
+type TupleOwnership struct {
+	// ObjectTypes lists the object types, e.g. "role", of the owned tuples.
+	// +kubebuilder:validation:MinItems=1
+	ObjectTypes []string `json:"objectTypes"`
+	// Relations optionally restricts ownership to tuples with the given
+	// relations.
+	// +optional
+	Relations []string `json:"relations,omitempty"`
+}
+
+// Owns reports whether the given tuple is selected by the ownership filter.
+func (o TupleOwnership) Owns(t Tuple) bool {
+	objectType, _, _ := strings.Cut(t.Object, ":")
+	if !slices.Contains(o.ObjectTypes, objectType) {
+		return false
+	}
+	return len(o.Relations) == 0 || slices.Contains(o.Relations, t.Relation)
+}
+
+// String returns the tuple in the form object#relation@user used by the fga
+// CLI.
 func (t Tuple) String() string {
-	return fmt.Sprintf("%s@%s#%s", t.Object, t.Relation, t.User)
+	return fmt.Sprintf("%s#%s@%s", t.Object, t.Relation, t.User)
+}
+
+// ParseTuple parses a tuple in the form object#relation@user. The user may be
+// a userset, e.g. group:admins#member.
+func ParseTuple(s string) (Tuple, error) {
+	object, rest, ok := strings.Cut(strings.TrimSpace(s), "#")
+	if !ok {
+		return Tuple{}, fmt.Errorf("tuple %q is not of the form object#relation@user", s)
+	}
+	relation, user, ok := strings.Cut(rest, "@")
+	if !ok || object == "" || relation == "" || user == "" {
+		return Tuple{}, fmt.Errorf("tuple %q is not of the form object#relation@user", s)
+	}
+	return Tuple{Object: object, Relation: relation, User: user}, nil
+}
+
+// IsExpired reports whether the tuple has an expiry that lies at or before now.
+func (t Tuple) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(now)
+}
+
+// Equal reports whether two tuples share the same key and condition. The
+// expiry is not taken into account as it is not part of the tuple in OpenFGA.
+func (t Tuple) Equal(other Tuple) bool {
+	if t.Object != other.Object || t.Relation != other.Relation || t.User != other.User {
+		return false
+	}
+	if t.Condition == nil || other.Condition == nil {
+		return t.Condition == nil && other.Condition == nil
+	}
+	return t.Condition.Name == other.Condition.Name && rawContextEqual(t.Condition.Context, other.Condition.Context)
+}
+
+// rawContextEqual compares two condition contexts by their decoded JSON value
+// so that formatting differences do not cause tuples to be rewritten.
+func rawContextEqual(a, b *runtime.RawExtension) bool {
+	var aRaw, bRaw []byte
+	if a != nil {
+		aRaw = a.Raw
+	}
+	if b != nil {
+		bRaw = b.Raw
+	}
+	if len(aRaw) == 0 || len(bRaw) == 0 {
+		return len(aRaw) == len(bRaw)
+	}
+
+	var aValue, bValue any
+	if err := json.Unmarshal(aRaw, &aValue); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(bRaw, &bValue); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
 }
 
 // StoreSpec defines the desired state of Store.
 type StoreSpec struct {
 	CoreModule string  `json:"coreModule"`
 	Tuples     []Tuple `json:"tuples,omitempty"`
+	// TuplesFrom adds the tuples held by ConfigMaps and Secrets to Tuples.
+	// Changes to them are picked up with the next reconciliation.
+	// +optional
+	TuplesFrom []TupleSource `json:"tuplesFrom,omitempty"`
+	// TupleOwnership optionally selects the tuples in the store that are
+	// exclusively managed through this Store.
+	// +optional
+	TupleOwnership *TupleOwnership `json:"tupleOwnership,omitempty"`
+	// Tests are evaluated before a changed authorization model is written.
+	// The model is not rolled forward while any of them fails.
+	// +optional
+	Tests []ModelTest `json:"tests,omitempty"`
+	// PinnedAuthorizationModelID makes tuples be written and checked with a
+	// previous authorization model of the store, e.g. while a bad module is
+	// being fixed. Changed modules are not written to the store while it is
+	// pinned, which the AuthorizationModelPinned condition reports with the
+	// reason WriteHeldBack, and are written once the pin is removed.
+	// +optional
+	PinnedAuthorizationModelID string `json:"pinnedAuthorizationModelId,omitempty"`
+	// StoreID adopts an existing OpenFGA store instead of looking one up by
+	// name or creating it. The name of an adopted store is only changed if
+	// StoreName is set.
+	// +optional
+	StoreID string `json:"storeId,omitempty"`
+	// StoreName is the name of the OpenFGA store and defaults to the name of
+	// the Store. Set it to a name qualified by e.g. the workspace if Stores
+	// with the same name exist in several workspaces.
+	// +optional
+	StoreName string `json:"storeName,omitempty"`
+	// DeletionPolicy decides what happens to the OpenFGA store once the Store
+	// is deleted.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy StoreDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// AuthorizationModelRevision records an authorization model written to the
+// OpenFGA store of a Store.
+type AuthorizationModelRevision struct {
+	ID        string      `json:"id"`
+	CreatedAt metav1.Time `json:"createdAt"`
+	// StoreGeneration is the generation of the Store the model was built from.
+	// +optional
+	StoreGeneration int64 `json:"storeGeneration,omitempty"`
+	// Modules lists the AuthorizationModels the model was built from.
+	// +optional
+	Modules []AuthorizationModelGeneration `json:"modules,omitempty"`
+}
+
+// AuthorizationModelGeneration identifies the generation of an
+// AuthorizationModel that contributed to a written authorization model.
+type AuthorizationModelGeneration struct {
+	Cluster    string `json:"cluster"`
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
 }
 
 // StoreStatus defines the observed state of Store.
 type StoreStatus struct {
-	Conditions           []metav1.Condition `json:"conditions,omitempty"`
-	StoreID              string             `json:"storeId,omitempty"`
-	AuthorizationModelID string             `json:"authorizationModelId,omitempty"`
-	ManagedTuples        []Tuple            `json:"managedTuples,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	StoreID    string             `json:"storeId,omitempty"`
+	// AuthorizationModelID is the ID of the authorization model tuples are
+	// written and checked with. It is the pinned model if one is set and the
+	// latest model written otherwise.
+	AuthorizationModelID string `json:"authorizationModelId,omitempty"`
+	// ManagedTuples is deprecated and only read to migrate to
+	// ManagedTupleSet.
+	// +optional
+	ManagedTuples []Tuple `json:"managedTuples,omitempty"`
+	// ManagedTupleSet records the tuples written to the store.
+	// +optional
+	ManagedTupleSet *ManagedTupleSet `json:"managedTupleSet,omitempty"`
+	// SharedTuples lists managed tuples that are managed by other Stores or
+	// AuthorizationModels as well, up to MaxReportedSharedTuples. It is
+	// refreshed whenever the managed tuples change and on drift checks, so
+	// changes of other objects are only picked up with drift detection on.
+	// +optional
+	SharedTuples []SharedTuple `json:"sharedTuples,omitempty"`
+	// LastTupleChange summarizes the last reconciliation that changed tuples.
+	// +optional
+	LastTupleChange *TupleChangeSummary `json:"lastTupleChange,omitempty"`
+	// AuthorizationModelHistory lists the most recently written authorization
+	// models, oldest first.
+	// +optional
+	AuthorizationModelHistory []AuthorizationModelRevision `json:"authorizationModelHistory,omitempty"`
+	// NextTupleExpiry is the earliest expiry of all managed tuples.
+	// +optional
+	NextTupleExpiry *metav1.Time `json:"nextTupleExpiry,omitempty"`
+	// LastDriftCheckTime is when the tuples were last read back from OpenFGA
+	// to correct drift.
+	// +optional
+	LastDriftCheckTime *metav1.Time `json:"lastDriftCheckTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
 // +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Store ID",type=string,JSONPath=`.status.storeId`,priority=1
+// +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.status.authorizationModelId`,priority=1
+// +kubebuilder:printcolumn:name="Pinned",type=string,JSONPath=`.spec.pinnedAuthorizationModelId`,priority=1
 
 // Store is the Schema for the stores API.
 type Store struct {
@@ -50,6 +275,14 @@ type Store struct {
 	Status StoreStatus `json:"status,omitempty"`
 }
 
+// OpenFGAStoreName returns the name of the OpenFGA store of the Store.
+func (in *Store) OpenFGAStoreName() string {
+	if in.Spec.StoreName != "" {
+		return in.Spec.StoreName
+	}
+	return in.Name
+}
+
 // GetConditions implements conditions.ConditionAccessor.
 func (in *Store) GetConditions() []metav1.Condition {
 	return in.Status.Conditions
@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	"github.com/platform-mesh/subroutines/conditions"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StoreRestoreSpec defines the desired state of StoreRestore.
+type StoreRestoreSpec struct {
+	// SnapshotName is the name of the StoreSnapshot to restore. The snapshot
+	// is restored into a new OpenFGA store that replaces the one of the Store
+	// it was taken of.
+	SnapshotName string `json:"snapshotName"`
+	// DeletePreviousStoreAfter opts into deleting the OpenFGA store the Store
+	// referenced before the restore once this long has passed since the
+	// restore was completed. The previous store is kept if unset, so it can
+	// still be adopted with spec.storeId after a bad restore.
+	// +optional
+	DeletePreviousStoreAfter *metav1.Duration `json:"deletePreviousStoreAfter,omitempty"`
+}
+
+// StoreRestoreStatus defines the observed state of StoreRestore.
+type StoreRestoreStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// StoreID is the ID of the OpenFGA store the snapshot was restored into.
+	// +optional
+	StoreID string `json:"storeId,omitempty"`
+	// AuthorizationModelID is the ID of the restored authorization model.
+	// +optional
+	AuthorizationModelID string `json:"authorizationModelId,omitempty"`
+	// TupleCount is the number of restored tuples.
+	// +optional
+	TupleCount int `json:"tupleCount,omitempty"`
+	// PreviousStoreID is the ID of the OpenFGA store the Store referenced
+	// before the restore.
+	// +optional
+	PreviousStoreID string `json:"previousStoreId,omitempty"`
+	// PreviousStoreDeletionTime is the time the previous OpenFGA store was
+	// deleted at.
+	// +optional
+	PreviousStoreDeletionTime *metav1.Time `json:"previousStoreDeletionTime,omitempty"`
+	// CompletionTime is the time the restore was completed at. A completed
+	// restore is never applied again.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Snapshot",type=string,JSONPath=`.spec.snapshotName`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+
+// StoreRestore applies a StoreSnapshot back to the Store it was taken of.
+type StoreRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StoreRestoreSpec   `json:"spec,omitempty"`
+	Status StoreRestoreStatus `json:"status,omitempty"`
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *StoreRestore) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *StoreRestore) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+var _ conditions.ConditionAccessor = &StoreRestore{}
+
+// +kubebuilder:object:root=true
+
+// StoreRestoreList contains a list of StoreRestore.
+type StoreRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StoreRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StoreRestore{}, &StoreRestoreList{})
+}
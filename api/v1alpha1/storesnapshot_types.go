@@ -0,0 +1,85 @@
+package v1alpha1
+
+import (
+	"github.com/platform-mesh/subroutines/conditions"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StoreSnapshotScheduleLabelKey is set on StoreSnapshots created by a
+	// StoreSnapshotSchedule.
+	StoreSnapshotScheduleLabelKey = "core.platform-mesh.io/store-snapshot-schedule"
+)
+
+// StoreSnapshotSpec defines the desired state of StoreSnapshot.
+type StoreSnapshotSpec struct {
+	// StoreRef references the Store the snapshot is taken of. Only the Store
+	// of the org the snapshot's workspace belongs to can be referenced.
+	StoreRef WorkspaceStoreRef `json:"storeRef"`
+}
+
+// StoreSnapshotStatus defines the observed state of StoreSnapshot.
+type StoreSnapshotStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// AuthorizationModelID is the ID of the authorization model contained in
+	// the snapshot.
+	// +optional
+	AuthorizationModelID string `json:"authorizationModelId,omitempty"`
+	// TupleCount is the number of tuples contained in the snapshot.
+	// +optional
+	TupleCount int `json:"tupleCount,omitempty"`
+	// SizeBytes is the compressed size of the snapshot.
+	// +optional
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// Secrets lists the Secrets in the operator's namespace holding the
+	// compressed snapshot in order.
+	// +optional
+	Secrets []string `json:"secrets,omitempty"`
+	// CompletionTime is the time the snapshot was completed at. A completed
+	// snapshot is never taken again.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Store",type=string,JSONPath=`.spec.storeRef.name`
+// +kubebuilder:printcolumn:name="Tuples",type=integer,JSONPath=`.status.tupleCount`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+
+// StoreSnapshot is a point-in-time backup of the authorization model and
+// tuples of a Store.
+type StoreSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StoreSnapshotSpec   `json:"spec,omitempty"`
+	Status StoreSnapshotStatus `json:"status,omitempty"`
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *StoreSnapshot) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *StoreSnapshot) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+var _ conditions.ConditionAccessor = &StoreSnapshot{}
+
+// +kubebuilder:object:root=true
+
+// StoreSnapshotList contains a list of StoreSnapshot.
+type StoreSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StoreSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StoreSnapshot{}, &StoreSnapshotList{})
+}
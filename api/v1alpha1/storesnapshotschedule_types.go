@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	"github.com/platform-mesh/subroutines/conditions"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StoreSnapshotScheduleSpec defines the desired state of
+// StoreSnapshotSchedule.
+type StoreSnapshotScheduleSpec struct {
+	// StoreRef references the Store snapshots are taken of.
+	StoreRef WorkspaceStoreRef `json:"storeRef"`
+	// Interval is the time between two snapshots.
+	Interval metav1.Duration `json:"interval"`
+	// Keep is the number of snapshots that are retained. Older snapshots
+	// created by the schedule are deleted.
+	// +kubebuilder:default=7
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Keep int `json:"keep,omitempty"`
+}
+
+// StoreSnapshotScheduleStatus defines the observed state of
+// StoreSnapshotSchedule.
+type StoreSnapshotScheduleStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastSnapshotName is the name of the StoreSnapshot created last.
+	// +optional
+	LastSnapshotName string `json:"lastSnapshotName,omitempty"`
+	// LastSnapshotTime is the time the last StoreSnapshot was created at.
+	// +optional
+	LastSnapshotTime *metav1.Time `json:"lastSnapshotTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Store",type=string,JSONPath=`.spec.storeRef.name`
+// +kubebuilder:printcolumn:name="Last Snapshot",type=date,JSONPath=`.status.lastSnapshotTime`
+
+// StoreSnapshotSchedule periodically creates StoreSnapshots of a Store.
+type StoreSnapshotSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StoreSnapshotScheduleSpec   `json:"spec,omitempty"`
+	Status StoreSnapshotScheduleStatus `json:"status,omitempty"`
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *StoreSnapshotSchedule) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *StoreSnapshotSchedule) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+var _ conditions.ConditionAccessor = &StoreSnapshotSchedule{}
+
+// +kubebuilder:object:root=true
+
+// StoreSnapshotScheduleList contains a list of StoreSnapshotSchedule.
+type StoreSnapshotScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StoreSnapshotSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StoreSnapshotSchedule{}, &StoreSnapshotScheduleList{})
+}
@@ -0,0 +1,184 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TupleSourceLabelKey must be set to TupleSourceLabelValue on the
+	// ConfigMaps and Secrets referenced as tuple sources. The permission
+	// claims of the core.platform-mesh.io APIExport default to selecting
+	// only objects with this label.
+	TupleSourceLabelKey   = "security.platform-mesh.io/tuple-source"
+	TupleSourceLabelValue = "true"
+)
+
+// TupleSource references a ConfigMap or Secret holding tuples. Every key is
+// parsed according to its extension: .yaml, .yml and .json keys hold a list
+// of tuples as in the tuple files of the fga CLI, .csv keys the CSV format of
+// the fga CLI and all other keys one tuple per line in the form
+// object#relation@user.
+//
+// The ConfigMap or Secret must be labelled with TupleSourceLabelKey and the
+// APIBinding of the workspace to core.platform-mesh.io must accept the
+// permission claim on configmaps or secrets. Tuple sources are left pending
+// until the claim is accepted.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type TupleSource struct {
+	// +optional
+	ConfigMapRef *TupleSourceRef `json:"configMapRef,omitempty"`
+	// +optional
+	SecretRef *TupleSourceRef `json:"secretRef,omitempty"`
+}
+
+// TupleSourceRef references a ConfigMap or Secret in the workspace of the
+// referencing object.
+type TupleSourceRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Key restricts the source to a single key instead of all keys.
+	// +optional
+	Key string `json:"key,omitempty"`
+	// Optional treats a missing ConfigMap, Secret or key as holding no tuples.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// MaxReportedSharedTuples is the maximum number of shared tuples listed in
+// the status of a Store or AuthorizationModel.
+const MaxReportedSharedTuples = 100
+
+// TupleOwnerReference identifies a Store or AuthorizationModel managing
+// tuples.
+type TupleOwnerReference struct {
+	// +kubebuilder:validation:Enum=Store;AuthorizationModel
+	Kind    string `json:"kind"`
+	Cluster string `json:"cluster,omitempty"`
+	Name    string `json:"name"`
+}
+
+func (r TupleOwnerReference) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Cluster, r.Name)
+}
+
+// SharedTuple is a managed tuple that other Stores or AuthorizationModels
+// manage as well. It is only deleted from OpenFGA once none of them manages
+// it anymore.
+type SharedTuple struct {
+	// Tuple is the shared tuple in the form object#relation@user.
+	Tuple    string                `json:"tuple"`
+	CoOwners []TupleOwnerReference `json:"coOwners"`
+}
+
+// TupleChangeSummary summarizes the last reconciliation that changed tuples in
+// OpenFGA.
+type TupleChangeSummary struct {
+	// Added is the number of tuples written.
+	Added int `json:"added"`
+	// Removed is the number of tuples deleted.
+	Removed int `json:"removed"`
+	// LastChangeTime is the time the tuples were changed.
+	LastChangeTime metav1.Time `json:"lastChangeTime"`
+}
+
+// ManagedTupleSet records the tuples written to OpenFGA for an object in a
+// compressed form, so that large tuple sets stay well below the size limit of
+// objects.
+type ManagedTupleSet struct {
+	// Hash is the SHA-256 of the tuples in their canonical order.
+	Hash string `json:"hash"`
+	// Count is the number of tuples.
+	Count int `json:"count"`
+	// Data holds the gzip compressed JSON encoding of the tuples.
+	// +optional
+	Data []byte `json:"data,omitempty"`
+}
+
+// NewManagedTupleSet records the given tuples. It returns nil for no tuples.
+func NewManagedTupleSet(tuples []Tuple) (*ManagedTupleSet, error) {
+	if len(tuples) == 0 {
+		return nil, nil
+	}
+
+	tuples = canonicalTuples(tuples)
+	raw, err := json.Marshal(tuples)
+	if err != nil {
+		return nil, fmt.Errorf("encoding tuples: %w", err)
+	}
+
+	var data bytes.Buffer
+	w := gzip.NewWriter(&data)
+	if _, err := w.Write(raw); err != nil {
+		return nil, fmt.Errorf("compressing tuples: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compressing tuples: %w", err)
+	}
+
+	return &ManagedTupleSet{
+		Hash:  hashEncodedTuples(raw),
+		Count: len(tuples),
+		Data:  data.Bytes(),
+	}, nil
+}
+
+// Tuples returns the recorded tuples. A nil set holds no tuples.
+func (s *ManagedTupleSet) Tuples() ([]Tuple, error) {
+	if s == nil || len(s.Data) == 0 {
+		return nil, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(s.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing tuples: %w", err)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing tuples: %w", err)
+	}
+
+	var tuples []Tuple
+	if err := json.Unmarshal(raw, &tuples); err != nil {
+		return nil, fmt.Errorf("decoding tuples: %w", err)
+	}
+	return tuples, nil
+}
+
+// HashTuples returns the hash a ManagedTupleSet of the given tuples records,
+// regardless of their order.
+func HashTuples(tuples []Tuple) string {
+	if len(tuples) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(canonicalTuples(tuples))
+	if err != nil {
+		return ""
+	}
+	return hashEncodedTuples(raw)
+}
+
+func hashEncodedTuples(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalTuples returns a sorted copy of the tuples, so that sets with the
+// same tuples are encoded the same.
+func canonicalTuples(tuples []Tuple) []Tuple {
+	tuples = slices.Clone(tuples)
+	slices.SortStableFunc(tuples, func(a, b Tuple) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return tuples
+}
@@ -0,0 +1,160 @@
+package v1alpha1
+
+import (
+	"github.com/platform-mesh/subroutines/conditions"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TupleRewriteRule rewrites or drops the tuples of a store. Exactly one of
+// its fields is set.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type TupleRewriteRule struct {
+	// RenameObjectType renames the type of tuple objects.
+	// +optional
+	RenameObjectType *TypeRename `json:"renameObjectType,omitempty"`
+	// RenameRelation renames a relation of an object type, both in the
+	// relation of tuples and in usersets referencing it.
+	// +optional
+	RenameRelation *RelationRename `json:"renameRelation,omitempty"`
+	// RenameUserType renames the type of tuple users, including usersets and
+	// wildcards.
+	// +optional
+	RenameUserType *TypeRename `json:"renameUserType,omitempty"`
+	// Drop deletes the tuples matching all of the given fields.
+	// +optional
+	Drop *TupleMatch `json:"drop,omitempty"`
+}
+
+// TypeRename renames an OpenFGA type.
+type TypeRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RelationRename renames a relation of an OpenFGA type.
+type RelationRename struct {
+	ObjectType string `json:"objectType"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// TupleMatch selects tuples by the type of their object, their relation and
+// the type of their user. Unset fields match any tuple.
+// +kubebuilder:validation:MinProperties=1
+type TupleMatch struct {
+	// +optional
+	ObjectType string `json:"objectType,omitempty"`
+	// +optional
+	Relation string `json:"relation,omitempty"`
+	// +optional
+	UserType string `json:"userType,omitempty"`
+}
+
+// TupleMigrationSpec defines the desired state of TupleMigration.
+type TupleMigrationSpec struct {
+	// StoreRef references the Store whose tuples are migrated. If unset, all
+	// Stores in the workspace of the TupleMigration are migrated, which for
+	// the orgs workspace are the stores of all organizations.
+	// +optional
+	StoreRef *WorkspaceStoreRef `json:"storeRef,omitempty"`
+	// Rules are applied in order to every tuple of the migrated stores. The
+	// target of a rename must not be renamed again by another rule.
+	// +kubebuilder:validation:MinItems=1
+	Rules []TupleRewriteRule `json:"rules"`
+	// DryRun only counts the tuples that would be rewritten and dropped
+	// without writing to the stores.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// TupleMigrationStoreStatus reports the progress of a TupleMigration for a
+// single store.
+type TupleMigrationStoreStatus struct {
+	Cluster string `json:"cluster"`
+	Name    string `json:"name"`
+	// StoreID is the OpenFGA store the progress was made in. The migration of
+	// a Store starts over if it references a different OpenFGA store.
+	StoreID string `json:"storeId"`
+	// ContinuationToken is the token the next page of tuples is read with.
+	// +optional
+	ContinuationToken string `json:"continuationToken,omitempty"`
+	// Scanned is the number of tuples read so far. Tuples rewritten by the
+	// migration may be read and counted again on later pages.
+	// +optional
+	Scanned int `json:"scanned,omitempty"`
+	// Rewritten is the number of tuples rewritten so far.
+	// +optional
+	Rewritten int `json:"rewritten,omitempty"`
+	// Dropped is the number of tuples dropped so far.
+	// +optional
+	Dropped int `json:"dropped,omitempty"`
+	// Skipped is the number of tuples the rules would change but that are
+	// left alone, because a Store or AuthorizationModel manages them.
+	// +optional
+	Skipped int `json:"skipped,omitempty"`
+	// Completed reports whether all tuples of the store were migrated.
+	// +optional
+	Completed bool `json:"completed,omitempty"`
+}
+
+// TupleMigrationStatus defines the observed state of TupleMigration.
+type TupleMigrationStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation the progress was made for. The
+	// migration starts over once the spec changes.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Stores reports the progress per migrated store.
+	// +optional
+	Stores []TupleMigrationStoreStatus `json:"stores,omitempty"`
+	// CompletionTime is the time all stores were migrated at.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Store",type=string,JSONPath=`.spec.storeRef.name`
+// +kubebuilder:printcolumn:name="Dry Run",type=boolean,JSONPath=`.spec.dryRun`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+
+// TupleMigration rewrites the tuples of stores after types or relations of
+// their authorization model were renamed. Tuples declared in Store and
+// AuthorizationModel specs are not migrated and have to be renamed there
+// instead, as their owners would write them again and delete the rewritten
+// ones. They are counted as skipped.
+type TupleMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TupleMigrationSpec   `json:"spec,omitempty"`
+	Status TupleMigrationStatus `json:"status,omitempty"`
+}
+
+// GetConditions implements conditions.ConditionAccessor.
+func (in *TupleMigration) GetConditions() []metav1.Condition {
+	return in.Status.Conditions
+}
+
+// SetConditions implements conditions.ConditionAccessor.
+func (in *TupleMigration) SetConditions(conditions []metav1.Condition) {
+	in.Status.Conditions = conditions
+}
+
+var _ conditions.ConditionAccessor = &TupleMigration{}
+
+// +kubebuilder:object:root=true
+
+// TupleMigrationList contains a list of TupleMigration.
+type TupleMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TupleMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TupleMigration{}, &TupleMigrationList{})
+}
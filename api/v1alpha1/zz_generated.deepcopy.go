@@ -104,6 +104,11 @@ func (in *APIExportPolicyStatus) DeepCopyInto(out *APIExportPolicyStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.LastTupleChange != nil {
+		in, out := &in.LastTupleChange, &out.LastTupleChange
+		*out = new(TupleChangeSummary)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIExportPolicyStatus.
@@ -131,6 +136,127 @@ func (in *APIExportRef) DeepCopy() *APIExportRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessCheck) DeepCopyInto(out *AccessCheck) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessCheck.
+func (in *AccessCheck) DeepCopy() *AccessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessCheck) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessCheckAttributes) DeepCopyInto(out *AccessCheckAttributes) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessCheckAttributes.
+func (in *AccessCheckAttributes) DeepCopy() *AccessCheckAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessCheckAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessCheckList) DeepCopyInto(out *AccessCheckList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccessCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessCheckList.
+func (in *AccessCheckList) DeepCopy() *AccessCheckList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessCheckList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessCheckList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessCheckSpec) DeepCopyInto(out *AccessCheckSpec) {
+	*out = *in
+	out.AccessCheckAttributes = in.AccessCheckAttributes
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessCheckSpec.
+func (in *AccessCheckSpec) DeepCopy() *AccessCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessCheckStatus) DeepCopyInto(out *AccessCheckStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Expansion != nil {
+		in, out := &in.Expansion, &out.Expansion
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessCheckStatus.
+func (in *AccessCheckStatus) DeepCopy() *AccessCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthorizationModel) DeepCopyInto(out *AuthorizationModel) {
 	*out = *in
@@ -158,6 +284,21 @@ func (in *AuthorizationModel) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationModelGeneration) DeepCopyInto(out *AuthorizationModelGeneration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationModelGeneration.
+func (in *AuthorizationModelGeneration) DeepCopy() *AuthorizationModelGeneration {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationModelGeneration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthorizationModelList) DeepCopyInto(out *AuthorizationModelList) {
 	*out = *in
@@ -190,6 +331,27 @@ func (in *AuthorizationModelList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthorizationModelRevision) DeepCopyInto(out *AuthorizationModelRevision) {
+	*out = *in
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]AuthorizationModelGeneration, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationModelRevision.
+func (in *AuthorizationModelRevision) DeepCopy() *AuthorizationModelRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthorizationModelRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthorizationModelSpec) DeepCopyInto(out *AuthorizationModelSpec) {
 	*out = *in
@@ -197,7 +359,28 @@ func (in *AuthorizationModelSpec) DeepCopyInto(out *AuthorizationModelSpec) {
 	if in.Tuples != nil {
 		in, out := &in.Tuples, &out.Tuples
 		*out = make([]Tuple, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TuplesFrom != nil {
+		in, out := &in.TuplesFrom, &out.TuplesFrom
+		*out = make([]TupleSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TupleOwnership != nil {
+		in, out := &in.TupleOwnership, &out.TupleOwnership
+		*out = new(TupleOwnership)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = make([]ModelTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -224,7 +407,34 @@ func (in *AuthorizationModelStatus) DeepCopyInto(out *AuthorizationModelStatus)
 	if in.ManagedTuples != nil {
 		in, out := &in.ManagedTuples, &out.ManagedTuples
 		*out = make([]Tuple, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedTupleSet != nil {
+		in, out := &in.ManagedTupleSet, &out.ManagedTupleSet
+		*out = new(ManagedTupleSet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SharedTuples != nil {
+		in, out := &in.SharedTuples, &out.SharedTuples
+		*out = make([]SharedTuple, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastTupleChange != nil {
+		in, out := &in.LastTupleChange, &out.LastTupleChange
+		*out = new(TupleChangeSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NextTupleExpiry != nil {
+		in, out := &in.NextTupleExpiry, &out.NextTupleExpiry
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftCheckTime != nil {
+		in, out := &in.LastDriftCheckTime, &out.LastDriftCheckTime
+		*out = (*in).DeepCopy()
 	}
 }
 
@@ -238,6 +448,28 @@ func (in *AuthorizationModelStatus) DeepCopy() *AuthorizationModelStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckAssertion) DeepCopyInto(out *CheckAssertion) {
+	*out = *in
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckAssertion.
+func (in *CheckAssertion) DeepCopy() *CheckAssertion {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckAssertion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IdentityProviderClientConfig) DeepCopyInto(out *IdentityProviderClientConfig) {
 	*out = *in
@@ -470,6 +702,37 @@ func (in *InviteStatus) DeepCopy() *InviteStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ListObjectsAssertion) DeepCopyInto(out *ListObjectsAssertion) {
+	*out = *in
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ListObjectsAssertion.
+func (in *ListObjectsAssertion) DeepCopy() *ListObjectsAssertion {
+	if in == nil {
+		return nil
+	}
+	out := new(ListObjectsAssertion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagedClient) DeepCopyInto(out *ManagedClient) {
 	*out = *in
@@ -487,26 +750,45 @@ func (in *ManagedClient) DeepCopy() *ManagedClient {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Store) DeepCopyInto(out *Store) {
+func (in *ManagedTupleSet) DeepCopyInto(out *ManagedTupleSet) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedTupleSet.
+func (in *ManagedTupleSet) DeepCopy() *ManagedTupleSet {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedTupleSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelTemplate) DeepCopyInto(out *ModelTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Store.
-func (in *Store) DeepCopy() *Store {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelTemplate.
+func (in *ModelTemplate) DeepCopy() *ModelTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(Store)
+	out := new(ModelTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Store) DeepCopyObject() runtime.Object {
+func (in *ModelTemplate) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -514,31 +796,31 @@ func (in *Store) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StoreList) DeepCopyInto(out *StoreList) {
+func (in *ModelTemplateList) DeepCopyInto(out *ModelTemplateList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Store, len(*in))
+		*out = make([]ModelTemplate, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreList.
-func (in *StoreList) DeepCopy() *StoreList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelTemplateList.
+func (in *ModelTemplateList) DeepCopy() *ModelTemplateList {
 	if in == nil {
 		return nil
 	}
-	out := new(StoreList)
+	out := new(ModelTemplateList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *StoreList) DeepCopyObject() runtime.Object {
+func (in *ModelTemplateList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -546,63 +828,982 @@ func (in *StoreList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StoreSpec) DeepCopyInto(out *StoreSpec) {
+func (in *ModelTemplateSpec) DeepCopyInto(out *ModelTemplateSpec) {
 	*out = *in
-	if in.Tuples != nil {
-		in, out := &in.Tuples, &out.Tuples
-		*out = make([]Tuple, len(*in))
-		copy(*out, *in)
+	if in.APIExportSelector != nil {
+		in, out := &in.APIExportSelector, &out.APIExportSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSpec.
-func (in *StoreSpec) DeepCopy() *StoreSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelTemplateSpec.
+func (in *ModelTemplateSpec) DeepCopy() *ModelTemplateSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(StoreSpec)
+	out := new(ModelTemplateSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StoreStatus) DeepCopyInto(out *StoreStatus) {
+func (in *ModelTest) DeepCopyInto(out *ModelTest) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+	if in.Tuples != nil {
+		in, out := &in.Tuples, &out.Tuples
+		*out = make([]Tuple, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.ManagedTuples != nil {
-		in, out := &in.ManagedTuples, &out.ManagedTuples
-		*out = make([]Tuple, len(*in))
-		copy(*out, *in)
+	if in.Check != nil {
+		in, out := &in.Check, &out.Check
+		*out = make([]CheckAssertion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ListObjects != nil {
+		in, out := &in.ListObjects, &out.ListObjects
+		*out = make([]ListObjectsAssertion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreStatus.
-func (in *StoreStatus) DeepCopy() *StoreStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelTest.
+func (in *ModelTest) DeepCopy() *ModelTest {
 	if in == nil {
 		return nil
 	}
-	out := new(StoreStatus)
+	out := new(ModelTest)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Tuple) DeepCopyInto(out *Tuple) {
+func (in *RelationRename) DeepCopyInto(out *RelationRename) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tuple.
-func (in *Tuple) DeepCopy() *Tuple {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelationRename.
+func (in *RelationRename) DeepCopy() *RelationRename {
 	if in == nil {
 		return nil
 	}
-	out := new(Tuple)
+	out := new(RelationRename)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelfAccessCheck) DeepCopyInto(out *SelfAccessCheck) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelfAccessCheck.
+func (in *SelfAccessCheck) DeepCopy() *SelfAccessCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SelfAccessCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SelfAccessCheck) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelfAccessCheckList) DeepCopyInto(out *SelfAccessCheckList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SelfAccessCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelfAccessCheckList.
+func (in *SelfAccessCheckList) DeepCopy() *SelfAccessCheckList {
+	if in == nil {
+		return nil
+	}
+	out := new(SelfAccessCheckList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SelfAccessCheckList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SelfAccessCheckSpec) DeepCopyInto(out *SelfAccessCheckSpec) {
+	*out = *in
+	out.AccessCheckAttributes = in.AccessCheckAttributes
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SelfAccessCheckSpec.
+func (in *SelfAccessCheckSpec) DeepCopy() *SelfAccessCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SelfAccessCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedTuple) DeepCopyInto(out *SharedTuple) {
+	*out = *in
+	if in.CoOwners != nil {
+		in, out := &in.CoOwners, &out.CoOwners
+		*out = make([]TupleOwnerReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedTuple.
+func (in *SharedTuple) DeepCopy() *SharedTuple {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedTuple)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Store) DeepCopyInto(out *Store) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Store.
+func (in *Store) DeepCopy() *Store {
+	if in == nil {
+		return nil
+	}
+	out := new(Store)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Store) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreList) DeepCopyInto(out *StoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Store, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreList.
+func (in *StoreList) DeepCopy() *StoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreRestore) DeepCopyInto(out *StoreRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreRestore.
+func (in *StoreRestore) DeepCopy() *StoreRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreRestoreList) DeepCopyInto(out *StoreRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StoreRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreRestoreList.
+func (in *StoreRestoreList) DeepCopy() *StoreRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreRestoreSpec) DeepCopyInto(out *StoreRestoreSpec) {
+	*out = *in
+	if in.DeletePreviousStoreAfter != nil {
+		in, out := &in.DeletePreviousStoreAfter, &out.DeletePreviousStoreAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreRestoreSpec.
+func (in *StoreRestoreSpec) DeepCopy() *StoreRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreRestoreStatus) DeepCopyInto(out *StoreRestoreStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreviousStoreDeletionTime != nil {
+		in, out := &in.PreviousStoreDeletionTime, &out.PreviousStoreDeletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreRestoreStatus.
+func (in *StoreRestoreStatus) DeepCopy() *StoreRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshot) DeepCopyInto(out *StoreSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshot.
+func (in *StoreSnapshot) DeepCopy() *StoreSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotList) DeepCopyInto(out *StoreSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StoreSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotList.
+func (in *StoreSnapshotList) DeepCopy() *StoreSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotSchedule) DeepCopyInto(out *StoreSnapshotSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotSchedule.
+func (in *StoreSnapshotSchedule) DeepCopy() *StoreSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreSnapshotSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotScheduleList) DeepCopyInto(out *StoreSnapshotScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StoreSnapshotSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotScheduleList.
+func (in *StoreSnapshotScheduleList) DeepCopy() *StoreSnapshotScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StoreSnapshotScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotScheduleSpec) DeepCopyInto(out *StoreSnapshotScheduleSpec) {
+	*out = *in
+	out.StoreRef = in.StoreRef
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotScheduleSpec.
+func (in *StoreSnapshotScheduleSpec) DeepCopy() *StoreSnapshotScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotScheduleStatus) DeepCopyInto(out *StoreSnapshotScheduleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSnapshotTime != nil {
+		in, out := &in.LastSnapshotTime, &out.LastSnapshotTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotScheduleStatus.
+func (in *StoreSnapshotScheduleStatus) DeepCopy() *StoreSnapshotScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotSpec) DeepCopyInto(out *StoreSnapshotSpec) {
+	*out = *in
+	out.StoreRef = in.StoreRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotSpec.
+func (in *StoreSnapshotSpec) DeepCopy() *StoreSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSnapshotStatus) DeepCopyInto(out *StoreSnapshotStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSnapshotStatus.
+func (in *StoreSnapshotStatus) DeepCopy() *StoreSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreSpec) DeepCopyInto(out *StoreSpec) {
+	*out = *in
+	if in.Tuples != nil {
+		in, out := &in.Tuples, &out.Tuples
+		*out = make([]Tuple, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TuplesFrom != nil {
+		in, out := &in.TuplesFrom, &out.TuplesFrom
+		*out = make([]TupleSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TupleOwnership != nil {
+		in, out := &in.TupleOwnership, &out.TupleOwnership
+		*out = new(TupleOwnership)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = make([]ModelTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreSpec.
+func (in *StoreSpec) DeepCopy() *StoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoreStatus) DeepCopyInto(out *StoreStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedTuples != nil {
+		in, out := &in.ManagedTuples, &out.ManagedTuples
+		*out = make([]Tuple, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedTupleSet != nil {
+		in, out := &in.ManagedTupleSet, &out.ManagedTupleSet
+		*out = new(ManagedTupleSet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SharedTuples != nil {
+		in, out := &in.SharedTuples, &out.SharedTuples
+		*out = make([]SharedTuple, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastTupleChange != nil {
+		in, out := &in.LastTupleChange, &out.LastTupleChange
+		*out = new(TupleChangeSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthorizationModelHistory != nil {
+		in, out := &in.AuthorizationModelHistory, &out.AuthorizationModelHistory
+		*out = make([]AuthorizationModelRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NextTupleExpiry != nil {
+		in, out := &in.NextTupleExpiry, &out.NextTupleExpiry
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftCheckTime != nil {
+		in, out := &in.LastDriftCheckTime, &out.LastDriftCheckTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoreStatus.
+func (in *StoreStatus) DeepCopy() *StoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tuple) DeepCopyInto(out *Tuple) {
+	*out = *in
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(TupleCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tuple.
+func (in *Tuple) DeepCopy() *Tuple {
+	if in == nil {
+		return nil
+	}
+	out := new(Tuple)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleChangeSummary) DeepCopyInto(out *TupleChangeSummary) {
+	*out = *in
+	in.LastChangeTime.DeepCopyInto(&out.LastChangeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleChangeSummary.
+func (in *TupleChangeSummary) DeepCopy() *TupleChangeSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleChangeSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleCondition) DeepCopyInto(out *TupleCondition) {
+	*out = *in
+	if in.Context != nil {
+		in, out := &in.Context, &out.Context
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleCondition.
+func (in *TupleCondition) DeepCopy() *TupleCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMatch) DeepCopyInto(out *TupleMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMatch.
+func (in *TupleMatch) DeepCopy() *TupleMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMigration) DeepCopyInto(out *TupleMigration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMigration.
+func (in *TupleMigration) DeepCopy() *TupleMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TupleMigration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMigrationList) DeepCopyInto(out *TupleMigrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TupleMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMigrationList.
+func (in *TupleMigrationList) DeepCopy() *TupleMigrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMigrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TupleMigrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMigrationSpec) DeepCopyInto(out *TupleMigrationSpec) {
+	*out = *in
+	if in.StoreRef != nil {
+		in, out := &in.StoreRef, &out.StoreRef
+		*out = new(WorkspaceStoreRef)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]TupleRewriteRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMigrationSpec.
+func (in *TupleMigrationSpec) DeepCopy() *TupleMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMigrationStatus) DeepCopyInto(out *TupleMigrationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Stores != nil {
+		in, out := &in.Stores, &out.Stores
+		*out = make([]TupleMigrationStoreStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMigrationStatus.
+func (in *TupleMigrationStatus) DeepCopy() *TupleMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleMigrationStoreStatus) DeepCopyInto(out *TupleMigrationStoreStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleMigrationStoreStatus.
+func (in *TupleMigrationStoreStatus) DeepCopy() *TupleMigrationStoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleMigrationStoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleOwnerReference) DeepCopyInto(out *TupleOwnerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleOwnerReference.
+func (in *TupleOwnerReference) DeepCopy() *TupleOwnerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleOwnerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleOwnership) DeepCopyInto(out *TupleOwnership) {
+	*out = *in
+	if in.ObjectTypes != nil {
+		in, out := &in.ObjectTypes, &out.ObjectTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Relations != nil {
+		in, out := &in.Relations, &out.Relations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleOwnership.
+func (in *TupleOwnership) DeepCopy() *TupleOwnership {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleOwnership)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleRewriteRule) DeepCopyInto(out *TupleRewriteRule) {
+	*out = *in
+	if in.RenameObjectType != nil {
+		in, out := &in.RenameObjectType, &out.RenameObjectType
+		*out = new(TypeRename)
+		**out = **in
+	}
+	if in.RenameRelation != nil {
+		in, out := &in.RenameRelation, &out.RenameRelation
+		*out = new(RelationRename)
+		**out = **in
+	}
+	if in.RenameUserType != nil {
+		in, out := &in.RenameUserType, &out.RenameUserType
+		*out = new(TypeRename)
+		**out = **in
+	}
+	if in.Drop != nil {
+		in, out := &in.Drop, &out.Drop
+		*out = new(TupleMatch)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleRewriteRule.
+func (in *TupleRewriteRule) DeepCopy() *TupleRewriteRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleRewriteRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleSource) DeepCopyInto(out *TupleSource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(TupleSourceRef)
+		**out = **in
+	}
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(TupleSourceRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleSource.
+func (in *TupleSource) DeepCopy() *TupleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TupleSourceRef) DeepCopyInto(out *TupleSourceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TupleSourceRef.
+func (in *TupleSourceRef) DeepCopy() *TupleSourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(TupleSourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TypeRename) DeepCopyInto(out *TypeRename) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TypeRename.
+func (in *TypeRename) DeepCopy() *TypeRename {
+	if in == nil {
+		return nil
+	}
+	out := new(TypeRename)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/authorizer"
+	"github.com/platform-mesh/security-operator/internal/authzen"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var authorizerCmd = &cobra.Command{
+	Use:   "authorizer",
+	Short: "Webhook authorizer answering SubjectAccessReviews from the org OpenFGA stores",
+	Long: `Webhook authorizer answering SubjectAccessReviews from the org OpenFGA stores.
+
+kcp posts SubjectAccessReviews to the path set with --authorizer-path on the
+webhook server. Each request is checked in the store of the org the
+workspace of the request belongs to, using the relations of the models
+generated for API resources. Requests that are not allowed are answered with
+no opinion.
+
+With --authzen-enabled the OpenID AuthZEN access evaluation and search APIs
+are served over TLS at --authzen-bind-address on top of the same stores.
+Callers authenticate with the bearer token of a kcp service account, which is
+checked with a TokenReview, and may only access the store of the org the
+service account's workspace belongs to.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kcpCfg, err := getKubeconfigFromPath(authorizerCfg.KCP.Kubeconfig)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to get KCP kubeconfig")
+			os.Exit(1)
+		}
+
+		disableHTTP2 := []func(*tls.Config){
+			func(c *tls.Config) {
+				log.Info().Msg("disabling http/2")
+				c.NextProtos = []string{"http/1.1"}
+			},
+		}
+		mgr, err := ctrl.NewManager(kcpCfg, ctrl.Options{
+			Scheme:                 scheme,
+			HealthProbeBindAddress: defaultCfg.HealthProbeBindAddress,
+			Metrics: server.Options{
+				BindAddress: defaultCfg.Metrics.BindAddress,
+				TLSOpts:     disableHTTP2,
+			},
+			WebhookServer: webhook.NewServer(webhook.Options{
+				TLSOpts: disableHTTP2,
+				CertDir: authorizerCfg.Webhooks.CertDir,
+				Port:    authorizerCfg.Webhooks.Port,
+			}),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to create manager")
+			os.Exit(1)
+		}
+
+		conn, err := fga.NewConnection(authorizerCfg.FGA)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create grpc client")
+			os.Exit(1)
+		}
+		defer func() { _ = conn.Close() }()
+		fgaClient := openfgav1.NewOpenFGAServiceClient(conn)
+		storeIDGetter, err := newStoreIDGetter(cmd.Context(), mgr, kcpCfg, fgaClient, authorizerCfg.FGA.StoreIDCacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create store ID getter")
+			os.Exit(1)
+		}
+		kcpClientGetter := iclient.NewConfigSchemeKCPClientGetter(kcpCfg, scheme)
+		accounts := fga.NewEntityResolver(authorizerCfg.FGA.ObjectType, clusterIDResolver(kcpClientGetter))
+
+		if authorizerCfg.AuthZEN.Enabled {
+			authenticator := authzen.NewTokenReviewAuthenticator(mgr.GetClient(), orgResolver(kcpClientGetter),
+				authorizerCfg.AuthZEN.TokenAudiences, authorizerCfg.AuthZEN.TokenCacheTTL)
+			authzenServer := authzen.NewServer(authorizerCfg.AuthZEN, fgaClient, storeIDGetter,
+				clusterIDResolver(kcpClientGetter), authenticator, authorizerCfg.FGA.ObjectType, log)
+			if err := mgr.Add(authzenServer); err != nil {
+				log.Error().Err(err).Msg("unable to add AuthZEN server to manager")
+				os.Exit(1)
+			}
+		}
+
+		mgr.GetWebhookServer().Register(authorizerCfg.Authorizer.Path,
+			authorizer.New(fgaClient, storeIDGetter, kcpClientGetter, accounts, authorizerCfg.Authorizer.DecisionCacheTTL, log))
+
+		if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+			log.Error().Err(err).Msg("unable to set up health check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("readyz", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			log.Error().Err(err).Msg("unable to set up ready check")
+			os.Exit(1)
+		}
+
+		setupLog.Info("starting manager")
+
+		return mgr.Start(ctrl.SetupSignalHandler())
+	},
+}
+
+// orgResolver looks up the org of a workspace through its AccountInfo, read
+// with a client of the given getter.
+func orgResolver(clients iclient.KCPClientGetter) authzen.OrgResolver {
+	return func(ctx context.Context, clusterName string) (string, error) {
+		cl, err := clients.NewClientForLogicalCluster(ctx, clusterName)
+		if err != nil {
+			return "", err
+		}
+
+		var accountInfo accountsv1alpha1.AccountInfo
+		if err := cl.Get(ctx, client.ObjectKey{Name: "account"}, &accountInfo); err != nil {
+			return "", err
+		}
+		if accountInfo.Spec.Organization.Name == "" {
+			return "", fmt.Errorf("organization name is empty in AccountInfo")
+		}
+		return accountInfo.Spec.Organization.Name, nil
+	}
+}
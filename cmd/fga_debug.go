@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	platformmeshpath "github.com/platform-mesh/security-operator/internal/platformmesh"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	kcpcorev1alpha1 "github.com/kcp-dev/sdk/apis/core/v1alpha1"
+)
+
+var (
+	fgaDebugCfg = config.NewConfig()
+	fgaDebugOrg string
+)
+
+// fgaDebugCmd bundles commands to query OpenFGA the way the operator writes
+// to it. The name "fga" is already taken by the operator command.
+var fgaDebugCmd = &cobra.Command{
+	Use:   "fga-debug",
+	Short: "Query OpenFGA stores using platform-mesh account paths",
+	Long: `Query OpenFGA stores using platform-mesh account paths.
+
+The store is looked up by the name of the organization given with --org.
+Entities can reference accounts by their kcp workspace path:
+
+  account:root:orgs:acme:team          the account "team" in org "acme"
+  role:root:orgs:acme:team/owner       the owner role of that account
+  role:root:orgs:acme:team/owner#assignee
+
+All other entities are passed to OpenFGA as they are.`,
+}
+
+var fgaCheckCmd = &cobra.Command{
+	Use:   "check <user> <relation> <object>",
+	Short: "Check whether a user has a relation to an object",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFGADebug(cmd, args, func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error) {
+			return d.fga.Check(ctx, &openfgav1.CheckRequest{
+				StoreId: d.storeID,
+				TupleKey: &openfgav1.CheckRequestTupleKey{
+					User:     args[0],
+					Relation: args[1],
+					Object:   args[2],
+				},
+			})
+		})
+	},
+}
+
+var fgaExpandCmd = &cobra.Command{
+	Use:   "expand <relation> <object>",
+	Short: "Expand the userset of a relation of an object",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFGADebug(cmd, args, func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error) {
+			return d.fga.Expand(ctx, &openfgav1.ExpandRequest{
+				StoreId: d.storeID,
+				TupleKey: &openfgav1.ExpandRequestTupleKey{
+					Relation: args[0],
+					Object:   args[1],
+				},
+			})
+		})
+	},
+}
+
+var fgaListObjectsCmd = &cobra.Command{
+	Use:   "list-objects <user> <relation> <type>",
+	Short: "List the objects of a type a user has a relation to",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFGADebug(cmd, args, func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error) {
+			return d.fga.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:  d.storeID,
+				User:     args[0],
+				Relation: args[1],
+				Type:     args[2],
+			})
+		})
+	},
+}
+
+var fgaListUsersCmd = &cobra.Command{
+	Use:   "list-users <object> <relation> <user-type>",
+	Short: "List the users of a type that have a relation to an object",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFGADebug(cmd, args, func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error) {
+			objectType, objectID, found := strings.Cut(args[0], ":")
+			if !found {
+				return nil, fmt.Errorf("object %q is not of the form <type>:<id>", args[0])
+			}
+			userType, userRelation, _ := strings.Cut(args[2], "#")
+			return d.fga.ListUsers(ctx, &openfgav1.ListUsersRequest{
+				StoreId:  d.storeID,
+				Object:   &openfgav1.Object{Type: objectType, Id: objectID},
+				Relation: args[1],
+				UserFilters: []*openfgav1.UserTypeFilter{
+					{Type: userType, Relation: userRelation},
+				},
+			})
+		})
+	},
+}
+
+var fgaReadCmd = &cobra.Command{
+	Use:   "read [object] [relation] [user]",
+	Short: "Read the tuples matching the given object, relation and user",
+	Args:  cobra.MaximumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFGADebug(cmd, args, func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error) {
+			key := &openfgav1.ReadRequestTupleKey{}
+			for i, field := range []*string{&key.Object, &key.Relation, &key.User} {
+				if i < len(args) {
+					*field = args[i]
+				}
+			}
+			if key.Object == "" && key.Relation == "" && key.User == "" {
+				key = nil
+			}
+
+			tuples, err := fga.NewTupleManager(d.fga, d.storeID, fga.AuthorizationModelIDLatest, log).ListWithKey(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range tuples {
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\n", t.Object, t.Relation, t.User); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		})
+	},
+}
+
+func init() {
+	fgaDebugCfg.FGA.AddConnectionFlags(fgaDebugCmd.PersistentFlags())
+	fgaDebugCmd.PersistentFlags().StringVar(&fgaDebugCfg.FGA.ObjectType, "fga-object-type", fgaDebugCfg.FGA.ObjectType, "Set the OpenFGA object type for account tuples")
+	fgaDebugCmd.PersistentFlags().StringVar(&fgaDebugCfg.KCP.Kubeconfig, "kcp-kubeconfig", fgaDebugCfg.KCP.Kubeconfig, "Set the KCP kubeconfig path, required to resolve account paths")
+	fgaDebugCmd.PersistentFlags().StringVar(&fgaDebugOrg, "org", fgaDebugOrg, "Organization whose store is queried, either as name or account path")
+
+	fgaDebugCmd.AddCommand(fgaCheckCmd, fgaExpandCmd, fgaListObjectsCmd, fgaListUsersCmd, fgaReadCmd)
+}
+
+// fgaDebugger holds what the fga-debug commands need to talk to a store.
+type fgaDebugger struct {
+	fga     openfgav1.OpenFGAServiceClient
+	storeID string
+}
+
+// runFGADebug resolves the store and the account paths in args before running
+// the given query and printing its response as JSON.
+func runFGADebug(cmd *cobra.Command, args []string, query func(ctx context.Context, d *fgaDebugger, args []string) (proto.Message, error)) error {
+	ctx := cmd.Context()
+
+	storeName, err := fgaDebugStoreName(fgaDebugOrg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := fga.NewConnection(fgaDebugCfg.FGA)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	fgaClient := openfgav1.NewOpenFGAServiceClient(conn)
+
+	storeID, err := fga.NewCachingStoreIDGetter(fgaClient, fgaDebugCfg.FGA.StoreIDCacheTTL, ctx, log).Get(ctx, storeName)
+	if err != nil {
+		return fmt.Errorf("getting store ID: %w", err)
+	}
+
+	resolver := fga.NewEntityResolver(fgaDebugCfg.FGA.ObjectType, kcpClusterIDResolver(fgaDebugCfg.KCP.Kubeconfig))
+	resolved := make([]string, 0, len(args))
+	for _, arg := range args {
+		entity, err := resolver.Resolve(ctx, arg)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", arg, err)
+		}
+		resolved = append(resolved, entity)
+	}
+
+	resp, err := query(ctx, &fgaDebugger{fga: fgaClient, storeID: storeID}, resolved)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+
+	out, err := protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return err
+}
+
+// fgaDebugStoreName returns the store name of an organization given either by
+// its name or by an account path within it.
+func fgaDebugStoreName(org string) (string, error) {
+	if org == "" {
+		return "", fmt.Errorf("missing value for required flag --org")
+	}
+	if !strings.Contains(org, ":") {
+		return org, nil
+	}
+
+	accountPath, err := platformmeshpath.NewAccountPath(org)
+	if err != nil {
+		return "", err
+	}
+	return accountPath.Org().Base(), nil
+}
+
+// kcpClusterIDResolver looks up the cluster ID of a workspace through its
+// LogicalCluster. The kubeconfig is only loaded once a path is resolved.
+func kcpClusterIDResolver(kubeconfigPath string) fga.ClusterIDResolver {
+	return func(ctx context.Context, path logicalcluster.Path) (string, error) {
+		restCfg, err := getKubeconfigFromPath(kubeconfigPath)
+		if err != nil {
+			return "", err
+		}
+		return clusterIDResolver(iclient.NewConfigSchemeKCPClientGetter(restCfg, scheme))(ctx, path)
+	}
+}
+
+// clusterIDResolver looks up the cluster ID of a workspace through its
+// LogicalCluster, read with a client of the given getter.
+func clusterIDResolver(clients iclient.KCPClientGetter) fga.ClusterIDResolver {
+	return func(ctx context.Context, path logicalcluster.Path) (string, error) {
+		cl, err := clients.NewClientForLogicalCluster(ctx, path.String())
+		if err != nil {
+			return "", err
+		}
+
+		var lc kcpcorev1alpha1.LogicalCluster
+		if err := cl.Get(ctx, client.ObjectKey{Name: "cluster"}, &lc); err != nil {
+			return "", err
+		}
+
+		clusterID, ok := lc.Annotations["kcp.io/cluster"]
+		if !ok || clusterID == "" {
+			return "", fmt.Errorf("cluster-annotation kcp.io/cluster on LogicalCluster is not set")
+		}
+		return clusterID, nil
+	}
+}
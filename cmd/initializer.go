@@ -12,8 +12,6 @@ import (
 	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/security-operator/internal/predicates"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -72,6 +70,8 @@ var initializerCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		dryRunOpts := setupDryRun(initializerCfg, &mgrOpts)
+
 		mgr, err := mcmanager.New(restCfg, provider, mgrOpts)
 		if err != nil {
 			setupLog.Error(err, "Failed to create manager")
@@ -108,19 +108,18 @@ var initializerCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		conn, err := grpc.NewClient(initializerCfg.FGA.Target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := fga.NewConnection(initializerCfg.FGA, dryRunOpts...)
 		if err != nil {
 			log.Error().Err(err).Msg("unable to create grpc client")
 			return err
 		}
 		defer func() { _ = conn.Close() }()
 		fgaClient := openfgav1.NewOpenFGAServiceClient(conn)
-		storeIDGetter := fga.NewCachingStoreIDGetter(
-			fgaClient,
-			initializerCfg.FGA.StoreIDCacheTTL,
-			cmd.Context(),
-			log,
-		)
+		storeIDGetter, err := newStoreIDGetter(cmd.Context(), mgr.GetLocalManager(), restCfg, fgaClient, initializerCfg.FGA.StoreIDCacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create store ID getter")
+			return err
+		}
 
 		alcReconciler, err := controller.NewAccountLogicalClusterController(log, initializerCfg, fgaClient, storeIDGetter, mgr, kcpClientGetter, controller.ControllerOptions{
 			Name:            "AccountLogicalClusterInitializer",
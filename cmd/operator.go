@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"os"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
@@ -14,10 +16,9 @@ import (
 	"github.com/platform-mesh/security-operator/internal/controller"
 	fga2 "github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/security-operator/internal/predicates"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
 	internalwebhook "github.com/platform-mesh/security-operator/internal/webhook"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -45,6 +46,10 @@ var (
 	scheme = runtime.NewScheme()
 )
 
+// accessCheckClusterIDTTL is how long the cluster IDs of workspace paths
+// referenced by access checks are remembered.
+const accessCheckClusterIDTTL = 10 * time.Minute
+
 var operatorCmd = &cobra.Command{
 	Use: "fga",
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -121,13 +126,15 @@ var operatorCmd = &cobra.Command{
 			return err
 		}
 
+		dryRunOpts := setupDryRun(operatorCfg, &mgrOpts)
+
 		mgr, err := mcmanager.New(restCfg, provider, mgrOpts)
 		if err != nil {
 			setupLog.Error(err, "Failed to create manager")
 			return err
 		}
 
-		conn, err := grpc.NewClient(operatorCfg.FGA.Target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := fga2.NewConnection(operatorCfg.FGA, dryRunOpts...)
 		if err != nil {
 			log.Error().Err(err).Msg("unable to create grpc client")
 			return err
@@ -135,12 +142,11 @@ var operatorCmd = &cobra.Command{
 		defer func() { _ = conn.Close() }()
 
 		fga := openfgav1.NewOpenFGAServiceClient(conn)
-		storeIDGetter := fga2.NewCachingStoreIDGetter(
-			fga,
-			operatorCfg.FGA.StoreIDCacheTTL,
-			ctx,
-			log,
-		)
+		storeIDGetter, err := newStoreIDGetter(ctx, mgr.GetLocalManager(), restCfg, fga, operatorCfg.FGA.StoreIDCacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create store ID getter")
+			return err
+		}
 
 		k8sCfg := ctrl.GetConfigOrDie()
 
@@ -157,15 +163,61 @@ var operatorCmd = &cobra.Command{
 			return err
 		}
 		if err = controller.
-			NewAuthorizationModelReconciler(log, fga, mgr).
+			NewAuthorizationModelReconciler(log, fga, mgr, &operatorCfg, providerLister).
 			SetupWithManager(mgr, defaultCfg); err != nil {
 			log.Error().Err(err).Str("controller", "authorizationmodel").Msg("unable to create controller")
 			return err
 		}
 
+		if err = controller.
+			NewStoreSnapshotReconciler(log, fga, mgr, runtimeClient, &operatorCfg).
+			SetupWithManager(mgr, defaultCfg); err != nil {
+			log.Error().Err(err).Str("controller", "storesnapshot").Msg("unable to create controller")
+			return err
+		}
+		if err = controller.NewStoreSnapshotScheduleReconciler(log, mgr).SetupWithManager(mgr, defaultCfg); err != nil {
+			log.Error().Err(err).Str("controller", "storesnapshotschedule").Msg("unable to create controller")
+			return err
+		}
+		if err = controller.
+			NewStoreRestoreReconciler(log, fga, mgr, runtimeClient, &operatorCfg).
+			SetupWithManager(mgr, defaultCfg); err != nil {
+			log.Error().Err(err).Str("controller", "storerestore").Msg("unable to create controller")
+			return err
+		}
+		if err = controller.
+			NewTupleMigrationReconciler(log, fga, mgr, &operatorCfg, providerLister).
+			SetupWithManager(mgr, defaultCfg); err != nil {
+			log.Error().Err(err).Str("controller", "tuplemigration").Msg("unable to create controller")
+			return err
+		}
+
 		kcpClientGetter := iclient.NewManagerKCPClientGetter(mgr, provider.Provider.Provider)
 		kcpClientGetterWithConfig := iclient.NewConfigSchemeKCPClientGetter(restCfg, scheme)
 
+		var accessCheckSigningKey []byte
+		if operatorCfg.AccessCheck.SigningKeyFile != "" {
+			if accessCheckSigningKey, err = os.ReadFile(operatorCfg.AccessCheck.SigningKeyFile); err != nil {
+				log.Error().Err(err).Msg("unable to read access check signing key")
+				return err
+			}
+		}
+		accessChecks := subroutine.NewAccessCheckSubroutine(fga, storeIDGetter, kcpClientGetterWithConfig,
+			fga2.NewEntityResolver(operatorCfg.FGA.ObjectType, fga2.NewCachingClusterIDResolver(clusterIDResolver(kcpClientGetterWithConfig), accessCheckClusterIDTTL)),
+			accessCheckSigningKey, operatorCfg.AccessCheck)
+		if err = controller.NewAccessCheckReconciler(log, mgr, &operatorCfg, accessChecks).SetupWithManager(mgr, defaultCfg); err != nil {
+			log.Error().Err(err).Str("controller", "accesscheck").Msg("unable to create controller")
+			return err
+		}
+		// The user of a SelfAccessCheck is recorded and signed on admission, so
+		// they are only answered with webhooks enabled and a signing key.
+		if operatorCfg.Webhooks.Enabled && len(accessCheckSigningKey) > 0 {
+			if err = controller.NewSelfAccessCheckReconciler(log, mgr, &operatorCfg, accessChecks).SetupWithManager(mgr, defaultCfg); err != nil {
+				log.Error().Err(err).Str("controller", "selfaccesscheck").Msg("unable to create controller")
+				return err
+			}
+		}
+
 		inviteReconciler, err := controller.NewInviteReconciler(ctx, mgr, &operatorCfg, log, kcpClientGetter)
 		if err != nil {
 			log.Error().Err(err).Str("controller", "invite").Msg("unable to create reconciler")
@@ -215,6 +267,20 @@ var operatorCmd = &cobra.Command{
 				log.Error().Err(err).Str("webhook", "IdentityProviderConfiguration").Msg("unable to create webhook")
 				return err
 			}
+			if err := internalwebhook.SetupAuthorizationModelValidatingWebhooksWithManager(mgr, providerLister); err != nil {
+				log.Error().Err(err).Str("webhook", "AuthorizationModel").Msg("unable to create webhook")
+				return err
+			}
+			if len(accessCheckSigningKey) > 0 {
+				if err := internalwebhook.SetupSelfAccessCheckDefaultingWebhookWithManager(mgr.GetLocalManager(), accessCheckSigningKey); err != nil {
+					log.Error().Err(err).Str("webhook", "SelfAccessCheck").Msg("unable to create webhook")
+					return err
+				}
+			}
+			if err := internalwebhook.SetupModelTemplateValidatingWebhookWithManager(mgr.GetLocalManager()); err != nil {
+				log.Error().Err(err).Str("webhook", "ModelTemplate").Msg("unable to create webhook")
+				return err
+			}
 		}
 		// +kubebuilder:scaffold:builder
 
@@ -1,14 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/go-logr/logr"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	platformeshconfig "github.com/platform-mesh/golang-commons/config"
 	"github.com/platform-mesh/golang-commons/logger"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -21,6 +31,7 @@ var (
 	operatorCfg    config.Config
 	generatorCfg   config.Config
 	systemCfg      config.Config
+	authorizerCfg  config.Config
 	log            *logger.Logger
 	setupLog       logr.Logger
 )
@@ -36,6 +47,9 @@ func init() {
 	rootCmd.AddCommand(modelGeneratorCmd)
 	rootCmd.AddCommand(initContainerCmd)
 	rootCmd.AddCommand(systemCmd)
+	rootCmd.AddCommand(fgaDebugCmd)
+	rootCmd.AddCommand(storeCmd)
+	rootCmd.AddCommand(authorizerCmd)
 
 	defaultCfg = platformeshconfig.NewDefaultConfig()
 	operatorCfg = config.NewConfig()
@@ -43,6 +57,7 @@ func init() {
 	initializerCfg = config.NewConfig()
 	terminatorCfg = config.NewConfig()
 	systemCfg = config.NewConfig()
+	authorizerCfg = config.NewConfig()
 	initContainerCfg = config.NewInitContainerConfig()
 
 	defaultCfg.AddFlags(rootCmd.PersistentFlags())
@@ -51,6 +66,7 @@ func init() {
 	initializerCfg.AddFlags(initializerCmd.Flags())
 	terminatorCfg.AddFlags(terminatorCmd.Flags())
 	systemCfg.AddFlags(systemCmd.Flags())
+	authorizerCfg.AddFlags(authorizerCmd.Flags())
 	initContainerCfg.AddFlags(initContainerCmd.Flags())
 
 	cobra.OnInitialize(initLog)
@@ -71,6 +87,46 @@ func getKubeconfigFromPath(kubeconfigPath string) (*rest.Config, error) {
 	return restCfg, nil
 }
 
+// newStoreIDGetter resolves store IDs from the Stores in the orgs workspace,
+// watched by a cache that is started with the manager, and falls back to
+// listing the stores in OpenFGA.
+func newStoreIDGetter(ctx context.Context, mgr manager.Manager, kcpCfg *rest.Config, fgaClient openfgav1.OpenFGAServiceClient, ttl time.Duration) (fga.StoreIDGetter, error) {
+	orgsCache, err := iclient.NewCacheForLogicalCluster(kcpCfg, scheme, config.OrgsClusterPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating orgs cache: %w", err)
+	}
+	if err := mgr.Add(orgsCache); err != nil {
+		return nil, fmt.Errorf("adding orgs cache to manager: %w", err)
+	}
+
+	return fga.NewStoreStatusIDGetter(orgsCache, fga.NewCachingStoreIDGetter(fgaClient, ttl, ctx, log)), nil
+}
+
+// setupDryRun enables dry-run mode if cfg asks for it. The report of skipped
+// mutations is served by the metrics server and requests to OpenFGA are
+// recorded by the returned dial options. The leader-election ID gets its own
+// suffix, so a dry-run instance runs next to the operator instead of taking
+// turns with it.
+func setupDryRun(cfg config.Config, mgrOpts *manager.Options) []grpc.DialOption {
+	if !cfg.DryRun {
+		return nil
+	}
+
+	recorder := dryrun.NewRecorder(dryrun.DefaultReportSize)
+	dryrun.Enable(recorder)
+	if mgrOpts.Metrics.ExtraHandlers == nil {
+		mgrOpts.Metrics.ExtraHandlers = map[string]http.Handler{}
+	}
+	mgrOpts.Metrics.ExtraHandlers[dryrun.ReportPath] = recorder
+	if mgrOpts.LeaderElectionID != "" {
+		mgrOpts.LeaderElectionID += dryrun.LeaderElectionIDSuffix
+	}
+
+	log.Warn().Str("report", dryrun.ReportPath).Str("leaderElectionID", mgrOpts.LeaderElectionID).
+		Msg("Dry-run mode is enabled, changes to OpenFGA, Keycloak and Secrets are only recorded")
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(recorder.UnaryClientInterceptor)}
+}
+
 func initLog() { // coverage-ignore
 	logcfg := logger.DefaultConfig()
 	logcfg.Level = defaultCfg.Log.Level
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	storeCfg          = config.NewConfig()
+	storeArchiveFile  string
+	storeImportName   string
+	storeImportForce  bool
+	storeExportAsJSON bool
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Export and import OpenFGA stores of Store resources",
+}
+
+var storeExportCmd = &cobra.Command{
+	Use:   "export <store>",
+	Short: "Export the authorization model and tuples of a Store into an archive",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		return withStoreClients(ctx, func(fgaClient openfgav1.OpenFGAServiceClient, orgsClient client.Client) error {
+			var store corev1alpha1.Store
+			if err := orgsClient.Get(ctx, client.ObjectKey{Name: args[0]}, &store); err != nil {
+				return fmt.Errorf("getting Store %s: %w", args[0], err)
+			}
+			if store.Status.StoreID == "" || store.Status.AuthorizationModelID == "" {
+				return fmt.Errorf("store %s has no OpenFGA store or authorization model yet", store.Name)
+			}
+
+			archive, err := fga.ExportStore(ctx, fgaClient, store.Name, store.Status.StoreID, store.Status.AuthorizationModelID, log)
+			if err != nil {
+				return err
+			}
+
+			var out []byte
+			if storeExportAsJSON {
+				out, err = json.MarshalIndent(archive, "", "  ")
+			} else {
+				out, err = yaml.Marshal(archive)
+			}
+			if err != nil {
+				return fmt.Errorf("marshalling archive: %w", err)
+			}
+
+			if storeArchiveFile == "" || storeArchiveFile == "-" {
+				_, err = cmd.OutOrStdout().Write(out)
+				return err
+			}
+			return os.WriteFile(storeArchiveFile, out, 0o600)
+		})
+	},
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Import an archive into a new OpenFGA store and adopt it for its Store",
+	Long: `Import an archive into a new OpenFGA store and adopt it for its Store.
+
+The store ID and authorization model ID of the Store resource are updated, so
+the operator continues to reconcile the restored store. A Store that already
+references an OpenFGA store is only updated with --force, in which case the
+previous OpenFGA store is deleted after the import succeeded.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		raw, err := os.ReadFile(filepath.Clean(args[0]))
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		var archive fga.StoreArchive
+		if err := yaml.Unmarshal(raw, &archive); err != nil {
+			return fmt.Errorf("parsing archive: %w", err)
+		}
+
+		name := archive.Store
+		if storeImportName != "" {
+			name = storeImportName
+		}
+
+		return withStoreClients(ctx, func(fgaClient openfgav1.OpenFGAServiceClient, orgsClient client.Client) error {
+			var store corev1alpha1.Store
+			if err := orgsClient.Get(ctx, client.ObjectKey{Name: name}, &store); err != nil {
+				return fmt.Errorf("getting Store %s: %w", name, err)
+			}
+			if store.Spec.StoreID != "" {
+				return fmt.Errorf("store %s adopts OpenFGA store %s and cannot be imported into a new store", name, store.Spec.StoreID)
+			}
+			previousStoreID := store.Status.StoreID
+			if previousStoreID != "" && !storeImportForce {
+				return fmt.Errorf("store %s already references OpenFGA store %s, use --force to replace it", name, previousStoreID)
+			}
+
+			storeID, modelID, err := fga.ImportStore(ctx, fgaClient, &archive, store.OpenFGAStoreName(), storeCfg.FGA.WriteChunkSize, log)
+			if err != nil {
+				return err
+			}
+			log.Info().Str("store", name).Str("storeID", storeID).Int("tuples", len(archive.Tuples)).Msg("Imported store")
+
+			store.Status.StoreID = storeID
+			store.Status.AuthorizationModelID = modelID
+			store.Status.AuthorizationModelHistory = []corev1alpha1.AuthorizationModelRevision{{ID: modelID, CreatedAt: metav1.Now()}}
+			if err := orgsClient.Status().Update(ctx, &store); err != nil {
+				return fmt.Errorf("updating status of Store %s to OpenFGA store %s: %w", name, storeID, err)
+			}
+
+			if previousStoreID != "" {
+				if _, err := fgaClient.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: previousStoreID}); err != nil {
+					return fmt.Errorf("deleting previous OpenFGA store %s: %w", previousStoreID, err)
+				}
+				log.Info().Str("store", name).Str("storeID", previousStoreID).Msg("Deleted previous store")
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	storeCfg.FGA.AddConnectionFlags(storeCmd.PersistentFlags())
+	storeCmd.PersistentFlags().StringVar(&storeCfg.KCP.Kubeconfig, "kcp-kubeconfig", storeCfg.KCP.Kubeconfig, "Set the KCP kubeconfig path")
+
+	storeExportCmd.Flags().StringVarP(&storeArchiveFile, "output", "o", storeArchiveFile, "File to write the archive to, defaults to stdout")
+	storeExportCmd.Flags().BoolVar(&storeExportAsJSON, "json", storeExportAsJSON, "Write the archive as JSON instead of YAML")
+
+	storeImportCmd.Flags().StringVar(&storeImportName, "store", storeImportName, "Name of the Store to import into, defaults to the store of the archive")
+	storeImportCmd.Flags().BoolVar(&storeImportForce, "force", storeImportForce, "Replace the OpenFGA store a Store already references")
+	storeImportCmd.Flags().IntVar(&storeCfg.FGA.WriteChunkSize, "fga-write-chunk-size", storeCfg.FGA.WriteChunkSize, "Set the maximum number of tuples per OpenFGA write request")
+
+	storeCmd.AddCommand(storeExportCmd, storeImportCmd)
+}
+
+// withStoreClients runs fn with an OpenFGA client and a client for the
+// workspace the Store resources live in.
+func withStoreClients(ctx context.Context, fn func(fgaClient openfgav1.OpenFGAServiceClient, orgsClient client.Client) error) error {
+	restCfg, err := getKubeconfigFromPath(storeCfg.KCP.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	orgsClient, err := iclient.NewConfigSchemeKCPClientGetter(restCfg, scheme).NewClientForLogicalCluster(ctx, config.OrgsClusterPath)
+	if err != nil {
+		return fmt.Errorf("getting client for %s: %w", config.OrgsClusterPath, err)
+	}
+
+	conn, err := fga.NewConnection(storeCfg.FGA)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	return fn(openfgav1.NewOpenFGAServiceClient(conn), orgsClient)
+}
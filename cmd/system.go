@@ -11,8 +11,6 @@ import (
 	"github.com/platform-mesh/security-operator/internal/controller"
 	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -89,13 +87,15 @@ var systemCmd = &cobra.Command{
 			return err
 		}
 
+		dryRunOpts := setupDryRun(systemCfg, &opts)
+
 		mgr, err := mcmanager.New(restCfg, multiProv, opts)
 		if err != nil {
 			setupLog.Error(err, "unable to create manager")
 			return err
 		}
 
-		conn, err := grpc.NewClient(systemCfg.FGA.Target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := fga.NewConnection(systemCfg.FGA, dryRunOpts...)
 		if err != nil {
 			log.Error().Err(err).Msg("unable to create grpc client")
 			return err
@@ -103,12 +103,11 @@ var systemCmd = &cobra.Command{
 
 		fgaClient := openfgav1.NewOpenFGAServiceClient(conn)
 
-		storeIDGetter := fga.NewCachingStoreIDGetter(
-			fgaClient,
-			systemCfg.FGA.StoreIDCacheTTL,
-			ctx,
-			log,
-		)
+		storeIDGetter, err := newStoreIDGetter(ctx, mgr.GetLocalManager(), restCfg, fgaClient, systemCfg.FGA.StoreIDCacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create store ID getter")
+			return err
+		}
 
 		kcpClientGetter := iclient.NewManagerKCPClientGetter(mgr, coreProvider.Provider.Provider)
 
@@ -11,8 +11,6 @@ import (
 	"github.com/platform-mesh/security-operator/internal/predicates"
 	"github.com/platform-mesh/security-operator/internal/terminatingworkspaces"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -66,25 +64,26 @@ var terminatorCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		dryRunOpts := setupDryRun(terminatorCfg, &mgrOpts)
+
 		mgr, err := mcmanager.New(kcpCfg, provider, mgrOpts)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to create manager")
 			os.Exit(1)
 		}
 
-		conn, err := grpc.NewClient(terminatorCfg.FGA.Target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := fga.NewConnection(terminatorCfg.FGA, dryRunOpts...)
 		if err != nil {
 			log.Error().Err(err).Msg("unable to create grpc client")
 			os.Exit(1)
 		}
 		defer func() { _ = conn.Close() }()
 		fgaClient := openfgav1.NewOpenFGAServiceClient(conn)
-		storeIDGetter := fga.NewCachingStoreIDGetter(
-			fgaClient,
-			terminatorCfg.FGA.StoreIDCacheTTL,
-			cmd.Context(),
-			log,
-		)
+		storeIDGetter, err := newStoreIDGetter(cmd.Context(), mgr.GetLocalManager(), kcpCfg, fgaClient, terminatorCfg.FGA.StoreIDCacheTTL)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create store ID getter")
+			os.Exit(1)
+		}
 		kcpClientGetter := iclient.NewConfigSchemeKCPClientGetter(mgr.GetLocalManager().GetConfig(), mgr.GetLocalManager().GetScheme())
 
 		alcReconciler, err := controller.NewAccountLogicalClusterController(log, terminatorCfg, fgaClient, storeIDGetter, mgr, kcpClientGetter, controller.ControllerOptions{
@@ -0,0 +1,17 @@
+// Command embeddedfga is the security-operator with an OpenFGA server
+// embedded for local development. It is a module of its own, so the
+// operator does not depend on OpenFGA. Set --fga-target=embedded to use it.
+package main
+
+import (
+	"github.com/platform-mesh/security-operator/cmd"
+	"github.com/platform-mesh/security-operator/internal/fga"
+)
+
+func init() {
+	fga.RegisterEmbeddedConnector(connect)
+}
+
+func main() {
+	cmd.Execute()
+}
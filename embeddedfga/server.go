@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	language "github.com/openfga/language/pkg/go/transformer"
+	fgaserver "github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// seedStoreName is the name of the store a seed module is written to.
+	seedStoreName = "seed"
+
+	bufferSize = 1024 * 1024
+)
+
+// server is an OpenFGA server running in the process with an in-memory
+// datastore, for local development and tests. It lives in its own module, so
+// the operator does not depend on OpenFGA.
+type server struct {
+	openfga  *fgaserver.Server
+	grpc     *grpc.Server
+	listener *bufconn.Listener
+}
+
+// newServer starts an embedded OpenFGA server.
+func newServer() (*server, error) {
+	openfga, err := fgaserver.NewServerWithOpts(fgaserver.WithDatastore(memory.New()))
+	if err != nil {
+		return nil, fmt.Errorf("creating embedded OpenFGA server: %w", err)
+	}
+
+	s := &server{
+		openfga:  openfga,
+		grpc:     grpc.NewServer(),
+		listener: bufconn.Listen(bufferSize),
+	}
+	openfgav1.RegisterOpenFGAServiceServer(s.grpc, openfga)
+	go func() { _ = s.grpc.Serve(s.listener) }()
+	return s, nil
+}
+
+// listen serves the API on the given TCP address as well, so other
+// processes, e.g. the initializer and terminator, can share the server. It
+// returns the address the server listens on.
+func (s *server) listen(address string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", address, err)
+	}
+	go func() { _ = s.grpc.Serve(listener) }()
+	return listener.Addr(), nil
+}
+
+// dial returns a connection to the server. The given dial options are
+// applied after the ones connecting to it.
+func (s *server) dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOpts := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	conn, err := grpc.NewClient("passthrough:///"+fga.EmbeddedTarget, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating grpc client: %w", err)
+	}
+	return conn, nil
+}
+
+// seed creates a store with the given name and writes the FGA module file
+// at path as its authorization model.
+func (s *server) seed(ctx context.Context, storeName, path string) (storeID, modelID string, err error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading seed module: %w", err)
+	}
+	model, err := language.TransformModuleFilesToModel([]language.ModuleFile{{
+		Name:     filepath.Base(path),
+		Contents: string(contents),
+	}}, "1.2")
+	if err != nil {
+		return "", "", fmt.Errorf("transforming seed module %s: %w", path, err)
+	}
+
+	store, err := s.openfga.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: storeName})
+	if err != nil {
+		return "", "", fmt.Errorf("creating store %s: %w", storeName, err)
+	}
+	res, err := s.openfga.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         store.GetId(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+		SchemaVersion:   model.GetSchemaVersion(),
+		Conditions:      model.GetConditions(),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("writing seed module to store %s: %w", storeName, err)
+	}
+	return store.GetId(), res.GetAuthorizationModelId(), nil
+}
+
+// close stops the server and drops all its data.
+func (s *server) close() {
+	s.grpc.Stop()
+	s.openfga.Close()
+}
+
+// connect starts an embedded server, seeded with the module of the config if
+// there is one, and dials it with the given options. The server runs until
+// the process exits and is exposed on the listen address of the config if
+// there is one.
+func connect(cfg config.FGAConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	s, err := newServer()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.EmbeddedListenAddress != "" {
+		if _, err := s.listen(cfg.EmbeddedListenAddress); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	if cfg.EmbeddedSeedModule != "" {
+		if _, _, err := s.seed(context.Background(), seedStoreName, cfg.EmbeddedSeedModule); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+
+	conn, err := s.dial(opts...)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	return conn, nil
+}
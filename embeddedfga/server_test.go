@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestEmbeddedServer(t *testing.T) {
+	s, err := newServer()
+	require.NoError(t, err)
+	t.Cleanup(s.close)
+
+	ctx := context.Background()
+	storeID, modelID, err := s.seed(ctx, seedStoreName, "../data/coreModule.fga")
+	require.NoError(t, err)
+	assert.NotEmpty(t, modelID)
+
+	conn, err := s.dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := openfgav1.NewOpenFGAServiceClient(conn)
+
+	_, err = client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{
+			{Object: "role:acme/owner", Relation: "assignee", User: "user:alice"},
+			{Object: "account:acme", Relation: "owner", User: "role:acme/owner#assignee"},
+		}},
+	})
+	require.NoError(t, err)
+
+	res, err := client.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: &openfgav1.CheckRequestTupleKey{Object: "account:acme", Relation: "delete", User: "user:alice"},
+	})
+	require.NoError(t, err)
+	assert.True(t, res.GetAllowed())
+}
+
+func TestEmbeddedServer_Listen(t *testing.T) {
+	s, err := newServer()
+	require.NoError(t, err)
+	t.Cleanup(s.close)
+
+	addr, err := s.listen("localhost:0")
+	require.NoError(t, err)
+
+	conn, err := grpc.NewClient(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_, err = openfgav1.NewOpenFGAServiceClient(conn).CreateStore(context.Background(), &openfgav1.CreateStoreRequest{Name: "shared"})
+	require.NoError(t, err)
+	stores, err := s.openfga.ListStores(context.Background(), &openfgav1.ListStoresRequest{})
+	require.NoError(t, err)
+	require.Len(t, stores.GetStores(), 1)
+	assert.Equal(t, "shared", stores.GetStores()[0].GetName())
+}
+
+func TestEmbeddedServer_SeedFailsForInvalidModule(t *testing.T) {
+	s, err := newServer()
+	require.NoError(t, err)
+	t.Cleanup(s.close)
+
+	_, _, err = s.seed(context.Background(), seedStoreName, "missing.fga")
+	assert.ErrorContains(t, err, "reading seed module")
+}
+
+func TestNewConnection_Embedded(t *testing.T) {
+	cfg := config.NewConfig().FGA
+	cfg.Target = fga.EmbeddedTarget
+	cfg.EmbeddedSeedModule = "../data/coreModule.fga"
+
+	conn, err := fga.NewConnection(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	stores, err := openfgav1.NewOpenFGAServiceClient(conn).ListStores(context.Background(), &openfgav1.ListStoresRequest{})
+	require.NoError(t, err)
+	require.Len(t, stores.GetStores(), 1)
+	assert.Equal(t, seedStoreName, stores.GetStores()[0].GetName())
+}
@@ -0,0 +1,403 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	platformmeshpath "github.com/platform-mesh/security-operator/internal/platformmesh"
+	"github.com/platform-mesh/security-operator/internal/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kcpcore "github.com/kcp-dev/sdk/apis/core"
+	kcpcorev1alpha1 "github.com/kcp-dev/sdk/apis/core/v1alpha1"
+)
+
+// ClusterNameExtraKey is the key of the SubjectAccessReview extra field kcp
+// passes the logical cluster a request is made in with.
+const ClusterNameExtraKey = "authorization.kubernetes.io/cluster-name"
+
+const (
+	namespaceObjectType = "core_namespace"
+
+	// clusterTTL is how long the account and REST mapper of a logical cluster
+	// are remembered.
+	clusterTTL = 10 * time.Minute
+	// modelTTL is how long the types of an authorization model are
+	// remembered, which delays picking up newly bound resources.
+	modelTTL = time.Minute
+
+	decisionCacheCapacity = 10000
+	clusterCacheCapacity  = 1000
+	modelCacheCapacity    = 1000
+)
+
+var (
+	// collectionVerbs are modelled as "<verb>_<group>_<resource>" relations
+	// of the parent of a resource.
+	collectionVerbs = sets.New("create", "list", "watch")
+	// objectVerbs are modelled as relations of the object itself. They are
+	// only checked if the type of the object defines them, bind and escalate
+	// e.g. only exist on the types of privileged resources.
+	objectVerbs = sets.New("get", "update", "delete", "patch", "watch", "bind", "escalate")
+)
+
+// decisionKey identifies the requests a cached decision applies to.
+type decisionKey struct {
+	user      string
+	cluster   string
+	verb      string
+	group     string
+	resource  string
+	namespace string
+	name      string
+}
+
+// modelType is what is needed to map requests to a type of an authorization
+// model.
+type modelType struct {
+	relations sets.Set[string]
+	// parents are the types the parent relation refers to.
+	parents []string
+}
+
+// clusterInfo is what is needed to map requests in a logical cluster to
+// checks. Clusters that are not accounts have no store name.
+type clusterInfo struct {
+	storeName string
+	account   string
+	mapper    meta.RESTMapper
+}
+
+// Authorizer answers SubjectAccessReviews of the kcp webhook authorizer with
+// OpenFGA checks in the store of the org the request's workspace belongs to.
+// Objects and relations are named like in the models generated for API
+// resources, whose type "<group>_<singular>" is looked up in the model of the
+// store:
+//
+//   - create, list and watch of a collection are relations
+//     "<verb>_<group>_<resource>" of the parent of the type, which must be the
+//     account, or "core_namespace:<clusterID>/<namespace>" for namespaced
+//     resources.
+//   - Other verbs on a named object are relations of
+//     "<group>_<singular>:<clusterID>/[<namespace>/]<name>".
+//
+// Requests that do not map to a relation in the model, or that are not
+// allowed, are answered with no opinion so that other authorizers can decide.
+type Authorizer struct {
+	fga           openfgav1.OpenFGAServiceClient
+	storeIDGetter fga.StoreIDGetter
+	clients       iclient.KCPClientGetter
+	accounts      *fga.EntityResolver
+	decisions     *ttlcache.Cache[decisionKey, authorizationv1.SubjectAccessReviewStatus]
+	clusters      *ttlcache.Cache[string, *clusterInfo]
+	models        *ttlcache.Cache[string, map[string]modelType]
+	log           *logger.Logger
+}
+
+// New returns an Authorizer that caches decisions for decisionTTL, 0
+// disables the decision cache.
+func New(fgaClient openfgav1.OpenFGAServiceClient, storeIDGetter fga.StoreIDGetter, clients iclient.KCPClientGetter, accounts *fga.EntityResolver, decisionTTL time.Duration, log *logger.Logger) *Authorizer {
+	a := &Authorizer{
+		fga:           fgaClient,
+		storeIDGetter: storeIDGetter,
+		clients:       clients,
+		accounts:      accounts,
+		clusters: ttlcache.New(
+			ttlcache.WithTTL[string, *clusterInfo](clusterTTL),
+			ttlcache.WithCapacity[string, *clusterInfo](clusterCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[string, *clusterInfo](),
+		),
+		models: ttlcache.New(
+			ttlcache.WithTTL[string, map[string]modelType](modelTTL),
+			ttlcache.WithCapacity[string, map[string]modelType](modelCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[string, map[string]modelType](),
+		),
+		log: log,
+	}
+	if decisionTTL > 0 {
+		a.decisions = ttlcache.New(
+			ttlcache.WithTTL[decisionKey, authorizationv1.SubjectAccessReviewStatus](decisionTTL),
+			ttlcache.WithCapacity[decisionKey, authorizationv1.SubjectAccessReviewStatus](decisionCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[decisionKey, authorizationv1.SubjectAccessReviewStatus](),
+		)
+	}
+	return a
+}
+
+// ServeHTTP answers a SubjectAccessReview posted by the webhook authorizer.
+func (a *Authorizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review authorizationv1.SubjectAccessReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("decoding SubjectAccessReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	review.Status = a.Authorize(r.Context(), review.Spec)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		a.log.Error().Err(err).Msg("Failed to write SubjectAccessReview response")
+	}
+}
+
+// Authorize decides a SubjectAccessReview.
+func (a *Authorizer) Authorize(ctx context.Context, spec authorizationv1.SubjectAccessReviewSpec) authorizationv1.SubjectAccessReviewStatus {
+	attrs := spec.ResourceAttributes
+	if attrs == nil {
+		return noOpinion("non-resource requests are not modelled")
+	}
+	if attrs.Subresource != "" {
+		return noOpinion("subresources are not modelled")
+	}
+	var clusterName string
+	if values := spec.Extra[ClusterNameExtraKey]; len(values) > 0 {
+		clusterName = values[0]
+	}
+	if clusterName == "" {
+		return noOpinion(fmt.Sprintf("request has no %s extra", ClusterNameExtraKey))
+	}
+
+	key := decisionKey{
+		user:      spec.User,
+		cluster:   clusterName,
+		verb:      attrs.Verb,
+		group:     attrs.Group,
+		resource:  attrs.Resource,
+		namespace: attrs.Namespace,
+		name:      attrs.Name,
+	}
+	if a.decisions != nil {
+		if item := a.decisions.Get(key); item != nil {
+			return item.Value()
+		}
+	}
+
+	status, err := a.decide(ctx, clusterName, spec.User, attrs)
+	if err != nil {
+		a.log.Error().Err(err).Str("cluster", clusterName).Str("user", spec.User).Str("verb", attrs.Verb).Str("resource", attrs.Resource).Msg("Failed to authorize request")
+		return authorizationv1.SubjectAccessReviewStatus{EvaluationError: err.Error()}
+	}
+	if a.decisions != nil {
+		a.decisions.Set(key, status, ttlcache.DefaultTTL)
+	}
+	return status
+}
+
+// decide maps a request to a check and runs it.
+func (a *Authorizer) decide(ctx context.Context, clusterName, user string, attrs *authorizationv1.ResourceAttributes) (authorizationv1.SubjectAccessReviewStatus, error) {
+	cluster, err := a.cluster(ctx, clusterName)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, err
+	}
+	if cluster.storeName == "" {
+		return noOpinion(fmt.Sprintf("cluster %s is not an account workspace", clusterName)), nil
+	}
+
+	version := attrs.Version
+	if version == "*" {
+		version = ""
+	}
+	gvr := schema.GroupVersionResource{Group: attrs.Group, Version: version, Resource: attrs.Resource}
+	gvk, err := cluster.mapper.KindFor(gvr)
+	if meta.IsNoMatchError(err) {
+		return noOpinion(fmt.Sprintf("resource %s is not known in cluster %s", gvr.GroupResource(), clusterName)), nil
+	}
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("resolving kind of %s: %w", gvr.GroupResource(), err)
+	}
+	mapping, err := cluster.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("resolving REST mapping of %s: %w", gvk, err)
+	}
+	namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+
+	collection := attrs.Name == "" && collectionVerbs.Has(attrs.Verb)
+	if !collection && (attrs.Name == "" || !objectVerbs.Has(attrs.Verb)) {
+		return noOpinion(fmt.Sprintf("verb %s is not modelled for %s", attrs.Verb, gvr.GroupResource())), nil
+	}
+	if collection && namespaced && attrs.Namespace == "" {
+		return noOpinion(fmt.Sprintf("%s across namespaces is not modelled", attrs.Verb)), nil
+	}
+	singular, err := cluster.mapper.ResourceSingularizer(attrs.Resource)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("resolving singular name of %s: %w", gvr.GroupResource(), err)
+	}
+	group := util.ModelGroup(attrs.Group, attrs.Resource)
+	resourceType := group + "_" + singular
+
+	storeID, err := a.storeIDGetter.Get(ctx, cluster.storeName)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("getting store ID of %s: %w", cluster.storeName, err)
+	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, a.storeIDGetter, cluster.storeName)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("getting authorization model of %s: %w", cluster.storeName, err)
+	}
+	types, err := a.modelTypes(ctx, storeID, modelID)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, err
+	}
+	resource, ok := types[resourceType]
+	if !ok {
+		return noOpinion(fmt.Sprintf("type %s is not in the model of %s", resourceType, cluster.storeName)), nil
+	}
+
+	var object, relation string
+	if collection {
+		// Collections can only be checked on the account or namespace of the
+		// request, not on parents chosen by ModelTemplates or permission
+		// profiles.
+		accountType, _, _ := strings.Cut(cluster.account, ":")
+		var parentType string
+		switch {
+		case namespaced && slices.Contains(resource.parents, namespaceObjectType):
+			parentType = namespaceObjectType
+			object = fga.RenderResourceEntity(namespaceObjectType, clusterName, "", attrs.Namespace)
+		case !namespaced && slices.Contains(resource.parents, accountType):
+			parentType = accountType
+			object = cluster.account
+		default:
+			return noOpinion(fmt.Sprintf("%s of %s in %s is not modelled", attrs.Verb, gvr.GroupResource(), strings.Join(resource.parents, ", "))), nil
+		}
+		relation = fmt.Sprintf("%s_%s_%s", attrs.Verb, group, attrs.Resource)
+		if !types[parentType].relations.Has(relation) {
+			return noOpinion(fmt.Sprintf("relation %s of %s is not in the model of %s", relation, parentType, cluster.storeName)), nil
+		}
+	} else {
+		if !resource.relations.Has(attrs.Verb) {
+			return noOpinion(fmt.Sprintf("relation %s of %s is not in the model of %s", attrs.Verb, resourceType, cluster.storeName)), nil
+		}
+		namespace := ""
+		if namespaced {
+			namespace = attrs.Namespace
+		}
+		object = fga.RenderResourceEntity(resourceType, clusterName, namespace, attrs.Name)
+		relation = attrs.Verb
+	}
+
+	fgaUser := fga.RenderUser(user)
+	res, err := a.fga.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     fgaUser,
+			Relation: relation,
+			Object:   object,
+		},
+	})
+	// OpenFGA rejects checks of types and relations missing from the model,
+	// e.g. while a changed model is being written.
+	if status.Code(err) == codes.InvalidArgument {
+		return noOpinion(fmt.Sprintf("%s %s %s is not modelled: %s", fgaUser, relation, object, status.Convert(err).Message())), nil
+	}
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("checking %s %s %s: %w", fgaUser, relation, object, err)
+	}
+	if !res.GetAllowed() {
+		return noOpinion(fmt.Sprintf("%s has no relation %s to %s", fgaUser, relation, object)), nil
+	}
+	return authorizationv1.SubjectAccessReviewStatus{
+		Allowed: true,
+		Reason:  fmt.Sprintf("%s has relation %s to %s", fgaUser, relation, object),
+	}, nil
+}
+
+// cluster returns the org store name, account and REST mapper of a logical
+// cluster.
+func (a *Authorizer) cluster(ctx context.Context, name string) (*clusterInfo, error) {
+	if item := a.clusters.Get(name); item != nil {
+		return item.Value(), nil
+	}
+
+	cl, err := a.clients.NewClientForLogicalCluster(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("getting client for cluster %s: %w", name, err)
+	}
+	var lc kcpcorev1alpha1.LogicalCluster
+	if err := cl.Get(ctx, client.ObjectKey{Name: "cluster"}, &lc); err != nil {
+		return nil, fmt.Errorf("getting LogicalCluster of cluster %s: %w", name, err)
+	}
+
+	info := &clusterInfo{}
+	if platformmeshpath.IsPlatformMeshAccountPath(lc.Annotations[kcpcore.LogicalClusterPathAnnotationKey]) {
+		accountPath, err := platformmeshpath.NewAccountPathFromLogicalCluster(&lc)
+		if err != nil {
+			return nil, fmt.Errorf("getting AccountPath from LogicalCluster: %w", err)
+		}
+		account, err := a.accounts.Resolve(ctx, "account:"+accountPath.String())
+		if err != nil {
+			return nil, fmt.Errorf("resolving account of %s: %w", accountPath, err)
+		}
+		info = &clusterInfo{
+			storeName: accountPath.Org().Base(),
+			account:   account,
+			mapper:    cl.RESTMapper(),
+		}
+	}
+
+	a.clusters.Set(name, info, ttlcache.DefaultTTL)
+	return info, nil
+}
+
+// modelTypes returns the types of an authorization model by name. The latest
+// model is read if modelID is AuthorizationModelIDLatest.
+func (a *Authorizer) modelTypes(ctx context.Context, storeID, modelID string) (map[string]modelType, error) {
+	key := storeID + "/" + modelID
+	if item := a.models.Get(key); item != nil {
+		return item.Value(), nil
+	}
+
+	var model *openfgav1.AuthorizationModel
+	if modelID == fga.AuthorizationModelIDLatest {
+		res, err := a.fga.ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: storeID, PageSize: wrapperspb.Int32(1)})
+		if err != nil {
+			return nil, fmt.Errorf("reading latest authorization model of store %s: %w", storeID, err)
+		}
+		if models := res.GetAuthorizationModels(); len(models) > 0 {
+			model = models[0]
+		}
+	} else {
+		res, err := a.fga.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{StoreId: storeID, Id: modelID})
+		if err != nil {
+			return nil, fmt.Errorf("reading authorization model %s of store %s: %w", modelID, storeID, err)
+		}
+		model = res.GetAuthorizationModel()
+	}
+
+	types := map[string]modelType{}
+	for _, typeDef := range model.GetTypeDefinitions() {
+		t := modelType{relations: sets.KeysSet(typeDef.GetRelations())}
+		for _, ref := range typeDef.GetMetadata().GetRelations()["parent"].GetDirectlyRelatedUserTypes() {
+			t.parents = append(t.parents, ref.GetType())
+		}
+		types[typeDef.GetType()] = t
+	}
+	a.models.Set(key, types, ttlcache.DefaultTTL)
+	return types, nil
+}
+
+func noOpinion(reason string) authorizationv1.SubjectAccessReviewStatus {
+	return authorizationv1.SubjectAccessReviewStatus{Reason: reason}
+}
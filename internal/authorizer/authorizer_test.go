@@ -0,0 +1,308 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	language "github.com/openfga/language/pkg/go/transformer"
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	kcpcore "github.com/kcp-dev/sdk/apis/core"
+	kcpcorev1alpha1 "github.com/kcp-dev/sdk/apis/core/v1alpha1"
+)
+
+// testModel holds the types of the resources of the test clusters. Widgets
+// are created in projects and have no get relation.
+const testModel = `model
+  schema 1.2
+
+type user
+
+type core_platform-mesh_io_account
+  relations
+    define parent: [core_platform-mesh_io_account]
+    define member: [user]
+    define get: member
+    define create_core_platform-mesh_io_accounts: member
+
+type core_namespace
+  relations
+    define parent: [core_platform-mesh_io_account]
+    define member: [user]
+    define list_core_pods: member
+
+type core_pod
+  relations
+    define parent: [core_namespace]
+    define member: [user]
+    define delete: member
+
+type example_io_project
+  relations
+    define member: [user]
+    define list_example_io_widgets: member
+
+type example_io_widget
+  relations
+    define parent: [example_io_project]
+    define member: [user]
+`
+
+func newTestAuthorizer(t *testing.T, fgaClient *mocks.MockOpenFGAServiceClient, decisionTTL time.Duration) *Authorizer {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(kcpcorev1alpha1.AddToScheme(scheme))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "core.platform-mesh.io", Version: "v1alpha1", Kind: "Account"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Widget"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	newCluster := func(path string) *fake.ClientBuilder {
+		return fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(&kcpcorev1alpha1.LogicalCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster",
+				Annotations: map[string]string{kcpcore.LogicalClusterPathAnnotationKey: path},
+			},
+		})
+	}
+
+	clients := mocks.NewMockKCPClientGetter(t)
+	clients.EXPECT().NewClientForLogicalCluster(mock.Anything, "team-id").Return(newCluster("root:orgs:acme:team").Build(), nil).Maybe()
+	clients.EXPECT().NewClientForLogicalCluster(mock.Anything, "root-id").Return(newCluster("root").Build(), nil).Maybe()
+
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme").Return("store-id", nil).Maybe()
+
+	model, err := language.TransformDSLToProto(testModel)
+	require.NoError(t, err)
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelsRequest) bool {
+		return req.StoreId == "store-id"
+	})).Return(&openfgav1.ReadAuthorizationModelsResponse{AuthorizationModels: []*openfgav1.AuthorizationModel{model}}, nil).Maybe()
+
+	accounts := fga.NewEntityResolver("core_platform-mesh_io_account", func(_ context.Context, path logicalcluster.Path) (string, error) {
+		require.Equal(t, "root:orgs:acme", path.String())
+		return "acme-id", nil
+	})
+
+	return New(fgaClient, storeIDGetter, clients, accounts, decisionTTL, testlogger.New().Logger)
+}
+
+func resourceReview(cluster string, attrs authorizationv1.ResourceAttributes) authorizationv1.SubjectAccessReviewSpec {
+	return authorizationv1.SubjectAccessReviewSpec{
+		User:               "alice@acme.corp",
+		ResourceAttributes: &attrs,
+		Extra:              map[string]authorizationv1.ExtraValue{ClusterNameExtraKey: {cluster}},
+	}
+}
+
+func expectCheck(fgaClient *mocks.MockOpenFGAServiceClient, relation, object string, allowed bool) *mocks.MockOpenFGAServiceClient_Check_Call {
+	return fgaClient.EXPECT().Check(mock.Anything, &openfgav1.CheckRequest{
+		StoreId: "store-id",
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     "user:alice@acme.corp",
+			Relation: relation,
+			Object:   object,
+		},
+	}).Return(&openfgav1.CheckResponse{Allowed: allowed}, nil)
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		attrs    authorizationv1.ResourceAttributes
+		relation string
+		object   string
+		allowed  bool
+	}{
+		{
+			name:     "list namespaced resources is a relation of the namespace",
+			attrs:    authorizationv1.ResourceAttributes{Verb: "list", Version: "v1", Resource: "pods", Namespace: "default"},
+			relation: "list_core_pods",
+			object:   "core_namespace:team-id/default",
+			allowed:  true,
+		},
+		{
+			name:     "create cluster-scoped resources is a relation of the account",
+			attrs:    authorizationv1.ResourceAttributes{Verb: "create", Group: "core.platform-mesh.io", Version: "*", Resource: "accounts"},
+			relation: "create_core_platform-mesh_io_accounts",
+			object:   "core_platform-mesh_io_account:acme-id/team",
+			allowed:  true,
+		},
+		{
+			name:     "verbs on a named namespaced resource are relations of the object",
+			attrs:    authorizationv1.ResourceAttributes{Verb: "delete", Version: "v1", Resource: "pods", Namespace: "default", Name: "web"},
+			relation: "delete",
+			object:   "core_pod:team-id/default/web",
+			allowed:  true,
+		},
+		{
+			name:     "verbs on a named cluster-scoped resource are relations of the object",
+			attrs:    authorizationv1.ResourceAttributes{Verb: "get", Group: "core.platform-mesh.io", Resource: "accounts", Name: "dev"},
+			relation: "get",
+			object:   "core_platform-mesh_io_account:team-id/dev",
+			allowed:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+			expectCheck(fgaClient, tt.relation, tt.object, tt.allowed).Once()
+			a := newTestAuthorizer(t, fgaClient, time.Minute)
+
+			status := a.Authorize(context.Background(), resourceReview("team-id", tt.attrs))
+			assert.Equal(t, tt.allowed, status.Allowed)
+			assert.False(t, status.Denied)
+			assert.Empty(t, status.EvaluationError)
+			assert.Contains(t, status.Reason, tt.object)
+
+			// The decision is cached.
+			assert.Equal(t, status, a.Authorize(context.Background(), resourceReview("team-id", tt.attrs)))
+		})
+	}
+}
+
+func TestAuthorize_NoOpinion(t *testing.T) {
+	tests := []struct {
+		name string
+		spec authorizationv1.SubjectAccessReviewSpec
+	}{
+		{
+			name: "non-resource request",
+			spec: authorizationv1.SubjectAccessReviewSpec{
+				User:                  "alice@acme.corp",
+				NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: "/healthz", Verb: "get"},
+				Extra:                 map[string]authorizationv1.ExtraValue{ClusterNameExtraKey: {"team-id"}},
+			},
+		},
+		{
+			name: "missing cluster",
+			spec: authorizationv1.SubjectAccessReviewSpec{
+				User:               "alice@acme.corp",
+				ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods", Namespace: "default"},
+			},
+		},
+		{
+			name: "cluster is no account",
+			spec: resourceReview("root-id", authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods", Namespace: "default"}),
+		},
+		{
+			name: "subresource",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "log", Namespace: "default", Name: "web"}),
+		},
+		{
+			name: "unknown resource",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Group: "batch", Resource: "jobs", Namespace: "default"}),
+		},
+		{
+			name: "resource without a type in the model",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "get", Group: "apps", Resource: "deployments", Namespace: "default", Name: "web"}),
+		},
+		{
+			name: "verb without a relation in the model",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "get", Group: "example.io", Resource: "widgets", Namespace: "default", Name: "w"}),
+		},
+		{
+			name: "collection of resources with another parent",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Group: "example.io", Resource: "widgets", Namespace: "default"}),
+		},
+		{
+			name: "bind on a type without the relation",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "bind", Resource: "pods", Namespace: "default", Name: "web"}),
+		},
+		{
+			name: "verb that is not modelled",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "deletecollection", Resource: "pods", Namespace: "default"}),
+		},
+		{
+			name: "namespaced resources across namespaces",
+			spec: resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthorizer(t, mocks.NewMockOpenFGAServiceClient(t), time.Minute)
+
+			status := a.Authorize(context.Background(), tt.spec)
+			assert.False(t, status.Allowed)
+			assert.False(t, status.Denied)
+			assert.Empty(t, status.EvaluationError)
+			assert.NotEmpty(t, status.Reason)
+		})
+	}
+}
+
+func TestAuthorize_CachesRejectedChecks(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(nil, status.Error(codes.InvalidArgument, "relation not found")).Once()
+	a := newTestAuthorizer(t, fgaClient, time.Minute)
+
+	spec := resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods", Namespace: "default"})
+	result := a.Authorize(context.Background(), spec)
+	assert.False(t, result.Allowed)
+	assert.Empty(t, result.EvaluationError)
+	assert.Contains(t, result.Reason, "relation not found")
+	assert.Equal(t, result, a.Authorize(context.Background(), spec))
+}
+
+func TestAuthorize_WithoutDecisionCache(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	expectCheck(fgaClient, "list_core_pods", "core_namespace:team-id/default", true).Once()
+	expectCheck(fgaClient, "list_core_pods", "core_namespace:team-id/default", false).Once()
+	a := newTestAuthorizer(t, fgaClient, 0)
+
+	spec := resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods", Namespace: "default"})
+	assert.True(t, a.Authorize(context.Background(), spec).Allowed)
+	assert.False(t, a.Authorize(context.Background(), spec).Allowed)
+}
+
+func TestServeHTTP(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	expectCheck(fgaClient, "list_core_pods", "core_namespace:team-id/default", true).Once()
+	a := newTestAuthorizer(t, fgaClient, time.Minute)
+
+	body, err := json.Marshal(authorizationv1.SubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "authorization.k8s.io/v1", Kind: "SubjectAccessReview"},
+		Spec:     resourceReview("team-id", authorizationv1.ResourceAttributes{Verb: "list", Resource: "pods", Namespace: "default"}),
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var review authorizationv1.SubjectAccessReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	assert.Equal(t, "SubjectAccessReview", review.Kind)
+	assert.True(t, review.Status.Allowed)
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/authorize", bytes.NewReader([]byte("{"))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/authorize", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,183 @@
+package authzen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// ClusterNameExtraKey is the user extra kcp sets on service accounts to the
+// logical cluster they live in.
+const ClusterNameExtraKey = "authentication.kubernetes.io/cluster-name"
+
+var (
+	// errUnauthenticated is returned for requests without a valid token.
+	errUnauthenticated = errors.New("unauthenticated")
+	// errForbidden is returned for requests outside of the caller's org.
+	errForbidden = errors.New("forbidden")
+)
+
+// Caller is the authenticated client of a request. Its requests are limited
+// to the store of its org.
+type Caller struct {
+	User string
+	Org  string
+}
+
+// Authenticator authenticates the bearer token of a request.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Caller, error)
+}
+
+// OrgResolver returns the org of a logical cluster.
+type OrgResolver func(ctx context.Context, clusterName string) (string, error)
+
+// callerCacheCapacity bounds the number of tokens whose caller is remembered.
+const callerCacheCapacity = 10000
+
+// TokenReviewAuthenticator authenticates kcp service account tokens with
+// TokenReviews. The org of a caller is the one of the workspace its service
+// account lives in.
+type TokenReviewAuthenticator struct {
+	client    client.Client
+	org       OrgResolver
+	audiences []string
+	ttl       time.Duration
+	callers   *ttlcache.Cache[[sha256.Size]byte, *Caller]
+}
+
+// NewTokenReviewAuthenticator returns a TokenReviewAuthenticator creating
+// TokenReviews with the given client. Tokens must be issued for one of the
+// audiences if any are set. The callers of authenticated tokens are
+// remembered for ttl, but not beyond the expiry of the token, 0 disables the
+// cache.
+func NewTokenReviewAuthenticator(cl client.Client, org OrgResolver, audiences []string, ttl time.Duration) *TokenReviewAuthenticator {
+	a := &TokenReviewAuthenticator{client: cl, org: org, audiences: audiences, ttl: ttl}
+	if ttl > 0 {
+		a.callers = ttlcache.New(
+			ttlcache.WithCapacity[[sha256.Size]byte, *Caller](callerCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[[sha256.Size]byte, *Caller](),
+		)
+	}
+	return a
+}
+
+// Authenticate implements Authenticator. Only tokens that were authenticated
+// are cached, under their hash.
+func (a *TokenReviewAuthenticator) Authenticate(ctx context.Context, token string) (*Caller, error) {
+	if a.callers == nil {
+		return a.review(ctx, token)
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if item := a.callers.Get(key); item != nil {
+		return item.Value(), nil
+	}
+	caller, err := a.review(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := a.ttl
+	if expiry, ok := tokenExpiry(token); ok {
+		ttl = min(ttl, time.Until(expiry))
+	}
+	if ttl > 0 {
+		a.callers.Set(key, caller, ttl)
+	}
+	return caller, nil
+}
+
+// review authenticates a token with a TokenReview and resolves the org of its
+// service account.
+func (a *TokenReviewAuthenticator) review(ctx context.Context, token string) (*Caller, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: a.audiences},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return nil, fmt.Errorf("creating TokenReview: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, fmt.Errorf("%w: %s", errUnauthenticated, review.Status.Error)
+	}
+
+	user := review.Status.User
+	clusterNames := user.Extra[ClusterNameExtraKey]
+	if len(clusterNames) != 1 || clusterNames[0] == "" {
+		return nil, fmt.Errorf("%w: %s is not a service account of a workspace", errUnauthenticated, user.Username)
+	}
+	org, err := a.org(ctx, clusterNames[0])
+	if err != nil {
+		return nil, fmt.Errorf("resolving org of %s: %w", user.Username, err)
+	}
+	return &Caller{User: user.Username, Org: org}, nil
+}
+
+// tokenExpiry returns the expiry of a JWT. Its signature is not verified, so
+// it must only be used for tokens that were authenticated.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+type callerKey struct{}
+
+// callerFrom returns the caller of a request.
+func callerFrom(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok
+}
+
+// authenticate rejects requests without a valid bearer token and passes the
+// caller on to the handler.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			s.writeError(w, fmt.Errorf("%w: bearer token is required", errUnauthenticated))
+			return
+		}
+		caller, err := s.authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			s.writeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, caller)))
+	})
+}
+
+// authorize rejects requests for stores of orgs other than the caller's.
+func authorize(ctx context.Context, org string) error {
+	caller, ok := callerFrom(ctx)
+	if !ok {
+		return fmt.Errorf("%w: no caller", errUnauthenticated)
+	}
+	if caller.Org != org {
+		return fmt.Errorf("%w: %s may only access org %s", errForbidden, caller.User, caller.Org)
+	}
+	return nil
+}
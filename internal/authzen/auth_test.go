@@ -0,0 +1,117 @@
+package authzen
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func TestTokenReviewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    authenticationv1.TokenReviewStatus
+		expect    *Caller
+		expectErr error
+	}{
+		{
+			name: "service accounts of a workspace are callers of its org",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User: authenticationv1.UserInfo{
+					Username: "system:serviceaccount:default:app",
+					Extra:    map[string]authenticationv1.ExtraValue{ClusterNameExtraKey: {"team-id"}},
+				},
+			},
+			expect: &Caller{User: "system:serviceaccount:default:app", Org: "acme"},
+		},
+		{
+			name:      "invalid tokens are rejected",
+			status:    authenticationv1.TokenReviewStatus{Error: "token expired"},
+			expectErr: errUnauthenticated,
+		},
+		{
+			name: "users without a workspace are rejected",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "alice@acme.corp"},
+			},
+			expectErr: errUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := mocks.NewMockClient(t)
+			cl.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+				review := obj.(*authenticationv1.TokenReview)
+				assert.Equal(t, "token", review.Spec.Token)
+				assert.Equal(t, []string{"authzen"}, review.Spec.Audiences)
+				review.Status = tt.status
+				return nil
+			}).Once()
+			org := func(_ context.Context, clusterName string) (string, error) {
+				if clusterName != "team-id" {
+					return "", errors.New("unknown cluster")
+				}
+				return "acme", nil
+			}
+
+			caller, err := NewTokenReviewAuthenticator(cl, org, []string{"authzen"}, 0).Authenticate(context.Background(), "token")
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, caller)
+		})
+	}
+}
+
+func TestTokenReviewAuthenticatorCachesCallers(t *testing.T) {
+	jwt := func(exp time.Time) string {
+		payload := base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, `{"exp":%d}`, exp.Unix()))
+		return "header." + payload + ".signature"
+	}
+	tests := []struct {
+		name          string
+		token         string
+		expectReviews int
+	}{
+		{name: "tokens are reviewed once", token: jwt(time.Now().Add(time.Hour)), expectReviews: 1},
+		{name: "tokens without an expiry are reviewed once", token: "token", expectReviews: 1},
+		{name: "expired tokens are reviewed every time", token: jwt(time.Now().Add(-time.Second)), expectReviews: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := mocks.NewMockClient(t)
+			cl.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+				obj.(*authenticationv1.TokenReview).Status = authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:app",
+						Extra:    map[string]authenticationv1.ExtraValue{ClusterNameExtraKey: {"team-id"}},
+					},
+				}
+				return nil
+			}).Times(tt.expectReviews)
+			org := func(context.Context, string) (string, error) { return "acme", nil }
+
+			authenticator := NewTokenReviewAuthenticator(cl, org, nil, time.Minute)
+			for range 2 {
+				caller, err := authenticator.Authenticate(context.Background(), tt.token)
+				require.NoError(t, err)
+				assert.Equal(t, "acme", caller.Org)
+			}
+		})
+	}
+}
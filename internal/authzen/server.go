@@ -0,0 +1,506 @@
+package authzen
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	platformmeshpath "github.com/platform-mesh/security-operator/internal/platformmesh"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+)
+
+const (
+	EvaluationPath     = "/access/v1/evaluation"
+	EvaluationsPath    = "/access/v1/evaluations"
+	SubjectSearchPath  = "/access/v1/search/subject"
+	ResourceSearchPath = "/access/v1/search/resource"
+
+	// PathProperty is the resource property holding the kcp workspace path a
+	// resource lives in.
+	PathProperty = "path"
+	// NamespaceProperty is the resource property holding the namespace of a
+	// namespaced resource.
+	NamespaceProperty = "namespace"
+
+	userSubjectType = "user"
+	requestIDHeader = "X-Request-ID"
+
+	// clusterIDTTL is how long the cluster IDs of workspace paths are
+	// remembered.
+	clusterIDTTL    = 10 * time.Minute
+	shutdownTimeout = 10 * time.Second
+)
+
+// errInvalidRequest is wrapped by errors caused by the request rather than by
+// the server.
+var errInvalidRequest = errors.New("invalid request")
+
+// Server serves the AuthZEN access evaluation and search APIs on top of the
+// org OpenFGA stores. Subjects of type "user" are users as written by the
+// operator, all other subjects are passed as "<type>:<id>". Actions are
+// relations. Resources are translated like this:
+//
+//   - Accounts, i.e. resources of the account object type, are referenced by
+//     their workspace path as ID.
+//   - Other resources are objects of generated resource types referenced by
+//     their name as ID and the workspace path they live in as "path"
+//     property, namespaced ones also by a "namespace" property.
+//
+// The store is the one of the org the resource's workspace belongs to.
+// Callers authenticate with a bearer token and may only access the store of
+// their own org.
+type Server struct {
+	addr          string
+	certDir       string
+	fga           openfgav1.OpenFGAServiceClient
+	storeIDGetter fga.StoreIDGetter
+	clusterID     fga.ClusterIDResolver
+	authenticator Authenticator
+	accounts      *fga.EntityResolver
+	accountType   string
+	log           *logger.Logger
+}
+
+// NewServer returns a Server listening on the bind address of cfg over TLS
+// with the tls.crt and tls.key of its certificate directory. Resolved cluster
+// IDs are cached.
+func NewServer(cfg config.AuthZENConfig, fgaClient openfgav1.OpenFGAServiceClient, storeIDGetter fga.StoreIDGetter, clusterID fga.ClusterIDResolver, authenticator Authenticator, accountType string, log *logger.Logger) *Server {
+	clusterID = fga.NewCachingClusterIDResolver(clusterID, clusterIDTTL)
+	return &Server{
+		addr:          cfg.BindAddress,
+		certDir:       cfg.CertDir,
+		fga:           fgaClient,
+		storeIDGetter: storeIDGetter,
+		clusterID:     clusterID,
+		authenticator: authenticator,
+		accounts:      fga.NewEntityResolver(accountType, clusterID),
+		accountType:   accountType,
+		log:           log,
+	}
+}
+
+// Handler returns the handler of the API endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+EvaluationPath, s.evaluation)
+	mux.HandleFunc("POST "+EvaluationsPath, s.evaluations)
+	mux.HandleFunc("POST "+SubjectSearchPath, s.searchSubject)
+	mux.HandleFunc("POST "+ResourceSearchPath, s.searchResource)
+	handler := s.authenticate(mux)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get(requestIDHeader); id != "" {
+			w.Header().Set(requestIDHeader, id)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Start serves the API until ctx is done. Rotated certificates are picked up
+// without a restart.
+func (s *Server) Start(ctx context.Context) error {
+	watcher, err := certwatcher.New(filepath.Join(s.certDir, "tls.crt"), filepath.Join(s.certDir, "tls.key"))
+	if err != nil {
+		return fmt.Errorf("loading AuthZEN serving certificate: %w", err)
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			s.log.Error().Err(err).Msg("Failed to watch AuthZEN serving certificate")
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"http/1.1"},
+			GetCertificate: watcher.GetCertificate,
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			s.log.Error().Err(err).Msg("Failed to shut down AuthZEN server")
+		}
+	}()
+
+	s.log.Info().Str("address", s.addr).Msg("Serving AuthZEN API")
+	if err := srv.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving AuthZEN API: %w", err)
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, every replica answers requests.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) evaluation(w http.ResponseWriter, r *http.Request) {
+	var req EvaluationRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	decision, err := s.evaluate(r.Context(), req)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.write(w, EvaluationResponse{Decision: decision})
+}
+
+func (s *Server) evaluations(w http.ResponseWriter, r *http.Request) {
+	var req EvaluationsRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	// Without evaluations the request is a single evaluation.
+	if len(req.Evaluations) == 0 {
+		decision, err := s.evaluate(r.Context(), req.EvaluationRequest)
+		if err != nil {
+			s.writeError(w, err)
+			return
+		}
+		s.write(w, EvaluationResponse{Decision: decision})
+		return
+	}
+
+	semantic := ExecuteAll
+	if req.Options != nil && req.Options.EvaluationsSemantic != "" {
+		semantic = req.Options.EvaluationsSemantic
+	}
+	if semantic != ExecuteAll && semantic != DenyOnFirstDeny && semantic != PermitOnFirstPermit {
+		s.writeError(w, fmt.Errorf("%w: unknown evaluations semantic %q", errInvalidRequest, semantic))
+		return
+	}
+
+	res := EvaluationsResponse{Evaluations: make([]EvaluationResponse, 0, len(req.Evaluations))}
+	for _, e := range req.Evaluations {
+		decision, err := s.evaluate(r.Context(), withDefaults(e, req.EvaluationRequest))
+		evaluation := EvaluationResponse{Decision: decision}
+		if err != nil {
+			evaluation.Context = map[string]any{
+				"error": map[string]any{
+					"status":  statusCode(err),
+					"message": err.Error(),
+				},
+			}
+		}
+		res.Evaluations = append(res.Evaluations, evaluation)
+
+		if (semantic == DenyOnFirstDeny && !decision) || (semantic == PermitOnFirstPermit && decision) {
+			break
+		}
+	}
+	s.write(w, res)
+}
+
+// searchSubject lists the subjects of the requested type that have the
+// action on the resource.
+func (s *Server) searchSubject(w http.ResponseWriter, r *http.Request) {
+	var req SearchRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.Subject.Type == "" || req.Action.Name == "" {
+		s.writeError(w, fmt.Errorf("%w: subject type and action name are required", errInvalidRequest))
+		return
+	}
+
+	store, object, err := s.resolveResource(r.Context(), &req.Resource)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	objectType, objectID, _ := strings.Cut(object, ":")
+	res, err := s.fga.ListUsers(r.Context(), &openfgav1.ListUsersRequest{
+		StoreId:              store.id,
+		AuthorizationModelId: store.modelID,
+		Object:               &openfgav1.Object{Type: objectType, Id: objectID},
+		Relation:             req.Action.Name,
+		UserFilters:          []*openfgav1.UserTypeFilter{{Type: req.Subject.Type}},
+	})
+	if err != nil {
+		s.writeError(w, fgaError("listing users", err))
+		return
+	}
+
+	results := []Subject{}
+	for _, user := range res.GetUsers() {
+		if o := user.GetObject(); o != nil {
+			results = append(results, Subject{Type: o.GetType(), ID: o.GetId()})
+		}
+	}
+	s.write(w, SearchResponse[Subject]{Results: results})
+}
+
+// searchResource lists the resources of the requested type in the workspace
+// of the resource's "path" property the subject has the action on. Accounts
+// are searched among the child accounts of that workspace.
+func (s *Server) searchResource(w http.ResponseWriter, r *http.Request) {
+	var req SearchRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	user, err := renderSubject(&req.Subject)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	if req.Resource.Type == "" || req.Action.Name == "" {
+		s.writeError(w, fmt.Errorf("%w: resource type and action name are required", errInvalidRequest))
+		return
+	}
+
+	workspace, err := workspacePath(req.Resource.Properties)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	store, err := s.store(r.Context(), workspace)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	clusterID, err := s.clusterID(r.Context(), workspace.Path)
+	if err != nil {
+		s.writeError(w, fmt.Errorf("resolving cluster ID of %s: %w", workspace, err))
+		return
+	}
+
+	res, err := s.fga.ListObjects(r.Context(), &openfgav1.ListObjectsRequest{
+		StoreId:              store.id,
+		AuthorizationModelId: store.modelID,
+		Type:                 req.Resource.Type,
+		Relation:             req.Action.Name,
+		User:                 user,
+	})
+	if err != nil {
+		s.writeError(w, fgaError("listing objects", err))
+		return
+	}
+
+	namespace := req.Resource.Properties.String(NamespaceProperty)
+	results := []Resource{}
+	for _, object := range res.GetObjects() {
+		_, id, _ := strings.Cut(object, ":")
+		rest, ok := strings.CutPrefix(id, clusterID+"/")
+		if !ok {
+			continue
+		}
+
+		if req.Resource.Type == s.accountType {
+			results = append(results, Resource{Type: req.Resource.Type, ID: workspace.String() + ":" + rest})
+			continue
+		}
+
+		ns, name, namespaced := strings.Cut(rest, "/")
+		if !namespaced {
+			ns, name = "", rest
+		}
+		if namespace != "" && ns != namespace {
+			continue
+		}
+		properties := Properties{PathProperty: workspace.String()}
+		if ns != "" {
+			properties[NamespaceProperty] = ns
+		}
+		results = append(results, Resource{Type: req.Resource.Type, ID: name, Properties: properties})
+	}
+	s.write(w, SearchResponse[Resource]{Results: results})
+}
+
+// evaluate checks whether the subject of a request has the action on its
+// resource.
+func (s *Server) evaluate(ctx context.Context, req EvaluationRequest) (bool, error) {
+	user, err := renderSubject(req.Subject)
+	if err != nil {
+		return false, err
+	}
+	if req.Action == nil || req.Action.Name == "" {
+		return false, fmt.Errorf("%w: action name is required", errInvalidRequest)
+	}
+	store, object, err := s.resolveResource(ctx, req.Resource)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.fga.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              store.id,
+		AuthorizationModelId: store.modelID,
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     user,
+			Relation: req.Action.Name,
+			Object:   object,
+		},
+	})
+	if err != nil {
+		return false, fgaError("checking", err)
+	}
+	return res.GetAllowed(), nil
+}
+
+// orgStore is the store of an org and the authorization model in use by it.
+type orgStore struct {
+	id      string
+	modelID string
+}
+
+// resolveResource returns the store and the object of a resource.
+func (s *Server) resolveResource(ctx context.Context, r *Resource) (orgStore, string, error) {
+	if r == nil || r.Type == "" || r.ID == "" {
+		return orgStore{}, "", fmt.Errorf("%w: resource type and ID are required", errInvalidRequest)
+	}
+
+	if r.Type == s.accountType {
+		accountPath, err := platformmeshpath.NewAccountPath(r.ID)
+		if err != nil {
+			return orgStore{}, "", fmt.Errorf("%w: %w", errInvalidRequest, err)
+		}
+		store, err := s.store(ctx, accountPath)
+		if err != nil {
+			return orgStore{}, "", err
+		}
+		object, err := s.accounts.Resolve(ctx, "account:"+accountPath.String())
+		if err != nil {
+			return orgStore{}, "", fmt.Errorf("resolving account %s: %w", accountPath, err)
+		}
+		return store, object, nil
+	}
+
+	workspace, err := workspacePath(r.Properties)
+	if err != nil {
+		return orgStore{}, "", err
+	}
+	store, err := s.store(ctx, workspace)
+	if err != nil {
+		return orgStore{}, "", err
+	}
+	clusterID, err := s.clusterID(ctx, workspace.Path)
+	if err != nil {
+		return orgStore{}, "", fmt.Errorf("resolving cluster ID of %s: %w", workspace, err)
+	}
+	return store, fga.RenderResourceEntity(r.Type, clusterID, r.Properties.String(NamespaceProperty), r.ID), nil
+}
+
+// store returns the store of the org an account belongs to. Orgs other than
+// the caller's are rejected.
+func (s *Server) store(ctx context.Context, accountPath platformmeshpath.AccountPath) (orgStore, error) {
+	org := accountPath.Org().Base()
+	if err := authorize(ctx, org); err != nil {
+		return orgStore{}, err
+	}
+	storeID, err := s.storeIDGetter.Get(ctx, org)
+	if err != nil {
+		return orgStore{}, fmt.Errorf("getting store ID of %s: %w", org, err)
+	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, s.storeIDGetter, org)
+	if err != nil {
+		return orgStore{}, fmt.Errorf("getting authorization model of %s: %w", org, err)
+	}
+	return orgStore{id: storeID, modelID: modelID}, nil
+}
+
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		s.writeError(w, fmt.Errorf("%w: decoding body: %w", errInvalidRequest, err))
+		return false
+	}
+	return true
+}
+
+func (s *Server) write(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.log.Error().Err(err).Msg("Failed to write AuthZEN response")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	code := statusCode(err)
+	if code == http.StatusInternalServerError {
+		s.log.Error().Err(err).Msg("Failed to answer AuthZEN request")
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// workspacePath returns the workspace path of a resource's properties.
+func workspacePath(properties Properties) (platformmeshpath.AccountPath, error) {
+	path := properties.String(PathProperty)
+	if path == "" {
+		return platformmeshpath.AccountPath{}, fmt.Errorf("%w: resource property %q is required", errInvalidRequest, PathProperty)
+	}
+	accountPath, err := platformmeshpath.NewAccountPath(path)
+	if err != nil {
+		return platformmeshpath.AccountPath{}, fmt.Errorf("%w: %w", errInvalidRequest, err)
+	}
+	return accountPath, nil
+}
+
+func renderSubject(subject *Subject) (string, error) {
+	if subject == nil || subject.Type == "" || subject.ID == "" {
+		return "", fmt.Errorf("%w: subject type and ID are required", errInvalidRequest)
+	}
+	if subject.Type == userSubjectType {
+		return fga.RenderUser(subject.ID), nil
+	}
+	return subject.Type + ":" + subject.ID, nil
+}
+
+// withDefaults fills what an evaluation of a batch leaves out from the
+// request.
+func withDefaults(e, defaults EvaluationRequest) EvaluationRequest {
+	if e.Subject == nil {
+		e.Subject = defaults.Subject
+	}
+	if e.Action == nil {
+		e.Action = defaults.Action
+	}
+	if e.Resource == nil {
+		e.Resource = defaults.Resource
+	}
+	if e.Context == nil {
+		e.Context = defaults.Context
+	}
+	return e
+}
+
+// fgaError marks errors of OpenFGA rejecting a request, e.g. for a relation
+// that is not in the model, as invalid requests.
+func fgaError(operation string, err error) error {
+	if status.Code(err) == codes.InvalidArgument {
+		return fmt.Errorf("%w: %s", errInvalidRequest, status.Convert(err).Message())
+	}
+	return fmt.Errorf("%s: %w", operation, err)
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, errInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,429 @@
+package authzen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+const accountType = "core_platform-mesh_io_account"
+
+// staticAuthenticator authenticates the token "token" as a caller of org
+// acme.
+type staticAuthenticator struct{}
+
+func (staticAuthenticator) Authenticate(_ context.Context, token string) (*Caller, error) {
+	if token != "token" {
+		return nil, errUnauthenticated
+	}
+	return &Caller{User: "system:serviceaccount:default:app", Org: "acme"}, nil
+}
+
+func newTestServer(t *testing.T, fgaClient *mocks.MockOpenFGAServiceClient) http.Handler {
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme").Return("store-id", nil).Maybe()
+
+	clusterIDs := map[string]string{
+		"root:orgs":           "orgs-id",
+		"root:orgs:acme":      "acme-id",
+		"root:orgs:acme:team": "team-id",
+	}
+	clusterID := func(_ context.Context, path logicalcluster.Path) (string, error) {
+		id, ok := clusterIDs[path.String()]
+		require.True(t, ok, "unexpected path %s", path)
+		return id, nil
+	}
+
+	return NewServer(config.AuthZENConfig{BindAddress: ":0"}, fgaClient, storeIDGetter, clusterID, staticAuthenticator{}, accountType, testlogger.New().Logger).Handler()
+}
+
+func post(t *testing.T, handler http.Handler, path string, body any) *httptest.ResponseRecorder {
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+	req.Header.Set(requestIDHeader, "request-id")
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "request-id", rec.Header().Get(requestIDHeader))
+	return rec
+}
+
+func expectCheck(fgaClient *mocks.MockOpenFGAServiceClient, relation, object string, allowed bool) *mocks.MockOpenFGAServiceClient_Check_Call {
+	return fgaClient.EXPECT().Check(mock.Anything, &openfgav1.CheckRequest{
+		StoreId: "store-id",
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     "user:alice@acme.corp",
+			Relation: relation,
+			Object:   object,
+		},
+	}).Return(&openfgav1.CheckResponse{Allowed: allowed}, nil)
+}
+
+func TestEvaluation(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource Resource
+		relation string
+		object   string
+	}{
+		{
+			name:     "accounts are referenced by their workspace path",
+			resource: Resource{Type: accountType, ID: "root:orgs:acme:team"},
+			relation: "member",
+			object:   "core_platform-mesh_io_account:acme-id/team",
+		},
+		{
+			name:     "cluster-scoped resources are referenced by workspace path and name",
+			resource: Resource{Type: "core_namespace", ID: "default", Properties: Properties{PathProperty: "root:orgs:acme:team"}},
+			relation: "get",
+			object:   "core_namespace:team-id/default",
+		},
+		{
+			name: "namespaced resources are referenced by workspace path, namespace and name",
+			resource: Resource{Type: "core_pod", ID: "web", Properties: Properties{
+				PathProperty:      "root:orgs:acme:team",
+				NamespaceProperty: "default",
+			}},
+			relation: "delete",
+			object:   "core_pod:team-id/default/web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+			expectCheck(fgaClient, tt.relation, tt.object, true).Once()
+
+			rec := post(t, newTestServer(t, fgaClient), EvaluationPath, EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   &Action{Name: tt.relation},
+				Resource: &tt.resource,
+			})
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var res EvaluationResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.True(t, res.Decision)
+		})
+	}
+}
+
+// pinnedStoreIDGetter resolves the stores of the mocked getter, pinned to an
+// authorization model.
+type pinnedStoreIDGetter struct {
+	*mocks.MockStoreIDGetter
+	modelID string
+}
+
+func (g pinnedStoreIDGetter) GetAuthorizationModelID(context.Context, string) (string, error) {
+	return g.modelID, nil
+}
+
+func TestEvaluation_PinnedAuthorizationModel(t *testing.T) {
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme").Return("store-id", nil)
+	clusterID := func(context.Context, logicalcluster.Path) (string, error) { return "team-id", nil }
+
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	fgaClient.EXPECT().Check(mock.Anything, &openfgav1.CheckRequest{
+		StoreId:              "store-id",
+		AuthorizationModelId: "model-0",
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     "user:alice@acme.corp",
+			Relation: "get",
+			Object:   "core_namespace:team-id/default",
+		},
+	}).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Once()
+
+	handler := NewServer(config.AuthZENConfig{BindAddress: ":0"}, fgaClient, pinnedStoreIDGetter{MockStoreIDGetter: storeIDGetter, modelID: "model-0"},
+		clusterID, staticAuthenticator{}, accountType, testlogger.New().Logger).Handler()
+	rec := post(t, handler, EvaluationPath, EvaluationRequest{
+		Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+		Action:   &Action{Name: "get"},
+		Resource: &Resource{Type: "core_namespace", ID: "default", Properties: Properties{PathProperty: "root:orgs:acme:team"}},
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestEvaluation_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  EvaluationRequest
+	}{
+		{
+			name: "missing subject",
+			req: EvaluationRequest{
+				Action:   &Action{Name: "get"},
+				Resource: &Resource{Type: accountType, ID: "root:orgs:acme:team"},
+			},
+		},
+		{
+			name: "missing path property",
+			req: EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   &Action{Name: "get"},
+				Resource: &Resource{Type: "core_namespace", ID: "default"},
+			},
+		},
+		{
+			name: "account outside of the orgs workspace",
+			req: EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   &Action{Name: "get"},
+				Resource: &Resource{Type: accountType, ID: "root:team"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(t, newTestServer(t, mocks.NewMockOpenFGAServiceClient(t)), EvaluationPath, tt.req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestEvaluation_Unauthenticated(t *testing.T) {
+	for _, header := range []string{"", "Bearer", "Bearer other", "Basic dXNlcjpwYXNz"} {
+		t.Run(header, func(t *testing.T) {
+			raw, err := json.Marshal(EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   &Action{Name: "get"},
+				Resource: &Resource{Type: accountType, ID: "root:orgs:acme:team"},
+			})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, EvaluationPath, bytes.NewReader(raw))
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			rec := httptest.NewRecorder()
+			newTestServer(t, mocks.NewMockOpenFGAServiceClient(t)).ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		})
+	}
+}
+
+func TestOtherOrgsAreForbidden(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body any
+	}{
+		{
+			name: "evaluation of an account",
+			path: EvaluationPath,
+			body: EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   &Action{Name: "get"},
+				Resource: &Resource{Type: accountType, ID: "root:orgs:other:team"},
+			},
+		},
+		{
+			name: "subject search",
+			path: SubjectSearchPath,
+			body: SearchRequest{
+				Subject:  Subject{Type: "user"},
+				Action:   Action{Name: "get"},
+				Resource: Resource{Type: "core_namespace", ID: "default", Properties: Properties{PathProperty: "root:orgs:other:team"}},
+			},
+		},
+		{
+			name: "resource search",
+			path: ResourceSearchPath,
+			body: SearchRequest{
+				Subject:  Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   Action{Name: "get"},
+				Resource: Resource{Type: "core_namespace", Properties: Properties{PathProperty: "root:orgs:other"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(t, newTestServer(t, mocks.NewMockOpenFGAServiceClient(t)), tt.path, tt.body)
+			assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestEvaluation_UnknownRelation(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).
+		Return(nil, status.Error(codes.InvalidArgument, "relation 'fly' not found")).Once()
+
+	rec := post(t, newTestServer(t, fgaClient), EvaluationPath, EvaluationRequest{
+		Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+		Action:   &Action{Name: "fly"},
+		Resource: &Resource{Type: accountType, ID: "root:orgs:acme:team"},
+	})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "relation 'fly' not found")
+}
+
+func TestEvaluations(t *testing.T) {
+	batch := func(semantic string) EvaluationsRequest {
+		return EvaluationsRequest{
+			EvaluationRequest: EvaluationRequest{
+				Subject:  &Subject{Type: "user", ID: "alice@acme.corp"},
+				Resource: &Resource{Type: accountType, ID: "root:orgs:acme:team"},
+			},
+			Evaluations: []EvaluationRequest{
+				{Action: &Action{Name: "get"}},
+				{Action: &Action{Name: "delete"}},
+				{Action: &Action{Name: "update"}},
+			},
+			Options: &EvaluationsOptions{EvaluationsSemantic: semantic},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		semantic  string
+		decisions []bool
+	}{
+		{name: "execute all", semantic: "", decisions: []bool{true, false, true}},
+		{name: "deny on first deny", semantic: DenyOnFirstDeny, decisions: []bool{true, false}},
+		{name: "permit on first permit", semantic: PermitOnFirstPermit, decisions: []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+			object := "core_platform-mesh_io_account:acme-id/team"
+			expectCheck(fgaClient, "get", object, true).Once()
+			expectCheck(fgaClient, "delete", object, false).Maybe()
+			expectCheck(fgaClient, "update", object, true).Maybe()
+
+			rec := post(t, newTestServer(t, fgaClient), EvaluationsPath, batch(tt.semantic))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var res EvaluationsResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			decisions := make([]bool, 0, len(res.Evaluations))
+			for _, e := range res.Evaluations {
+				decisions = append(decisions, e.Decision)
+			}
+			assert.Equal(t, tt.decisions, decisions)
+		})
+	}
+}
+
+func TestEvaluations_ErrorsAreReportedPerEvaluation(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	expectCheck(fgaClient, "get", "core_platform-mesh_io_account:acme-id/team", true).Once()
+
+	rec := post(t, newTestServer(t, fgaClient), EvaluationsPath, EvaluationsRequest{
+		EvaluationRequest: EvaluationRequest{
+			Subject: &Subject{Type: "user", ID: "alice@acme.corp"},
+			Action:  &Action{Name: "get"},
+		},
+		Evaluations: []EvaluationRequest{
+			{Resource: &Resource{Type: accountType, ID: "root:orgs:acme:team"}},
+			{Resource: &Resource{Type: "core_namespace", ID: "default"}},
+		},
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res EvaluationsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Evaluations, 2)
+	assert.True(t, res.Evaluations[0].Decision)
+	assert.False(t, res.Evaluations[1].Decision)
+	assert.Contains(t, res.Evaluations[1].Context, "error")
+}
+
+func TestSearchSubject(t *testing.T) {
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListUsers(mock.Anything, &openfgav1.ListUsersRequest{
+		StoreId:     "store-id",
+		Object:      &openfgav1.Object{Type: "core_pod", Id: "team-id/default/web"},
+		Relation:    "get",
+		UserFilters: []*openfgav1.UserTypeFilter{{Type: "user"}},
+	}).Return(&openfgav1.ListUsersResponse{Users: []*openfgav1.User{
+		{User: &openfgav1.User_Object{Object: &openfgav1.Object{Type: "user", Id: "alice@acme.corp"}}},
+		{User: &openfgav1.User_Wildcard{Wildcard: &openfgav1.TypedWildcard{Type: "user"}}},
+	}}, nil).Once()
+
+	rec := post(t, newTestServer(t, fgaClient), SubjectSearchPath, SearchRequest{
+		Subject: Subject{Type: "user"},
+		Action:  Action{Name: "get"},
+		Resource: Resource{Type: "core_pod", ID: "web", Properties: Properties{
+			PathProperty:      "root:orgs:acme:team",
+			NamespaceProperty: "default",
+		}},
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res SearchResponse[Subject]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, []Subject{{Type: "user", ID: "alice@acme.corp"}}, res.Results)
+}
+
+func TestSearchResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource Resource
+		objects  []string
+		results  []Resource
+	}{
+		{
+			name:     "accounts are returned by their workspace path",
+			resource: Resource{Type: accountType, Properties: Properties{PathProperty: "root:orgs:acme"}},
+			objects: []string{
+				"core_platform-mesh_io_account:acme-id/team",
+				"core_platform-mesh_io_account:orgs-id/acme",
+			},
+			results: []Resource{{Type: accountType, ID: "root:orgs:acme:team"}},
+		},
+		{
+			name: "namespaced resources are filtered by namespace",
+			resource: Resource{Type: "core_pod", Properties: Properties{
+				PathProperty:      "root:orgs:acme:team",
+				NamespaceProperty: "default",
+			}},
+			objects: []string{
+				"core_pod:team-id/default/web",
+				"core_pod:team-id/kube-system/dns",
+				"core_pod:acme-id/default/db",
+			},
+			results: []Resource{{Type: "core_pod", ID: "web", Properties: Properties{
+				PathProperty:      "root:orgs:acme:team",
+				NamespaceProperty: "default",
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+			fgaClient.EXPECT().ListObjects(mock.Anything, &openfgav1.ListObjectsRequest{
+				StoreId:  "store-id",
+				Type:     tt.resource.Type,
+				Relation: "get",
+				User:     "user:alice@acme.corp",
+			}).Return(&openfgav1.ListObjectsResponse{Objects: tt.objects}, nil).Once()
+
+			rec := post(t, newTestServer(t, fgaClient), ResourceSearchPath, SearchRequest{
+				Subject:  Subject{Type: "user", ID: "alice@acme.corp"},
+				Action:   Action{Name: "get"},
+				Resource: tt.resource,
+			})
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var res SearchResponse[Resource]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Equal(t, tt.results, res.Results)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package authzen
+
+// The types of the OpenID AuthZEN Authorization API 1.0 requests and
+// responses, see https://openid.net/specs/authorization-api-1_0.html.
+
+// Properties are the additional attributes of a subject, resource or action.
+type Properties map[string]any
+
+// String returns a property if it is a string.
+func (p Properties) String(name string) string {
+	s, _ := p[name].(string)
+	return s
+}
+
+type Subject struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id,omitempty"`
+	Properties Properties `json:"properties,omitempty"`
+}
+
+type Resource struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id,omitempty"`
+	Properties Properties `json:"properties,omitempty"`
+}
+
+type Action struct {
+	Name       string     `json:"name"`
+	Properties Properties `json:"properties,omitempty"`
+}
+
+type EvaluationRequest struct {
+	Subject  *Subject       `json:"subject,omitempty"`
+	Action   *Action        `json:"action,omitempty"`
+	Resource *Resource      `json:"resource,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+type EvaluationResponse struct {
+	Decision bool           `json:"decision"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+// Evaluation semantics of a batch, executing all evaluations is the default.
+const (
+	ExecuteAll          = "execute_all"
+	DenyOnFirstDeny     = "deny_on_first_deny"
+	PermitOnFirstPermit = "permit_on_first_permit"
+)
+
+type EvaluationsOptions struct {
+	EvaluationsSemantic string `json:"evaluations_semantic,omitempty"`
+}
+
+// EvaluationsRequest holds a batch of evaluations. The subject, action,
+// resource and context of the request are the defaults of each evaluation.
+type EvaluationsRequest struct {
+	EvaluationRequest
+	Evaluations []EvaluationRequest `json:"evaluations,omitempty"`
+	Options     *EvaluationsOptions `json:"options,omitempty"`
+}
+
+type EvaluationsResponse struct {
+	Evaluations []EvaluationResponse `json:"evaluations"`
+}
+
+type Page struct {
+	NextToken string `json:"next_token,omitempty"`
+}
+
+type SearchRequest struct {
+	Subject  Subject        `json:"subject"`
+	Action   Action         `json:"action"`
+	Resource Resource       `json:"resource"`
+	Context  map[string]any `json:"context,omitempty"`
+	Page     *Page          `json:"page,omitempty"`
+}
+
+type SearchResponse[T any] struct {
+	Results []T   `json:"results"`
+	Page    *Page `json:"page,omitempty"`
+}
@@ -3,7 +3,9 @@ package client
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jellydator/ttlcache/v3"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
@@ -76,23 +78,36 @@ func (p *ProviderLister) List(ctx context.Context, list client.ObjectList, opts
 	return p.provider.Lister().List(ctx, list, opts...)
 }
 
+const (
+	// clusterClientTTL is how long the clients of a logical cluster are
+	// reused, as building one sets up its own transport and REST mapper.
+	clusterClientTTL           = 10 * time.Minute
+	clusterClientCacheCapacity = 1000
+)
+
 // ConfigSchemeKCPClientGetter builds cluster and all-Clients via a given config
-// and scheme.
+// and scheme. The clients of a logical cluster are reused for a while.
 type ConfigSchemeKCPClientGetter struct {
-	config *rest.Config
-	scheme *runtime.Scheme
+	config  *rest.Config
+	scheme  *runtime.Scheme
+	clients *ttlcache.Cache[string, client.Client]
 }
 
 func NewConfigSchemeKCPClientGetter(config *rest.Config, scheme *runtime.Scheme) *ConfigSchemeKCPClientGetter {
 	return &ConfigSchemeKCPClientGetter{
 		config: config,
 		scheme: scheme,
+		clients: ttlcache.New(
+			ttlcache.WithTTL[string, client.Client](clusterClientTTL),
+			ttlcache.WithCapacity[string, client.Client](clusterClientCacheCapacity),
+			ttlcache.WithDisableTouchOnHit[string, client.Client](),
+		),
 	}
 }
 
 func (f *ConfigSchemeKCPClientGetter) NewClientForLogicalCluster(ctx context.Context, cluster string) (client.Client, error) {
 	_ = ctx
-	return NewForLogicalCluster(f.config, f.scheme, logicalcluster.Name(cluster))
+	return f.clientFor(logicalcluster.Name(cluster))
 }
 
 func (f *ConfigSchemeKCPClientGetter) NewClientFromContext(ctx context.Context) (client.Client, error) {
@@ -101,5 +116,19 @@ func (f *ConfigSchemeKCPClientGetter) NewClientFromContext(ctx context.Context)
 		return nil, fmt.Errorf("no cluster set in context, use ReconcilerWithCluster helper when building the controller")
 	}
 
-	return NewForLogicalCluster(f.config, f.scheme, logicalcluster.Name(clusterName))
+	return f.clientFor(logicalcluster.Name(clusterName))
+}
+
+// clientFor returns the cached client of a logical cluster or builds one.
+func (f *ConfigSchemeKCPClientGetter) clientFor(cluster logicalcluster.Name) (client.Client, error) {
+	if item := f.clients.Get(cluster.String()); item != nil {
+		return item.Value(), nil
+	}
+
+	cl, err := NewForLogicalCluster(f.config, f.scheme, cluster)
+	if err != nil {
+		return nil, err
+	}
+	f.clients.Set(cluster.String(), cl, ttlcache.DefaultTTL)
+	return cl, nil
 }
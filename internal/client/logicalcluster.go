@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,8 +21,34 @@ func NewForLogicalCluster(config *rest.Config, scheme *runtime.Scheme, clusterKe
 	return clientForPath(config, scheme, path)
 }
 
+// NewCacheForLogicalCluster returns an informer cache for a given logical
+// cluster name or path, based on a KCP base config. The cache has to be
+// started, e.g. by adding it to a manager.
+func NewCacheForLogicalCluster(config *rest.Config, scheme *runtime.Scheme, clusterKey logicalcluster.Name) (cache.Cache, error) {
+	copy, err := configForPath(config, fmt.Sprintf("/clusters/%s", clusterKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.New(copy, cache.Options{
+		Scheme: scheme,
+	})
+}
+
 // clientForPath returns a client for a give raw URL path.
 func clientForPath(config *rest.Config, scheme *runtime.Scheme, path string) (client.Client, error) {
+	copy, err := configForPath(config, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.New(copy, client.Options{
+		Scheme: scheme,
+	})
+}
+
+// configForPath returns a copy of the config for a given raw URL path.
+func configForPath(config *rest.Config, path string) (*rest.Config, error) {
 	copy := rest.CopyConfig(config)
 
 	parsed, err := url.Parse(copy.Host)
@@ -31,7 +58,5 @@ func clientForPath(config *rest.Config, scheme *runtime.Scheme, path string) (cl
 	parsed.Path = path
 	copy.Host = parsed.String()
 
-	return client.New(copy, client.Options{
-		Scheme: scheme,
-	})
+	return copy, nil
 }
@@ -27,6 +27,44 @@ type WebhooksConfig struct {
 	CertDir string
 }
 
+// AuthorizerConfig configures the webhook authorizer. It is served by the
+// webhook server.
+type AuthorizerConfig struct {
+	Path string
+	// DecisionCacheTTL is how long decisions are remembered, 0 disables the
+	// decision cache.
+	DecisionCacheTTL time.Duration
+}
+
+// AuthZENConfig configures the OpenID AuthZEN API served next to the webhook
+// authorizer.
+type AuthZENConfig struct {
+	Enabled     bool
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key the API is served with.
+	CertDir string
+	// TokenAudiences are the audiences bearer tokens of callers must be
+	// issued for, if any.
+	TokenAudiences []string
+	// TokenCacheTTL is how long the callers of authenticated tokens are
+	// remembered, 0 disables the token cache.
+	TokenCacheTTL time.Duration
+}
+
+// AccessCheckConfig configures how AccessChecks and SelfAccessChecks are
+// answered.
+type AccessCheckConfig struct {
+	// TTL is how long completed checks are kept before they are deleted.
+	TTL time.Duration
+	// RateLimit is the number of checks per second answered for a workspace,
+	// Burst the number of checks answered at once.
+	RateLimit float64
+	Burst     int
+	// SigningKeyFile holds the key the users of SelfAccessChecks are signed
+	// with on admission. It must be the same for all replicas.
+	SigningKeyFile string
+}
+
 type InitializerConfig struct {
 	WorkspaceInitializerEnabled bool
 	IDPEnabled                  bool
@@ -34,12 +72,64 @@ type InitializerConfig struct {
 	WorkspaceAuthEnabled        bool
 }
 
+// FGATLSConfig configures TLS for the connection to the OpenFGA API. The
+// files are read again whenever they change.
+type FGATLSConfig struct {
+	Enabled bool
+	// CAFile replaces the system roots the server certificate is verified
+	// with.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mutual TLS.
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// FGAAuthConfig configures how requests to the OpenFGA API are
+// authenticated. At most one of a preshared key and client credentials is
+// used. Secrets are read from files so they can be rotated.
+type FGAAuthConfig struct {
+	PresharedKeyFile string
+
+	ClientID         string
+	ClientSecretFile string
+	TokenURL         string
+	Audience         string
+	Scopes           []string
+
+	// AllowInsecure sends credentials over connections without TLS.
+	AllowInsecure bool
+}
+
 type FGAConfig struct {
-	Target          string
-	ObjectType      string
-	ParentRelation  string
-	CreatorRelation string
-	StoreIDCacheTTL time.Duration
+	Target string
+	TLS    FGATLSConfig
+	Auth   FGAAuthConfig
+	// EmbeddedSeedModule is an FGA module file written to a new store of the
+	// embedded OpenFGA server when it is started.
+	EmbeddedSeedModule string
+	// EmbeddedListenAddress exposes the embedded OpenFGA server on a TCP
+	// address, so other commands can use it as their target.
+	EmbeddedListenAddress string
+	// Timeout is the deadline of a single request to OpenFGA unless
+	// MethodTimeouts holds one for its method.
+	Timeout        time.Duration
+	MethodTimeouts map[string]string
+	// MaxRetries is the number of times idempotent requests are retried
+	// while OpenFGA is unavailable or exhausted.
+	MaxRetries int
+	// CircuitBreakerThreshold is the number of consecutive failed requests
+	// after which requests are rejected for CircuitBreakerCooldown, 0
+	// disables the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+
+	ObjectType         string
+	ParentRelation     string
+	CreatorRelation    string
+	StoreIDCacheTTL    time.Duration
+	WriteChunkSize     int
+	DriftCheckInterval time.Duration
 }
 
 type KCPConfig struct {
@@ -92,16 +182,35 @@ type Config struct {
 	Keycloak                         KeycloakConfig
 	Initializer                      InitializerConfig
 	Webhooks                         WebhooksConfig
+	Authorizer                       AuthorizerConfig
+	AuthZEN                          AuthZENConfig
+	AccessCheck                      AccessCheckConfig
 	AdditionalAudiences              []string
+	StoreSnapshotNamespace           string
+	// DryRun records changes to OpenFGA, Keycloak and Secrets instead of
+	// making them.
+	DryRun bool
 }
 
 func NewConfig() Config {
 	return Config{
 		FGA: FGAConfig{
-			ObjectType:      "core_platform-mesh_io_account",
-			ParentRelation:  "parent",
-			CreatorRelation: "owner",
-			StoreIDCacheTTL: 24 * time.Hour,
+			ObjectType:         "core_platform-mesh_io_account",
+			ParentRelation:     "parent",
+			CreatorRelation:    "owner",
+			StoreIDCacheTTL:    24 * time.Hour,
+			WriteChunkSize:     100,
+			DriftCheckInterval: 10 * time.Minute,
+			Timeout:            10 * time.Second,
+			MethodTimeouts: map[string]string{
+				"ListObjects": "30s",
+				"ListUsers":   "30s",
+				"Read":        "30s",
+				"Write":       "30s",
+			},
+			MaxRetries:              3,
+			CircuitBreakerThreshold: 5,
+			CircuitBreakerCooldown:  30 * time.Second,
 		},
 		KCP: KCPConfig{
 			Kubeconfig: "/api-kubeconfig/kubeconfig",
@@ -116,6 +225,7 @@ func NewConfig() Config {
 		WorkspacePath:            "root",
 		WorkspaceTypeName:        "security",
 		HttpClientTimeoutSeconds: 30,
+		StoreSnapshotNamespace:   "platform-mesh-system",
 		IDP: IDPConfig{
 			KubectlClientRedirectURLs: []string{"http://localhost:8000", "http://localhost:18000"},
 			AccessTokenLifespan:       28800,
@@ -134,12 +244,53 @@ func NewConfig() Config {
 			Port:    9443,
 			CertDir: "/tmp/k8s-webhook-server/serving-certs",
 		},
+		Authorizer: AuthorizerConfig{
+			Path:             "/authorize",
+			DecisionCacheTTL: 10 * time.Second,
+		},
+		AuthZEN: AuthZENConfig{
+			BindAddress:   "localhost:8090",
+			CertDir:       "/tmp/k8s-webhook-server/serving-certs",
+			TokenCacheTTL: time.Minute,
+		},
+		AccessCheck: AccessCheckConfig{
+			TTL:       10 * time.Minute,
+			RateLimit: 1,
+			Burst:     10,
+		},
 	}
 }
 
+// AddConnectionFlags adds the flags configuring how the OpenFGA API is
+// reached.
+func (c *FGAConfig) AddConnectionFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&c.Target, "fga-target", c.Target, "Set the OpenFGA API target, \"embedded\" starts an OpenFGA server with an in-memory datastore in the process, which requires the binary of the embeddedfga module")
+	fs.StringVar(&c.EmbeddedSeedModule, "fga-embedded-seed-module", c.EmbeddedSeedModule, "Seed the embedded OpenFGA server with a store holding the given module, e.g. data/coreModule.fga")
+	fs.StringVar(&c.EmbeddedListenAddress, "fga-embedded-listen-address", c.EmbeddedListenAddress, "Expose the embedded OpenFGA server on the given address, e.g. localhost:8081, so other commands can set it as their --fga-target")
+	fs.BoolVar(&c.TLS.Enabled, "fga-tls-enabled", c.TLS.Enabled, "Connect to the OpenFGA API using TLS")
+	fs.StringVar(&c.TLS.CAFile, "fga-tls-ca-file", c.TLS.CAFile, "Set the CA bundle the OpenFGA server certificate is verified with")
+	fs.StringVar(&c.TLS.CertFile, "fga-tls-cert-file", c.TLS.CertFile, "Set the client certificate for mutual TLS with the OpenFGA API")
+	fs.StringVar(&c.TLS.KeyFile, "fga-tls-key-file", c.TLS.KeyFile, "Set the client key for mutual TLS with the OpenFGA API")
+	fs.StringVar(&c.TLS.ServerName, "fga-tls-server-name", c.TLS.ServerName, "Override the server name the OpenFGA server certificate is verified for")
+	fs.StringVar(&c.Auth.PresharedKeyFile, "fga-preshared-key-file", c.Auth.PresharedKeyFile, "Set the file holding the preshared key to authenticate to the OpenFGA API with")
+	fs.StringVar(&c.Auth.ClientID, "fga-client-id", c.Auth.ClientID, "Set the OAuth2 client ID to authenticate to the OpenFGA API with")
+	fs.StringVar(&c.Auth.ClientSecretFile, "fga-client-secret-file", c.Auth.ClientSecretFile, "Set the file holding the OAuth2 client secret")
+	fs.StringVar(&c.Auth.TokenURL, "fga-token-url", c.Auth.TokenURL, "Set the OAuth2 token endpoint for client credentials")
+	fs.StringVar(&c.Auth.Audience, "fga-token-audience", c.Auth.Audience, "Set the audience requested for OAuth2 client credentials tokens")
+	fs.StringSliceVar(&c.Auth.Scopes, "fga-token-scopes", c.Auth.Scopes, "Set the scopes requested for OAuth2 client credentials tokens")
+	fs.BoolVar(&c.Auth.AllowInsecure, "fga-allow-insecure-credentials", c.Auth.AllowInsecure, "Allow sending credentials to the OpenFGA API without TLS")
+	fs.DurationVar(&c.Timeout, "fga-timeout", c.Timeout, "Set the deadline of a single OpenFGA request, 0 disables it")
+	fs.StringToStringVar(&c.MethodTimeouts, "fga-method-timeouts", c.MethodTimeouts, "Override the deadline per OpenFGA method, e.g. ListObjects=1m")
+	fs.IntVar(&c.MaxRetries, "fga-max-retries", c.MaxRetries, "Set how often idempotent OpenFGA requests are retried while OpenFGA is unavailable")
+	fs.IntVar(&c.CircuitBreakerThreshold, "fga-circuit-breaker-threshold", c.CircuitBreakerThreshold, "Number of consecutive failed OpenFGA requests that open the circuit breaker, 0 disables it")
+	fs.DurationVar(&c.CircuitBreakerCooldown, "fga-circuit-breaker-cooldown", c.CircuitBreakerCooldown, "Time the circuit breaker rejects OpenFGA requests before letting one through again")
+}
+
 func (c *Config) AddFlags(fs *pflag.FlagSet) {
-	fs.StringVar(&c.FGA.Target, "fga-target", c.FGA.Target, "Set the OpenFGA API target")
+	c.FGA.AddConnectionFlags(fs)
 	fs.DurationVar(&c.FGA.StoreIDCacheTTL, "fga-store-id-cache-ttl", c.FGA.StoreIDCacheTTL, "TTL for the OpenFGA store ID cache (e.g. 5m, 1h)")
+	fs.IntVar(&c.FGA.WriteChunkSize, "fga-write-chunk-size", c.FGA.WriteChunkSize, "Set the maximum number of tuples per OpenFGA write request")
+	fs.DurationVar(&c.FGA.DriftCheckInterval, "fga-drift-check-interval", c.FGA.DriftCheckInterval, "Interval for reading managed tuples back from OpenFGA to repair drift, 0 disables drift detection. Objects with a tuple ownership read their whole store")
 	fs.StringVar(&c.FGA.ObjectType, "fga-object-type", c.FGA.ObjectType, "Set the OpenFGA object type for account tuples")
 	fs.StringVar(&c.FGA.ParentRelation, "fga-parent-relation", c.FGA.ParentRelation, "Set the OpenFGA parent relation name")
 	fs.StringVar(&c.FGA.CreatorRelation, "fga-creator-relation", c.FGA.CreatorRelation, "Set the OpenFGA creator relation name")
@@ -177,9 +328,22 @@ func (c *Config) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&c.Initializer.InviteEnabled, "initializer-invite-enabled", c.Initializer.InviteEnabled, "Enable invite initialization")
 	fs.BoolVar(&c.Initializer.WorkspaceAuthEnabled, "initializer-workspace-auth-enabled", c.Initializer.WorkspaceAuthEnabled, "Enable workspace auth initialization")
 	fs.StringSliceVar(&c.AdditionalAudiences, "additional-audiences", c.AdditionalAudiences, "Additional audiences to trust in workspace JWT authentication configurations")
+	fs.StringVar(&c.StoreSnapshotNamespace, "store-snapshot-namespace", c.StoreSnapshotNamespace, "Set the namespace of the runtime cluster store snapshots are written to")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun, "Record changes to OpenFGA, Keycloak and Secrets instead of making them, the report is served on the metrics server at /debug/dry-run. Status and finalizers are left alone and the leader-election ID gets a -dry-run suffix")
 	fs.BoolVar(&c.Webhooks.Enabled, "webhooks-enabled", c.Webhooks.Enabled, "Enable validating webhooks")
 	fs.IntVar(&c.Webhooks.Port, "webhooks-port", c.Webhooks.Port, "Set webhook server port")
 	fs.StringVar(&c.Webhooks.CertDir, "webhooks-cert-dir", c.Webhooks.CertDir, "Set webhook certificate directory")
+	fs.StringVar(&c.Authorizer.Path, "authorizer-path", c.Authorizer.Path, "Set the path the webhook authorizer is served at")
+	fs.DurationVar(&c.Authorizer.DecisionCacheTTL, "authorizer-decision-cache-ttl", c.Authorizer.DecisionCacheTTL, "Set how long webhook authorizer decisions are cached, 0 disables the cache")
+	fs.BoolVar(&c.AuthZEN.Enabled, "authzen-enabled", c.AuthZEN.Enabled, "Enable the AuthZEN access evaluation API")
+	fs.StringVar(&c.AuthZEN.BindAddress, "authzen-bind-address", c.AuthZEN.BindAddress, "Set the address the AuthZEN API is served at")
+	fs.StringVar(&c.AuthZEN.CertDir, "authzen-cert-dir", c.AuthZEN.CertDir, "Set the directory holding the tls.crt and tls.key the AuthZEN API is served with")
+	fs.StringSliceVar(&c.AuthZEN.TokenAudiences, "authzen-token-audiences", c.AuthZEN.TokenAudiences, "Set the audiences bearer tokens of AuthZEN callers must be issued for")
+	fs.DurationVar(&c.AuthZEN.TokenCacheTTL, "authzen-token-cache-ttl", c.AuthZEN.TokenCacheTTL, "Set how long authenticated AuthZEN bearer tokens are cached, 0 disables the cache")
+	fs.DurationVar(&c.AccessCheck.TTL, "access-check-ttl", c.AccessCheck.TTL, "Set how long completed AccessChecks and SelfAccessChecks are kept")
+	fs.Float64Var(&c.AccessCheck.RateLimit, "access-check-rate-limit", c.AccessCheck.RateLimit, "Set the number of AccessChecks and SelfAccessChecks per second answered for a workspace")
+	fs.IntVar(&c.AccessCheck.Burst, "access-check-burst", c.AccessCheck.Burst, "Set the number of AccessChecks and SelfAccessChecks answered at once for a workspace")
+	fs.StringVar(&c.AccessCheck.SigningKeyFile, "access-check-signing-key-file", c.AccessCheck.SigningKeyFile, "Set the file holding the key the users of SelfAccessChecks are signed with, SelfAccessChecks are only answered if it is set")
 }
 
 func (config Config) InitializerName() string {
@@ -27,6 +27,11 @@ func TestConfigAddFlags(t *testing.T) {
 
 	err := fs.Parse([]string{
 		"--fga-target=fga:8080",
+		"--fga-tls-enabled=true",
+		"--fga-tls-ca-file=/etc/fga/ca.crt",
+		"--fga-token-scopes=read,write",
+		"--fga-method-timeouts=ListObjects=1m",
+		"--fga-max-retries=5",
 		"--kcp-kubeconfig=/tmp/kubeconfig",
 		"--idp-kubectl-client-redirect-urls=http://localhost:7000,http://localhost:17000",
 		"--webhooks-enabled=true",
@@ -36,6 +41,11 @@ func TestConfigAddFlags(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.Equal(t, "fga:8080", cfg.FGA.Target)
+	assert.True(t, cfg.FGA.TLS.Enabled)
+	assert.Equal(t, "/etc/fga/ca.crt", cfg.FGA.TLS.CAFile)
+	assert.Equal(t, []string{"read", "write"}, cfg.FGA.Auth.Scopes)
+	assert.Equal(t, map[string]string{"ListObjects": "1m"}, cfg.FGA.MethodTimeouts)
+	assert.Equal(t, 5, cfg.FGA.MaxRetries)
 	assert.Equal(t, "/tmp/kubeconfig", cfg.KCP.Kubeconfig)
 	assert.Equal(t, []string{"http://localhost:7000", "http://localhost:17000"}, cfg.IDP.KubectlClientRedirectURLs)
 	assert.True(t, cfg.Webhooks.Enabled)
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	platformeshconfig "github.com/platform-mesh/golang-commons/config"
+	"github.com/platform-mesh/golang-commons/controller/filter"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/metrics"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/subroutines"
+	"github.com/platform-mesh/subroutines/conditions"
+	"github.com/platform-mesh/subroutines/lifecycle"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+)
+
+// AccessCheckReconciler answers AccessChecks or SelfAccessChecks and deletes
+// them once they expired.
+type AccessCheckReconciler struct {
+	log       *logger.Logger
+	mgr       mcmanager.Manager
+	name      string
+	newObj    func() subroutine.AccessCheckObject
+	ttl       time.Duration
+	lifecycle *lifecycle.Lifecycle
+}
+
+// NewAccessCheckReconciler returns the reconciler of AccessChecks. The
+// subroutine is shared with the SelfAccessCheck reconciler, so both kinds of
+// checks count against the same rate limit.
+func NewAccessCheckReconciler(log *logger.Logger, mcMgr mcmanager.Manager, cfg *config.Config, checks subroutines.Subroutine) *AccessCheckReconciler {
+	return newAccessCheckReconciler(log, mcMgr, cfg, checks, "AccessCheckReconciler", "accesscheck", func() subroutine.AccessCheckObject {
+		return &corev1alpha1.AccessCheck{}
+	})
+}
+
+// NewSelfAccessCheckReconciler returns the reconciler of SelfAccessChecks.
+func NewSelfAccessCheckReconciler(log *logger.Logger, mcMgr mcmanager.Manager, cfg *config.Config, checks subroutines.Subroutine) *AccessCheckReconciler {
+	return newAccessCheckReconciler(log, mcMgr, cfg, checks, "SelfAccessCheckReconciler", "selfaccesscheck", func() subroutine.AccessCheckObject {
+		return &corev1alpha1.SelfAccessCheck{}
+	})
+}
+
+func newAccessCheckReconciler(log *logger.Logger, mcMgr mcmanager.Manager, cfg *config.Config, checks subroutines.Subroutine, reconcilerName, name string, newObj func() subroutine.AccessCheckObject) *AccessCheckReconciler {
+	lc := lifecycle.New(mcMgr, reconcilerName, func() client.Object {
+		return newObj()
+	}, checks).
+		WithConditions(conditions.NewManager())
+
+	return &AccessCheckReconciler{
+		log:       log,
+		mgr:       mcMgr,
+		name:      name,
+		newObj:    newObj,
+		ttl:       cfg.AccessCheck.TTL,
+		lifecycle: dryrun.ReadOnly(lc),
+	}
+}
+
+func (r *AccessCheckReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := r.reconcile(ctx, req)
+	labelResult := "success"
+	if err != nil {
+		labelResult = "error"
+	}
+	metrics.ReconcileTotal.WithLabelValues(r.name, labelResult).Inc()
+	metrics.ReconcileDuration.WithLabelValues(r.name).Observe(time.Since(start).Seconds())
+	return result, err
+}
+
+func (r *AccessCheckReconciler) reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
+	cluster, err := r.mgr.GetCluster(ctx, req.ClusterName)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("getting cluster %s: %w", req.ClusterName, err)
+	}
+	cl := cluster.GetClient()
+
+	check := r.newObj()
+	if err := cl.Get(ctx, req.NamespacedName, check); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if check.GetAccessCheckStatus().CompletionTime != nil && subroutine.AccessCheckExpiresIn(check, r.ttl) <= 0 {
+		if err := cl.Delete(ctx, check); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("deleting expired check %s: %w", req.Name, err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	return r.lifecycle.Reconcile(ctx, req)
+}
+
+func (r *AccessCheckReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *platformeshconfig.CommonServiceConfig, evp ...predicate.Predicate) error {
+	opts := controller.TypedOptions[mcreconcile.Request]{
+		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+	}
+	predicates := append([]predicate.Predicate{filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)}, evp...)
+	return mcbuilder.ControllerManagedBy(mgr).
+		Named(r.name).
+		For(r.newObj()).
+		WithOptions(opts).
+		WithEventFilter(predicate.And(predicates...)).
+		Complete(r)
+}
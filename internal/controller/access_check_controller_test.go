@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+func newAccessCheckTestReconciler(t *testing.T, objects ...client.Object) (*AccessCheckReconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(securityv1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	cluster := mocks.NewMockCluster(t)
+	cluster.EXPECT().GetClient().Return(cl)
+	mgr := mocks.NewMockManager(t)
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("team-id")).Return(cluster, nil)
+
+	cfg := config.NewConfig()
+	cfg.AccessCheck.TTL = time.Minute
+	// The subroutine is not reached by the cases below.
+	return NewSelfAccessCheckReconciler(testlogger.New().Logger, mgr, &cfg, nil), cl
+}
+
+func accessCheckRequest() mcreconcile.Request {
+	return mcreconcile.Request{
+		Request:     reconcile.Request{NamespacedName: client.ObjectKey{Name: "check"}},
+		ClusterName: "team-id",
+	}
+}
+
+func TestAccessCheckReconcilerDeletesExpiredChecks(t *testing.T) {
+	check := &securityv1alpha1.SelfAccessCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "check"},
+		Status: securityv1alpha1.AccessCheckStatus{
+			CompletionTime: &metav1.Time{Time: time.Now().Add(-2 * time.Minute)},
+		},
+	}
+	r, cl := newAccessCheckTestReconciler(t, check)
+
+	result, err := r.Reconcile(context.Background(), accessCheckRequest())
+	require.NoError(t, err)
+	assert.Zero(t, result)
+
+	err = cl.Get(context.Background(), client.ObjectKey{Name: "check"}, &securityv1alpha1.SelfAccessCheck{})
+	assert.True(t, kerrors.IsNotFound(err), "expected the expired check to be deleted, got %v", err)
+}
+
+func TestAccessCheckReconcilerIgnoresDeletedChecks(t *testing.T) {
+	r, _ := newAccessCheckTestReconciler(t)
+
+	result, err := r.Reconcile(context.Background(), accessCheckRequest())
+	require.NoError(t, err)
+	assert.Zero(t, result)
+}
@@ -8,6 +8,7 @@ import (
 	platformeshconfig "github.com/platform-mesh/golang-commons/config"
 	"github.com/platform-mesh/golang-commons/controller/filter"
 	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
 	"github.com/platform-mesh/subroutines/lifecycle"
@@ -32,7 +33,7 @@ func NewAccountInfoReconciler(log *logger.Logger, mcMgr mcmanager.Manager) *Acco
 
 	return &AccountInfoReconciler{
 		log:       log,
-		lifecycle: lc,
+		lifecycle: dryrun.ReadOnly(lc),
 	}
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
@@ -44,7 +45,7 @@ func NewAccountLogicalClusterController(log *logger.Logger, cfg config.Config, f
 
 	lc := lifecycle.New(mgr, opts.Name, func() client.Object {
 		return &kcpcorev1alpha1.LogicalCluster{}
-	}, subroutine.NewAccountTuplesSubroutine(mgr, fgaClient, storeIDGetter, cfg.FGA.CreatorRelation, cfg.FGA.ParentRelation, cfg.FGA.ObjectType, kcpClientGetter))
+	}, subroutine.NewAccountTuplesSubroutine(mgr, fgaClient, storeIDGetter, cfg.FGA.CreatorRelation, cfg.FGA.ParentRelation, cfg.FGA.ObjectType, cfg.FGA.WriteChunkSize, kcpClientGetter))
 
 	if opts.InitializerName != "" {
 		lc = lc.WithInitializer(opts.InitializerName)
@@ -56,7 +57,7 @@ func NewAccountLogicalClusterController(log *logger.Logger, cfg config.Config, f
 	return &AccountLogicalClusterController{
 		log:         log,
 		name:        opts.Name,
-		lifecycle:   lc,
+		lifecycle:   dryrun.ReadOnly(lc),
 		rateLimiter: rl,
 	}, nil
 }
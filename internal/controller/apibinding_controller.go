@@ -7,19 +7,29 @@ import (
 	platformeshconfig "github.com/platform-mesh/golang-commons/config"
 	"github.com/platform-mesh/golang-commons/controller/filter"
 	"github.com/platform-mesh/golang-commons/logger"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
 	"github.com/platform-mesh/subroutines/lifecycle"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	"sigs.k8s.io/multicluster-runtime/pkg/handler"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
 
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kcp-dev/logicalcluster/v3"
 	kcpapisv1alpha2 "github.com/kcp-dev/sdk/apis/apis/v1alpha2"
 )
 
@@ -30,12 +40,14 @@ func NewAPIBindingReconciler(logger *logger.Logger, mcMgr mcmanager.Manager, lis
 
 	return &APIBindingReconciler{
 		log:       logger,
-		lifecycle: lc,
+		lister:    lister,
+		lifecycle: dryrun.ReadOnly(lc),
 	}
 }
 
 type APIBindingReconciler struct {
 	log       *logger.Logger
+	lister    iclient.Lister
 	lifecycle *lifecycle.Lifecycle
 }
 
@@ -61,5 +73,44 @@ func (r *APIBindingReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *plat
 		For(&kcpapisv1alpha2.APIBinding{}).
 		WithOptions(opts).
 		WithEventFilter(predicate.And(predicates...)).
+		Watches(
+			&corev1alpha1.ModelTemplate{},
+			r.enqueueModelTemplateBindings,
+			mcbuilder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Complete(r)
 }
+
+// enqueueModelTemplateBindings enqueues the APIBindings to the APIExports in
+// the workspace of a ModelTemplate, so their models are generated again when
+// the template changes. Bindings to APIExports the template cannot select are
+// skipped; templates with a selector enqueue all bindings of the workspace.
+func (r *APIBindingReconciler) enqueueModelTemplateBindings(clusterName multicluster.ClusterName, _ cluster.Cluster) ctrhandler.TypedEventHandler[client.Object, mcreconcile.Request] {
+	return handler.TypedEnqueueRequestsFromMapFuncWithClusterPreservation(func(ctx context.Context, obj client.Object) []mcreconcile.Request {
+		modelTemplate, ok := obj.(*corev1alpha1.ModelTemplate)
+		if !ok {
+			return nil
+		}
+
+		var bindings kcpapisv1alpha2.APIBindingList
+		if err := r.lister.List(ctx, &bindings); err != nil {
+			r.log.Error().Err(err).Str("cluster", string(clusterName)).Str("modelTemplate", modelTemplate.Name).Msg("Unable to list APIBindings of ModelTemplate")
+			return nil
+		}
+
+		var requests []mcreconcile.Request
+		for _, binding := range bindings.Items {
+			if binding.Status.APIExportClusterName != string(clusterName) {
+				continue
+			}
+			if modelTemplate.Spec.APIExportSelector == nil && binding.Spec.Reference.Export.Name != modelTemplate.Spec.APIExportName {
+				continue
+			}
+			requests = append(requests, mcreconcile.Request{
+				Request:     reconcile.Request{NamespacedName: types.NamespacedName{Name: binding.Name}},
+				ClusterName: multicluster.ClusterName(logicalcluster.From(&binding).String()),
+			})
+		}
+		return requests
+	})
+}
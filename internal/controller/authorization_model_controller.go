@@ -9,8 +9,12 @@ import (
 	"github.com/platform-mesh/golang-commons/controller/filter"
 	"github.com/platform-mesh/golang-commons/logger"
 	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/subroutines"
 	"github.com/platform-mesh/subroutines/lifecycle"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -19,6 +23,8 @@ import (
 	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 type AuthorizationModelReconciler struct {
@@ -26,14 +32,22 @@ type AuthorizationModelReconciler struct {
 	lifecycle *lifecycle.Lifecycle
 }
 
-func NewAuthorizationModelReconciler(log *logger.Logger, fga openfgav1.OpenFGAServiceClient, mcMgr mcmanager.Manager) *AuthorizationModelReconciler {
+func NewAuthorizationModelReconciler(log *logger.Logger, fga openfgav1.OpenFGAServiceClient, mcMgr mcmanager.Manager, cfg *config.Config, lister iclient.Lister) *AuthorizationModelReconciler {
+	subs := []subroutines.Subroutine{
+		subroutine.NewTupleExpirySubroutine(fga, mcMgr, lister, cfg.FGA.WriteChunkSize),
+		subroutine.NewTupleSubroutine(fga, mcMgr, lister, cfg.FGA.WriteChunkSize),
+	}
+	if cfg.FGA.DriftCheckInterval > 0 {
+		subs = append(subs, subroutine.NewTupleDriftSubroutine(fga, mcMgr, lister, cfg.FGA.DriftCheckInterval, cfg.FGA.WriteChunkSize))
+	}
+
 	lc := lifecycle.New(mcMgr, "AuthorizationModelReconciler", func() client.Object {
 		return &corev1alpha1.AuthorizationModel{}
-	}, subroutine.NewTupleSubroutine(fga, mcMgr))
+	}, subs...)
 
 	return &AuthorizationModelReconciler{
 		log:       log,
-		lifecycle: lc,
+		lifecycle: dryrun.ReadOnly(lc),
 	}
 }
 
@@ -59,5 +73,7 @@ func (r *AuthorizationModelReconciler) SetupWithManager(mgr mcmanager.Manager, c
 		For(&corev1alpha1.AuthorizationModel{}).
 		WithOptions(opts).
 		WithEventFilter(predicate.And(predicates...)).
+		Watches(&corev1.ConfigMap{}, enqueueTupleSourceReferrers(r.log, authorizationModelTupleSourceReferrers), mcbuilder.WithPredicates(tupleSourcePredicate())).
+		Watches(&corev1.Secret{}, enqueueTupleSourceReferrers(r.log, authorizationModelTupleSourceReferrers), mcbuilder.WithPredicates(tupleSourcePredicate())).
 		Complete(r)
 }
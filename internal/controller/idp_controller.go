@@ -13,6 +13,7 @@ import (
 	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine/idp"
 	"github.com/platform-mesh/subroutines/conditions"
@@ -54,7 +55,7 @@ func NewIdentityProviderConfigurationReconciler(ctx context.Context, mgr mcmanag
 
 	return &IdentityProviderConfigurationReconciler{
 		log:         log,
-		lifecycle:   lc,
+		lifecycle:   dryrun.ReadOnly(lc),
 		rateLimiter: rl,
 	}, nil
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine/invite"
 	"github.com/platform-mesh/subroutines/conditions"
@@ -51,7 +52,7 @@ func NewInviteReconciler(ctx context.Context, mgr mcmanager.Manager, cfg *config
 
 	return &InviteReconciler{
 		log:         log,
-		lifecycle:   lc,
+		lifecycle:   dryrun.ReadOnly(lc),
 		rateLimiter: rl,
 	}, nil
 }
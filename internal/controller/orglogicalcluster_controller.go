@@ -11,6 +11,7 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
 	"github.com/platform-mesh/subroutines"
@@ -85,7 +86,7 @@ func NewOrgLogicalClusterController(log *logger.Logger, kcpClientGetter iclient.
 	return &OrgLogicalClusterController{
 		log:         log,
 		name:        opts.Name,
-		lifecycle:   lc,
+		lifecycle:   dryrun.ReadOnly(lc),
 		rateLimiter: rl,
 	}, nil
 }
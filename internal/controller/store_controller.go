@@ -11,8 +11,10 @@ import (
 	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/metrics"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/subroutines"
 	"github.com/platform-mesh/subroutines/conditions"
 	"github.com/platform-mesh/subroutines/lifecycle"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -28,9 +30,8 @@ import (
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/rest"
 )
 
 // StoreReconciler reconciles a Store object
@@ -41,20 +42,24 @@ type StoreReconciler struct {
 }
 
 func NewStoreReconciler(ctx context.Context, log *logger.Logger, fga openfgav1.OpenFGAServiceClient, mcMgr mcmanager.Manager, cfg *config.Config, lister iclient.Lister) *StoreReconciler {
+	subs := []subroutines.Subroutine{
+		subroutine.NewStoreSubroutine(fga, mcMgr, lister),
+		subroutine.NewAuthorizationModelSubroutine(fga, mcMgr, lister, subroutine.NewDiscoveryClient, log),
+		subroutine.NewTupleExpirySubroutine(fga, mcMgr, lister, cfg.FGA.WriteChunkSize),
+		subroutine.NewTupleSubroutine(fga, mcMgr, lister, cfg.FGA.WriteChunkSize),
+	}
+	if cfg.FGA.DriftCheckInterval > 0 {
+		subs = append(subs, subroutine.NewTupleDriftSubroutine(fga, mcMgr, lister, cfg.FGA.DriftCheckInterval, cfg.FGA.WriteChunkSize))
+	}
+
 	lc := lifecycle.New(mcMgr, "StoreReconciler", func() client.Object {
 		return &corev1alpha1.Store{}
-	},
-		subroutine.NewStoreSubroutine(fga, mcMgr, lister),
-		subroutine.NewAuthorizationModelSubroutine(fga, mcMgr, lister, func(cfg *rest.Config) discovery.DiscoveryInterface {
-			return discovery.NewDiscoveryClientForConfigOrDie(cfg)
-		}, log),
-		subroutine.NewTupleSubroutine(fga, mcMgr),
-	).WithConditions(conditions.NewManager())
+	}, subs...).WithConditions(conditions.NewManager())
 
 	return &StoreReconciler{
 		fga:       fga,
 		log:       log,
-		lifecycle: lc,
+		lifecycle: dryrun.ReadOnly(lc),
 	}
 }
 
@@ -102,5 +107,8 @@ func (r *StoreReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *platforme
 				})
 			},
 			mcbuilder.WithPredicates(predicate.GenerationChangedPredicate{}),
-		).Complete(r)
+		).
+		Watches(&corev1.ConfigMap{}, enqueueTupleSourceReferrers(r.log, storeTupleSourceReferrers), mcbuilder.WithPredicates(tupleSourcePredicate())).
+		Watches(&corev1.Secret{}, enqueueTupleSourceReferrers(r.log, storeTupleSourceReferrers), mcbuilder.WithPredicates(tupleSourcePredicate())).
+		Complete(r)
 }
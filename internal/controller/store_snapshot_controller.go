@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	platformeshconfig "github.com/platform-mesh/golang-commons/config"
+	"github.com/platform-mesh/golang-commons/controller/filter"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/metrics"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/subroutines/lifecycle"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+)
+
+type StoreSnapshotReconciler struct {
+	log       *logger.Logger
+	lifecycle *lifecycle.Lifecycle
+}
+
+func NewStoreSnapshotReconciler(log *logger.Logger, fga openfgav1.OpenFGAServiceClient, mcMgr mcmanager.Manager, runtimeClient client.Client, cfg *config.Config) *StoreSnapshotReconciler {
+	lc := lifecycle.New(mcMgr, "StoreSnapshotReconciler", func() client.Object {
+		return &corev1alpha1.StoreSnapshot{}
+	}, subroutine.NewStoreSnapshotSubroutine(fga, mcMgr, runtimeClient, cfg.StoreSnapshotNamespace))
+
+	return &StoreSnapshotReconciler{
+		log:       log,
+		lifecycle: dryrun.ReadOnly(lc),
+	}
+}
+
+func (r *StoreSnapshotReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := r.lifecycle.Reconcile(ctx, req)
+	labelResult := "success"
+	if err != nil {
+		labelResult = "error"
+	}
+	metrics.ReconcileTotal.WithLabelValues("storesnapshot", labelResult).Inc()
+	metrics.ReconcileDuration.WithLabelValues("storesnapshot").Observe(time.Since(start).Seconds())
+	return result, err
+}
+
+func (r *StoreSnapshotReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *platformeshconfig.CommonServiceConfig, evp ...predicate.Predicate) error {
+	opts := controller.TypedOptions[mcreconcile.Request]{
+		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+	}
+	predicates := append([]predicate.Predicate{filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)}, evp...)
+	return mcbuilder.ControllerManagedBy(mgr).
+		Named("storesnapshot").
+		For(&corev1alpha1.StoreSnapshot{}).
+		WithOptions(opts).
+		WithEventFilter(predicate.And(predicates...)).
+		Complete(r)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	platformeshconfig "github.com/platform-mesh/golang-commons/config"
+	"github.com/platform-mesh/golang-commons/controller/filter"
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/metrics"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/subroutines/lifecycle"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	mcbuilder "sigs.k8s.io/multicluster-runtime/pkg/builder"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+)
+
+type StoreSnapshotScheduleReconciler struct {
+	log       *logger.Logger
+	lifecycle *lifecycle.Lifecycle
+}
+
+func NewStoreSnapshotScheduleReconciler(log *logger.Logger, mcMgr mcmanager.Manager) *StoreSnapshotScheduleReconciler {
+	lc := lifecycle.New(mcMgr, "StoreSnapshotScheduleReconciler", func() client.Object {
+		return &corev1alpha1.StoreSnapshotSchedule{}
+	}, subroutine.NewStoreSnapshotScheduleSubroutine(mcMgr))
+
+	return &StoreSnapshotScheduleReconciler{
+		log:       log,
+		lifecycle: dryrun.ReadOnly(lc),
+	}
+}
+
+func (r *StoreSnapshotScheduleReconciler) Reconcile(ctx context.Context, req mcreconcile.Request) (ctrl.Result, error) {
+	start := time.Now()
+	result, err := r.lifecycle.Reconcile(ctx, req)
+	labelResult := "success"
+	if err != nil {
+		labelResult = "error"
+	}
+	metrics.ReconcileTotal.WithLabelValues("storesnapshotschedule", labelResult).Inc()
+	metrics.ReconcileDuration.WithLabelValues("storesnapshotschedule").Observe(time.Since(start).Seconds())
+	return result, err
+}
+
+func (r *StoreSnapshotScheduleReconciler) SetupWithManager(mgr mcmanager.Manager, cfg *platformeshconfig.CommonServiceConfig, evp ...predicate.Predicate) error {
+	opts := controller.TypedOptions[mcreconcile.Request]{
+		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+	}
+	predicates := append([]predicate.Predicate{filter.DebugResourcesBehaviourPredicate(cfg.DebugLabelValue)}, evp...)
+	return mcbuilder.ControllerManagedBy(mgr).
+		Named("storesnapshotschedule").
+		For(&corev1alpha1.StoreSnapshotSchedule{}).
+		WithOptions(opts).
+		WithEventFilter(predicate.And(predicates...)).
+		Complete(r)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/multicluster-runtime/pkg/handler"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+	mcreconcile "sigs.k8s.io/multicluster-runtime/pkg/reconcile"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// tupleSourceReferrers lists the objects of a cluster whose tuplesFrom may
+// reference a ConfigMap or Secret.
+type tupleSourceReferrers func(ctx context.Context, cl client.Client) (map[string][]corev1alpha1.TupleSource, error)
+
+func storeTupleSourceReferrers(ctx context.Context, cl client.Client) (map[string][]corev1alpha1.TupleSource, error) {
+	var stores corev1alpha1.StoreList
+	if err := cl.List(ctx, &stores); err != nil {
+		return nil, err
+	}
+	referrers := make(map[string][]corev1alpha1.TupleSource, len(stores.Items))
+	for _, store := range stores.Items {
+		referrers[store.Name] = store.Spec.TuplesFrom
+	}
+	return referrers, nil
+}
+
+func authorizationModelTupleSourceReferrers(ctx context.Context, cl client.Client) (map[string][]corev1alpha1.TupleSource, error) {
+	var models corev1alpha1.AuthorizationModelList
+	if err := cl.List(ctx, &models); err != nil {
+		return nil, err
+	}
+	referrers := make(map[string][]corev1alpha1.TupleSource, len(models.Items))
+	for _, model := range models.Items {
+		referrers[model.Name] = model.Spec.TuplesFrom
+	}
+	return referrers, nil
+}
+
+// enqueueTupleSourceReferrers enqueues the objects of the same cluster whose
+// tuplesFrom reference a changed ConfigMap or Secret.
+func enqueueTupleSourceReferrers(log *logger.Logger, referrers tupleSourceReferrers) func(multicluster.ClusterName, cluster.Cluster) ctrhandler.TypedEventHandler[client.Object, mcreconcile.Request] {
+	return func(clusterName multicluster.ClusterName, cl cluster.Cluster) ctrhandler.TypedEventHandler[client.Object, mcreconcile.Request] {
+		return handler.TypedEnqueueRequestsFromMapFuncWithClusterPreservation(func(ctx context.Context, obj client.Object) []mcreconcile.Request {
+			objects, err := referrers(ctx, cl.GetClient())
+			if err != nil {
+				log.Error().Err(err).Str("cluster", string(clusterName)).Msg("Unable to list referrers of tuple sources")
+				return nil
+			}
+
+			var requests []mcreconcile.Request
+			for name, sources := range objects {
+				for _, source := range sources {
+					if referencesTupleSource(source, obj) {
+						requests = append(requests, mcreconcile.Request{
+							Request:     reconcile.Request{NamespacedName: types.NamespacedName{Name: name}},
+							ClusterName: clusterName,
+						})
+						break
+					}
+				}
+			}
+			return requests
+		})
+	}
+}
+
+func referencesTupleSource(source corev1alpha1.TupleSource, obj client.Object) bool {
+	var ref *corev1alpha1.TupleSourceRef
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		ref = source.ConfigMapRef
+	case *corev1.Secret:
+		ref = source.SecretRef
+	}
+	return ref != nil && ref.Namespace == obj.GetNamespace() && ref.Name == obj.GetName()
+}
+
+// isTupleSource reports whether a ConfigMap or Secret is labelled as a tuple
+// source.
+func isTupleSource(obj client.Object) bool {
+	return obj.GetLabels()[corev1alpha1.TupleSourceLabelKey] == corev1alpha1.TupleSourceLabelValue
+}
+
+// tupleSourcePredicate only passes events of ConfigMaps and Secrets labelled
+// as tuple sources. Updates removing the label pass as well, so the
+// referrers notice.
+func tupleSourcePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isTupleSource(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return isTupleSource(e.ObjectOld) || isTupleSource(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isTupleSource(e.Object) },
+		GenericFunc: func(e event.GenericEvent) bool { return isTupleSource(e.Object) },
+	}
+}
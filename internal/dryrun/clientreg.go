@@ -0,0 +1,37 @@
+package dryrun
+
+import (
+	"context"
+
+	"github.com/platform-mesh/security-operator/pkg/clientreg"
+)
+
+// ClientRegistration returns a clientreg.Client that reads clients through c
+// and records registrations, updates and deletions. Registered clients have
+// no ID.
+func ClientRegistration(c clientreg.Client) clientreg.Client {
+	return &clientRegistration{Client: c}
+}
+
+type clientRegistration struct {
+	clientreg.Client
+}
+
+func (c *clientRegistration) Register(ctx context.Context, registrationEndpoint string, metadata clientreg.ClientMetadata) (clientreg.ClientInformation, error) {
+	Record(ctx, SystemKeycloak, "RegisterClient", metadata.ClientName, metadata)
+	return clientreg.ClientInformation{ClientMetadata: metadata}, nil
+}
+
+func (c *clientRegistration) Update(ctx context.Context, registrationClientURI, registrationAccessToken string, metadata clientreg.ClientMetadata) (clientreg.ClientInformation, error) {
+	Record(ctx, SystemKeycloak, "UpdateClient", metadata.ClientID, metadata)
+	return clientreg.ClientInformation{
+		ClientID:              metadata.ClientID,
+		RegistrationClientURI: registrationClientURI,
+		ClientMetadata:        metadata,
+	}, nil
+}
+
+func (c *clientRegistration) Delete(ctx context.Context, clientID, registrationClientURI, registrationAccessToken string) error {
+	Record(ctx, SystemKeycloak, "DeleteClient", clientID, nil)
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package dryrun records the changes the operator would make to OpenFGA,
+// Keycloak and Secrets instead of making them. Objects are still reconciled,
+// but every subroutine checks Enabled before mutating anything outside of
+// the objects it reconciles, and the lifecycles neither write status nor
+// add or remove finalizers.
+//
+// A dry-run instance reconciles the same objects as the operator it is
+// compared against, so it must run with its own leader-election ID. Sharing
+// the ID would make the two instances take turns instead of running side by
+// side, which is why the commands append LeaderElectionIDSuffix to theirs.
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/subroutines"
+	"github.com/platform-mesh/subroutines/lifecycle"
+)
+
+const (
+	SystemOpenFGA    = "openfga"
+	SystemKeycloak   = "keycloak"
+	SystemKubernetes = "kubernetes"
+
+	// ReportPath is the path the report is served on by the metrics server.
+	ReportPath = "/debug/dry-run"
+	// DefaultReportSize is the number of mutations kept in the report.
+	DefaultReportSize = 1000
+	// FinalizeRequeue is how often objects being deleted are finalized again
+	// in dry-run mode.
+	FinalizeRequeue = 5 * time.Minute
+	// LeaderElectionIDSuffix is appended to the leader-election ID of the
+	// commands in dry-run mode.
+	LeaderElectionIDSuffix = "-dry-run"
+)
+
+// Mutation is a change that was skipped in dry-run mode.
+type Mutation struct {
+	Time time.Time `json:"time"`
+	// System is the system the change would have been made in.
+	System    string `json:"system"`
+	Operation string `json:"operation"`
+	// Target identifies what would have been changed, e.g. a store ID or a
+	// realm.
+	Target  string `json:"target,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Report holds the most recent skipped mutations.
+type Report struct {
+	Mutations []Mutation `json:"mutations"`
+	// Dropped is the number of older mutations that did not fit into the
+	// report anymore.
+	Dropped int `json:"dropped"`
+}
+
+// Recorder logs skipped mutations and keeps the most recent ones in memory.
+type Recorder struct {
+	mu        sync.Mutex
+	size      int
+	mutations []Mutation
+	dropped   int
+}
+
+// NewRecorder returns a Recorder keeping the given number of mutations.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{size: size}
+}
+
+// Record logs a skipped mutation and adds it to the report.
+func (r *Recorder) Record(ctx context.Context, system, operation, target string, details any) {
+	m := Mutation{
+		Time:      time.Now(),
+		System:    system,
+		Operation: operation,
+		Target:    target,
+		Details:   details,
+	}
+
+	logger.LoadLoggerFromContext(ctx).Info().
+		Str("system", m.System).
+		Str("operation", m.Operation).
+		Str("target", m.Target).
+		Interface("details", m.Details).
+		Msg("Skipped mutation in dry-run mode")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mutations = append(r.mutations, m)
+	if over := len(r.mutations) - r.size; over > 0 {
+		r.mutations = append([]Mutation(nil), r.mutations[over:]...)
+		r.dropped += over
+	}
+}
+
+// Report returns a copy of the recorded mutations, the oldest first.
+func (r *Recorder) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Report{
+		Mutations: append([]Mutation{}, r.mutations...),
+		Dropped:   r.dropped,
+	}
+}
+
+// ServeHTTP serves the report as JSON.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Report())
+}
+
+var active atomic.Pointer[Recorder]
+
+// Enable turns on dry-run mode for the whole process, recording to r. A nil
+// r turns it off again.
+func Enable(r *Recorder) {
+	active.Store(r)
+}
+
+// Enabled reports whether the operator runs in dry-run mode.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// Record records a skipped mutation if dry-run mode is enabled.
+func Record(ctx context.Context, system, operation, target string, details any) {
+	if r := active.Load(); r != nil {
+		r.Record(ctx, system, operation, target, details)
+	}
+}
+
+// Finalized is the result of a successful Finalize. In dry-run mode it keeps
+// the object pending, so its finalizers stay in place and the deletion is
+// left to the operator that is not running in dry-run mode.
+func Finalized() subroutines.Result {
+	if Enabled() {
+		return subroutines.Pending(FinalizeRequeue, "dry-run mode, finalizers are kept")
+	}
+	return subroutines.OK()
+}
+
+// ReadOnly turns off status and finalizer writes of lc in dry-run mode.
+func ReadOnly(lc *lifecycle.Lifecycle) *lifecycle.Lifecycle {
+	if Enabled() {
+		return lc.WithReadOnly()
+	}
+	return lc
+}
@@ -0,0 +1,119 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/security-operator/pkg/clientreg"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestRecorder_KeepsMostRecentMutations(t *testing.T) {
+	r := NewRecorder(2)
+	r.Record(context.Background(), SystemOpenFGA, "Write", "store-1", nil)
+	r.Record(context.Background(), SystemOpenFGA, "Write", "store-2", nil)
+	r.Record(context.Background(), SystemKeycloak, "DeleteRealm", "acme", nil)
+
+	report := r.Report()
+	require.Len(t, report.Mutations, 2)
+	assert.Equal(t, "store-2", report.Mutations[0].Target)
+	assert.Equal(t, "acme", report.Mutations[1].Target)
+	assert.Equal(t, 1, report.Dropped)
+}
+
+func TestRecorder_ServeHTTP(t *testing.T) {
+	r := NewRecorder(DefaultReportSize)
+	r.Record(context.Background(), SystemKeycloak, "DeleteRealm", "acme", nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReportPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Len(t, report.Mutations, 1)
+	assert.Equal(t, SystemKeycloak, report.Mutations[0].System)
+	assert.Equal(t, "DeleteRealm", report.Mutations[0].Operation)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ReportPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestRecorder_UnaryClientInterceptor(t *testing.T) {
+	r := NewRecorder(DefaultReportSize)
+	var invoked []string
+	invoker := func(_ context.Context, method string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		invoked = append(invoked, method)
+		return nil
+	}
+
+	write := &openfgav1.WriteRequest{StoreId: "store-id", Writes: &openfgav1.WriteRequestWrites{
+		TupleKeys: []*openfgav1.TupleKey{{Object: "doc:a", Relation: "viewer", User: "user:alice"}},
+	}}
+	require.NoError(t, r.UnaryClientInterceptor(context.Background(), "/openfga.v1.OpenFGAService/Write", write, &openfgav1.WriteResponse{}, nil, invoker))
+
+	created := &openfgav1.CreateStoreResponse{}
+	require.NoError(t, r.UnaryClientInterceptor(context.Background(), "/openfga.v1.OpenFGAService/CreateStore", &openfgav1.CreateStoreRequest{Name: "acme"}, created, nil, invoker))
+	assert.Empty(t, created.GetId())
+
+	require.NoError(t, r.UnaryClientInterceptor(context.Background(), "/openfga.v1.OpenFGAService/Read", &openfgav1.ReadRequest{StoreId: "store-id"}, &openfgav1.ReadResponse{}, nil, invoker))
+	assert.Equal(t, []string{"/openfga.v1.OpenFGAService/Read"}, invoked)
+
+	report := r.Report()
+	require.Len(t, report.Mutations, 2)
+	assert.Equal(t, SystemOpenFGA, report.Mutations[0].System)
+	assert.Equal(t, "Write", report.Mutations[0].Operation)
+	assert.Equal(t, "store-id", report.Mutations[0].Target)
+	assert.Contains(t, string(report.Mutations[0].Details.(json.RawMessage)), "user:alice")
+	assert.Equal(t, "CreateStore", report.Mutations[1].Operation)
+	assert.Equal(t, "acme", report.Mutations[1].Target)
+}
+
+func TestClientRegistration(t *testing.T) {
+	r := NewRecorder(DefaultReportSize)
+	Enable(r)
+	t.Cleanup(func() { Enable(nil) })
+	require.True(t, Enabled())
+
+	c := ClientRegistration(nil)
+	info, err := c.Register(context.Background(), "https://idp/register", clientreg.ClientMetadata{ClientName: "portal"})
+	require.NoError(t, err)
+	assert.Empty(t, info.ClientID)
+	assert.Equal(t, "portal", info.ClientName)
+
+	info, err = c.Update(context.Background(), "https://idp/clients/abc", "token", clientreg.ClientMetadata{ClientID: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", info.ClientID)
+	assert.Equal(t, "https://idp/clients/abc", info.RegistrationClientURI)
+
+	require.NoError(t, c.Delete(context.Background(), "abc", "https://idp/clients/abc", "token"))
+
+	var operations []string
+	for _, m := range r.Report().Mutations {
+		operations = append(operations, m.Operation)
+	}
+	assert.Equal(t, []string{"RegisterClient", "UpdateClient", "DeleteClient"}, operations)
+}
+
+func TestRecord_Disabled(t *testing.T) {
+	assert.False(t, Enabled())
+	Record(context.Background(), SystemKeycloak, "DeleteRealm", "acme", nil)
+}
+
+func TestFinalized(t *testing.T) {
+	assert.Equal(t, subroutines.OK(), Finalized())
+
+	Enable(NewRecorder(DefaultReportSize))
+	t.Cleanup(func() { Enable(nil) })
+	result := Finalized()
+	assert.True(t, result.IsPending())
+	assert.Equal(t, FinalizeRequeue, result.Requeue())
+}
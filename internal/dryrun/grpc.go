@@ -0,0 +1,50 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// openFGAMutations are the OpenFGA methods that change a store.
+var openFGAMutations = map[string]bool{
+	"CreateStore":             true,
+	"UpdateStore":             true,
+	"DeleteStore":             true,
+	"Write":                   true,
+	"WriteAuthorizationModel": true,
+	"WriteAssertions":         true,
+}
+
+// UnaryClientInterceptor records requests changing OpenFGA instead of
+// sending them and lets all other requests through. The skipped requests
+// return an empty response, so created stores and written authorization
+// models have no ID.
+func (r *Recorder) UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	name := path.Base(method)
+	if !openFGAMutations[name] {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	var target string
+	switch req := req.(type) {
+	case interface{ GetStoreId() string }:
+		target = req.GetStoreId()
+	case interface{ GetName() string }:
+		target = req.GetName()
+	}
+
+	var details any
+	if msg, ok := req.(proto.Message); ok {
+		if raw, err := protojson.Marshal(msg); err == nil {
+			details = json.RawMessage(raw)
+		}
+	}
+
+	r.Record(ctx, SystemOpenFGA, name, target, details)
+	return nil
+}
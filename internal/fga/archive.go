@@ -0,0 +1,107 @@
+package fga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// StoreArchiveVersion is the version of the archive format written by
+// ExportStore.
+const StoreArchiveVersion = 1
+
+// StoreArchive is a portable snapshot of an OpenFGA store that can be
+// imported into another OpenFGA installation.
+type StoreArchive struct {
+	Version int `json:"version"`
+	// Store is the name of the exported store.
+	Store string `json:"store"`
+	// AuthorizationModel is the authorization model in the JSON
+	// representation of the OpenFGA API.
+	AuthorizationModel json.RawMessage  `json:"authorizationModel"`
+	Tuples             []v1alpha1.Tuple `json:"tuples,omitempty"`
+}
+
+// ExportStore reads the given authorization model and all tuples of a store
+// into an archive.
+func ExportStore(ctx context.Context, client openfgav1.OpenFGAServiceClient, storeName, storeID, authorizationModelID string, log *logger.Logger) (*StoreArchive, error) {
+	res, err := client.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+		StoreId: storeID,
+		Id:      authorizationModelID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading authorization model: %w", err)
+	}
+
+	model, err := protojson.Marshal(res.GetAuthorizationModel())
+	if err != nil {
+		return nil, fmt.Errorf("marshalling authorization model: %w", err)
+	}
+
+	tuples, err := NewTupleManager(client, storeID, authorizationModelID, log).ListWithFilter(ctx, func(v1alpha1.Tuple) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("reading tuples: %w", err)
+	}
+
+	return &StoreArchive{
+		Version:            StoreArchiveVersion,
+		Store:              storeName,
+		AuthorizationModel: model,
+		Tuples:             tuples,
+	}, nil
+}
+
+// ImportStore creates a new store with the given name and writes the
+// authorization model and tuples of the archive to it. The tuples are written
+// in chunks of at most writeChunkSize. It returns the IDs of the created store
+// and authorization model. The store is deleted again if the import fails, so
+// a retry does not leave a second store with the same name behind.
+func ImportStore(ctx context.Context, client openfgav1.OpenFGAServiceClient, archive *StoreArchive, storeName string, writeChunkSize int, log *logger.Logger) (string, string, error) {
+	if archive.Version != StoreArchiveVersion {
+		return "", "", fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+
+	var model openfgav1.AuthorizationModel
+	if err := protojson.Unmarshal(archive.AuthorizationModel, &model); err != nil {
+		return "", "", fmt.Errorf("parsing authorization model: %w", err)
+	}
+
+	store, err := client.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: storeName})
+	if err != nil {
+		return "", "", fmt.Errorf("creating store: %w", err)
+	}
+
+	modelID, err := importIntoStore(ctx, client, store.GetId(), &model, archive.Tuples, writeChunkSize, log)
+	if err != nil {
+		if _, deleteErr := client.DeleteStore(context.WithoutCancel(ctx), &openfgav1.DeleteStoreRequest{StoreId: store.GetId()}); deleteErr != nil {
+			log.Error().Err(deleteErr).Str("storeID", store.GetId()).Msg("unable to delete partially imported store")
+		}
+		return "", "", err
+	}
+	return store.GetId(), modelID, nil
+}
+
+// importIntoStore writes an authorization model and tuples to a new store and
+// returns the ID of the model.
+func importIntoStore(ctx context.Context, client openfgav1.OpenFGAServiceClient, storeID string, model *openfgav1.AuthorizationModel, tuples []v1alpha1.Tuple, writeChunkSize int, log *logger.Logger) (string, error) {
+	res, err := client.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		TypeDefinitions: model.GetTypeDefinitions(),
+		SchemaVersion:   model.GetSchemaVersion(),
+		Conditions:      model.GetConditions(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("writing authorization model to store %s: %w", storeID, err)
+	}
+
+	tm := NewTupleManager(client, storeID, res.GetAuthorizationModelId(), log, WithWriteChunkSize(writeChunkSize))
+	if err := tm.Apply(ctx, tuples); err != nil {
+		return "", fmt.Errorf("writing tuples to store %s: %w", storeID, err)
+	}
+	return res.GetAuthorizationModelId(), nil
+}
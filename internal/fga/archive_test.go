@@ -0,0 +1,89 @@
+package fga
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/yaml"
+)
+
+func TestStoreArchive_roundtrip(t *testing.T) {
+	model := &openfgav1.AuthorizationModel{
+		Id:            "model-id",
+		SchemaVersion: "1.2",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+		},
+	}
+	tuples := []*openfgav1.TupleKey{
+		{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:2", Relation: "viewer", User: "user:bob"},
+		{Object: "doc:3", Relation: "viewer", User: "user:carol"},
+	}
+
+	source := mocks.NewMockOpenFGAServiceClient(t)
+	source.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelRequest) bool {
+		return req.StoreId == "store-id" && req.Id == "model-id"
+	})).Return(&openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: model}, nil)
+	source.EXPECT().Read(mock.Anything, mock.Anything).Return(&openfgav1.ReadResponse{
+		Tuples: []*openfgav1.Tuple{{Key: tuples[0]}, {Key: tuples[1]}, {Key: tuples[2]}},
+	}, nil)
+
+	log := testlogger.New()
+	archive, err := ExportStore(context.Background(), source, "org", "store-id", "model-id", log.Logger)
+	require.NoError(t, err)
+	assert.Equal(t, "org", archive.Store)
+	assert.Len(t, archive.Tuples, 3)
+
+	raw, err := yaml.Marshal(archive)
+	require.NoError(t, err)
+	var restored StoreArchive
+	require.NoError(t, yaml.Unmarshal(raw, &restored))
+
+	var written []v1alpha1.Tuple
+	target := mocks.NewMockOpenFGAServiceClient(t)
+	target.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "org"}).Return(&openfgav1.CreateStoreResponse{Id: "new-store-id"}, nil)
+	target.EXPECT().WriteAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteAuthorizationModelRequest) bool {
+		return req.StoreId == "new-store-id" && req.SchemaVersion == "1.2" && len(req.TypeDefinitions) == 1
+	})).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "new-model-id"}, nil)
+	target.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		for _, key := range req.GetWrites().GetTupleKeys() {
+			written = append(written, v1alpha1.Tuple{Object: key.Object, Relation: key.Relation, User: key.User})
+		}
+		return &openfgav1.WriteResponse{}, nil
+	}).Times(2)
+
+	storeID, modelID, err := ImportStore(context.Background(), target, &restored, "org", 2, log.Logger)
+	require.NoError(t, err)
+	assert.Equal(t, "new-store-id", storeID)
+	assert.Equal(t, "new-model-id", modelID)
+	assert.Equal(t, archive.Tuples, written)
+}
+
+func TestImportStore_rejectsUnknownVersion(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	log := testlogger.New()
+
+	_, _, err := ImportStore(context.Background(), client, &StoreArchive{Version: 2}, "org", 0, log.Logger)
+	assert.ErrorContains(t, err, "unsupported archive version")
+}
+
+func TestImportStore_deletesStoreOnFailure(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "org"}).Return(&openfgav1.CreateStoreResponse{Id: "new-store-id"}, nil)
+	client.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	client.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "new-store-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+	log := testlogger.New()
+
+	archive := &StoreArchive{Version: StoreArchiveVersion, AuthorizationModel: []byte(`{"schema_version":"1.2"}`)}
+	_, _, err := ImportStore(context.Background(), client, archive, "org", 0, log.Logger)
+	assert.ErrorIs(t, err, assert.AnError)
+}
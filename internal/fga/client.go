@@ -0,0 +1,292 @@
+package fga
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/security-operator/internal/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// EmbeddedTarget makes NewConnection start an OpenFGA server with an
+// in-memory datastore in the process instead of dialing one. Everything
+// written to it is lost when the process exits.
+const EmbeddedTarget = "embedded"
+
+// NewConnection dials the OpenFGA API with the transport security and
+// credentials of the given config. Additional dial options are applied after
+// the ones derived from the config.
+func NewConnection(cfg config.FGAConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if cfg.Target == EmbeddedTarget {
+		return newEmbeddedConnection(cfg, opts...)
+	}
+
+	dialOpts, err := DialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(cfg.Target, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating grpc client: %w", err)
+	}
+	return conn, nil
+}
+
+// DialOptions returns the gRPC dial options for the transport security,
+// credentials, timeouts, retries and circuit breaker of the given config.
+// Certificates and secrets are read from their files once to fail early and
+// again whenever the files change.
+func DialOptions(cfg config.FGAConfig) ([]grpc.DialOption, error) {
+	transport := insecure.NewCredentials()
+	if cfg.TLS.Enabled {
+		tlsConfig, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport = credentials.NewTLS(tlsConfig)
+	} else if cfg.TLS.CAFile != "" || cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		return nil, errors.New("TLS files are configured but TLS is not enabled")
+	}
+	interceptors, err := unaryInterceptors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transport),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+
+	perRPC, err := newPerRPCCredentials(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if perRPC != nil {
+		if !cfg.TLS.Enabled && !cfg.Auth.AllowInsecure {
+			return nil, errors.New("credentials for the OpenFGA API require TLS unless sending them insecurely is allowed")
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(perRPC))
+	}
+	return opts, nil
+}
+
+func newTLSConfig(cfg config.FGATLSConfig) (*tls.Config, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be configured together")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+
+	if cfg.CertFile != "" {
+		keyPair := newReloadingFiles(func(contents [][]byte) (*tls.Certificate, error) {
+			cert, err := tls.X509KeyPair(contents[0], contents[1])
+			return &cert, err
+		}, cfg.CertFile, cfg.KeyFile)
+		if _, err := keyPair.get(); err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return keyPair.get()
+		}
+	}
+
+	if cfg.CAFile != "" {
+		roots := newReloadingFiles(func(contents [][]byte) (*x509.CertPool, error) {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(contents[0]) {
+				return nil, errors.New("no certificates found")
+			}
+			return pool, nil
+		}, cfg.CAFile)
+		if _, err := roots.get(); err != nil {
+			return nil, fmt.Errorf("loading CA bundle: %w", err)
+		}
+
+		// The CA bundle may rotate, so the server certificate is verified
+		// against the current bundle instead of a fixed pool.
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec // verified in VerifyConnection
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			pool, err := roots.get()
+			if err != nil {
+				return err
+			}
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			opts := x509.VerifyOptions{
+				Roots:         pool,
+				DNSName:       cs.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err = cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+func newPerRPCCredentials(cfg config.FGAAuthConfig) (credentials.PerRPCCredentials, error) {
+	switch {
+	case cfg.PresharedKeyFile != "" && cfg.TokenURL != "":
+		return nil, errors.New("only one of a preshared key and client credentials can be configured")
+	case cfg.PresharedKeyFile != "":
+		key := newReloadingFiles(func(contents [][]byte) (string, error) {
+			key := strings.TrimSpace(string(contents[0]))
+			if key == "" {
+				return "", errors.New("preshared key is empty")
+			}
+			return key, nil
+		}, cfg.PresharedKeyFile)
+		if _, err := key.get(); err != nil {
+			return nil, fmt.Errorf("loading preshared key: %w", err)
+		}
+		return &bearerCredentials{
+			token:      func(context.Context) (string, error) { return key.get() },
+			requireTLS: !cfg.AllowInsecure,
+		}, nil
+	case cfg.TokenURL != "":
+		if cfg.ClientID == "" || cfg.ClientSecretFile == "" {
+			return nil, errors.New("client credentials require a client ID and a client secret file")
+		}
+		tokenSource := newReloadingFiles(func(contents [][]byte) (oauth2.TokenSource, error) {
+			ccConfig := clientcredentials.Config{
+				ClientID:     cfg.ClientID,
+				ClientSecret: strings.TrimSpace(string(contents[0])),
+				TokenURL:     cfg.TokenURL,
+				Scopes:       cfg.Scopes,
+			}
+			if cfg.Audience != "" {
+				ccConfig.EndpointParams = url.Values{"audience": {cfg.Audience}}
+			}
+			return ccConfig.TokenSource(context.Background()), nil
+		}, cfg.ClientSecretFile)
+		if _, err := tokenSource.get(); err != nil {
+			return nil, fmt.Errorf("loading client secret: %w", err)
+		}
+		return &bearerCredentials{
+			token: func(context.Context) (string, error) {
+				ts, err := tokenSource.get()
+				if err != nil {
+					return "", err
+				}
+				token, err := ts.Token()
+				if err != nil {
+					return "", fmt.Errorf("getting token: %w", err)
+				}
+				return token.AccessToken, nil
+			},
+			requireTLS: !cfg.AllowInsecure,
+		}, nil
+	case cfg.ClientID != "" || cfg.ClientSecretFile != "":
+		return nil, errors.New("client credentials require a token URL")
+	}
+	return nil, nil
+}
+
+// bearerCredentials sends a bearer token with every request.
+type bearerCredentials struct {
+	token      func(ctx context.Context) (string, error)
+	requireTLS bool
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c *bearerCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (c *bearerCredentials) RequireTransportSecurity() bool { return c.requireTLS }
+
+// reloadingFiles caches a value parsed from a set of files until one of them
+// changes, e.g. when a mounted Secret is rotated. If the changed files cannot
+// be parsed, for example because only some of them were replaced yet, the
+// previous value is kept until the next change.
+type reloadingFiles[T any] struct {
+	paths []string
+	parse func(contents [][]byte) (T, error)
+
+	mu     sync.Mutex
+	stamps []fileStamp
+	value  T
+	loaded bool
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+func newReloadingFiles[T any](parse func(contents [][]byte) (T, error), paths ...string) *reloadingFiles[T] {
+	return &reloadingFiles[T]{paths: paths, parse: parse}
+}
+
+func (f *reloadingFiles[T]) get() (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stamps := make([]fileStamp, 0, len(f.paths))
+	for _, path := range f.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if f.loaded {
+				return f.value, nil
+			}
+			return f.value, err
+		}
+		stamps = append(stamps, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	if f.loaded && slices.EqualFunc(stamps, f.stamps, fileStamp.equal) {
+		return f.value, nil
+	}
+
+	contents := make([][]byte, 0, len(f.paths))
+	for _, path := range f.paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if f.loaded {
+				return f.value, nil
+			}
+			return f.value, err
+		}
+		contents = append(contents, content)
+	}
+
+	value, err := f.parse(contents)
+	if err != nil {
+		if f.loaded {
+			return f.value, nil
+		}
+		return f.value, err
+	}
+
+	f.value, f.stamps, f.loaded = value, stamps, true
+	return f.value, nil
+}
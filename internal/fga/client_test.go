@@ -0,0 +1,254 @@
+package fga
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialOptions(t *testing.T) {
+	keyFile := writeFile(t, "key", "secret")
+
+	tests := []struct {
+		name            string
+		cfg             config.FGAConfig
+		wantOptions     int
+		wantErrContains string
+	}{
+		{
+			name:        "insecure without credentials",
+			cfg:         config.FGAConfig{Target: "fga:8081"},
+			wantOptions: 2,
+		},
+		{
+			name:        "preshared key over an insecure connection if allowed",
+			cfg:         config.FGAConfig{Auth: config.FGAAuthConfig{PresharedKeyFile: keyFile, AllowInsecure: true}},
+			wantOptions: 3,
+		},
+		{
+			name:            "preshared key requires TLS",
+			cfg:             config.FGAConfig{Auth: config.FGAAuthConfig{PresharedKeyFile: keyFile}},
+			wantErrContains: "require TLS",
+		},
+		{
+			name:            "TLS files without TLS",
+			cfg:             config.FGAConfig{TLS: config.FGATLSConfig{CAFile: keyFile}},
+			wantErrContains: "TLS is not enabled",
+		},
+		{
+			name:            "client certificate without key",
+			cfg:             config.FGAConfig{TLS: config.FGATLSConfig{Enabled: true, CertFile: keyFile}},
+			wantErrContains: "must be configured together",
+		},
+		{
+			name:            "preshared key and client credentials",
+			cfg:             config.FGAConfig{Auth: config.FGAAuthConfig{PresharedKeyFile: keyFile, TokenURL: "https://idp/token"}},
+			wantErrContains: "only one of",
+		},
+		{
+			name:            "client credentials without token URL",
+			cfg:             config.FGAConfig{Auth: config.FGAAuthConfig{ClientID: "operator", ClientSecretFile: keyFile}},
+			wantErrContains: "require a token URL",
+		},
+		{
+			name:            "invalid method timeout",
+			cfg:             config.FGAConfig{MethodTimeouts: map[string]string{"Read": "soon"}},
+			wantErrContains: "parsing timeout of method Read",
+		},
+		{
+			name:            "missing preshared key file",
+			cfg:             config.FGAConfig{Auth: config.FGAAuthConfig{PresharedKeyFile: filepath.Join(t.TempDir(), "missing")}},
+			wantErrContains: "loading preshared key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := DialOptions(test.cfg)
+			if test.wantErrContains != "" {
+				assert.ErrorContains(t, err, test.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, opts, test.wantOptions)
+		})
+	}
+}
+
+func TestNewConnection_embeddedRequiresConnector(t *testing.T) {
+	cfg := config.NewConfig().FGA
+	cfg.Target = EmbeddedTarget
+
+	_, err := NewConnection(cfg)
+	assert.ErrorContains(t, err, "embedded OpenFGA server is not included")
+}
+
+func TestPresharedKeyCredentials_reloadsRotatedKey(t *testing.T) {
+	keyFile := writeFile(t, "key", "first\n")
+
+	creds, err := newPerRPCCredentials(config.FGAAuthConfig{PresharedKeyFile: keyFile})
+	require.NoError(t, err)
+	assert.True(t, creds.RequireTransportSecurity())
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer first", md["authorization"])
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("second\n"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, time.Now(), time.Now().Add(time.Minute)))
+
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer second", md["authorization"])
+
+	require.NoError(t, os.WriteFile(keyFile, nil, 0o600))
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer second", md["authorization"], "an invalid key keeps the previous one")
+}
+
+func TestClientCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "openfga", r.Form.Get("audience"))
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "operator", user)
+		assert.Equal(t, "secret", password)
+
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "token",
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		}))
+	}))
+	defer server.Close()
+
+	creds, err := newPerRPCCredentials(config.FGAAuthConfig{
+		ClientID:         "operator",
+		ClientSecretFile: writeFile(t, "secret", "secret"),
+		TokenURL:         server.URL,
+		Audience:         "openfga",
+	})
+	require.NoError(t, err)
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", md["authorization"])
+}
+
+func TestTLSConfig_mutualTLS(t *testing.T) {
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "localhost", ca, caKey)
+	clientCert, clientKey := newTestCertificate(t, "operator", ca, caKey)
+	otherCA, _ := newTestCertificate(t, "other", nil, nil)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	certFile := writeFile(t, "tls.crt", string(encodeCertificate(clientCert)))
+	keyFile := writeFile(t, "tls.key", string(encodeKey(t, clientKey)))
+
+	dial := func(caFile string) error {
+		tlsConfig, err := newTLSConfig(config.FGATLSConfig{
+			Enabled:    true,
+			CAFile:     caFile,
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			ServerName: "localhost",
+		})
+		require.NoError(t, err)
+
+		conn, err := tls.Dial("tcp", listener.Addr().String(), tlsConfig)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+		return conn.Handshake()
+	}
+
+	assert.NoError(t, dial(writeFile(t, "ca.crt", string(encodeCertificate(ca)))))
+	assert.Error(t, dial(writeFile(t, "other-ca.crt", string(encodeCertificate(otherCA)))))
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// newTestCertificate creates a certificate for the given name, self-signed if
+// no parent is given.
+func newTestCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func encodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func encodeKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	raw, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: raw})
+}
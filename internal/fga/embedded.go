@@ -0,0 +1,35 @@
+package fga
+
+import (
+	"errors"
+
+	"github.com/platform-mesh/security-operator/internal/config"
+	"google.golang.org/grpc"
+)
+
+// EmbeddedConnector starts an OpenFGA server in the process and dials it
+// with the given options.
+type EmbeddedConnector func(cfg config.FGAConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error)
+
+var embeddedConnector EmbeddedConnector
+
+// RegisterEmbeddedConnector makes NewConnection start the server of the
+// given connector for the embedded target. It is registered by the binary of
+// the embeddedfga module, so that this module does not depend on OpenFGA.
+func RegisterEmbeddedConnector(connector EmbeddedConnector) {
+	embeddedConnector = connector
+}
+
+// newEmbeddedConnection starts the embedded server and dials it with the
+// interceptors of the config. It fails in binaries without one.
+func newEmbeddedConnection(cfg config.FGAConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if embeddedConnector == nil {
+		return nil, errors.New("the embedded OpenFGA server is not included in this binary, run the one of the embeddedfga module")
+	}
+
+	interceptors, err := unaryInterceptors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return embeddedConnector(cfg, append([]grpc.DialOption{grpc.WithChainUnaryInterceptor(interceptors...)}, opts...)...)
+}
@@ -0,0 +1,107 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	platformmeshpath "github.com/platform-mesh/security-operator/internal/platformmesh"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+const (
+	accountEntityPrefix = "account:"
+	roleEntityPrefix    = "role:"
+)
+
+// ClusterIDResolver returns the logical cluster ID of a kcp workspace path.
+type ClusterIDResolver func(ctx context.Context, path logicalcluster.Path) (string, error)
+
+// NewCachingClusterIDResolver returns a ClusterIDResolver that remembers the
+// cluster IDs resolved by resolver for ttl.
+func NewCachingClusterIDResolver(resolver ClusterIDResolver, ttl time.Duration) ClusterIDResolver {
+	cache := ttlcache.New(
+		ttlcache.WithTTL[string, string](ttl),
+		ttlcache.WithDisableTouchOnHit[string, string](),
+	)
+	return func(ctx context.Context, path logicalcluster.Path) (string, error) {
+		if item := cache.Get(path.String()); item != nil {
+			return item.Value(), nil
+		}
+		clusterID, err := resolver(ctx, path)
+		if err != nil {
+			return "", err
+		}
+		cache.Set(path.String(), clusterID, ttlcache.DefaultTTL)
+		return clusterID, nil
+	}
+}
+
+// EntityResolver translates entities referencing accounts by their kcp
+// workspace path into the entities stored in OpenFGA. Supported are
+// "account:<path>", which becomes "<objectType>:<clusterID>/<name>", and
+// "role:<path>/<role>[#relation]", which becomes
+// "role:<objectType>/<clusterID>/<name>/<role>[#relation]". The cluster ID is
+// the one of the account's parent workspace. All other entities are returned
+// unchanged.
+type EntityResolver struct {
+	objectType string
+	clusterID  ClusterIDResolver
+}
+
+func NewEntityResolver(objectType string, clusterID ClusterIDResolver) *EntityResolver {
+	return &EntityResolver{
+		objectType: objectType,
+		clusterID:  clusterID,
+	}
+}
+
+// ObjectType returns the object type of accounts.
+func (r *EntityResolver) ObjectType() string {
+	return r.objectType
+}
+
+// Resolve translates a single entity.
+func (r *EntityResolver) Resolve(ctx context.Context, entity string) (string, error) {
+	if path, ok := strings.CutPrefix(entity, accountEntityPrefix); ok {
+		accountPath, err := platformmeshpath.NewAccountPath(path)
+		if err != nil {
+			return "", err
+		}
+		clusterID, err := r.parentClusterID(ctx, accountPath)
+		if err != nil {
+			return "", err
+		}
+		return renderAccountEntity(r.objectType, clusterID, accountPath.Base()), nil
+	}
+
+	if rest, ok := strings.CutPrefix(entity, roleEntityPrefix); ok {
+		path, role, found := strings.Cut(rest, "/")
+		if !found || !platformmeshpath.IsPlatformMeshAccountPath(path) {
+			return entity, nil
+		}
+		accountPath, err := platformmeshpath.NewAccountPath(path)
+		if err != nil {
+			return "", err
+		}
+		clusterID, err := r.parentClusterID(ctx, accountPath)
+		if err != nil {
+			return "", err
+		}
+		return RenderRolePrefix(r.objectType, clusterID, accountPath.Base()) + role, nil
+	}
+
+	return entity, nil
+}
+
+func (r *EntityResolver) parentClusterID(ctx context.Context, accountPath platformmeshpath.AccountPath) (string, error) {
+	parent, _ := accountPath.Parent()
+	clusterID, err := r.clusterID(ctx, parent)
+	if err != nil {
+		return "", fmt.Errorf("resolving cluster ID of %s: %w", parent, err)
+	}
+	return clusterID, nil
+}
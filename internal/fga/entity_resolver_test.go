@@ -0,0 +1,106 @@
+package fga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+func TestEntityResolver_Resolve(t *testing.T) {
+	clusterIDs := map[string]string{
+		"root:orgs":      "orgs-id",
+		"root:orgs:acme": "acme-id",
+	}
+	resolver := NewEntityResolver("core_platform-mesh_io_account", func(_ context.Context, path logicalcluster.Path) (string, error) {
+		id, ok := clusterIDs[path.String()]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return id, nil
+	})
+
+	tests := []struct {
+		name        string
+		entity      string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "resolves an account path",
+			entity:   "account:root:orgs:acme:team",
+			expected: "core_platform-mesh_io_account:acme-id/team",
+		},
+		{
+			name:     "resolves an organization path",
+			entity:   "account:root:orgs:acme",
+			expected: "core_platform-mesh_io_account:orgs-id/acme",
+		},
+		{
+			name:     "resolves a role with relation",
+			entity:   "role:root:orgs:acme:team/owner#assignee",
+			expected: "role:core_platform-mesh_io_account/acme-id/team/owner#assignee",
+		},
+		{
+			name:     "keeps raw roles",
+			entity:   "role:core_platform-mesh_io_account/acme-id/team/owner#assignee",
+			expected: "role:core_platform-mesh_io_account/acme-id/team/owner#assignee",
+		},
+		{
+			name:     "keeps other entities",
+			entity:   "user:alice@example.com",
+			expected: "user:alice@example.com",
+		},
+		{
+			name:        "fails for paths outside of the orgs workspace",
+			entity:      "account:root:foo",
+			expectError: true,
+		},
+		{
+			name:        "fails if the cluster ID cannot be resolved",
+			entity:      "account:root:orgs:other:team",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entity, err := resolver.Resolve(context.Background(), test.entity)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, entity)
+		})
+	}
+}
+
+func TestNewCachingClusterIDResolver(t *testing.T) {
+	calls := 0
+	resolver := NewCachingClusterIDResolver(func(_ context.Context, path logicalcluster.Path) (string, error) {
+		calls++
+		if path.String() == "root:orgs:missing" {
+			return "", errors.New("not found")
+		}
+		return "acme-id", nil
+	}, time.Minute)
+
+	for range 2 {
+		id, err := resolver(context.Background(), logicalcluster.NewPath("root:orgs:acme"))
+		require.NoError(t, err)
+		assert.Equal(t, "acme-id", id)
+	}
+	assert.Equal(t, 1, calls)
+
+	for range 2 {
+		_, err := resolver(context.Background(), logicalcluster.NewPath("root:orgs:missing"))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 3, calls)
+}
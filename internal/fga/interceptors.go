@@ -0,0 +1,197 @@
+package fga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	retryBaseBackoff = 100 * time.Millisecond
+	retryMaxBackoff  = 5 * time.Second
+)
+
+// ErrCircuitOpen is returned for requests rejected by the circuit breaker
+// while OpenFGA is considered unavailable.
+var ErrCircuitOpen = status.Error(codes.Unavailable, "OpenFGA circuit breaker is open")
+
+// nonIdempotentMethods are not retried, as a request that failed on the
+// client may still have been applied by OpenFGA.
+var nonIdempotentMethods = map[string]bool{
+	"CreateStore":             true,
+	"Write":                   true,
+	"WriteAuthorizationModel": true,
+}
+
+// unaryInterceptors returns the interceptors every request to OpenFGA passes
+// through. Retries wrap the circuit breaker, so every attempt is counted by
+// it, and every attempt has its own deadline and metrics.
+func unaryInterceptors(cfg config.FGAConfig) ([]grpc.UnaryClientInterceptor, error) {
+	timeouts := make(map[string]time.Duration, len(cfg.MethodTimeouts))
+	for method, value := range cfg.MethodTimeouts {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timeout of method %s: %w", method, err)
+		}
+		timeouts[method] = timeout
+	}
+
+	interceptors := []grpc.UnaryClientInterceptor{retryInterceptor(cfg.MaxRetries, jitteredBackoff)}
+	if cfg.CircuitBreakerThreshold > 0 {
+		breaker := newCircuitBreaker(cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+		interceptors = append(interceptors, breaker.interceptor)
+	}
+	return append(interceptors, metricsInterceptor, timeoutInterceptor(cfg.Timeout, timeouts)), nil
+}
+
+func methodName(fullMethod string) string {
+	return path.Base(fullMethod)
+}
+
+// jitteredBackoff returns a random duration up to an exponentially growing
+// limit, so that clients retrying at the same time spread out.
+func jitteredBackoff(attempt int) time.Duration {
+	limit := min(retryBaseBackoff<<attempt, retryMaxBackoff)
+	return rand.N(limit) + 1
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.ResourceExhausted
+}
+
+// retryInterceptor retries idempotent requests while OpenFGA is unavailable
+// or rate limits them.
+func retryInterceptor(maxRetries int, backoff func(attempt int) time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if nonIdempotentMethods[methodName(method)] {
+			return err
+		}
+		for attempt := 0; attempt < maxRetries && isRetryable(err); attempt++ {
+			timer := time.NewTimer(backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return err
+	}
+}
+
+// timeoutInterceptor bounds every request by the timeout of its method or the
+// default timeout. An earlier deadline of the caller is kept.
+func timeoutInterceptor(defaultTimeout time.Duration, timeouts map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout, ok := timeouts[methodName(method)]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// metricsInterceptor records the latency and status code of every request,
+// labelled by the store it targets.
+func metricsInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var store string
+	if r, ok := req.(interface{ GetStoreId() string }); ok {
+		store = r.GetStoreId()
+	}
+	name := methodName(method)
+
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	metrics.FGARPCDuration.WithLabelValues(name, store).Observe(time.Since(start).Seconds())
+	metrics.FGARPCTotal.WithLabelValues(name, store, status.Code(err).String()).Inc()
+	return err
+}
+
+// circuitBreaker rejects requests after a number of consecutive requests
+// failed because OpenFGA was unreachable. Once the cooldown passed, a single
+// request is let through and closes the circuit again if it succeeds.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *circuitBreaker) interceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	b.record(err)
+	return err
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	code := status.Code(err)
+	if code == codes.Canceled {
+		return
+	}
+	if code != codes.Unavailable && code != codes.DeadlineExceeded {
+		b.failures = 0
+		if b.open {
+			b.open = false
+			metrics.FGACircuitBreakerOpen.Set(0)
+		}
+		return
+	}
+
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+		metrics.FGACircuitBreakerOpen.Set(1)
+	}
+}
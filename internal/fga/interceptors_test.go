@@ -0,0 +1,163 @@
+package fga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/security-operator/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const checkMethod = "/openfga.v1.OpenFGAService/Check"
+
+// failingInvoker returns the given errors in order and nil once they are used
+// up, counting its calls.
+func failingInvoker(calls *int, errs ...error) grpc.UnaryInvoker {
+	return func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		*calls++
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	}
+}
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestRetryInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	tests := []struct {
+		name      string
+		method    string
+		errs      []error
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{
+			name:      "retries idempotent requests until they succeed",
+			method:    checkMethod,
+			errs:      []error{unavailable, status.Error(codes.ResourceExhausted, "slow down")},
+			wantCalls: 3,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "gives up after the maximum retries",
+			method:    checkMethod,
+			errs:      []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls: 3,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "does not retry non-idempotent requests",
+			method:    "/openfga.v1.OpenFGAService/Write",
+			errs:      []error{unavailable},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "does not retry other errors",
+			method:    checkMethod,
+			errs:      []error{status.Error(codes.InvalidArgument, "invalid")},
+			wantCalls: 1,
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "does not retry while the circuit is open",
+			method:    checkMethod,
+			errs:      []error{ErrCircuitOpen},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls int
+			err := retryInterceptor(2, noBackoff)(context.Background(), test.method, nil, nil, nil, failingInvoker(&calls, test.errs...))
+			assert.Equal(t, test.wantCode, status.Code(err))
+			assert.Equal(t, test.wantCalls, calls)
+		})
+	}
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	interceptor := timeoutInterceptor(time.Second, map[string]time.Duration{"ListObjects": time.Minute})
+
+	deadlineOf := func(method string) time.Duration {
+		var remaining time.Duration
+		_ = interceptor(context.Background(), method, nil, nil, nil, func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			remaining = time.Until(deadline)
+			return nil
+		})
+		return remaining
+	}
+
+	assert.LessOrEqual(t, deadlineOf(checkMethod), time.Second)
+	assert.Greater(t, deadlineOf("/openfga.v1.OpenFGAService/ListObjects"), time.Second)
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	counter := metrics.FGARPCTotal.WithLabelValues("Check", "metrics-store", "NotFound")
+	before := testutil.ToFloat64(counter)
+
+	var calls int
+	err := metricsInterceptor(context.Background(), checkMethod, &openfgav1.CheckRequest{StoreId: "metrics-store"}, nil, nil,
+		failingInvoker(&calls, status.Error(codes.NotFound, "not found")))
+
+	assert.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	call := func(errs ...error) (int, error) {
+		var calls int
+		err := breaker.interceptor(context.Background(), checkMethod, nil, nil, nil, failingInvoker(&calls, errs...))
+		return calls, err
+	}
+
+	_, _ = call(unavailable)
+	_, _ = call(status.Error(codes.NotFound, "not found"))
+	_, _ = call(unavailable)
+	calls, err := call()
+	assert.NoError(t, err, "a successful request resets the consecutive failures")
+	assert.Equal(t, 1, calls)
+
+	_, _ = call(unavailable)
+	_, _ = call(unavailable)
+	calls, err = call()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Zero(t, calls)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.FGACircuitBreakerOpen))
+
+	now = now.Add(time.Minute)
+	calls, err = call(unavailable)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls, "a probe is let through after the cooldown")
+	_, err = call()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "a failed probe opens the circuit again")
+
+	now = now.Add(time.Minute)
+	calls, err = call()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	calls, err = call()
+	assert.NoError(t, err, "a successful probe closes the circuit")
+	assert.Equal(t, 1, calls)
+	assert.Zero(t, testutil.ToFloat64(metrics.FGACircuitBreakerOpen))
+}
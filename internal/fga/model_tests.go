@@ -0,0 +1,169 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ModelTestsStorePrefix is reserved for the names of the scratch stores model
+// tests run in. Stores with this prefix are never looked up by name.
+const ModelTestsStorePrefix = "__model-tests-"
+
+// ModelTestsStoreName returns the name of the scratch store the model tests
+// of the Store with the given UID run in.
+func ModelTestsStoreName(storeUID string) string {
+	return ModelTestsStorePrefix + storeUID
+}
+
+// IsModelTestsStore reports whether an OpenFGA store name is reserved for the
+// scratch stores of model tests.
+func IsModelTestsStore(name string) bool {
+	return strings.HasPrefix(name, ModelTestsStorePrefix)
+}
+
+// RunModelTests evaluates the given tests against an authorization model in a
+// scratch store of the Store with the given UID, which is deleted afterwards.
+// Scratch stores left behind by earlier runs are deleted first. Each test
+// starts from an empty store with only its own tuples. It returns a
+// description of every failed assertion; an error is only returned if the
+// tests could not be evaluated.
+func RunModelTests(ctx context.Context, client openfgav1.OpenFGAServiceClient, model *openfgav1.AuthorizationModel, tests []v1alpha1.ModelTest, storeUID string, log *logger.Logger) ([]string, error) {
+	if err := DeleteModelTestsStores(ctx, client, storeUID); err != nil {
+		return nil, err
+	}
+
+	store, err := client.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: ModelTestsStoreName(storeUID)})
+	if err != nil {
+		return nil, fmt.Errorf("creating scratch store: %w", err)
+	}
+	storeID := store.GetId()
+	defer func() {
+		if _, err := client.DeleteStore(context.WithoutCancel(ctx), &openfgav1.DeleteStoreRequest{StoreId: storeID}); err != nil {
+			log.Error().Err(err).Str("storeID", storeID).Msg("unable to delete scratch store")
+		}
+	}()
+
+	res, err := client.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         storeID,
+		TypeDefinitions: model.GetTypeDefinitions(),
+		SchemaVersion:   model.GetSchemaVersion(),
+		Conditions:      model.GetConditions(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writing authorization model to scratch store: %w", err)
+	}
+	modelID := res.GetAuthorizationModelId()
+	tm := NewTupleManager(client, storeID, modelID, log)
+
+	var failures []string
+	for _, test := range tests {
+		failures = append(failures, runModelTest(ctx, client, tm, storeID, modelID, test)...)
+
+		// Tuples are deleted even if writing them failed half-way, so the
+		// next test starts from an empty store again.
+		if err := tm.Delete(ctx, test.Tuples); err != nil {
+			return nil, fmt.Errorf("deleting tuples of test %s: %w", test.Name, err)
+		}
+	}
+
+	return failures, nil
+}
+
+// DeleteModelTestsStores deletes the scratch stores of the model tests of the
+// Store with the given UID, e.g. the ones left behind when the operator
+// stopped during a run.
+func DeleteModelTestsStores(ctx context.Context, client openfgav1.OpenFGAServiceClient, storeUID string) error {
+	name := ModelTestsStoreName(storeUID)
+	var continuationToken string
+	for {
+		list, err := client.ListStores(ctx, &openfgav1.ListStoresRequest{
+			PageSize:          wrapperspb.Int32(100),
+			ContinuationToken: continuationToken,
+			Name:              name,
+		})
+		if err != nil {
+			return fmt.Errorf("listing scratch stores: %w", err)
+		}
+
+		// Servers that do not support filtering by name return all stores.
+		for _, store := range list.GetStores() {
+			if store.GetName() != name {
+				continue
+			}
+			_, err := client.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: store.GetId()})
+			if s, ok := status.FromError(err); err != nil && !(ok && s.Code() == codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found)) {
+				return fmt.Errorf("deleting scratch store %s: %w", store.GetId(), err)
+			}
+		}
+
+		continuationToken = list.GetContinuationToken()
+		if continuationToken == "" {
+			return nil
+		}
+	}
+}
+
+// runModelTest writes the tuples of a test and evaluates its assertions.
+func runModelTest(ctx context.Context, client openfgav1.OpenFGAServiceClient, tm *TupleManager, storeID, modelID string, test v1alpha1.ModelTest) []string {
+	if err := tm.Apply(ctx, test.Tuples); err != nil {
+		// Tuples the model rejects are a failure of the test, not of the
+		// evaluation.
+		return []string{fmt.Sprintf("%s: writing tuples: %v", test.Name, err)}
+	}
+
+	var failures []string
+
+	for _, check := range test.Check {
+		for _, relation := range slices.Sorted(maps.Keys(check.Assertions)) {
+			expected := check.Assertions[relation]
+			res, err := client.Check(ctx, &openfgav1.CheckRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: modelID,
+				TupleKey: &openfgav1.CheckRequestTupleKey{
+					User:     check.User,
+					Relation: relation,
+					Object:   check.Object,
+				},
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: check %s %s %s: %v", test.Name, check.User, relation, check.Object, err))
+				continue
+			}
+			if res.GetAllowed() != expected {
+				failures = append(failures, fmt.Sprintf("%s: check %s %s %s: expected %t, got %t", test.Name, check.User, relation, check.Object, expected, res.GetAllowed()))
+			}
+		}
+	}
+
+	for _, listObjects := range test.ListObjects {
+		for _, relation := range slices.Sorted(maps.Keys(listObjects.Assertions)) {
+			expected := slices.Sorted(slices.Values(listObjects.Assertions[relation]))
+			res, err := client.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: modelID,
+				User:                 listObjects.User,
+				Relation:             relation,
+				Type:                 listObjects.Type,
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: list objects %s %s %s: %v", test.Name, listObjects.User, relation, listObjects.Type, err))
+				continue
+			}
+			if actual := slices.Sorted(slices.Values(res.GetObjects())); !slices.Equal(actual, expected) {
+				failures = append(failures, fmt.Sprintf("%s: list objects %s %s %s: expected %v, got %v", test.Name, listObjects.User, relation, listObjects.Type, expected, actual))
+			}
+		}
+	}
+
+	return failures
+}
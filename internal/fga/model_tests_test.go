@@ -0,0 +1,95 @@
+package fga
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunModelTests(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{}, nil)
+	client.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "__model-tests-uid"}).Return(&openfgav1.CreateStoreResponse{Id: "scratch-id"}, nil)
+	client.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "model-id"}, nil)
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.StoreId == "scratch-id" && req.AuthorizationModelId == "model-id"
+	})).Return(&openfgav1.WriteResponse{}, nil).Times(4)
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.TupleKey.Relation == "viewer"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.TupleKey.Relation == "editor"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+	client.EXPECT().ListObjects(mock.Anything, mock.Anything).Return(&openfgav1.ListObjectsResponse{Objects: []string{"doc:2", "doc:1"}}, nil)
+	client.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "scratch-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+
+	tests := []v1alpha1.ModelTest{
+		{
+			Name: "viewer",
+			Tuples: []v1alpha1.Tuple{
+				{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+			},
+			Check: []v1alpha1.CheckAssertion{{
+				User:       "user:alice",
+				Object:     "doc:1",
+				Assertions: map[string]bool{"viewer": true, "editor": false},
+			}},
+		},
+		{
+			Name: "list",
+			Tuples: []v1alpha1.Tuple{
+				{Object: "doc:1", Relation: "viewer", User: "user:bob"},
+			},
+			ListObjects: []v1alpha1.ListObjectsAssertion{{
+				User:       "user:bob",
+				Type:       "doc",
+				Assertions: map[string][]string{"viewer": {"doc:1", "doc:2"}},
+			}},
+		},
+	}
+
+	log := testlogger.New()
+	failures, err := RunModelTests(context.Background(), client, &openfgav1.AuthorizationModel{SchemaVersion: "1.2"}, tests, "uid", log.Logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer: check user:alice editor doc:1: expected false, got true"}, failures)
+}
+
+func TestRunModelTests_deletesScratchStoreOnError(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{}, nil)
+	client.EXPECT().CreateStore(mock.Anything, mock.Anything).Return(&openfgav1.CreateStoreResponse{Id: "scratch-id"}, nil)
+	client.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	client.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "scratch-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+
+	log := testlogger.New()
+	_, err := RunModelTests(context.Background(), client, &openfgav1.AuthorizationModel{}, []v1alpha1.ModelTest{{Name: "test"}}, "uid", log.Logger)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestRunModelTests_deletesLeftoverScratchStores(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListStoresRequest) bool {
+		return req.GetName() == "__model-tests-uid"
+	})).Return(&openfgav1.ListStoresResponse{Stores: []*openfgav1.Store{
+		{Id: "leftover-id", Name: "__model-tests-uid"},
+		{Id: "other-id", Name: "__model-tests-other"},
+	}}, nil)
+	client.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "leftover-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+	client.EXPECT().CreateStore(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	log := testlogger.New()
+	_, err := RunModelTests(context.Background(), client, &openfgav1.AuthorizationModel{}, []v1alpha1.ModelTest{{Name: "test"}}, "uid", log.Logger)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestIsModelTestsStore(t *testing.T) {
+	assert.True(t, IsModelTestsStore(ModelTestsStoreName("uid")))
+	assert.False(t, IsModelTestsStore("org-model-tests"))
+}
@@ -0,0 +1,121 @@
+package fga
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+)
+
+// ValidateModelReferences checks that all types and relations referenced by
+// the relation definitions of a model are defined in it. The openfga language
+// transformer only validates the syntax of modules and how they are combined.
+func ValidateModelReferences(model *openfgav1.AuthorizationModel) error {
+	typeDefs := typeDefinitions(model)
+
+	var errs []error
+	for _, typeName := range slices.Sorted(maps.Keys(typeDefs)) {
+		typeDef := typeDefs[typeName]
+		metadata := typeDef.GetMetadata().GetRelations()
+		for _, relation := range slices.Sorted(maps.Keys(typeDef.GetRelations())) {
+			for _, ref := range metadata[relation].GetDirectlyRelatedUserTypes() {
+				target, ok := typeDefs[ref.GetType()]
+				if !ok {
+					errs = append(errs, fmt.Errorf("relation %s#%s references unknown type %s", typeName, relation, ref.GetType()))
+					continue
+				}
+				if ref.GetRelation() != "" && target.GetRelations()[ref.GetRelation()] == nil {
+					errs = append(errs, fmt.Errorf("relation %s#%s references unknown relation %s#%s", typeName, relation, ref.GetType(), ref.GetRelation()))
+				}
+			}
+			errs = append(errs, validateUsersetReferences(typeDef, relation, typeDef.GetRelations()[relation])...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateUsersetReferences checks that the relations a userset rewrite
+// refers to exist on its type.
+func validateUsersetReferences(typeDef *openfgav1.TypeDefinition, relation string, userset *openfgav1.Userset) []error {
+	exists := func(name string) bool { return typeDef.GetRelations()[name] != nil }
+
+	switch u := userset.GetUserset().(type) {
+	case *openfgav1.Userset_ComputedUserset:
+		if !exists(u.ComputedUserset.GetRelation()) {
+			return []error{fmt.Errorf("relation %s#%s references unknown relation %s", typeDef.GetType(), relation, u.ComputedUserset.GetRelation())}
+		}
+	case *openfgav1.Userset_TupleToUserset:
+		if !exists(u.TupleToUserset.GetTupleset().GetRelation()) {
+			return []error{fmt.Errorf("relation %s#%s references unknown relation %s", typeDef.GetType(), relation, u.TupleToUserset.GetTupleset().GetRelation())}
+		}
+	case *openfgav1.Userset_Union:
+		return validateChildReferences(typeDef, relation, u.Union.GetChild())
+	case *openfgav1.Userset_Intersection:
+		return validateChildReferences(typeDef, relation, u.Intersection.GetChild())
+	case *openfgav1.Userset_Difference:
+		return validateChildReferences(typeDef, relation, []*openfgav1.Userset{u.Difference.GetBase(), u.Difference.GetSubtract()})
+	}
+	return nil
+}
+
+func validateChildReferences(typeDef *openfgav1.TypeDefinition, relation string, children []*openfgav1.Userset) []error {
+	var errs []error
+	for _, child := range children {
+		errs = append(errs, validateUsersetReferences(typeDef, relation, child)...)
+	}
+	return errs
+}
+
+// ValidateTuples checks that the object types, relations and user types of
+// the tuples are defined in the model.
+func ValidateTuples(model *openfgav1.AuthorizationModel, tuples []v1alpha1.Tuple) error {
+	typeDefs := typeDefinitions(model)
+
+	var errs []error
+	for _, tuple := range tuples {
+		if err := validateTuple(typeDefs, tuple); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateTuple(typeDefs map[string]*openfgav1.TypeDefinition, tuple v1alpha1.Tuple) error {
+	objectType, _, found := strings.Cut(tuple.Object, ":")
+	if !found {
+		return fmt.Errorf("tuple %s: object is not of the form <type>:<id>", tuple)
+	}
+	typeDef, ok := typeDefs[objectType]
+	if !ok {
+		return fmt.Errorf("tuple %s: unknown object type %s", tuple, objectType)
+	}
+	if typeDef.GetRelations()[tuple.Relation] == nil {
+		return fmt.Errorf("tuple %s: type %s has no relation %s", tuple, objectType, tuple.Relation)
+	}
+
+	user, userRelation, _ := strings.Cut(tuple.User, "#")
+	userType, _, found := strings.Cut(user, ":")
+	if !found {
+		return fmt.Errorf("tuple %s: user is not of the form <type>:<id>", tuple)
+	}
+	userTypeDef, ok := typeDefs[userType]
+	if !ok {
+		return fmt.Errorf("tuple %s: unknown user type %s", tuple, userType)
+	}
+	if userRelation != "" && userTypeDef.GetRelations()[userRelation] == nil {
+		return fmt.Errorf("tuple %s: type %s has no relation %s", tuple, userType, userRelation)
+	}
+	return nil
+}
+
+func typeDefinitions(model *openfgav1.AuthorizationModel) map[string]*openfgav1.TypeDefinition {
+	typeDefs := make(map[string]*openfgav1.TypeDefinition, len(model.GetTypeDefinitions()))
+	for _, typeDef := range model.GetTypeDefinitions() {
+		typeDefs[typeDef.GetType()] = typeDef
+	}
+	return typeDefs
+}
@@ -0,0 +1,95 @@
+package fga
+
+import (
+	"testing"
+
+	language "github.com/openfga/language/pkg/go/transformer"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateModelReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		dsl         string
+		expectError string
+	}{
+		{
+			name: "accepts a consistent model",
+			dsl: `model
+  schema 1.2
+type user
+type role
+  relations
+    define assignee: [user]
+type doc
+  relations
+    define parent: [doc]
+    define owner: [role#assignee]
+    define viewer: owner or viewer from parent`,
+		},
+		{
+			name: "rejects unknown directly related types",
+			dsl: `model
+  schema 1.2
+type doc
+  relations
+    define viewer: [user]`,
+			expectError: "relation doc#viewer references unknown type user",
+		},
+		{
+			name: "rejects unknown relations of related types",
+			dsl: `model
+  schema 1.2
+type role
+type doc
+  relations
+    define viewer: [role#assignee]`,
+			expectError: "relation doc#viewer references unknown relation role#assignee",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			model, err := language.TransformDSLToProto(test.dsl)
+			require.NoError(t, err)
+
+			err = ValidateModelReferences(model)
+			if test.expectError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.expectError)
+		})
+	}
+}
+
+func TestValidateTuples(t *testing.T) {
+	model, err := language.TransformDSLToProto(`model
+  schema 1.2
+type user
+type role
+  relations
+    define assignee: [user]
+type doc
+  relations
+    define viewer: [user, role#assignee]`)
+	require.NoError(t, err)
+
+	assert.NoError(t, ValidateTuples(model, []v1alpha1.Tuple{
+		{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:1", Relation: "viewer", User: "role:admin#assignee"},
+	}))
+
+	err = ValidateTuples(model, []v1alpha1.Tuple{
+		{Object: "folder:1", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:1", Relation: "editor", User: "user:alice"},
+		{Object: "doc:1", Relation: "viewer", User: "group:admins"},
+		{Object: "doc:1", Relation: "viewer", User: "role:admin#member"},
+	})
+	assert.ErrorContains(t, err, "unknown object type folder")
+	assert.ErrorContains(t, err, "type doc has no relation editor")
+	assert.ErrorContains(t, err, "unknown user type group")
+	assert.ErrorContains(t, err, "type role has no relation member")
+}
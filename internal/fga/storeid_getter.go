@@ -2,103 +2,196 @@ package fga
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// storeIDNegativeTTL is how long a store name that was not found in OpenFGA
+// is remembered, so that repeated lookups of a store that does not exist yet
+// do not list all stores every time.
+const storeIDNegativeTTL = 10 * time.Second
+
+// ErrDuplicateStoreName is returned if more than one OpenFGA store has the
+// requested name, in which case its ID cannot be resolved by name.
+var ErrDuplicateStoreName = errors.New("multiple OpenFGA stores have the same name")
+
 // StoreIDGetter should return the OpenFGA store ID for a store name.
 type StoreIDGetter interface {
 	Get(ctx context.Context, storeName string) (string, error)
 }
 
+// AuthorizationModelIDGetter is implemented by StoreIDGetters that know the
+// authorization model tuples of a store are written and checked with.
+type AuthorizationModelIDGetter interface {
+	GetAuthorizationModelID(ctx context.Context, storeName string) (string, error)
+}
+
+// GetAuthorizationModelID returns the ID of the authorization model tuples of
+// a store are written and checked with, which differs from the latest model
+// while a Store is pinned to a previous one. It is AuthorizationModelIDLatest
+// if the getter does not know the model of the store.
+func GetAuthorizationModelID(ctx context.Context, getter StoreIDGetter, storeName string) (string, error) {
+	if modelIDGetter, ok := getter.(AuthorizationModelIDGetter); ok {
+		return modelIDGetter.GetAuthorizationModelID(ctx, storeName)
+	}
+	return AuthorizationModelIDLatest, nil
+}
+
+// StoreStatusIDGetter resolves store IDs from the status of the Store
+// resources, read through an informer cache of the workspace they live in.
+// Stores that are unknown or have no ID yet are resolved by the fallback,
+// under the name of their OpenFGA store if the Store is known.
+type StoreStatusIDGetter struct {
+	reader   client.Reader
+	fallback StoreIDGetter
+}
+
+func NewStoreStatusIDGetter(reader client.Reader, fallback StoreIDGetter) *StoreStatusIDGetter {
+	return &StoreStatusIDGetter{reader: reader, fallback: fallback}
+}
+
+// Get returns the store ID for the given store name.
+func (g *StoreStatusIDGetter) Get(ctx context.Context, storeName string) (string, error) {
+	var store v1alpha1.Store
+	err := g.reader.Get(ctx, client.ObjectKey{Name: storeName}, &store)
+	if kerrors.IsNotFound(err) {
+		return g.fallback.Get(ctx, storeName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting Store %s: %w", storeName, err)
+	}
+	if store.Status.StoreID != "" {
+		return store.Status.StoreID, nil
+	}
+	return g.fallback.Get(ctx, store.OpenFGAStoreName())
+}
+
+// GetAuthorizationModelID returns the authorization model in use by the
+// Store of the given name. Unknown Stores use the latest model.
+func (g *StoreStatusIDGetter) GetAuthorizationModelID(ctx context.Context, storeName string) (string, error) {
+	var store v1alpha1.Store
+	err := g.reader.Get(ctx, client.ObjectKey{Name: storeName}, &store)
+	if kerrors.IsNotFound(err) {
+		return AuthorizationModelIDLatest, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting Store %s: %w", storeName, err)
+	}
+	return store.Status.AuthorizationModelID, nil
+}
+
+var (
+	_ StoreIDGetter              = (*StoreStatusIDGetter)(nil)
+	_ AuthorizationModelIDGetter = (*StoreStatusIDGetter)(nil)
+)
+
+// storeIDEntry is a cached lookup result. Names without an ID were not found.
+type storeIDEntry struct {
+	id string
+}
+
 // CachingStoreIDGetter maps store names to IDs by listing stores in OpenFGA but keeps
-// a local cache to avoid frequent list calls.
+// a local cache to avoid frequent list calls. Concurrent cache misses share a
+// single listing, and names that were not found are cached for a short time.
+// Names used by several stores are not cached, so they resolve as soon as the
+// duplicates are cleaned up.
 type CachingStoreIDGetter struct {
-	cache  *ttlcache.Cache[string, string]
-	loader *storeIDLoader
-	logger *logger.Logger
+	fga     openfgav1.OpenFGAServiceClient
+	loadCtx context.Context
+	cache   *ttlcache.Cache[string, storeIDEntry]
+	group   singleflight.Group
+	logger  *logger.Logger
 }
 
 func NewCachingStoreIDGetter(fga openfgav1.OpenFGAServiceClient, ttl time.Duration, loadCtx context.Context, log *logger.Logger) *CachingStoreIDGetter {
-	loader := &storeIDLoader{fga: fga, loadCtx: loadCtx}
-
 	cache := ttlcache.New(
-		ttlcache.WithTTL[string, string](ttl),
-		ttlcache.WithLoader(loader),
+		ttlcache.WithTTL[string, storeIDEntry](ttl),
 	)
-	cache.OnInsertion(func(_ context.Context, item *ttlcache.Item[string, string]) {
+	cache.OnInsertion(func(_ context.Context, item *ttlcache.Item[string, storeIDEntry]) {
 		log.Debug().
 			Str("store", item.Key()).
-			Str("id", item.Value()).
+			Str("id", item.Value().id).
 			Msg("StoreID cache inserted item")
 	})
-	cache.OnUpdate(func(_ context.Context, item *ttlcache.Item[string, string]) {
+	cache.OnUpdate(func(_ context.Context, item *ttlcache.Item[string, storeIDEntry]) {
 		log.Debug().
 			Str("store", item.Key()).
-			Str("id", item.Value()).
+			Str("id", item.Value().id).
 			Msg("StoreID cache updated item")
 	})
-	cache.OnEviction(func(_ context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[string, string]) {
+	cache.OnEviction(func(_ context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[string, storeIDEntry]) {
 		log.Debug().
 			Str("store", item.Key()).
-			Str("id", item.Value()).
+			Str("id", item.Value().id).
 			Str("reason", fmt.Sprint(reason)).
 			Msg("StoreID cache evicted item")
 	})
 
 	return &CachingStoreIDGetter{
-		cache:  cache,
-		loader: loader,
-		logger: log,
+		fga:     fga,
+		loadCtx: loadCtx,
+		cache:   cache,
+		logger:  log,
 	}
 }
 
 // Get returns the store ID for the given store name.
 func (m *CachingStoreIDGetter) Get(ctx context.Context, storeName string) (string, error) {
-	item := m.cache.Get(storeName)
-	if err := m.loader.Err(); err != nil {
-		return "", fmt.Errorf("populating cache: %w", err)
-	}
+	item := m.cache.Get(storeName, ttlcache.WithDisableTouchOnHit[string, storeIDEntry]())
+	if item == nil {
+		// A listing covers all stores, so lookups of any name wait for the
+		// one in flight instead of starting another.
+		duplicates, err, _ := m.group.Do("", func() (any, error) { return m.load() })
+		if err != nil {
+			return "", fmt.Errorf("populating cache: %w", err)
+		}
+		if duplicates.(map[string]bool)[storeName] {
+			return "", fmt.Errorf("%w: %q", ErrDuplicateStoreName, storeName)
+		}
 
-	if item != nil {
-		return item.Value(), nil
+		item = m.cache.Get(storeName, ttlcache.WithDisableTouchOnHit[string, storeIDEntry]())
+		if item == nil {
+			item = m.cache.Set(storeName, storeIDEntry{}, storeIDNegativeTTL)
+		}
 	}
 
-	return "", fmt.Errorf("store %q not found", storeName)
-}
-
-type storeIDLoader struct {
-	fga       openfgav1.OpenFGAServiceClient
-	loadErrer error
-	loadCtx   context.Context
+	if item.Value().id == "" {
+		return "", fmt.Errorf("store %q not found", storeName)
+	}
+	return item.Value().id, nil
 }
 
-// Load lists all stores from OpenFGA, adds them to the cache, and returns the
-// requested store's item or nil if not found. Caller is supposed to check
-// Err(). Implements ttlcache.Loader.
-func (l *storeIDLoader) Load(c *ttlcache.Cache[string, string], storeName string) *ttlcache.Item[string, string] {
+// load lists all stores from OpenFGA and adds them to the cache. It returns
+// the names used by several stores, which are removed from the cache instead.
+func (m *CachingStoreIDGetter) load() (map[string]bool, error) {
+	ids := map[string][]string{}
 	var continuationToken string
-	var wantedItem *ttlcache.Item[string, string]
-
 	for {
-		resp, err := l.fga.ListStores(l.loadCtx, &openfgav1.ListStoresRequest{
+		resp, err := m.fga.ListStores(m.loadCtx, &openfgav1.ListStoresRequest{
 			PageSize:          wrapperspb.Int32(100),
 			ContinuationToken: continuationToken,
 		})
 		if err != nil {
-			l.loadErrer = fmt.Errorf("listing Stores in OpenFGA: %w", err)
-			return nil
+			return nil, fmt.Errorf("listing Stores in OpenFGA: %w", err)
 		}
 
 		for _, store := range resp.GetStores() {
-			if item := c.Set(store.GetName(), store.GetId(), ttlcache.DefaultTTL); item.Key() == storeName {
-				wantedItem = item
+			// Scratch stores of model tests are never looked up.
+			if IsModelTestsStore(store.GetName()) {
+				continue
 			}
+			ids[store.GetName()] = append(ids[store.GetName()], store.GetId())
 		}
 
 		continuationToken = resp.GetContinuationToken()
@@ -107,14 +200,17 @@ func (l *storeIDLoader) Load(c *ttlcache.Cache[string, string], storeName string
 		}
 	}
 
-	return wantedItem
-}
-
-// Err returns the last error occured during Load. See [0] for why it works like
-// this.
-// [0] https://github.com/jellydator/ttlcache/issues/74#issuecomment-1133012806
-func (l *storeIDLoader) Err() error {
-	return l.loadErrer
+	duplicates := map[string]bool{}
+	for name, storeIDs := range ids {
+		if len(storeIDs) > 1 {
+			m.logger.Warn().Str("store", name).Strs("ids", storeIDs).Msg("Found multiple OpenFGA stores with the same name")
+			m.cache.Delete(name)
+			duplicates[name] = true
+			continue
+		}
+		m.cache.Set(name, storeIDEntry{id: storeIDs[0]}, ttlcache.DefaultTTL)
+	}
+	return duplicates, nil
 }
 
 var _ StoreIDGetter = (*CachingStoreIDGetter)(nil)
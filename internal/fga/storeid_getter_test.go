@@ -3,15 +3,22 @@ package fga
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestCachingStoreIDGetter_Get(t *testing.T) {
@@ -54,6 +61,21 @@ func TestCachingStoreIDGetter_Get(t *testing.T) {
 		client.AssertExpectations(t)
 	})
 
+	t.Run("ignores scratch stores of model tests", func(t *testing.T) {
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+			Stores: []*openfgav1.Store{
+				{Name: "__model-tests-uid", Id: "SCRATCH-ID"},
+			},
+		}, nil).Once()
+
+		log := testlogger.New()
+		getter := NewCachingStoreIDGetter(client, 5*time.Minute, context.Background(), log.Logger)
+
+		_, err := getter.Get(context.Background(), "__model-tests-uid")
+		assert.ErrorContains(t, err, "not found")
+	})
+
 	t.Run("returns error when store not found in OpenFGA", func(t *testing.T) {
 		client := mocks.NewMockOpenFGAServiceClient(t)
 		client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
@@ -85,3 +107,164 @@ func TestCachingStoreIDGetter_Get(t *testing.T) {
 		assert.Empty(t, id)
 	})
 }
+
+func TestCachingStoreIDGetter_retriesAfterListStoresFailed(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(nil, errors.New("connection refused")).Once()
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Name: "foo", Id: "DEADBEEF"}},
+	}, nil).Once()
+
+	log := testlogger.New()
+	getter := NewCachingStoreIDGetter(client, 5*time.Minute, context.Background(), log.Logger)
+
+	_, err := getter.Get(context.Background(), "foo")
+	assert.Error(t, err)
+
+	id, err := getter.Get(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "DEADBEEF", id)
+}
+
+func TestCachingStoreIDGetter_cachesMissingStores(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{}, nil).Once()
+
+	log := testlogger.New()
+	getter := NewCachingStoreIDGetter(client, 5*time.Minute, context.Background(), log.Logger)
+
+	for range 3 {
+		_, err := getter.Get(context.Background(), "missing-store")
+		assert.ErrorContains(t, err, "store \"missing-store\" not found")
+	}
+}
+
+func TestCachingStoreIDGetter_duplicateStoreNames(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores:            []*openfgav1.Store{{Name: "foo", Id: "FIRST"}, {Name: "bar", Id: "BAR"}},
+		ContinuationToken: "next",
+	}, nil).Once()
+	client.EXPECT().ListStores(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListStoresRequest) bool {
+		return req.ContinuationToken == "next"
+	})).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Name: "foo", Id: "SECOND"}},
+	}, nil).Once()
+
+	log := testlogger.New()
+	getter := NewCachingStoreIDGetter(client, 5*time.Minute, context.Background(), log.Logger)
+
+	_, err := getter.Get(context.Background(), "foo")
+	assert.ErrorIs(t, err, ErrDuplicateStoreName)
+
+	id, err := getter.Get(context.Background(), "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "BAR", id)
+
+	// Ambiguous names are not cached, so the name resolves once one of the
+	// stores is deleted.
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Name: "foo", Id: "SECOND"}, {Name: "bar", Id: "BAR"}},
+	}, nil).Once()
+
+	id, err = getter.Get(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "SECOND", id)
+}
+
+func TestCachingStoreIDGetter_sharesConcurrentListings(t *testing.T) {
+	release := make(chan struct{})
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *openfgav1.ListStoresRequest, ...grpc.CallOption) (*openfgav1.ListStoresResponse, error) {
+		<-release
+		return &openfgav1.ListStoresResponse{
+			Stores: []*openfgav1.Store{{Name: "foo", Id: "FOO"}, {Name: "bar", Id: "BAR"}},
+		}, nil
+	}).Once()
+
+	log := testlogger.New()
+	getter := NewCachingStoreIDGetter(client, 5*time.Minute, context.Background(), log.Logger)
+
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Go(func() {
+			name := "foo"
+			if i%2 == 1 {
+				name = "bar"
+			}
+			id, err := getter.Get(context.Background(), name)
+			assert.NoError(t, err)
+			ids[i] = id
+		})
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, id := range ids {
+		if i%2 == 1 {
+			assert.Equal(t, "BAR", id)
+		} else {
+			assert.Equal(t, "FOO", id)
+		}
+	}
+}
+
+func TestStoreStatusIDGetter_Get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1alpha1.Store{ObjectMeta: metav1.ObjectMeta{Name: "ready"}, Status: v1alpha1.StoreStatus{StoreID: "READY-ID"}},
+		&v1alpha1.Store{ObjectMeta: metav1.ObjectMeta{Name: "pending"}},
+		&v1alpha1.Store{ObjectMeta: metav1.ObjectMeta{Name: "renamed"}, Spec: v1alpha1.StoreSpec{StoreName: "team-a/renamed"}},
+	).Build()
+
+	fallback := mocks.NewMockStoreIDGetter(t)
+	fallback.EXPECT().Get(mock.Anything, "pending").Return("PENDING-ID", nil).Once()
+	fallback.EXPECT().Get(mock.Anything, "team-a/renamed").Return("RENAMED-ID", nil).Once()
+	fallback.EXPECT().Get(mock.Anything, "unknown").Return("", errors.New("store \"unknown\" not found")).Once()
+
+	getter := NewStoreStatusIDGetter(reader, fallback)
+
+	id, err := getter.Get(context.Background(), "ready")
+	require.NoError(t, err)
+	assert.Equal(t, "READY-ID", id)
+
+	id, err = getter.Get(context.Background(), "pending")
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING-ID", id)
+
+	id, err = getter.Get(context.Background(), "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "RENAMED-ID", id)
+
+	_, err = getter.Get(context.Background(), "unknown")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestGetAuthorizationModelID(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1alpha1.Store{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinned"},
+			Spec:       v1alpha1.StoreSpec{PinnedAuthorizationModelID: "PINNED"},
+			Status:     v1alpha1.StoreStatus{StoreID: "STORE-ID", AuthorizationModelID: "PINNED"},
+		},
+	).Build()
+	getter := NewStoreStatusIDGetter(reader, mocks.NewMockStoreIDGetter(t))
+
+	modelID, err := GetAuthorizationModelID(context.Background(), getter, "pinned")
+	require.NoError(t, err)
+	assert.Equal(t, "PINNED", modelID)
+
+	modelID, err = GetAuthorizationModelID(context.Background(), getter, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, AuthorizationModelIDLatest, modelID)
+
+	// Getters that do not know the models of stores use the latest one.
+	modelID, err = GetAuthorizationModelID(context.Background(), mocks.NewMockStoreIDGetter(t), "pinned")
+	require.NoError(t, err)
+	assert.Equal(t, AuthorizationModelIDLatest, modelID)
+}
@@ -0,0 +1,84 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+)
+
+// TupleDiff holds the changes needed to get from one set of tuples to
+// another.
+type TupleDiff struct {
+	// Add holds the desired tuples that are missing.
+	Add []v1alpha1.Tuple
+	// Remove holds the current tuples that are not desired anymore.
+	Remove []v1alpha1.Tuple
+}
+
+// Empty reports whether nothing needs to be changed.
+func (d TupleDiff) Empty() bool {
+	return len(d.Add) == 0 && len(d.Remove) == 0
+}
+
+// DiffTuples returns the tuples to add and remove to get from current to
+// desired. Tuples whose condition changed are removed and added again, as
+// OpenFGA refuses to overwrite an existing tuple with a different condition.
+// Duplicates in either set are ignored.
+func DiffTuples(current, desired []v1alpha1.Tuple) TupleDiff {
+	currentByKey := make(map[string][]v1alpha1.Tuple, len(current))
+	for _, t := range current {
+		currentByKey[t.String()] = append(currentByKey[t.String()], t)
+	}
+	desiredByKey := make(map[string]v1alpha1.Tuple, len(desired))
+	for _, t := range desired {
+		desiredByKey[t.String()] = t
+	}
+
+	var diff TupleDiff
+	removed := map[string]bool{}
+	for _, t := range current {
+		key := t.String()
+		if d, ok := desiredByKey[key]; (ok && d.Equal(t)) || removed[key] {
+			continue
+		}
+		diff.Remove = append(diff.Remove, t)
+		removed[key] = true
+	}
+	added := map[string]bool{}
+	for _, t := range desired {
+		key := t.String()
+		if added[key] || (!removed[key] && slices.ContainsFunc(currentByKey[key], t.Equal)) {
+			continue
+		}
+		diff.Add = append(diff.Add, t)
+		added[key] = true
+	}
+	return diff
+}
+
+// ReadTuples returns the tuples stored with the same keys as the given ones,
+// including their stored condition. Tuples missing in the store are omitted.
+func (m *TupleManager) ReadTuples(ctx context.Context, tuples []v1alpha1.Tuple) ([]v1alpha1.Tuple, error) {
+	var result []v1alpha1.Tuple
+	seen := make(map[string]bool, len(tuples))
+	for _, t := range tuples {
+		if seen[t.String()] {
+			continue
+		}
+		seen[t.String()] = true
+
+		stored, err := m.ListWithKey(ctx, &openfgav1.ReadRequestTupleKey{
+			Object:   t.Object,
+			Relation: t.Relation,
+			User:     t.User,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading tuple %s: %w", t, err)
+		}
+		result = append(result, stored...)
+	}
+	return result, nil
+}
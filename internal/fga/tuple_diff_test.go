@@ -0,0 +1,127 @@
+package fga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger/testlogger"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDiffTuples(t *testing.T) {
+	viewer := v1alpha1.Tuple{Object: "doc:a", Relation: "viewer", User: "user:alice"}
+	editor := v1alpha1.Tuple{Object: "doc:a", Relation: "editor", User: "user:bob"}
+	owner := v1alpha1.Tuple{Object: "doc:a", Relation: "owner", User: "user:carol"}
+	conditional := func(cidr string) v1alpha1.Tuple {
+		return v1alpha1.Tuple{
+			Object:    "doc:a",
+			Relation:  "viewer",
+			User:      "user:alice",
+			Condition: &v1alpha1.TupleCondition{Name: "in_network", Context: &runtime.RawExtension{Raw: []byte(`{"cidr":"` + cidr + `"}`)}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		current    []v1alpha1.Tuple
+		desired    []v1alpha1.Tuple
+		wantAdd    []v1alpha1.Tuple
+		wantRemove []v1alpha1.Tuple
+	}{
+		{
+			name: "no tuples",
+		},
+		{
+			name:    "unchanged tuples",
+			current: []v1alpha1.Tuple{viewer, editor},
+			desired: []v1alpha1.Tuple{editor, viewer},
+		},
+		{
+			name:    "adds missing tuples",
+			current: []v1alpha1.Tuple{viewer},
+			desired: []v1alpha1.Tuple{viewer, editor},
+			wantAdd: []v1alpha1.Tuple{editor},
+		},
+		{
+			name:       "removes tuples no longer desired",
+			current:    []v1alpha1.Tuple{viewer, editor},
+			desired:    []v1alpha1.Tuple{viewer},
+			wantRemove: []v1alpha1.Tuple{editor},
+		},
+		{
+			name:       "adds and removes tuples",
+			current:    []v1alpha1.Tuple{viewer, editor},
+			desired:    []v1alpha1.Tuple{viewer, owner},
+			wantAdd:    []v1alpha1.Tuple{owner},
+			wantRemove: []v1alpha1.Tuple{editor},
+		},
+		{
+			name:       "replaces tuples whose condition changed",
+			current:    []v1alpha1.Tuple{conditional("10.0.0.0/8")},
+			desired:    []v1alpha1.Tuple{conditional("192.168.0.0/16")},
+			wantAdd:    []v1alpha1.Tuple{conditional("192.168.0.0/16")},
+			wantRemove: []v1alpha1.Tuple{conditional("10.0.0.0/8")},
+		},
+		{
+			name:       "replaces tuples that gained a condition",
+			current:    []v1alpha1.Tuple{viewer},
+			desired:    []v1alpha1.Tuple{conditional("10.0.0.0/8")},
+			wantAdd:    []v1alpha1.Tuple{conditional("10.0.0.0/8")},
+			wantRemove: []v1alpha1.Tuple{viewer},
+		},
+		{
+			name:       "ignores duplicates",
+			current:    []v1alpha1.Tuple{editor, editor},
+			desired:    []v1alpha1.Tuple{owner, owner},
+			wantAdd:    []v1alpha1.Tuple{owner},
+			wantRemove: []v1alpha1.Tuple{editor},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffTuples(tt.current, tt.desired)
+			assert.Equal(t, tt.wantAdd, diff.Add)
+			assert.Equal(t, tt.wantRemove, diff.Remove)
+			assert.Equal(t, len(tt.wantAdd) == 0 && len(tt.wantRemove) == 0, diff.Empty())
+		})
+	}
+}
+
+func TestTupleManager_ReadTuples(t *testing.T) {
+	viewer := v1alpha1.Tuple{Object: "doc:a", Relation: "viewer", User: "user:alice"}
+	editor := v1alpha1.Tuple{Object: "doc:a", Relation: "editor", User: "user:bob"}
+
+	t.Run("returns the stored tuples", func(t *testing.T) {
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+			return req.StoreId == "store-id" && req.TupleKey.Relation == "viewer"
+		})).Return(&openfgav1.ReadResponse{Tuples: []*openfgav1.Tuple{
+			{Key: &openfgav1.TupleKey{Object: "doc:a", Relation: "viewer", User: "user:alice"}},
+		}}, nil).Once()
+		client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+			return req.StoreId == "store-id" && req.TupleKey.Relation == "editor"
+		})).Return(&openfgav1.ReadResponse{}, nil).Once()
+
+		tm := NewTupleManager(client, "store-id", "model-id", testlogger.New().Logger)
+		stored, err := tm.ReadTuples(context.Background(), []v1alpha1.Tuple{viewer, editor, viewer})
+		require.NoError(t, err)
+		assert.Equal(t, []v1alpha1.Tuple{viewer}, stored)
+	})
+
+	t.Run("returns read errors", func(t *testing.T) {
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, errors.New("unavailable")).Once()
+
+		tm := NewTupleManager(client, "store-id", "model-id", testlogger.New().Logger)
+		_, err := tm.ReadTuples(context.Background(), []v1alpha1.Tuple{viewer})
+		assert.ErrorContains(t, err, "reading tuple doc:a#viewer@user:alice")
+	})
+}
@@ -0,0 +1,138 @@
+package fga
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// ParseTupleFile parses tuples in the format indicated by the extension of
+// name. YAML and JSON files hold a list of tuples as written by the fga CLI,
+// CSV files use the CSV format of the fga CLI and all other files hold one
+// tuple per line in the form object#relation@user. Empty lines and lines
+// starting with # are skipped in the latter.
+func ParseTupleFile(name string, data []byte) ([]v1alpha1.Tuple, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		var tuples []v1alpha1.Tuple
+		if err := yaml.UnmarshalStrict(data, &tuples); err != nil {
+			return nil, fmt.Errorf("parsing tuples of %s: %w", name, err)
+		}
+		return tuples, nil
+	case ".csv":
+		tuples, err := parseTupleCSV(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing tuples of %s: %w", name, err)
+		}
+		return tuples, nil
+	default:
+		tuples, err := parseTupleLines(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing tuples of %s: %w", name, err)
+		}
+		return tuples, nil
+	}
+}
+
+func parseTupleLines(data []byte) ([]v1alpha1.Tuple, error) {
+	var tuples []v1alpha1.Tuple
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		tuple, err := v1alpha1.ParseTuple(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, scanner.Err()
+}
+
+// tupleCSVColumns are the columns of the CSV format of the fga CLI. Only the
+// user and object columns are required.
+var tupleCSVColumns = []string{
+	"user_type", "user_id", "user_relation", "relation",
+	"object_type", "object_id", "condition_name", "condition_context",
+}
+
+func parseTupleCSV(data []byte) ([]v1alpha1.Tuple, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if !slices.Contains(tupleCSVColumns, column) {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		columns[column] = i
+	}
+	for _, column := range []string{"user_type", "user_id", "relation", "object_type", "object_id"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	var tuples []v1alpha1.Tuple
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return tuples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		tuple := v1alpha1.Tuple{
+			Object:   field("object_type") + ":" + field("object_id"),
+			Relation: field("relation"),
+			User:     field("user_type") + ":" + field("user_id"),
+		}
+		if relation := field("user_relation"); relation != "" {
+			tuple.User += "#" + relation
+		}
+		if name := field("condition_name"); name != "" {
+			tuple.Condition = &v1alpha1.TupleCondition{Name: name}
+			if conditionContext := field("condition_context"); conditionContext != "" {
+				if !json.Valid([]byte(conditionContext)) {
+					return nil, fmt.Errorf("line %d: condition context is not valid JSON", line)
+				}
+				tuple.Condition.Context = &runtime.RawExtension{Raw: []byte(conditionContext)}
+			}
+		} else if field("condition_context") != "" {
+			return nil, fmt.Errorf("line %d: condition context without a condition name", line)
+		}
+		if tuple.Relation == "" || field("object_type") == "" || field("object_id") == "" || field("user_type") == "" || field("user_id") == "" {
+			return nil, fmt.Errorf("line %d: incomplete tuple %q", line, tuple.String())
+		}
+		tuples = append(tuples, tuple)
+	}
+}
@@ -0,0 +1,109 @@
+package fga
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParseTupleFile(t *testing.T) {
+	conditional := v1alpha1.Tuple{
+		Object:   "document:readme",
+		Relation: "viewer",
+		User:     "group:admins#member",
+		Condition: &v1alpha1.TupleCondition{
+			Name:    "in_network",
+			Context: &runtime.RawExtension{Raw: []byte(`{"cidr":"10.0.0.0/8"}`)},
+		},
+	}
+	plain := v1alpha1.Tuple{Object: "document:readme", Relation: "owner", User: "user:alice"}
+
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    []v1alpha1.Tuple
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			file: "tuples.yaml",
+			data: `
+- user: group:admins#member
+  relation: viewer
+  object: document:readme
+  condition:
+    name: in_network
+    context:
+      cidr: 10.0.0.0/8
+- user: user:alice
+  relation: owner
+  object: document:readme
+`,
+			want: []v1alpha1.Tuple{conditional, plain},
+		},
+		{
+			name: "json",
+			file: "tuples.json",
+			data: `[{"user":"user:alice","relation":"owner","object":"document:readme"}]`,
+			want: []v1alpha1.Tuple{plain},
+		},
+		{
+			name:    "yaml with unknown fields",
+			file:    "tuples.yml",
+			data:    `[{"user":"user:alice","relation":"owner","object":"document:readme","subject":"x"}]`,
+			wantErr: true,
+		},
+		{
+			name: "csv",
+			file: "tuples.csv",
+			data: `user_type,user_id,user_relation,relation,object_type,object_id,condition_name,condition_context
+group,admins,member,viewer,document,readme,in_network,"{""cidr"":""10.0.0.0/8""}"
+user,alice,,owner,document,readme,,
+`,
+			want: []v1alpha1.Tuple{conditional, plain},
+		},
+		{
+			name:    "csv without object column",
+			file:    "tuples.csv",
+			data:    "user_type,user_id,relation,object_type\nuser,alice,owner,document\n",
+			wantErr: true,
+		},
+		{
+			name:    "csv with incomplete tuple",
+			file:    "tuples.csv",
+			data:    "user_type,user_id,relation,object_type,object_id\nuser,,owner,document,readme\n",
+			wantErr: true,
+		},
+		{
+			name: "lines",
+			file: "tuples",
+			data: "# owners\ndocument:readme#owner@user:alice\n\n  document:readme#viewer@group:admins#member\n",
+			want: []v1alpha1.Tuple{plain, {Object: "document:readme", Relation: "viewer", User: "group:admins#member"}},
+		},
+		{
+			name:    "malformed line",
+			file:    "tuples.txt",
+			data:    "document:readme#owner\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tuples, err := ParseTupleFile(test.file, []byte(test.data))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tuples, len(test.want))
+			for i := range test.want {
+				assert.True(t, test.want[i].Equal(tuples[i]), "tuple %d: want %s, got %s", i, test.want[i], tuples[i])
+			}
+		})
+	}
+}
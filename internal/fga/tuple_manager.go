@@ -2,39 +2,79 @@ package fga
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	"github.com/platform-mesh/security-operator/internal/metrics"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // AuthorizationModelIDLatest is to explicitely acknowledge that no ID means
 // latest.
 const AuthorizationModelIDLatest = ""
 
+// DefaultWriteChunkSize is the maximum number of tuple keys OpenFGA accepts
+// in a single write request unless configured otherwise.
+const DefaultWriteChunkSize = 100
+
+// PartialWriteError is returned if some chunks could not be written. It records which tuples made it to the store.
+type PartialWriteError struct {
+	Written []v1alpha1.Tuple
+	Failed  []v1alpha1.Tuple
+	Err     error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("wrote %d of %d tuples: %v", len(e.Written), len(e.Written)+len(e.Failed), e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error { return e.Err }
+
 // TupleManager wraps around FGA attributes to write and delete sets of tuples.
 type TupleManager struct {
 	client               openfgav1.OpenFGAServiceClient
 	storeID              string
 	authorizationModelID string
 	logger               logger.Logger
+	chunkSize            int
 }
 
 type TupleFilter func(t v1alpha1.Tuple) bool
 
-func NewTupleManager(client openfgav1.OpenFGAServiceClient, storeID, authorizationModelID string, log *logger.Logger) *TupleManager {
-	return &TupleManager{
+type TupleManagerOption func(*TupleManager)
+
+// WithWriteChunkSize sets the maximum number of tuples per write request.
+// Non-positive values keep the default.
+func WithWriteChunkSize(size int) TupleManagerOption {
+	return func(m *TupleManager) {
+		if size > 0 {
+			m.chunkSize = size
+		}
+	}
+}
+
+func NewTupleManager(client openfgav1.OpenFGAServiceClient, storeID, authorizationModelID string, log *logger.Logger, opts ...TupleManagerOption) *TupleManager {
+	m := &TupleManager{
 		client:               client,
 		storeID:              storeID,
 		authorizationModelID: authorizationModelID,
 		logger:               *log.ComponentLogger("tuple_manager").MustChildLoggerWithAttributes("store_id", storeID, "authorization_model", authorizationModelID),
+		chunkSize:            DefaultWriteChunkSize,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
-// Apply writes a given set of tuples within a single transaction and ignores
-// duplicate writes.
+// Apply writes a given set of tuples in chunks and ignores duplicate writes.
 func (m *TupleManager) Apply(ctx context.Context, tuples []v1alpha1.Tuple) error {
 	if len(tuples) == 0 {
 		return nil
@@ -42,33 +82,33 @@ func (m *TupleManager) Apply(ctx context.Context, tuples []v1alpha1.Tuple) error
 
 	tupleKeys := make([]*openfgav1.TupleKey, 0, len(tuples))
 	for _, t := range tuples {
-		tupleKeys = append(tupleKeys, &openfgav1.TupleKey{
-			Object:   t.Object,
-			Relation: t.Relation,
-			User:     t.User,
-		})
+		key, err := tupleKeyFromTuple(t)
+		if err != nil {
+			return fmt.Errorf("building tuple key for %s: %w", t, err)
+		}
+		tupleKeys = append(tupleKeys, key)
 	}
 
-	_, err := m.client.Write(ctx, &openfgav1.WriteRequest{
-		StoreId:              m.storeID,
-		AuthorizationModelId: m.authorizationModelID,
-		Writes: &openfgav1.WriteRequestWrites{
-			TupleKeys:   tupleKeys,
-			OnDuplicate: "ignore",
-		},
+	err := m.write(ctx, "apply", tuples, func(start, end int) *openfgav1.WriteRequest {
+		return &openfgav1.WriteRequest{
+			StoreId:              m.storeID,
+			AuthorizationModelId: m.authorizationModelID,
+			Writes: &openfgav1.WriteRequestWrites{
+				TupleKeys:   tupleKeys[start:end],
+				OnDuplicate: "ignore",
+			},
+		}
 	})
 	if err != nil {
-		metrics.FGAOperations.WithLabelValues("apply", "error").Inc()
 		return err
 	}
 
 	m.logger.Debug().Int("count", len(tuples)).Msg("Ensured tuples")
-	metrics.FGAOperations.WithLabelValues("apply", "success").Inc()
 	return nil
 }
 
-// Delete deletes a given set of tuples within a single transaction and ignores
-// duplicate deletions.
+// Delete deletes a given set of tuples in chunks and ignores duplicate
+// deletions.
 func (m *TupleManager) Delete(ctx context.Context, tuples []v1alpha1.Tuple) error {
 	if len(tuples) == 0 {
 		return nil
@@ -83,21 +123,53 @@ func (m *TupleManager) Delete(ctx context.Context, tuples []v1alpha1.Tuple) erro
 		})
 	}
 
-	_, err := m.client.Write(ctx, &openfgav1.WriteRequest{
-		StoreId:              m.storeID,
-		AuthorizationModelId: m.authorizationModelID,
-		Deletes: &openfgav1.WriteRequestDeletes{
-			TupleKeys: tupleKeys,
-			OnMissing: "ignore",
-		},
+	err := m.write(ctx, "delete", tuples, func(start, end int) *openfgav1.WriteRequest {
+		return &openfgav1.WriteRequest{
+			StoreId:              m.storeID,
+			AuthorizationModelId: m.authorizationModelID,
+			Deletes: &openfgav1.WriteRequestDeletes{
+				TupleKeys: tupleKeys[start:end],
+				OnMissing: "ignore",
+			},
+		}
 	})
 	if err != nil {
-		metrics.FGAOperations.WithLabelValues("delete", "error").Inc()
 		return err
 	}
 
 	m.logger.Debug().Int("count", len(tuples)).Msg("Deleted tuples")
-	metrics.FGAOperations.WithLabelValues("delete", "success").Inc()
+	return nil
+}
+
+// write sends the tuples in chunks of at most chunkSize write requests built
+// by newRequest. Failing chunks do not stop the remaining ones and are
+// collected into a PartialWriteError.
+func (m *TupleManager) write(ctx context.Context, operation string, tuples []v1alpha1.Tuple, newRequest func(start, end int) *openfgav1.WriteRequest) error {
+	var written, failed []v1alpha1.Tuple
+	var errs []error
+	for start := 0; start < len(tuples); start += m.chunkSize {
+		end := min(start+m.chunkSize, len(tuples))
+		metrics.FGAWriteChunkSize.WithLabelValues(operation).Observe(float64(end - start))
+
+		if _, err := m.client.Write(ctx, newRequest(start, end)); err != nil {
+			metrics.FGAOperations.WithLabelValues(operation, "error").Inc()
+			m.logger.Warn().Err(err).Int("chunk_start", start).Int("chunk_size", end-start).Msg("Failed to write chunk of tuples")
+			failed = append(failed, tuples[start:end]...)
+			errs = append(errs, err)
+			continue
+		}
+
+		metrics.FGAOperations.WithLabelValues(operation, "success").Inc()
+		written = append(written, tuples[start:end]...)
+	}
+
+	if len(errs) > 0 {
+		return &PartialWriteError{
+			Written: written,
+			Failed:  failed,
+			Err:     errors.Join(errs...),
+		}
+	}
 	return nil
 }
 
@@ -125,10 +197,10 @@ func (m *TupleManager) ListWithFilter(ctx context.Context, filter TupleFilter) (
 			if t.Key == nil {
 				continue
 			}
-			tuple := v1alpha1.Tuple{
-				Object:   t.Key.Object,
-				Relation: t.Key.Relation,
-				User:     t.Key.User,
+			tuple, err := tupleFromKey(t.Key)
+			if err != nil {
+				metrics.FGAOperations.WithLabelValues("list", "error").Inc()
+				return nil, err
 			}
 			if filter(tuple) {
 				result = append(result, tuple)
@@ -145,6 +217,37 @@ func (m *TupleManager) ListWithFilter(ctx context.Context, filter TupleFilter) (
 	return result, nil
 }
 
+// ListPage reads a single page of all tuples in the store, starting at the
+// given continuation token. It returns the continuation token of the next
+// page, which is empty once the last page was read.
+func (m *TupleManager) ListPage(ctx context.Context, continuationToken string, pageSize int32) ([]v1alpha1.Tuple, string, error) {
+	resp, err := m.client.Read(ctx, &openfgav1.ReadRequest{
+		StoreId:           m.storeID,
+		PageSize:          wrapperspb.Int32(pageSize),
+		ContinuationToken: continuationToken,
+	})
+	if err != nil {
+		metrics.FGAOperations.WithLabelValues("list", "error").Inc()
+		return nil, "", err
+	}
+
+	result := make([]v1alpha1.Tuple, 0, len(resp.Tuples))
+	for _, t := range resp.Tuples {
+		if t.Key == nil {
+			continue
+		}
+		tuple, err := tupleFromKey(t.Key)
+		if err != nil {
+			metrics.FGAOperations.WithLabelValues("list", "error").Inc()
+			return nil, "", err
+		}
+		result = append(result, tuple)
+	}
+
+	metrics.FGAOperations.WithLabelValues("list", "success").Inc()
+	return result, resp.ContinuationToken, nil
+}
+
 // ListWithKey reads tuples from the store filtered by the given
 // ReadRequestTupleKey.
 func (m *TupleManager) ListWithKey(ctx context.Context, key *openfgav1.ReadRequestTupleKey) ([]v1alpha1.Tuple, error) {
@@ -165,11 +268,12 @@ func (m *TupleManager) ListWithKey(ctx context.Context, key *openfgav1.ReadReque
 			if t.Key == nil {
 				continue
 			}
-			result = append(result, v1alpha1.Tuple{
-				Object:   t.Key.Object,
-				Relation: t.Key.Relation,
-				User:     t.Key.User,
-			})
+			tuple, err := tupleFromKey(t.Key)
+			if err != nil {
+				metrics.FGAOperations.WithLabelValues("list", "error").Inc()
+				return nil, err
+			}
+			result = append(result, tuple)
 		}
 
 		continuationToken = resp.ContinuationToken
@@ -181,3 +285,55 @@ func (m *TupleManager) ListWithKey(ctx context.Context, key *openfgav1.ReadReque
 	metrics.FGAOperations.WithLabelValues("list", "success").Inc()
 	return result, nil
 }
+
+// tupleKeyFromTuple converts a tuple into an OpenFGA TupleKey including its
+// optional condition.
+func tupleKeyFromTuple(t v1alpha1.Tuple) (*openfgav1.TupleKey, error) {
+	key := &openfgav1.TupleKey{
+		Object:   t.Object,
+		Relation: t.Relation,
+		User:     t.User,
+	}
+	if t.Condition == nil {
+		return key, nil
+	}
+
+	key.Condition = &openfgav1.RelationshipCondition{
+		Name: t.Condition.Name,
+	}
+	if t.Condition.Context != nil && len(t.Condition.Context.Raw) > 0 {
+		conditionContext := &structpb.Struct{}
+		if err := protojson.Unmarshal(t.Condition.Context.Raw, conditionContext); err != nil {
+			return nil, fmt.Errorf("parsing context of condition %s: %w", t.Condition.Name, err)
+		}
+		key.Condition.Context = conditionContext
+	}
+
+	return key, nil
+}
+
+// tupleFromKey converts an OpenFGA TupleKey read from a store into a tuple
+// including its optional condition.
+func tupleFromKey(key *openfgav1.TupleKey) (v1alpha1.Tuple, error) {
+	t := v1alpha1.Tuple{
+		Object:   key.GetObject(),
+		Relation: key.GetRelation(),
+		User:     key.GetUser(),
+	}
+	if key.GetCondition() == nil {
+		return t, nil
+	}
+
+	t.Condition = &v1alpha1.TupleCondition{
+		Name: key.GetCondition().GetName(),
+	}
+	if len(key.GetCondition().GetContext().GetFields()) > 0 {
+		raw, err := protojson.Marshal(key.GetCondition().GetContext())
+		if err != nil {
+			return v1alpha1.Tuple{}, fmt.Errorf("marshalling context of condition %s: %w", t.Condition.Name, err)
+		}
+		t.Condition.Context = &runtime.RawExtension{Raw: raw}
+	}
+
+	return t, nil
+}
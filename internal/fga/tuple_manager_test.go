@@ -15,8 +15,10 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestTupleManager_Apply(t *testing.T) {
@@ -180,6 +182,119 @@ func TestTupleManager_Delete_verifies_tuple_contents(t *testing.T) {
 		(keys[1].Object == "doc:2" && keys[1].Relation == "owner" && keys[1].User == "user:bob"))
 }
 
+func TestTupleManager_Apply_conditions(t *testing.T) {
+	t.Run("writes condition name and context", func(t *testing.T) {
+		var capturedReq *openfgav1.WriteRequest
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, req *openfgav1.WriteRequest, opts ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+			capturedReq = req
+			return &openfgav1.WriteResponse{}, nil
+		})
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger)
+
+		err := mgr.Apply(context.Background(), []v1alpha1.Tuple{
+			{
+				Object:   "doc:1",
+				Relation: "viewer",
+				User:     "user:alice",
+				Condition: &v1alpha1.TupleCondition{
+					Name:    "in_company_network",
+					Context: &runtime.RawExtension{Raw: []byte(`{"cidr":"192.168.0.0/24"}`)},
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, capturedReq.Writes.TupleKeys, 1)
+
+		condition := capturedReq.Writes.TupleKeys[0].Condition
+		require.NotNil(t, condition)
+		assert.Equal(t, "in_company_network", condition.Name)
+		assert.Equal(t, "192.168.0.0/24", condition.Context.Fields["cidr"].GetStringValue())
+	})
+
+	t.Run("returns error for invalid context", func(t *testing.T) {
+		client := mocks.NewMockOpenFGAServiceClient(t)
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger)
+
+		err := mgr.Apply(context.Background(), []v1alpha1.Tuple{
+			{
+				Object:   "doc:1",
+				Relation: "viewer",
+				User:     "user:alice",
+				Condition: &v1alpha1.TupleCondition{
+					Name:    "in_company_network",
+					Context: &runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)},
+				},
+			},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestTupleManager_chunking(t *testing.T) {
+	tuples := []v1alpha1.Tuple{
+		{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:2", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:3", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:4", Relation: "viewer", User: "user:alice"},
+		{Object: "doc:5", Relation: "viewer", User: "user:alice"},
+	}
+
+	t.Run("writes tuples in chunks", func(t *testing.T) {
+		var chunkSizes []int
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, req *openfgav1.WriteRequest, opts ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+			chunkSizes = append(chunkSizes, len(req.Writes.GetTupleKeys()))
+			return &openfgav1.WriteResponse{}, nil
+		}).Times(3)
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger, WithWriteChunkSize(2))
+
+		err := mgr.Apply(context.Background(), tuples)
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 2, 1}, chunkSizes)
+	})
+
+	t.Run("deletes tuples in chunks", func(t *testing.T) {
+		var chunkSizes []int
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, req *openfgav1.WriteRequest, opts ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+			chunkSizes = append(chunkSizes, len(req.Deletes.GetTupleKeys()))
+			return &openfgav1.WriteResponse{}, nil
+		}).Times(2)
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger, WithWriteChunkSize(3))
+
+		err := mgr.Delete(context.Background(), tuples)
+		require.NoError(t, err)
+		assert.Equal(t, []int{3, 2}, chunkSizes)
+	})
+
+	t.Run("reports partial progress", func(t *testing.T) {
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+			return req.Writes.GetTupleKeys()[0].Object == "doc:3"
+		})).Return(nil, errors.New("write failed")).Once()
+		client.EXPECT().Write(mock.Anything, mock.Anything).Return(&openfgav1.WriteResponse{}, nil).Twice()
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger, WithWriteChunkSize(2))
+
+		err := mgr.Apply(context.Background(), tuples)
+		var partial *PartialWriteError
+		require.ErrorAs(t, err, &partial)
+		assert.Equal(t, []v1alpha1.Tuple{tuples[0], tuples[1], tuples[4]}, partial.Written)
+		assert.Equal(t, []v1alpha1.Tuple{tuples[2], tuples[3]}, partial.Failed)
+		assert.ErrorContains(t, err, "write failed")
+	})
+}
+
 func TestIsTupleOfAccountFilter_returnsFalseForAllTuplesWhenGeneratedClusterIdEmpty(t *testing.T) {
 	_, ai := testAccountAndInfo("test-account", "")
 	filter := IsTupleOfAccountFilter(ai.Spec.Account.GeneratedClusterId)
@@ -376,6 +491,33 @@ func TestTupleManager_ListWithKey(t *testing.T) {
 		assert.Equal(t, "user:alice", result[0].User)
 	})
 
+	t.Run("returns tuple conditions", func(t *testing.T) {
+		conditionContext, err := structpb.NewStruct(map[string]any{"cidr": "10.0.0.0/8"})
+		require.NoError(t, err)
+
+		client := mocks.NewMockOpenFGAServiceClient(t)
+		client.EXPECT().Read(mock.Anything, mock.Anything).Return(&openfgav1.ReadResponse{
+			Tuples: []*openfgav1.Tuple{
+				{Key: &openfgav1.TupleKey{
+					Object:    "doc:1",
+					Relation:  "viewer",
+					User:      "user:alice",
+					Condition: &openfgav1.RelationshipCondition{Name: "in_company_network", Context: conditionContext},
+				}},
+			},
+		}, nil)
+
+		log := testlogger.New()
+		mgr := NewTupleManager(client, "store-id", "model-id", log.Logger)
+
+		result, err := mgr.ListWithKey(context.Background(), &openfgav1.ReadRequestTupleKey{Object: "doc:1"})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.NotNil(t, result[0].Condition)
+		assert.Equal(t, "in_company_network", result[0].Condition.Name)
+		assert.JSONEq(t, `{"cidr":"10.0.0.0/8"}`, string(result[0].Condition.Context.Raw))
+	})
+
 	t.Run("Read error returns error", func(t *testing.T) {
 		client := mocks.NewMockOpenFGAServiceClient(t)
 		client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, errors.New("read failed"))
@@ -473,3 +615,21 @@ func tuplesToOpenFGA(tuples []v1alpha1.Tuple) []*openfgav1.Tuple {
 	}
 	return out
 }
+
+func TestTupleManager_ListPage(t *testing.T) {
+	client := mocks.NewMockOpenFGAServiceClient(t)
+	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+		return req.StoreId == "store-id" && req.ContinuationToken == "page2-token" && req.GetPageSize().GetValue() == 50
+	})).Return(&openfgav1.ReadResponse{
+		Tuples:            []*openfgav1.Tuple{{Key: &openfgav1.TupleKey{Object: "doc:2", Relation: "owner", User: "user:bob"}}},
+		ContinuationToken: "page3-token",
+	}, nil)
+
+	log := testlogger.New()
+	mgr := NewTupleManager(client, "store-id", "model-id", log.Logger)
+
+	result, continuationToken, err := mgr.ListPage(context.Background(), "page2-token", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.Tuple{{Object: "doc:2", Relation: "owner", User: "user:bob"}}, result)
+	assert.Equal(t, "page3-token", continuationToken)
+}
@@ -0,0 +1,96 @@
+package fga
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+)
+
+// ValidateRewriteRules checks that every rule sets exactly one rewrite and
+// that no rename target is renamed again by another rule, which would make
+// the result depend on how often a tuple is read.
+func ValidateRewriteRules(rules []v1alpha1.TupleRewriteRule) error {
+	objectTypes := map[string]string{}
+	userTypes := map[string]string{}
+	relations := map[string]string{}
+	for i, rule := range rules {
+		set := 0
+		if r := rule.RenameObjectType; r != nil {
+			set++
+			if r.From == "" || r.To == "" {
+				return fmt.Errorf("rule %d: renameObjectType requires from and to", i)
+			}
+			objectTypes[r.From] = r.To
+		}
+		if r := rule.RenameUserType; r != nil {
+			set++
+			if r.From == "" || r.To == "" {
+				return fmt.Errorf("rule %d: renameUserType requires from and to", i)
+			}
+			userTypes[r.From] = r.To
+		}
+		if r := rule.RenameRelation; r != nil {
+			set++
+			if r.ObjectType == "" || r.From == "" || r.To == "" {
+				return fmt.Errorf("rule %d: renameRelation requires objectType, from and to", i)
+			}
+			relations[r.ObjectType+"#"+r.From] = r.ObjectType + "#" + r.To
+		}
+		if r := rule.Drop; r != nil {
+			set++
+			if r.ObjectType == "" && r.Relation == "" && r.UserType == "" {
+				return fmt.Errorf("rule %d: drop requires at least one of objectType, relation and userType", i)
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("rule %d: exactly one rewrite must be set, got %d", i, set)
+		}
+	}
+
+	for _, renames := range []map[string]string{objectTypes, userTypes, relations} {
+		for from, to := range renames {
+			if _, ok := renames[to]; ok {
+				return fmt.Errorf("%s is renamed to %s, which is renamed again", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+// RewriteTuple applies the rules in order to a tuple. It returns the
+// rewritten tuple and false if the tuple is dropped.
+func RewriteTuple(rules []v1alpha1.TupleRewriteRule, t v1alpha1.Tuple) (v1alpha1.Tuple, bool) {
+	for _, rule := range rules {
+		objectType, objectID, _ := strings.Cut(t.Object, ":")
+		userType, userRest, _ := strings.Cut(t.User, ":")
+		userID, userRelation, isUserset := strings.Cut(userRest, "#")
+
+		switch {
+		case rule.RenameObjectType != nil:
+			if objectType == rule.RenameObjectType.From {
+				t.Object = rule.RenameObjectType.To + ":" + objectID
+			}
+		case rule.RenameUserType != nil:
+			if userType == rule.RenameUserType.From {
+				t.User = rule.RenameUserType.To + ":" + userRest
+			}
+		case rule.RenameRelation != nil:
+			r := rule.RenameRelation
+			if objectType == r.ObjectType && t.Relation == r.From {
+				t.Relation = r.To
+			}
+			if isUserset && userType == r.ObjectType && userRelation == r.From {
+				t.User = userType + ":" + userID + "#" + r.To
+			}
+		case rule.Drop != nil:
+			d := rule.Drop
+			if (d.ObjectType == "" || d.ObjectType == objectType) &&
+				(d.Relation == "" || d.Relation == t.Relation) &&
+				(d.UserType == "" || d.UserType == userType) {
+				return v1alpha1.Tuple{}, false
+			}
+		}
+	}
+	return t, true
+}
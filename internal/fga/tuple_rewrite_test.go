@@ -0,0 +1,113 @@
+package fga
+
+import (
+	"testing"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteTuple(t *testing.T) {
+	rules := []v1alpha1.TupleRewriteRule{
+		{RenameObjectType: &v1alpha1.TypeRename{From: "core_namespace", To: "core_ns"}},
+		{RenameUserType: &v1alpha1.TypeRename{From: "group", To: "team"}},
+		{RenameRelation: &v1alpha1.RelationRename{ObjectType: "role", From: "assignee", To: "member"}},
+		{Drop: &v1alpha1.TupleMatch{ObjectType: "doc", Relation: "legacy"}},
+	}
+
+	tests := []struct {
+		name     string
+		tuple    v1alpha1.Tuple
+		expected v1alpha1.Tuple
+		dropped  bool
+	}{
+		{
+			name:     "renames object types",
+			tuple:    v1alpha1.Tuple{Object: "core_namespace:a/b", Relation: "parent", User: "core_platform-mesh_io_account:a/b"},
+			expected: v1alpha1.Tuple{Object: "core_ns:a/b", Relation: "parent", User: "core_platform-mesh_io_account:a/b"},
+		},
+		{
+			name:     "renames user types of wildcards and usersets",
+			tuple:    v1alpha1.Tuple{Object: "doc:1", Relation: "viewer", User: "group:*"},
+			expected: v1alpha1.Tuple{Object: "doc:1", Relation: "viewer", User: "team:*"},
+		},
+		{
+			name:     "renames relations and usersets referencing them",
+			tuple:    v1alpha1.Tuple{Object: "role:admin", Relation: "assignee", User: "role:owner#assignee"},
+			expected: v1alpha1.Tuple{Object: "role:admin", Relation: "member", User: "role:owner#member"},
+		},
+		{
+			name:     "keeps relations of other types",
+			tuple:    v1alpha1.Tuple{Object: "doc:1", Relation: "assignee", User: "user:alice"},
+			expected: v1alpha1.Tuple{Object: "doc:1", Relation: "assignee", User: "user:alice"},
+		},
+		{
+			name:    "drops matching tuples",
+			tuple:   v1alpha1.Tuple{Object: "doc:1", Relation: "legacy", User: "user:alice"},
+			dropped: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rewritten, keep := RewriteTuple(rules, test.tuple)
+			assert.Equal(t, !test.dropped, keep)
+			if keep {
+				assert.Equal(t, test.expected, rewritten)
+			}
+		})
+	}
+}
+
+func TestValidateRewriteRules(t *testing.T) {
+	tests := []struct {
+		name            string
+		rules           []v1alpha1.TupleRewriteRule
+		wantErrContains string
+	}{
+		{
+			name: "accepts independent rules",
+			rules: []v1alpha1.TupleRewriteRule{
+				{RenameObjectType: &v1alpha1.TypeRename{From: "a", To: "b"}},
+				{RenameUserType: &v1alpha1.TypeRename{From: "a", To: "b"}},
+			},
+		},
+		{
+			name:            "rejects rules without rewrite",
+			rules:           []v1alpha1.TupleRewriteRule{{}},
+			wantErrContains: "exactly one rewrite must be set",
+		},
+		{
+			name: "rejects rules with several rewrites",
+			rules: []v1alpha1.TupleRewriteRule{{
+				RenameObjectType: &v1alpha1.TypeRename{From: "a", To: "b"},
+				Drop:             &v1alpha1.TupleMatch{ObjectType: "c"},
+			}},
+			wantErrContains: "exactly one rewrite must be set",
+		},
+		{
+			name:            "rejects empty drops",
+			rules:           []v1alpha1.TupleRewriteRule{{Drop: &v1alpha1.TupleMatch{}}},
+			wantErrContains: "drop requires",
+		},
+		{
+			name: "rejects chained renames",
+			rules: []v1alpha1.TupleRewriteRule{
+				{RenameRelation: &v1alpha1.RelationRename{ObjectType: "role", From: "a", To: "b"}},
+				{RenameRelation: &v1alpha1.RelationRename{ObjectType: "role", From: "b", To: "c"}},
+			},
+			wantErrContains: "which is renamed again",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateRewriteRules(test.rules)
+			if test.wantErrContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.wantErrContains)
+		})
+	}
+}
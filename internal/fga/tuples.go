@@ -79,7 +79,7 @@ func baseTuples(in BaseTuplesInput) ([]v1alpha1.Tuple, error) {
 
 	return []v1alpha1.Tuple{
 		{
-			User:     renderCreatorUser(in.Creator),
+			User:     RenderUser(in.Creator),
 			Relation: "assignee",
 			Object:   renderOwnerRole(in.ObjectType, in.AccountOriginClusterID, in.AccountName),
 		},
@@ -101,8 +101,19 @@ func renderAccountEntity(objectType, originClusterID, name string) string {
 	return fmt.Sprintf("%s:%s/%s", objectType, originClusterID, name)
 }
 
-func renderCreatorUser(creator string) string {
-	return fmt.Sprintf("user:%s", formatUser(creator))
+// RenderResourceEntity returns the object of an API resource in the models
+// generated for it, "<objectType>:<clusterID>/[<namespace>/]<name>".
+func RenderResourceEntity(objectType, clusterID, namespace, name string) string {
+	if namespace == "" {
+		return fmt.Sprintf("%s:%s/%s", objectType, clusterID, name)
+	}
+	return fmt.Sprintf("%s:%s/%s/%s", objectType, clusterID, namespace, name)
+}
+
+// RenderUser returns the User string of a Kubernetes user name, e.g. of an
+// Account's creator or of the subject of an authorization request.
+func RenderUser(name string) string {
+	return fmt.Sprintf("user:%s", formatUser(name))
 }
 
 // RenderRolePrefix returns the prefix for role User strings that reference an
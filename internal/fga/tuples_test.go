@@ -95,3 +95,8 @@ func TestInitialTuplesForAccount_nilCreator(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "creator is empty")
 }
+
+func TestRenderResourceEntity(t *testing.T) {
+	assert.Equal(t, "core_platform-mesh_io_account:cluster-id/team", RenderResourceEntity("core_platform-mesh_io_account", "cluster-id", "", "team"))
+	assert.Equal(t, "core_pod:cluster-id/default/web", RenderResourceEntity("core_pod", "cluster-id", "default", "web"))
+}
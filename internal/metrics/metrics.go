@@ -25,7 +25,8 @@ var (
 		[]string{"controller"},
 	)
 
-	// FGAOperations counts OpenFGA tuple operations by operation (apply/delete/list) and result.
+	// FGAOperations counts OpenFGA tuple operations by operation (apply/delete/list) and result
+	// (success/error).
 	FGAOperations = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "security_operator_fga_operations_total",
@@ -33,6 +34,43 @@ var (
 		},
 		[]string{"operation", "result"},
 	)
+
+	// FGAWriteChunkSize observes the number of tuples sent per OpenFGA write request, labelled by operation (apply/delete).
+	FGAWriteChunkSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "security_operator_fga_write_chunk_size",
+			Help:    "Number of tuples per OpenFGA write request by operation.",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500},
+		},
+		[]string{"operation"},
+	)
+
+	// FGARPCDuration observes the latency of single OpenFGA RPC attempts, labelled by method and store ID.
+	FGARPCDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "security_operator_fga_rpc_duration_seconds",
+			Help:    "Duration of OpenFGA RPCs in seconds by method and store.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "store"},
+	)
+
+	// FGARPCTotal counts OpenFGA RPC attempts by method, store ID and gRPC status code.
+	FGARPCTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "security_operator_fga_rpcs_total",
+			Help: "Total number of OpenFGA RPCs by method, store and gRPC status code.",
+		},
+		[]string{"method", "store", "code"},
+	)
+
+	// FGACircuitBreakerOpen is 1 while requests to OpenFGA are rejected by the circuit breaker.
+	FGACircuitBreakerOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "security_operator_fga_circuit_breaker_open",
+			Help: "Whether the circuit breaker rejects requests to OpenFGA.",
+		},
+	)
 )
 
 func init() {
@@ -40,5 +78,9 @@ func init() {
 		ReconcileTotal,
 		ReconcileDuration,
 		FGAOperations,
+		FGAWriteChunkSize,
+		FGARPCDuration,
+		FGARPCTotal,
+		FGACircuitBreakerOpen,
 	)
 }
@@ -51,3 +51,19 @@ func (s *MetricsTestSuite) TestFGAOperations() {
 	metrics.FGAOperations.WithLabelValues("list", "success").Inc()
 	s.Require().Equal(before+1, testutil.ToFloat64(metrics.FGAOperations.WithLabelValues("list", "success")))
 }
+
+// TestFGAWriteChunkSize verifies that the FGAWriteChunkSize histogram records
+// observations per operation label.
+func (s *MetricsTestSuite) TestFGAWriteChunkSize() {
+	before := testutil.CollectAndCount(metrics.FGAWriteChunkSize)
+	metrics.FGAWriteChunkSize.WithLabelValues("apply").Observe(100)
+	s.Assert().Greater(testutil.CollectAndCount(metrics.FGAWriteChunkSize), before)
+}
+
+// TestFGARPCTotal verifies that the FGARPCTotal counter increments per
+// method, store and code.
+func (s *MetricsTestSuite) TestFGARPCTotal() {
+	before := testutil.ToFloat64(metrics.FGARPCTotal.WithLabelValues("Check", "store-id", "OK"))
+	metrics.FGARPCTotal.WithLabelValues("Check", "store-id", "OK").Inc()
+	s.Require().Equal(before+1, testutil.ToFloat64(metrics.FGARPCTotal.WithLabelValues("Check", "store-id", "OK")))
+}
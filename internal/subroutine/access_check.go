@@ -0,0 +1,246 @@
+package subroutine
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// accessCheckLimiterTTL is how long the rate limiter of a workspace is kept
+// after its last check.
+const accessCheckLimiterTTL = time.Hour
+
+// AccessCheckObject is implemented by AccessCheck and SelfAccessCheck.
+type AccessCheckObject interface {
+	client.Object
+	GetUser() string
+	GetAttributes() securityv1alpha1.AccessCheckAttributes
+	GetAccessCheckStatus() *securityv1alpha1.AccessCheckStatus
+}
+
+// accessCheckSubroutine answers AccessChecks and SelfAccessChecks from the
+// store of the organization of their workspace. Every check is made once,
+// the number of checks made for a workspace is rate limited. SelfAccessChecks
+// are only answered if their user was signed with the signing key on
+// admission.
+type accessCheckSubroutine struct {
+	fga             openfgav1.OpenFGAServiceClient
+	storeIDGetter   fga.StoreIDGetter
+	kcpClientGetter iclient.KCPClientGetter
+	entities        *fga.EntityResolver
+	signingKey      []byte
+	ttl             time.Duration
+	rateLimit       rate.Limit
+	burst           int
+	limiters        *ttlcache.Cache[string, *rate.Limiter]
+}
+
+func NewAccessCheckSubroutine(fgaClient openfgav1.OpenFGAServiceClient, storeIDGetter fga.StoreIDGetter, kcpClientGetter iclient.KCPClientGetter, entities *fga.EntityResolver, signingKey []byte, cfg config.AccessCheckConfig) *accessCheckSubroutine {
+	return &accessCheckSubroutine{
+		fga:             fgaClient,
+		storeIDGetter:   storeIDGetter,
+		kcpClientGetter: kcpClientGetter,
+		entities:        entities,
+		signingKey:      signingKey,
+		ttl:             cfg.TTL,
+		rateLimit:       rate.Limit(cfg.RateLimit),
+		burst:           max(cfg.Burst, 1),
+		limiters:        ttlcache.New(ttlcache.WithTTL[string, *rate.Limiter](accessCheckLimiterTTL)),
+	}
+}
+
+var _ subroutines.Processor = &accessCheckSubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (a *accessCheckSubroutine) GetName() string { return "AccessCheckSubroutine" }
+
+// Process implements subroutines.Processor.
+func (a *accessCheckSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	check := obj.(AccessCheckObject)
+	checkStatus := check.GetAccessCheckStatus()
+
+	if checkStatus.CompletionTime != nil {
+		return subroutines.OKWithRequeue(max(AccessCheckExpiresIn(check, a.ttl), time.Second)), nil
+	}
+	if check.GetUser() == "" {
+		return subroutines.OK(), fmt.Errorf("user is empty")
+	}
+	if selfCheck, ok := check.(*securityv1alpha1.SelfAccessCheck); ok && !VerifySelfAccessCheck(a.signingKey, selfCheck) {
+		now := metav1.Now()
+		checkStatus.CompletionTime = &now
+		return subroutines.StopWithRequeue(a.ttl, "the user was not signed on admission"), nil
+	}
+
+	clusterName, ok := mccontext.ClusterFrom(ctx)
+	if !ok {
+		return subroutines.OK(), fmt.Errorf("failed to get cluster from context")
+	}
+
+	reservation := a.limiter(string(clusterName)).Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return subroutines.StopWithRequeue(delay, "access checks of the workspace are rate limited"), nil
+	}
+
+	cl, err := a.kcpClientGetter.NewClientForLogicalCluster(ctx, string(clusterName))
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting client for cluster %s: %w", clusterName, err)
+	}
+	var accountInfo accountsv1alpha1.AccountInfo
+	if err := cl.Get(ctx, client.ObjectKey{Name: "account"}, &accountInfo); err != nil {
+		return subroutines.OK(), fmt.Errorf("getting AccountInfo: %w", err)
+	}
+	org := accountInfo.Spec.Organization.Name
+	if org == "" {
+		return subroutines.OK(), fmt.Errorf("organization name is empty in AccountInfo")
+	}
+	storeID, err := a.storeIDGetter.Get(ctx, org)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting store ID for org %s: %w", org, err)
+	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, a.storeIDGetter, org)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting authorization model for org %s: %w", org, err)
+	}
+
+	attributes := check.GetAttributes()
+	object, err := a.entities.Resolve(ctx, attributes.Object)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("resolving object %s: %w", attributes.Object, err)
+	}
+	if !objectInWorkspace(object, a.entities.ObjectType(), accountInfo.Spec.Account) {
+		now := metav1.Now()
+		checkStatus.CompletionTime = &now
+		return subroutines.StopWithRequeue(a.ttl, fmt.Sprintf("object %s is outside of the workspace of the check", attributes.Object)), nil
+	}
+
+	res, err := a.fga.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     fga.RenderUser(check.GetUser()),
+			Relation: attributes.Relation,
+			Object:   object,
+		},
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		// Checks OpenFGA rejects, e.g. for a relation that is not in the
+		// model, are not made again.
+		now := metav1.Now()
+		checkStatus.CompletionTime = &now
+		return subroutines.StopWithRequeue(a.ttl, fmt.Sprintf("invalid check: %s", status.Convert(err).Message())), nil
+	}
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("checking %s of %s: %w", attributes.Relation, object, err)
+	}
+
+	var expansion *runtime.RawExtension
+	if attributes.Expand {
+		expandRes, err := a.fga.Expand(ctx, &openfgav1.ExpandRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			TupleKey: &openfgav1.ExpandRequestTupleKey{
+				Relation: attributes.Relation,
+				Object:   object,
+			},
+		})
+		if err != nil {
+			return subroutines.OK(), fmt.Errorf("expanding %s of %s: %w", attributes.Relation, object, err)
+		}
+		raw, err := protojson.Marshal(expandRes.GetTree())
+		if err != nil {
+			return subroutines.OK(), fmt.Errorf("encoding expansion: %w", err)
+		}
+		expansion = &runtime.RawExtension{Raw: raw}
+	}
+
+	now := metav1.Now()
+	checkStatus.Allowed = res.GetAllowed()
+	checkStatus.Object = object
+	checkStatus.Expansion = expansion
+	checkStatus.CompletionTime = &now
+	log.Debug().Str("object", object).Str("relation", attributes.Relation).Bool("allowed", checkStatus.Allowed).Msg("Answered access check")
+
+	return subroutines.OKWithRequeue(a.ttl), nil
+}
+
+// objectInWorkspace returns whether an OpenFGA object is the account of a
+// workspace, one of its roles, or lives in the workspace, which includes the
+// child accounts of the workspace.
+func objectInWorkspace(object, accountType string, account accountsv1alpha1.AccountLocation) bool {
+	_, id, ok := strings.Cut(object, ":")
+	if !ok {
+		return false
+	}
+	// Roles are "role:<accountType>/<clusterID>/<account>/<role>".
+	id = strings.TrimPrefix(id, accountType+"/")
+
+	self := account.OriginClusterId + "/" + account.Name
+	if account.OriginClusterId != "" && (id == self || strings.HasPrefix(id, self+"/")) {
+		return true
+	}
+	return account.GeneratedClusterId != "" && strings.HasPrefix(id, account.GeneratedClusterId+"/")
+}
+
+// SignSelfAccessCheck returns the signature of the user and attributes of a
+// SelfAccessCheck.
+func SignSelfAccessCheck(key []byte, check *securityv1alpha1.SelfAccessCheck) string {
+	mac := hmac.New(sha256.New, key)
+	for _, field := range []string{check.Spec.User, check.Spec.Relation, check.Spec.Object, strconv.FormatBool(check.Spec.Expand)} {
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySelfAccessCheck returns whether a SelfAccessCheck carries a valid
+// signature of its user. Without a signing key nothing is valid.
+func VerifySelfAccessCheck(key []byte, check *securityv1alpha1.SelfAccessCheck) bool {
+	signature, ok := check.GetAnnotations()[securityv1alpha1.SelfAccessCheckSignatureAnnotation]
+	if len(key) == 0 || !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignSelfAccessCheck(key, check)))
+}
+
+func (a *accessCheckSubroutine) limiter(clusterName string) *rate.Limiter {
+	item, _ := a.limiters.GetOrSetFunc(clusterName, func() *rate.Limiter {
+		return rate.NewLimiter(a.rateLimit, a.burst)
+	})
+	return item.Value()
+}
+
+// AccessCheckExpiresIn returns how long a completed check is kept before it
+// is deleted.
+func AccessCheckExpiresIn(check AccessCheckObject, ttl time.Duration) time.Duration {
+	completed := check.GetAccessCheckStatus().CompletionTime
+	if completed == nil {
+		return ttl
+	}
+	return time.Until(completed.Add(ttl))
+}
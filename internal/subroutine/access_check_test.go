@@ -0,0 +1,253 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/platform-mesh/subroutines"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+var accessCheckConfig = config.AccessCheckConfig{TTL: time.Minute, RateLimit: 1, Burst: 2}
+
+func newAccessCheckSubroutine(t *testing.T, fgaClient *mocks.MockOpenFGAServiceClient, cfg config.AccessCheckConfig) subroutines.Processor {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(accountsv1alpha1.AddToScheme(scheme))
+	workspaceClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&accountsv1alpha1.AccountInfo{
+		ObjectMeta: metav1.ObjectMeta{Name: "account"},
+		Spec: accountsv1alpha1.AccountInfoSpec{
+			Account:      accountsv1alpha1.AccountLocation{Name: "team", OriginClusterId: "acme-id", GeneratedClusterId: "team-id"},
+			Organization: accountsv1alpha1.AccountLocation{Name: "acme"},
+		},
+	}).Build()
+
+	clients := mocks.NewMockKCPClientGetter(t)
+	clients.EXPECT().NewClientForLogicalCluster(mock.Anything, "team-id").Return(workspaceClient, nil).Maybe()
+
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme").Return("store-id", nil).Maybe()
+
+	entities := fga.NewEntityResolver("core_platform-mesh_io_account", func(_ context.Context, path logicalcluster.Path) (string, error) {
+		require.Equal(t, "root:orgs:acme", path.String())
+		return "acme-id", nil
+	})
+
+	return subroutine.NewAccessCheckSubroutine(fgaClient, storeIDGetter, clients, entities, signingKey, cfg)
+}
+
+var signingKey = []byte("signing-key")
+
+func selfAccessCheck(signed bool) *securityv1alpha1.SelfAccessCheck {
+	check := &securityv1alpha1.SelfAccessCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "check"},
+		Spec: securityv1alpha1.SelfAccessCheckSpec{
+			User:                  "alice@acme.corp",
+			AccessCheckAttributes: accessCheck(false).Spec.AccessCheckAttributes,
+		},
+	}
+	if signed {
+		check.Annotations = map[string]string{
+			securityv1alpha1.SelfAccessCheckSignatureAnnotation: subroutine.SignSelfAccessCheck(signingKey, check),
+		}
+	}
+	return check
+}
+
+func accessCheck(expand bool) *securityv1alpha1.AccessCheck {
+	return &securityv1alpha1.AccessCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "check"},
+		Spec: securityv1alpha1.AccessCheckSpec{
+			User: "alice@acme.corp",
+			AccessCheckAttributes: securityv1alpha1.AccessCheckAttributes{
+				Relation: "member",
+				Object:   "account:root:orgs:acme:team",
+				Expand:   expand,
+			},
+		},
+	}
+}
+
+func expectAccessCheck(fgaClient *mocks.MockOpenFGAServiceClient, allowed bool) {
+	fgaClient.EXPECT().Check(mock.Anything, &openfgav1.CheckRequest{
+		StoreId: "store-id",
+		TupleKey: &openfgav1.CheckRequestTupleKey{
+			User:     "user:alice@acme.corp",
+			Relation: "member",
+			Object:   "core_platform-mesh_io_account:acme-id/team",
+		},
+	}).Return(&openfgav1.CheckResponse{Allowed: allowed}, nil).Once()
+}
+
+func TestAccessCheckProcess(t *testing.T) {
+	ctx := mccontext.WithCluster(context.Background(), "team-id")
+
+	t.Run("should report the decision", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		expectAccessCheck(fgaClient, true)
+		s := newAccessCheckSubroutine(t, fgaClient, accessCheckConfig)
+
+		check := accessCheck(false)
+		result, err := s.Process(ctx, check)
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, result.Requeue())
+		assert.True(t, check.Status.Allowed)
+		assert.Equal(t, "core_platform-mesh_io_account:acme-id/team", check.Status.Object)
+		assert.Nil(t, check.Status.Expansion)
+		assert.NotNil(t, check.Status.CompletionTime)
+
+		// Completed checks are not made again.
+		_, err = s.Process(ctx, check)
+		require.NoError(t, err)
+	})
+
+	t.Run("should report the expansion", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		expectAccessCheck(fgaClient, false)
+		fgaClient.EXPECT().Expand(mock.Anything, &openfgav1.ExpandRequest{
+			StoreId: "store-id",
+			TupleKey: &openfgav1.ExpandRequestTupleKey{
+				Relation: "member",
+				Object:   "core_platform-mesh_io_account:acme-id/team",
+			},
+		}).Return(&openfgav1.ExpandResponse{Tree: &openfgav1.UsersetTree{
+			Root: &openfgav1.UsersetTree_Node{Name: "core_platform-mesh_io_account:acme-id/team#member"},
+		}}, nil).Once()
+		s := newAccessCheckSubroutine(t, fgaClient, accessCheckConfig)
+
+		check := accessCheck(true)
+		_, err := s.Process(ctx, check)
+		require.NoError(t, err)
+		assert.False(t, check.Status.Allowed)
+		require.NotNil(t, check.Status.Expansion)
+		assert.Contains(t, string(check.Status.Expansion.Raw), "acme-id/team#member")
+	})
+
+	t.Run("should complete checks rejected by OpenFGA", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		fgaClient.EXPECT().Check(mock.Anything, mock.Anything).
+			Return(nil, status.Error(codes.InvalidArgument, "relation 'member' not found")).Once()
+		s := newAccessCheckSubroutine(t, fgaClient, accessCheckConfig)
+
+		check := accessCheck(false)
+		result, err := s.Process(ctx, check)
+		require.NoError(t, err)
+		assert.True(t, result.IsStopWithRequeue())
+		assert.Contains(t, result.Message(), "relation 'member' not found")
+		assert.NotNil(t, check.Status.CompletionTime)
+	})
+
+	t.Run("should rate limit checks of a workspace", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Times(2)
+		s := newAccessCheckSubroutine(t, fgaClient, accessCheckConfig)
+
+		for range 2 {
+			_, err := s.Process(ctx, accessCheck(false))
+			require.NoError(t, err)
+		}
+
+		check := accessCheck(false)
+		result, err := s.Process(ctx, check)
+		require.NoError(t, err)
+		assert.True(t, result.IsStopWithRequeue())
+		assert.Positive(t, result.Requeue())
+		assert.Nil(t, check.Status.CompletionTime)
+	})
+
+	t.Run("should answer signed SelfAccessChecks", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		expectAccessCheck(fgaClient, true)
+		s := newAccessCheckSubroutine(t, fgaClient, accessCheckConfig)
+
+		check := selfAccessCheck(true)
+		_, err := s.Process(ctx, check)
+		require.NoError(t, err)
+		assert.True(t, check.Status.Allowed)
+	})
+
+	t.Run("should not answer SelfAccessChecks with a forged user", func(t *testing.T) {
+		s := newAccessCheckSubroutine(t, mocks.NewMockOpenFGAServiceClient(t), accessCheckConfig)
+
+		for _, check := range []*securityv1alpha1.SelfAccessCheck{
+			selfAccessCheck(false),
+			func() *securityv1alpha1.SelfAccessCheck {
+				check := selfAccessCheck(true)
+				check.Spec.User = "bob@acme.corp"
+				return check
+			}(),
+		} {
+			result, err := s.Process(ctx, check)
+			require.NoError(t, err)
+			assert.True(t, result.IsStopWithRequeue())
+			assert.Contains(t, result.Message(), "not signed")
+			assert.False(t, check.Status.Allowed)
+			assert.NotNil(t, check.Status.CompletionTime)
+		}
+	})
+
+	t.Run("should not check objects outside of the workspace", func(t *testing.T) {
+		fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+		fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Times(3)
+		s := newAccessCheckSubroutine(t, fgaClient, config.AccessCheckConfig{TTL: time.Minute, RateLimit: 1, Burst: 10})
+
+		for object, inWorkspace := range map[string]bool{
+			"core_platform-mesh_io_account:acme-id/team":             true,
+			"core_platform-mesh_io_account:team-id/child":            true,
+			"core_namespace:team-id/default":                         true,
+			"core_platform-mesh_io_account:acme-id/other":            false,
+			"core_platform-mesh_io_account:acme-id/team-other":       false,
+			"core_namespace:other-id/default":                        false,
+			"role:core_platform-mesh_io_account/acme-id/other/owner": false,
+		} {
+			check := accessCheck(false)
+			check.Spec.Object = object
+			result, err := s.Process(ctx, check)
+			require.NoError(t, err, object)
+			if inWorkspace {
+				assert.True(t, check.Status.Allowed, object)
+				continue
+			}
+			assert.True(t, result.IsStopWithRequeue(), object)
+			assert.Contains(t, result.Message(), "outside of the workspace", object)
+			assert.False(t, check.Status.Allowed, object)
+		}
+	})
+
+	t.Run("should fail without a user", func(t *testing.T) {
+		s := newAccessCheckSubroutine(t, mocks.NewMockOpenFGAServiceClient(t), accessCheckConfig)
+
+		check := &securityv1alpha1.SelfAccessCheck{
+			Spec: securityv1alpha1.SelfAccessCheckSpec{AccessCheckAttributes: accessCheck(false).Spec.AccessCheckAttributes},
+		}
+		_, err := s.Process(ctx, check)
+		assert.ErrorContains(t, err, "user is empty")
+	})
+}
+
+func TestAccessCheckExpiresIn(t *testing.T) {
+	check := accessCheck(false)
+	assert.Equal(t, time.Minute, subroutine.AccessCheckExpiresIn(check, time.Minute))
+
+	check.Status.CompletionTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+	assert.Negative(t, subroutine.AccessCheckExpiresIn(check, time.Minute))
+}
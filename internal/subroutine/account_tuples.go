@@ -29,6 +29,7 @@ type AccountTuplesSubroutine struct {
 	objectType      string
 	parentRelation  string
 	creatorRelation string
+	writeChunkSize  int
 	kcpClientGetter iclient.KCPClientGetter
 }
 
@@ -54,6 +55,10 @@ func (s *AccountTuplesSubroutine) reconcile(ctx context.Context, obj client.Obje
 	if err != nil {
 		return subroutines.OK(), fmt.Errorf("getting store ID: %w", err)
 	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, s.storeIDGetter, accountPath.Org().Base())
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting authorization model: %w", err)
+	}
 
 	// Determine the parent's and grandParent's LogicalCluster ID
 	parentPath, _ := accountPath.Parent()
@@ -94,11 +99,23 @@ func (s *AccountTuplesSubroutine) reconcile(ctx context.Context, obj client.Obje
 	if err != nil {
 		return subroutines.OK(), fmt.Errorf("building tuples for account: %w", err)
 	}
-	if err := fga.NewTupleManager(s.fga, storeID, fga.AuthorizationModelIDLatest, logger.LoadLoggerFromContext(ctx)).Apply(ctx, tuples); err != nil {
+
+	// Only tuples missing in the store, or stored with a different condition,
+	// are written.
+	tm := s.tupleManager(ctx, storeID, modelID)
+	stored, err := tm.ReadTuples(ctx, tuples)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("reading tuples for Account: %w", err)
+	}
+	diff := fga.DiffTuples(stored, tuples)
+	if err := tm.Delete(ctx, diff.Remove); err != nil {
+		return subroutines.OK(), fmt.Errorf("deleting outdated tuples for Account: %w", err)
+	}
+	if err := tm.Apply(ctx, diff.Add); err != nil {
 		return subroutines.OK(), fmt.Errorf("applying tuples for Account: %w", err)
 	}
 
-	return subroutines.OK(), nil
+	return subroutines.OK(), recordTupleChanges(ctx, s.mgr, lc, len(diff.Add), len(diff.Remove))
 }
 
 // Terminate implements subroutines.Terminator.
@@ -121,9 +138,13 @@ func (s *AccountTuplesSubroutine) Terminate(ctx context.Context, obj client.Obje
 	if err != nil {
 		return subroutines.OK(), fmt.Errorf("getting store ID: %w", err)
 	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, s.storeIDGetter, accountPath.Org().Base())
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting authorization model: %w", err)
+	}
 
 	// List tuples that reference the account.
-	tm := fga.NewTupleManager(s.fga, storeID, fga.AuthorizationModelIDLatest, logger.LoadLoggerFromContext(ctx))
+	tm := s.tupleManager(ctx, storeID, modelID)
 	accountReferenceTuples, err := tm.ListWithKey(ctx, fga.ReferencingAccountTupleKey(s.objectType, parentClusterID, accountPath.Base()))
 	if err != nil {
 		return subroutines.OK(), fmt.Errorf("listing tuples referencing Account: %w", err)
@@ -155,7 +176,7 @@ func (s *AccountTuplesSubroutine) Terminate(ctx context.Context, obj client.Obje
 // GetName implements subroutines.Subroutine.
 func (s *AccountTuplesSubroutine) GetName() string { return "AccountTuplesSubroutine" }
 
-func NewAccountTuplesSubroutine(mgr mcmanager.Manager, fga openfgav1.OpenFGAServiceClient, storeIDGetter fga.StoreIDGetter, creatorRelation, parentRelation, objectType string, kcpHelper iclient.KCPClientGetter) *AccountTuplesSubroutine {
+func NewAccountTuplesSubroutine(mgr mcmanager.Manager, fga openfgav1.OpenFGAServiceClient, storeIDGetter fga.StoreIDGetter, creatorRelation, parentRelation, objectType string, writeChunkSize int, kcpHelper iclient.KCPClientGetter) *AccountTuplesSubroutine {
 	return &AccountTuplesSubroutine{
 		mgr:             mgr,
 		fga:             fga,
@@ -163,6 +184,7 @@ func NewAccountTuplesSubroutine(mgr mcmanager.Manager, fga openfgav1.OpenFGAServ
 		creatorRelation: creatorRelation,
 		parentRelation:  parentRelation,
 		objectType:      objectType,
+		writeChunkSize:  writeChunkSize,
 		kcpClientGetter: kcpHelper,
 	}
 }
@@ -173,6 +195,14 @@ var (
 	_ subroutines.Terminator  = &AccountTuplesSubroutine{}
 )
 
+// tupleManager returns a TupleManager for the given store and model that
+// writes in chunks, as accounts with many roles and assignees easily exceed
+// the write limit of OpenFGA. Writes are idempotent, so failed chunks are
+// retried with the next reconciliation.
+func (s *AccountTuplesSubroutine) tupleManager(ctx context.Context, storeID, modelID string) *fga.TupleManager {
+	return fga.NewTupleManager(s.fga, storeID, modelID, logger.LoadLoggerFromContext(ctx), fga.WithWriteChunkSize(s.writeChunkSize))
+}
+
 // clusterAndIDFromLogicalClusterForPath retrieves the LogicalCluster of a given
 // path and returns its cluster ID and the LogicalCluster object.
 func (s *AccountTuplesSubroutine) clusterAndIDFromLogicalClusterForPath(ctx context.Context, p logicalcluster.Path) (string, kcpcorev1alpha1.LogicalCluster, error) {
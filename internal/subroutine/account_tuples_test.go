@@ -2,6 +2,7 @@ package subroutine_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -48,7 +50,7 @@ func mockParentLogicalCluster(kcpHelper *mocks.MockKCPClientGetter, parentClient
 }
 
 func TestAccountTuplesSubroutine_GetName(t *testing.T) {
-	sub := subroutine.NewAccountTuplesSubroutine(nil, nil, nil, "creator", "parent", "type", nil)
+	sub := subroutine.NewAccountTuplesSubroutine(nil, nil, nil, "creator", "parent", "type", 0, nil)
 	assert.Equal(t, "AccountTuplesSubroutine", sub.GetName())
 }
 
@@ -101,10 +103,48 @@ func TestAccountTuplesSubroutine_Process(t *testing.T) {
 		}
 		return true
 	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+	storeHoldsNoTuples(fgaClient)
 
-	sub := subroutine.NewAccountTuplesSubroutine(nil, fgaClient, storeIDGetter, "creator", "parent", "account", kcpHelper)
+	manager := mocks.NewMockManager(t)
+	recorder := recordsTupleChanges(t, manager)
+
+	sub := subroutine.NewAccountTuplesSubroutine(manager, fgaClient, storeIDGetter, "creator", "parent", "account", 0, kcpHelper)
 	_, err = sub.Process(context.Background(), newAccountLogicalCluster())
 	assert.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, fmt.Sprintf("Normal TuplesChanged Added %d and removed 0 tuples", len(expectedTuples)), <-recorder.Events)
+}
+
+func TestAccountTuplesSubroutine_ProcessSkipsStoredTuples(t *testing.T) {
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	kcpHelper := mocks.NewMockKCPClientGetter(t)
+	parentClient := mocks.NewMockClient(t)
+	fgaClient := mocks.NewMockOpenFGAServiceClient(t)
+
+	storeIDGetter.EXPECT().Get(mock.Anything, "myorg").Return("store-id", nil)
+	mockParentLogicalCluster(kcpHelper, parentClient)
+	kcpHelper.EXPECT().NewClientForLogicalCluster(mock.Anything, mock.Anything).Return(parentClient, nil).Once()
+	creator := "user@example.com"
+	parentClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "myaccount"}, mock.Anything).
+		RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
+			if acc, ok := o.(*accountsv1alpha1.Account); ok {
+				acc.Spec.Creator = &creator
+			}
+			return nil
+		}).Once()
+
+	// Every tuple is already in the store, so nothing is written.
+	fgaClient.EXPECT().Read(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.ReadRequest, _ ...grpc.CallOption) (*openfgav1.ReadResponse, error) {
+		return &openfgav1.ReadResponse{Tuples: []*openfgav1.Tuple{{Key: &openfgav1.TupleKey{
+			Object:   req.TupleKey.Object,
+			Relation: req.TupleKey.Relation,
+			User:     req.TupleKey.User,
+		}}}}, nil
+	})
+
+	sub := subroutine.NewAccountTuplesSubroutine(mocks.NewMockManager(t), fgaClient, storeIDGetter, "creator", "parent", "account", 0, kcpHelper)
+	_, err := sub.Process(context.Background(), newAccountLogicalCluster())
+	assert.NoError(t, err)
 }
 
 func TestAccountTuplesSubroutine_Initialize(t *testing.T) {
@@ -238,8 +278,11 @@ func TestAccountTuplesSubroutine_Initialize(t *testing.T) {
 			fgaClient := mocks.NewMockOpenFGAServiceClient(t)
 
 			test.mockSetup(storeIDGetter, kcpHelper, parentClient, fgaClient)
+			storeHoldsNoTuples(fgaClient)
+			manager := mocks.NewMockManager(t)
+			recordsTupleChanges(t, manager)
 
-			sub := subroutine.NewAccountTuplesSubroutine(nil, fgaClient, storeIDGetter, "creator", "parent", "account", kcpHelper)
+			sub := subroutine.NewAccountTuplesSubroutine(manager, fgaClient, storeIDGetter, "creator", "parent", "account", 0, kcpHelper)
 			_, err := sub.Initialize(context.Background(), test.obj)
 			if test.expectError {
 				assert.Error(t, err)
@@ -368,7 +411,7 @@ func TestAccountTuplesSubroutine_Terminate(t *testing.T) {
 
 			test.mockSetup(storeIDGetter, kcpHelper, parentClient, fgaClient)
 
-			sub := subroutine.NewAccountTuplesSubroutine(nil, fgaClient, storeIDGetter, "creator", "parent", "account", kcpHelper)
+			sub := subroutine.NewAccountTuplesSubroutine(nil, fgaClient, storeIDGetter, "creator", "parent", "account", 0, kcpHelper)
 			_, err := sub.Terminate(context.Background(), test.obj)
 			if test.expectError {
 				assert.Error(t, err)
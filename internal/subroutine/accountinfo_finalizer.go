@@ -6,6 +6,7 @@ import (
 
 	accountv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
 	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/subroutines"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -63,5 +64,5 @@ func (a *AccountInfoFinalizerSubroutine) Finalize(ctx context.Context, obj clien
 	}
 
 	log.Info().Msg("No APIBindings with finalizer found, allowing AccountInfo deletion")
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), nil
 }
@@ -3,6 +3,7 @@ package subroutine
 import (
 	"context"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
@@ -12,9 +13,11 @@ import (
 	corev1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/subroutines"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 
 	kcpcorev1alpha1 "github.com/kcp-dev/sdk/apis/core/v1alpha1"
 )
@@ -27,15 +30,17 @@ const (
 
 type APIExportPolicySubroutine struct {
 	fga             openfgav1.OpenFGAServiceClient
+	mgr             mcmanager.Manager
 	cfg             *config.Config
 	storeIDGetter   fga.StoreIDGetter
 	lister          iclient.Lister
 	kcpClientGetter iclient.KCPClientGetter
 }
 
-func NewAPIExportPolicySubroutine(fgaClient openfgav1.OpenFGAServiceClient, cfg *config.Config, storeIDGetter fga.StoreIDGetter, lister iclient.Lister, kcpClientGetter iclient.KCPClientGetter) *APIExportPolicySubroutine {
+func NewAPIExportPolicySubroutine(fgaClient openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, cfg *config.Config, storeIDGetter fga.StoreIDGetter, lister iclient.Lister, kcpClientGetter iclient.KCPClientGetter) *APIExportPolicySubroutine {
 	return &APIExportPolicySubroutine{
 		fga:             fgaClient,
+		mgr:             mgr,
 		cfg:             cfg,
 		storeIDGetter:   storeIDGetter,
 		lister:          lister,
@@ -62,77 +67,57 @@ func (a *APIExportPolicySubroutine) Process(ctx context.Context, obj client.Obje
 		return subroutines.OK(), fmt.Errorf("getting provider cluster ID for %s: %w", policy.Spec.APIExportRef.ClusterPath, err)
 	}
 
-	// Delete tuples for expressions that were removed from the spec
-	if err := a.deleteRemovedExpressions(ctx, policy); err != nil {
+	// Tuples of expressions that were removed from the spec are deleted
+	// unless a remaining expression still requires them.
+	removedTuples, err := a.tuplesForRemovedExpressions(ctx, policy, providerClusterID)
+	if err != nil {
 		return subroutines.OK(), fmt.Errorf("removing tuples for policy %s: %w", policy.Name, err)
 	}
 
+	desiredTuples := map[policyStore][]corev1alpha1.Tuple{}
 	for _, expression := range policy.Spec.AllowPathExpressions {
-		workspacePath, relation, err := a.parseAllowExpression(expression)
+		// for orgs workspace we need to write 1 tuple in every org's store
+		tuples, err := a.tuplesForExpression(ctx, expression, providerClusterID, policy.Spec.APIExportRef.Name, true)
 		if err != nil {
-			return subroutines.OK(), fmt.Errorf("parsing allow expression %s: %w", expression, err)
-		}
-
-		// for orgs workspace we need to write 1 tuple in every store
-		// for this we need to get cluster id for every org's workspace
-		if workspacePath == orgsWorkspacePath {
-			var accountInfoList accountsv1alpha1.AccountInfoList
-			if err := a.lister.List(ctx, &accountInfoList); err != nil {
-				return subroutines.OK(), fmt.Errorf("listing AccountInfo resources: %w", err)
-			}
-
-			for _, ai := range accountInfoList.Items {
-				if ai.Spec.Account.Type != accountsv1alpha1.AccountTypeOrg {
-					continue
-				}
-
-				storeID, err := a.storeIDGetter.Get(ctx, ai.Spec.Organization.Name)
-				if err != nil {
-					return subroutines.OK(), fmt.Errorf("getting store ID for org %s: %w", ai.Spec.Organization.Name, err)
-				}
-
-				tuple := corev1alpha1.Tuple{
-					Object:   fmt.Sprintf("core_platform-mesh_io_account:%s/%s", ai.Spec.Account.OriginClusterId, ai.Spec.Account.Name),
-					Relation: relation,
-					User:     fmt.Sprintf("apis_kcp_io_apiexport:%s/%s", providerClusterID, policy.Spec.APIExportRef.Name),
-				}
-
-				tm := fga.NewTupleManager(a.fga, storeID, fga.AuthorizationModelIDLatest, log)
-				if err := tm.Apply(ctx, []corev1alpha1.Tuple{tuple}); err != nil {
-					return subroutines.OK(), fmt.Errorf("applying tuple for expression %s: %w", expression, err)
-				}
-			}
-			continue
+			return subroutines.OK(), fmt.Errorf("building tuples for expression %s: %w", expression, err)
 		}
-
-		// for all valid expressions except of :root:orgs:*
-		// e.g :root:orgs:A:B, find store id
-		// and clusterID of logical cluster where account B lives (logical cluster A)
-		cl, err := a.kcpClientGetter.NewClientForLogicalCluster(ctx, string(config.MultiProviderName(config.CoreProviderName, workspacePath)))
-		if err != nil {
-			return subroutines.OK(), fmt.Errorf("getting client for workspace %s: %w", workspacePath, err)
+		for store, storeTuples := range tuples {
+			desiredTuples[store] = append(desiredTuples[store], storeTuples...)
 		}
+	}
 
-		var ai accountsv1alpha1.AccountInfo
-		if err := cl.Get(ctx, client.ObjectKey{Name: "account"}, &ai); err != nil {
-			return subroutines.OK(), fmt.Errorf("getting AccountInfo for workspace %s: %w", workspacePath, err)
+	// Only tuples missing in or removed from a store are written, based on
+	// what the store currently holds.
+	var added, removed int
+	stores := slices.Collect(maps.Keys(desiredTuples))
+	for store := range removedTuples {
+		if _, ok := desiredTuples[store]; !ok {
+			stores = append(stores, store)
 		}
-
-		storeID, err := a.storeIDGetter.Get(ctx, ai.Spec.Organization.Name)
+	}
+	slices.SortFunc(stores, comparePolicyStores)
+	for _, store := range stores {
+		tm := a.tupleManager(store, log)
+		stored, err := tm.ReadTuples(ctx, append(slices.Clone(desiredTuples[store]), removedTuples[store]...))
 		if err != nil {
-			return subroutines.OK(), fmt.Errorf("getting store ID for org %s: %w", ai.Spec.Organization.Name, err)
+			return subroutines.OK(), fmt.Errorf("reading tuples of store %s: %w", store.id, err)
 		}
 
-		tuple := corev1alpha1.Tuple{
-			Object:   fmt.Sprintf("core_platform-mesh_io_account:%s/%s", ai.Spec.Account.OriginClusterId, ai.Spec.Account.Name),
-			Relation: relation,
-			User:     fmt.Sprintf("apis_kcp_io_apiexport:%s/%s", providerClusterID, policy.Spec.APIExportRef.Name),
+		diff := fga.DiffTuples(stored, desiredTuples[store])
+		if err := tm.Delete(ctx, diff.Remove); err != nil {
+			return subroutines.OK(), fmt.Errorf("removing tuples for policy %s: %w", policy.Name, err)
 		}
-
-		tm := fga.NewTupleManager(a.fga, storeID, fga.AuthorizationModelIDLatest, log)
-		if err := tm.Apply(ctx, []corev1alpha1.Tuple{tuple}); err != nil {
-			return subroutines.OK(), fmt.Errorf("applying tuple for expression %s: %w", expression, err)
+		if err := tm.Apply(ctx, diff.Add); err != nil {
+			return subroutines.OK(), fmt.Errorf("applying tuples for policy %s: %w", policy.Name, err)
 		}
+		added, removed = added+len(diff.Add), removed+len(diff.Remove)
+	}
+
+	// The managed expressions are only updated once their tuples were
+	// written, so removed expressions are deleted again outside of dry-run
+	// mode.
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
 	}
 
 	cl, err := a.kcpClientGetter.NewClientFromContext(ctx)
@@ -143,6 +128,9 @@ func (a *APIExportPolicySubroutine) Process(ctx context.Context, obj client.Obje
 	// Update status with managed expressions
 	original := policy.DeepCopy()
 	policy.Status.ManagedAllowExpressions = policy.Spec.AllowPathExpressions
+	if err := recordTupleChanges(ctx, a.mgr, policy, added, removed); err != nil {
+		return subroutines.OK(), err
+	}
 
 	if err := cl.Status().Patch(ctx, policy, client.MergeFrom(original)); err != nil {
 		return subroutines.OK(), fmt.Errorf("failed to patch APIExportPolicy status: %w", err)
@@ -171,7 +159,7 @@ func (a *APIExportPolicySubroutine) Finalize(ctx context.Context, obj client.Obj
 	}
 
 	log.Info().Msg("Finalized APIExportPolicy")
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), nil
 }
 
 func (a *APIExportPolicySubroutine) getClusterIDFromPath(ctx context.Context, clusterPath string) (string, error) {
@@ -209,26 +197,24 @@ func (a *APIExportPolicySubroutine) parseAllowExpression(expr string) (workspace
 	return expr, bindRelation, nil
 }
 
-// finds expressions which are present in the status but aren't in the spec
-// and do the cleanup of the tupels for removed expressions
-func (a *APIExportPolicySubroutine) deleteRemovedExpressions(ctx context.Context, policy *corev1alpha1.APIExportPolicy) error {
-	providerClusterID, err := a.getClusterIDFromPath(ctx, policy.Spec.APIExportRef.ClusterPath)
-	if err != nil {
-		return fmt.Errorf("getting provider cluster ID for %s: %w", policy.Spec.APIExportRef.ClusterPath, err)
-	}
-
+// tuplesForRemovedExpressions returns the tuples, per store, of the
+// expressions which are present in the status but aren't in the spec anymore.
+func (a *APIExportPolicySubroutine) tuplesForRemovedExpressions(ctx context.Context, policy *corev1alpha1.APIExportPolicy, providerClusterID string) (map[policyStore][]corev1alpha1.Tuple, error) {
+	result := map[policyStore][]corev1alpha1.Tuple{}
 	for _, managedExpr := range policy.Status.ManagedAllowExpressions {
-		exists := slices.Contains(policy.Spec.AllowPathExpressions, managedExpr)
-		if exists {
+		if slices.Contains(policy.Spec.AllowPathExpressions, managedExpr) {
 			continue
 		}
 
-		err := a.deleteTuplesForExpression(ctx, managedExpr, providerClusterID, policy.Spec.APIExportRef.Name)
+		tuples, err := a.tuplesForExpression(ctx, managedExpr, providerClusterID, policy.Spec.APIExportRef.Name, false)
 		if err != nil {
-			return fmt.Errorf("removing tuples for expression %s: %w", managedExpr, err)
+			return nil, fmt.Errorf("removing tuples for expression %s: %w", managedExpr, err)
+		}
+		for store, storeTuples := range tuples {
+			result[store] = append(result[store], storeTuples...)
 		}
 	}
-	return nil
+	return result, nil
 }
 
 // based on the expression and apiexport data
@@ -236,62 +222,108 @@ func (a *APIExportPolicySubroutine) deleteRemovedExpressions(ctx context.Context
 func (a *APIExportPolicySubroutine) deleteTuplesForExpression(ctx context.Context, expression string, providerClusterID string, apiExportName string) error {
 	log := logger.LoadLoggerFromContext(ctx)
 
+	tuples, err := a.tuplesForExpression(ctx, expression, providerClusterID, apiExportName, false)
+	if err != nil {
+		return err
+	}
+
+	for _, store := range slices.SortedFunc(maps.Keys(tuples), comparePolicyStores) {
+		tm := a.tupleManager(store, log)
+		if err := tm.Delete(ctx, tuples[store]); err != nil {
+			return fmt.Errorf("removing tuples in openFGA: %w", err)
+		}
+	}
+	return nil
+}
+
+// tuplesForExpression returns the tuples an expression grants the APIExport,
+// per store. For the orgs workspace this is one tuple in the store of every
+// org. Unless orgAccountsOnly is set, AccountInfos of other account types are
+// considered as well, so that no tuple is left behind on deletion.
+func (a *APIExportPolicySubroutine) tuplesForExpression(ctx context.Context, expression string, providerClusterID string, apiExportName string, orgAccountsOnly bool) (map[policyStore][]corev1alpha1.Tuple, error) {
 	workspacePath, relation, err := a.parseAllowExpression(expression)
 	if err != nil {
-		return fmt.Errorf("parsing expression %s: %w", expression, err)
+		return nil, fmt.Errorf("parsing expression %s: %w", expression, err)
+	}
+
+	tupleFor := func(ai accountsv1alpha1.AccountInfo) corev1alpha1.Tuple {
+		return corev1alpha1.Tuple{
+			Object:   fmt.Sprintf("core_platform-mesh_io_account:%s/%s", ai.Spec.Account.OriginClusterId, ai.Spec.Account.Name),
+			Relation: relation,
+			User:     fmt.Sprintf("apis_kcp_io_apiexport:%s/%s", providerClusterID, apiExportName),
+		}
 	}
 
+	tuples := map[policyStore][]corev1alpha1.Tuple{}
 	if workspacePath == orgsWorkspacePath {
 		var accountInfoList accountsv1alpha1.AccountInfoList
 		if err := a.lister.List(ctx, &accountInfoList); err != nil {
-			return fmt.Errorf("listing AccountInfo resources for %s: %w", expression, err)
+			return nil, fmt.Errorf("listing AccountInfo resources for %s: %w", expression, err)
 		}
 
 		for _, ai := range accountInfoList.Items {
-			storeID, err := a.storeIDGetter.Get(ctx, ai.Spec.Organization.Name)
-			if err != nil {
-				return fmt.Errorf("getting store ID for org %s: %w", ai.Spec.Organization.Name, err)
-			}
-
-			tupleToDelete := corev1alpha1.Tuple{
-				Object:   fmt.Sprintf("core_platform-mesh_io_account:%s/%s", ai.Spec.Account.OriginClusterId, ai.Spec.Account.Name),
-				Relation: relation,
-				User:     fmt.Sprintf("apis_kcp_io_apiexport:%s/%s", providerClusterID, apiExportName),
+			if orgAccountsOnly && ai.Spec.Account.Type != accountsv1alpha1.AccountTypeOrg {
+				continue
 			}
 
-			tm := fga.NewTupleManager(a.fga, storeID, fga.AuthorizationModelIDLatest, log)
-			if err := tm.Delete(ctx, []corev1alpha1.Tuple{tupleToDelete}); err != nil {
-				return fmt.Errorf("removing tuple in openFGA: %w", err)
+			store, err := a.store(ctx, ai.Spec.Organization.Name)
+			if err != nil {
+				return nil, err
 			}
+			tuples[store] = append(tuples[store], tupleFor(ai))
 		}
-		return nil
+		return tuples, nil
 	}
 
+	// for all valid expressions except of :root:orgs:*
+	// e.g :root:orgs:A:B, find store id
+	// and clusterID of logical cluster where account B lives (logical cluster A)
 	cl, err := a.kcpClientGetter.NewClientForLogicalCluster(ctx, string(config.MultiProviderName(config.CoreProviderName, workspacePath)))
 	if err != nil {
-		return fmt.Errorf("getting client for workspace %s: %w", workspacePath, err)
+		return nil, fmt.Errorf("getting client for workspace %s: %w", workspacePath, err)
 	}
 
 	var ai accountsv1alpha1.AccountInfo
 	if err := cl.Get(ctx, client.ObjectKey{Name: "account"}, &ai); err != nil {
-		return fmt.Errorf("getting AccountInfo for workspace %s: %w", workspacePath, err)
+		return nil, fmt.Errorf("getting AccountInfo for workspace %s: %w", workspacePath, err)
 	}
 
-	storeID, err := a.storeIDGetter.Get(ctx, ai.Spec.Organization.Name)
+	store, err := a.store(ctx, ai.Spec.Organization.Name)
 	if err != nil {
-		return fmt.Errorf("getting store ID for org %s: %w", ai.Spec.Organization.Name, err)
+		return nil, err
 	}
+	tuples[store] = []corev1alpha1.Tuple{tupleFor(ai)}
+	return tuples, nil
+}
 
-	tupleToDelete := corev1alpha1.Tuple{
-		Object:   fmt.Sprintf("core_platform-mesh_io_account:%s/%s", ai.Spec.Account.OriginClusterId, ai.Spec.Account.Name),
-		Relation: relation,
-		User:     fmt.Sprintf("apis_kcp_io_apiexport:%s/%s", providerClusterID, apiExportName),
-	}
+// policyStore is the store of an org and the authorization model tuples are
+// written with.
+type policyStore struct {
+	id      string
+	modelID string
+}
 
-	tm := fga.NewTupleManager(a.fga, storeID, fga.AuthorizationModelIDLatest, log)
-	if err := tm.Delete(ctx, []corev1alpha1.Tuple{tupleToDelete}); err != nil {
-		return fmt.Errorf("removing tuples: %w", err)
-	}
+func comparePolicyStores(a, b policyStore) int {
+	return strings.Compare(a.id, b.id)
+}
 
-	return nil
+// tupleManager returns a TupleManager for the given store that writes in
+// chunks of the configured size, as policies allowing many workspaces exceed
+// the write limit of OpenFGA. The stored tuples are read again with every
+// reconciliation, so failed chunks are retried.
+func (a *APIExportPolicySubroutine) tupleManager(store policyStore, log *logger.Logger) *fga.TupleManager {
+	return fga.NewTupleManager(a.fga, store.id, store.modelID, log, fga.WithWriteChunkSize(a.cfg.FGA.WriteChunkSize))
+}
+
+// store returns the store of an org.
+func (a *APIExportPolicySubroutine) store(ctx context.Context, org string) (policyStore, error) {
+	storeID, err := a.storeIDGetter.Get(ctx, org)
+	if err != nil {
+		return policyStore{}, fmt.Errorf("getting store ID for org %s: %w", org, err)
+	}
+	modelID, err := fga.GetAuthorizationModelID(ctx, a.storeIDGetter, org)
+	if err != nil {
+		return policyStore{}, fmt.Errorf("getting authorization model for org %s: %w", org, err)
+	}
+	return policyStore{id: storeID, modelID: modelID}, nil
 }
@@ -14,6 +14,8 @@ import (
 	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -35,12 +37,12 @@ func getAPIExportPolicyTestScheme() *runtime.Scheme {
 }
 
 func TestAPIExportPolicySubroutine_GetName(t *testing.T) {
-	sub := subroutine.NewAPIExportPolicySubroutine(nil, nil, nil, nil, nil)
+	sub := subroutine.NewAPIExportPolicySubroutine(nil, nil, nil, nil, nil, nil)
 	assert.Equal(t, "APIExportPolicySubroutine", sub.GetName())
 }
 
 func TestAPIExportPolicySubroutine_Finalizers(t *testing.T) {
-	sub := subroutine.NewAPIExportPolicySubroutine(nil, nil, nil, nil, nil)
+	sub := subroutine.NewAPIExportPolicySubroutine(nil, nil, nil, nil, nil, nil)
 	assert.Equal(t, []string{"system.platform-mesh.io/apiexportpolicy-finalizer"}, sub.Finalizers(nil))
 }
 
@@ -129,11 +131,14 @@ func TestAPIExportPolicySubroutine_Process(t *testing.T) {
 			if tt.setupMocks != nil {
 				tt.setupMocks(t, fga, storeIDGetter, kcpClientGetter, lister)
 			}
+			storeHoldsNoTuples(fga)
+			manager := mocks.NewMockManager(t)
+			recordsTupleChanges(t, manager)
 
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga, tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, manager, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Process(ctx, tt.policy)
 
@@ -213,7 +218,7 @@ func TestAPIExportPolicySubroutine_Finalize(t *testing.T) {
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga, tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, nil, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Finalize(ctx, tt.policy)
 
@@ -489,11 +494,14 @@ func TestAPIExportPolicySubroutine_Process_Success(t *testing.T) {
 			if tt.setupMocks != nil {
 				tt.setupMocks(t, fga, storeIDGetter, kcpClientGetter, lister)
 			}
+			storeHoldsNoTuples(fga)
+			manager := mocks.NewMockManager(t)
+			recordsTupleChanges(t, manager)
 
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga, tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, manager, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Process(ctx, tt.policy)
 
@@ -735,7 +743,7 @@ func TestAPIExportPolicySubroutine_Finalize_Success(t *testing.T) {
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga,tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, nil, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Finalize(ctx, tt.policy)
 
@@ -748,6 +756,123 @@ func TestAPIExportPolicySubroutine_Finalize_Success(t *testing.T) {
 	}
 }
 
+func TestAPIExportPolicySubroutine_Process_WritesOnlyChanges(t *testing.T) {
+	scheme := getAPIExportPolicyTestScheme()
+	policy := &corev1alpha1.APIExportPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+		Spec: corev1alpha1.APIExportPolicySpec{
+			APIExportRef:         corev1alpha1.APIExportRef{Name: "my-export", ClusterPath: "root:providers:my-provider"},
+			AllowPathExpressions: []string{"root:orgs:acme"},
+		},
+		Status: corev1alpha1.APIExportPolicyStatus{
+			ManagedAllowExpressions: []string{"root:orgs:acme", "root:orgs:acme:*"},
+		},
+	}
+
+	kcpClientGetter := mocks.NewMockKCPClientGetter(t)
+	kcpClientGetter.EXPECT().NewClientForLogicalCluster(mock.Anything, string(config.MultiProviderName(config.CoreProviderName, "root:providers:my-provider"))).Return(newProviderClient(scheme), nil)
+	kcpClientGetter.EXPECT().NewClientForLogicalCluster(mock.Anything, string(config.MultiProviderName(config.CoreProviderName, "root:orgs:acme"))).Return(fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&accountsv1alpha1.AccountInfo{
+			ObjectMeta: metav1.ObjectMeta{Name: "account"},
+			Spec: accountsv1alpha1.AccountInfoSpec{
+				Account:      accountsv1alpha1.AccountLocation{Name: "acme-account", OriginClusterId: "acme-cluster-id", Type: accountsv1alpha1.AccountTypeOrg},
+				Organization: accountsv1alpha1.AccountLocation{Name: "acme-org"},
+			},
+		}).
+		Build(), nil)
+	kcpClientGetter.EXPECT().NewClientFromContext(mock.Anything).Return(fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1alpha1.APIExportPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-policy"}}).
+		WithStatusSubresource(&corev1alpha1.APIExportPolicy{}).
+		Build(), nil)
+
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme-org").Return("test-store-id", nil)
+
+	// Both the tuple of the remaining and of the removed expression are in
+	// the store, so only the latter is deleted and nothing is written.
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Read(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.ReadRequest, _ ...grpc.CallOption) (*openfgav1.ReadResponse, error) {
+		return &openfgav1.ReadResponse{Tuples: []*openfgav1.Tuple{{Key: &openfgav1.TupleKey{
+			Object:   req.TupleKey.Object,
+			Relation: req.TupleKey.Relation,
+			User:     req.TupleKey.User,
+		}}}}, nil
+	}).Times(2)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.StoreId == "test-store-id" && req.Writes == nil &&
+			len(req.Deletes.GetTupleKeys()) == 1 && req.Deletes.GetTupleKeys()[0].Relation == "bind_inherited"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	manager := mocks.NewMockManager(t)
+	recorder := recordsTupleChanges(t, manager)
+
+	ctx := testlogger.New().WithContext(context.Background())
+	sub := subroutine.NewAPIExportPolicySubroutine(fga, manager, &config.Config{}, storeIDGetter, mocks.NewMockLister(t), kcpClientGetter)
+
+	_, err := sub.Process(ctx, policy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root:orgs:acme"}, policy.Status.ManagedAllowExpressions)
+	require.NotNil(t, policy.Status.LastTupleChange)
+	assert.Equal(t, 0, policy.Status.LastTupleChange.Added)
+	assert.Equal(t, 1, policy.Status.LastTupleChange.Removed)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal TuplesChanged Added 0 and removed 1 tuples", <-recorder.Events)
+}
+
+func TestAPIExportPolicySubroutine_Process_WritesInConfiguredChunks(t *testing.T) {
+	scheme := getAPIExportPolicyTestScheme()
+	policy := &corev1alpha1.APIExportPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-policy"},
+		Spec: corev1alpha1.APIExportPolicySpec{
+			APIExportRef:         corev1alpha1.APIExportRef{Name: "my-export", ClusterPath: "root:providers:my-provider"},
+			AllowPathExpressions: []string{"root:orgs:acme", "root:orgs:acme:*"},
+		},
+	}
+
+	kcpClientGetter := mocks.NewMockKCPClientGetter(t)
+	kcpClientGetter.EXPECT().NewClientForLogicalCluster(mock.Anything, string(config.MultiProviderName(config.CoreProviderName, "root:providers:my-provider"))).Return(newProviderClient(scheme), nil)
+	kcpClientGetter.EXPECT().NewClientForLogicalCluster(mock.Anything, string(config.MultiProviderName(config.CoreProviderName, "root:orgs:acme"))).Return(fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&accountsv1alpha1.AccountInfo{
+			ObjectMeta: metav1.ObjectMeta{Name: "account"},
+			Spec: accountsv1alpha1.AccountInfoSpec{
+				Account:      accountsv1alpha1.AccountLocation{Name: "acme-account", OriginClusterId: "acme-cluster-id", Type: accountsv1alpha1.AccountTypeOrg},
+				Organization: accountsv1alpha1.AccountLocation{Name: "acme-org"},
+			},
+		}).
+		Build(), nil)
+	kcpClientGetter.EXPECT().NewClientFromContext(mock.Anything).Return(fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1alpha1.APIExportPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-policy"}}).
+		WithStatusSubresource(&corev1alpha1.APIExportPolicy{}).
+		Build(), nil)
+
+	storeIDGetter := mocks.NewMockStoreIDGetter(t)
+	storeIDGetter.EXPECT().Get(mock.Anything, "acme-org").Return("test-store-id", nil)
+
+	// Each of the two tuples is written in a request of its own.
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	storeHoldsNoTuples(fga)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.StoreId == "test-store-id" && len(req.Writes.GetTupleKeys()) == 1
+	})).Return(&openfgav1.WriteResponse{}, nil).Times(2)
+
+	manager := mocks.NewMockManager(t)
+	recordsTupleChanges(t, manager)
+
+	cfg := &config.Config{}
+	cfg.FGA.WriteChunkSize = 1
+	ctx := testlogger.New().WithContext(context.Background())
+	sub := subroutine.NewAPIExportPolicySubroutine(fga, manager, cfg, storeIDGetter, mocks.NewMockLister(t), kcpClientGetter)
+
+	_, err := sub.Process(ctx, policy)
+	require.NoError(t, err)
+	require.NotNil(t, policy.Status.LastTupleChange)
+	assert.Equal(t, 2, policy.Status.LastTupleChange.Added)
+}
+
 func newProviderClient(scheme *runtime.Scheme) client.Client {
 	return fake.NewClientBuilder().
 		WithScheme(scheme).
@@ -1077,11 +1202,14 @@ func TestAPIExportPolicySubroutine_Process_AdditionalErrorPaths(t *testing.T) {
 			if tt.setupMocks != nil {
 				tt.setupMocks(t, fga, storeIDGetter, kcpClientGetter, lister)
 			}
+			storeHoldsNoTuples(fga)
+			manager := mocks.NewMockManager(t)
+			recordsTupleChanges(t, manager)
 
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga, tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, manager, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Process(ctx, tt.policy)
 
@@ -1281,7 +1409,7 @@ func TestAPIExportPolicySubroutine_Finalize_AdditionalErrorPaths(t *testing.T) {
 			l := testlogger.New()
 			ctx := l.WithContext(context.Background())
 
-			sub := subroutine.NewAPIExportPolicySubroutine(fga, tt.cfg, storeIDGetter, lister, kcpClientGetter)
+			sub := subroutine.NewAPIExportPolicySubroutine(fga, nil, tt.cfg, storeIDGetter, lister, kcpClientGetter)
 
 			_, err := sub.Finalize(ctx, tt.policy)
 
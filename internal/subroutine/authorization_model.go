@@ -5,14 +5,18 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	language "github.com/openfga/language/pkg/go/transformer"
 	"github.com/platform-mesh/golang-commons/logger"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/security-operator/internal/util"
 	"github.com/platform-mesh/subroutines"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -21,14 +25,26 @@ import (
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+
+	"github.com/kcp-dev/logicalcluster/v3"
 )
 
 const (
 	schemaVersion = "1.2"
+
+	// modelTestsRetryInterval is the interval failing model tests are
+	// evaluated again at, as discovered modules may change without an event.
+	modelTestsRetryInterval      = 5 * time.Minute
+	maxReportedModelTestFailures = 10
+
+	// maxAuthorizationModelHistory is the number of written authorization
+	// models kept in the status of a Store.
+	maxAuthorizationModelHistory = 10
 )
 
 var (
@@ -73,7 +89,13 @@ type {{ .Group }}_{{ .Singular }}
 `))
 )
 
-type NewDiscoveryClientFunc func(cfg *rest.Config) discovery.DiscoveryInterface
+type NewDiscoveryClientFunc func(cfg *rest.Config) (discovery.DiscoveryInterface, error)
+
+// NewDiscoveryClient is the NewDiscoveryClientFunc of the reconcilers and
+// webhooks. It returns an error for invalid configs instead of panicking.
+func NewDiscoveryClient(cfg *rest.Config) (discovery.DiscoveryInterface, error) {
+	return discovery.NewDiscoveryClientForConfig(cfg)
+}
 
 type authorizationModelSubroutine struct {
 	fga                    openfgav1.OpenFGAServiceClient
@@ -127,47 +149,10 @@ func (a *authorizationModelSubroutine) Process(ctx context.Context, obj client.O
 		return subroutines.OK(), err
 	}
 
-	moduleFiles := []language.ModuleFile{{
-		Name:     fmt.Sprintf("%s.fga", client.ObjectKeyFromObject(store)),
-		Contents: store.Spec.CoreModule,
-	}}
-	for _, module := range extendingModules.Items {
-		moduleFiles = append(moduleFiles, language.ModuleFile{
-			Name:     fmt.Sprintf("%s.fga", client.ObjectKeyFromObject(&module)),
-			Contents: module.Spec.Model,
-		})
-	}
-
-	if store.Name != "orgs" {
-		cfg := rest.CopyConfig(a.mgr.GetLocalManager().GetConfig())
-
-		parsed, err := url.Parse(cfg.Host)
-		if err != nil {
-			log.Error().Err(err).Msg("unable to parse host from config")
-			return subroutines.OK(), err
-		}
-
-		parsed.Path, err = url.JoinPath("clusters", fmt.Sprintf("root:orgs:%s", store.Name))
-		if err != nil {
-			log.Error().Err(err).Msg("unable to join path")
-			return subroutines.OK(), err
-		}
-
-		cfg.Host = parsed.String()
-
-		discoveryClient := a.newDiscoveryClientFunc(cfg)
-
-		coreModules, err := discoverAndRender(discoveryClient, modelTpl, groupVersions)
-		if err != nil {
-			return subroutines.OK(), err
-		}
-		moduleFiles = append(moduleFiles, coreModules...)
-
-		privilegedModules, err := discoverAndRender(discoveryClient, privilegedTemplate, privilegedGroupVersions)
-		if err != nil {
-			return subroutines.OK(), err
-		}
-		moduleFiles = append(moduleFiles, privilegedModules...)
+	moduleFiles, err := BuildStoreModuleFiles(a.mgr.GetLocalManager().GetConfig(), a.newDiscoveryClientFunc, store, extendingModules.Items)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to build module files")
+		return subroutines.OK(), err
 	}
 
 	authorizationModel, err := language.TransformModuleFilesToModel(moduleFiles, schemaVersion)
@@ -176,10 +161,24 @@ func (a *authorizationModelSubroutine) Process(ctx context.Context, obj client.O
 		return subroutines.OK(), err
 	}
 
-	if store.Status.AuthorizationModelID != "" {
+	// Stores written before the history was kept start it with their
+	// current model.
+	if store.Status.AuthorizationModelID != "" && len(store.Status.AuthorizationModelHistory) == 0 {
+		store.Status.AuthorizationModelHistory = []securityv1alpha1.AuthorizationModelRevision{{
+			ID:        store.Status.AuthorizationModelID,
+			CreatedAt: metav1.Now(),
+		}}
+	}
+
+	if err := a.applyPinnedModel(ctx, store); err != nil {
+		log.Error().Err(err).Msg("unable to apply pinned authorization model")
+		return subroutines.OK(), err
+	}
+
+	if latestModelID := latestAuthorizationModelID(store); latestModelID != "" {
 		res, err := a.fga.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
 			StoreId: store.Status.StoreID,
-			Id:      store.Status.AuthorizationModelID,
+			Id:      latestModelID,
 		})
 		if err != nil {
 			log.Error().Err(err).Msg("unable to read authorization model")
@@ -206,6 +205,24 @@ func (a *authorizationModelSubroutine) Process(ctx context.Context, obj client.O
 
 	}
 
+	if pinned := store.Spec.PinnedAuthorizationModelID; pinned != "" {
+		// Readers check against the model in use by the Store, but writing
+		// would still change the latest model of the OpenFGA store, so changes
+		// are held back until the pin is removed.
+		meta.SetStatusCondition(&store.Status.Conditions, metav1.Condition{
+			Type:               securityv1alpha1.AuthorizationModelPinnedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "WriteHeldBack",
+			Message:            fmt.Sprintf("Using pinned authorization model %s, the changed modules are written once the pin is removed", pinned),
+			ObservedGeneration: store.Generation,
+		})
+		return subroutines.OK(), nil
+	}
+
+	if result, err := a.runModelTests(ctx, store, extendingModules, authorizationModel); err != nil || !result.IsContinue() {
+		return result, err
+	}
+
 	res, err := a.fga.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
 		StoreId:         store.Status.StoreID,
 		TypeDefinitions: authorizationModel.TypeDefinitions,
@@ -216,9 +233,197 @@ func (a *authorizationModelSubroutine) Process(ctx context.Context, obj client.O
 		log.Error().Err(err).Msg("unable to write authorization model")
 		return subroutines.OK(), err
 	}
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
+	}
+
+	recordAuthorizationModel(store, extendingModules, res.AuthorizationModelId)
+	setAuthorizationModelInUse(store)
+
+	return subroutines.OK(), nil
+}
+
+// latestAuthorizationModelID returns the ID of the authorization model that
+// was written last to the store.
+func latestAuthorizationModelID(store *securityv1alpha1.Store) string {
+	history := store.Status.AuthorizationModelHistory
+	if len(history) == 0 {
+		return store.Status.AuthorizationModelID
+	}
+	return history[len(history)-1].ID
+}
+
+// recordAuthorizationModel appends a written authorization model to the
+// history of the store. The oldest models are dropped once the history is
+// full.
+func recordAuthorizationModel(store *securityv1alpha1.Store, extendingModules securityv1alpha1.AuthorizationModelList, id string) {
+	revision := securityv1alpha1.AuthorizationModelRevision{
+		ID:              id,
+		CreatedAt:       metav1.Now(),
+		StoreGeneration: store.Generation,
+	}
+	for _, module := range extendingModules.Items {
+		revision.Modules = append(revision.Modules, securityv1alpha1.AuthorizationModelGeneration{
+			Cluster:    logicalcluster.From(&module).String(),
+			Name:       module.Name,
+			Generation: module.Generation,
+		})
+	}
+
+	history := append(store.Status.AuthorizationModelHistory, revision)
+	if len(history) > maxAuthorizationModelHistory {
+		history = history[len(history)-maxAuthorizationModelHistory:]
+	}
+	store.Status.AuthorizationModelHistory = history
+}
+
+// applyPinnedModel makes sure a pinned authorization model exists in the
+// store before it is used for tuples.
+func (a *authorizationModelSubroutine) applyPinnedModel(ctx context.Context, store *securityv1alpha1.Store) error {
+	pinned := store.Spec.PinnedAuthorizationModelID
+	if pinned != "" && !slices.ContainsFunc(store.Status.AuthorizationModelHistory, func(r securityv1alpha1.AuthorizationModelRevision) bool {
+		return r.ID == pinned
+	}) {
+		_, err := a.fga.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+			StoreId: store.Status.StoreID,
+			Id:      pinned,
+		})
+		if err != nil {
+			meta.SetStatusCondition(&store.Status.Conditions, metav1.Condition{
+				Type:               securityv1alpha1.AuthorizationModelPinnedCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             "NotFound",
+				Message:            fmt.Sprintf("Pinned authorization model %s cannot be read: %v", pinned, err),
+				ObservedGeneration: store.Generation,
+			})
+			return fmt.Errorf("reading pinned authorization model %s: %w", pinned, err)
+		}
+	}
+
+	setAuthorizationModelInUse(store)
+	return nil
+}
+
+// setAuthorizationModelInUse records the authorization model tuples are
+// written and checked with, which is the pinned model if one is set and the
+// latest model otherwise.
+func setAuthorizationModelInUse(store *securityv1alpha1.Store) {
+	pinned := store.Spec.PinnedAuthorizationModelID
+	if pinned == "" {
+		store.Status.AuthorizationModelID = latestAuthorizationModelID(store)
+		meta.RemoveStatusCondition(&store.Status.Conditions, securityv1alpha1.AuthorizationModelPinnedCondition)
+		return
+	}
+
+	store.Status.AuthorizationModelID = pinned
+	meta.SetStatusCondition(&store.Status.Conditions, metav1.Condition{
+		Type:               securityv1alpha1.AuthorizationModelPinnedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Pinned",
+		Message:            fmt.Sprintf("Using pinned authorization model %s, the latest model is %s", pinned, latestAuthorizationModelID(store)),
+		ObservedGeneration: store.Generation,
+	})
+}
+
+// BuildStoreModuleFiles returns the module files the authorization model of a
+// Store is built from: its core module, the modules of the AuthorizationModels
+// extending it and, for organization stores, the modules rendered for the
+// resources discovered in the organization workspace.
+func BuildStoreModuleFiles(baseCfg *rest.Config, newDiscoveryClientFunc NewDiscoveryClientFunc, store *securityv1alpha1.Store, extendingModules []securityv1alpha1.AuthorizationModel) ([]language.ModuleFile, error) {
+	moduleFiles := []language.ModuleFile{{
+		Name:     fmt.Sprintf("%s.fga", client.ObjectKeyFromObject(store)),
+		Contents: store.Spec.CoreModule,
+	}}
+	for _, module := range extendingModules {
+		moduleFiles = append(moduleFiles, language.ModuleFile{
+			Name:     fmt.Sprintf("%s.fga", client.ObjectKeyFromObject(&module)),
+			Contents: module.Spec.Model,
+		})
+	}
+
+	if store.Name == "orgs" {
+		return moduleFiles, nil
+	}
+
+	cfg := rest.CopyConfig(baseCfg)
+
+	parsed, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse host from config: %w", err)
+	}
+
+	parsed.Path, err = url.JoinPath("clusters", fmt.Sprintf("root:orgs:%s", store.Name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to join path: %w", err)
+	}
+
+	cfg.Host = parsed.String()
+
+	discoveryClient, err := newDiscoveryClientFunc(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery client: %w", err)
+	}
+
+	coreModules, err := discoverAndRender(discoveryClient, modelTpl, groupVersions)
+	if err != nil {
+		return nil, err
+	}
+	moduleFiles = append(moduleFiles, coreModules...)
+
+	privilegedModules, err := discoverAndRender(discoveryClient, privilegedTemplate, privilegedGroupVersions)
+	if err != nil {
+		return nil, err
+	}
+	return append(moduleFiles, privilegedModules...), nil
+}
+
+// runModelTests evaluates the tests of the store and its extending modules
+// against the model that is about to be written. The current model is kept
+// while any of them fails.
+func (a *authorizationModelSubroutine) runModelTests(ctx context.Context, store *securityv1alpha1.Store, extendingModules securityv1alpha1.AuthorizationModelList, model *openfgav1.AuthorizationModel) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	tests := slices.Clone(store.Spec.Tests)
+	for _, module := range extendingModules.Items {
+		for _, test := range module.Spec.Tests {
+			test.Name = fmt.Sprintf("%s/%s", module.Name, test.Name)
+			tests = append(tests, test)
+		}
+	}
+	if len(tests) == 0 {
+		meta.RemoveStatusCondition(&store.Status.Conditions, securityv1alpha1.ModelTestsCondition)
+		return subroutines.OK(), nil
+	}
+
+	failures, err := fga.RunModelTests(ctx, a.fga, model, tests, string(store.UID), log)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to run model tests")
+		return subroutines.OK(), err
+	}
 
-	store.Status.AuthorizationModelID = res.AuthorizationModelId
+	if len(failures) > 0 {
+		message := strings.Join(failures, "; ")
+		if len(failures) > maxReportedModelTestFailures {
+			message = fmt.Sprintf("%s; and %d more", strings.Join(failures[:maxReportedModelTestFailures], "; "), len(failures)-maxReportedModelTestFailures)
+		}
+		meta.SetStatusCondition(&store.Status.Conditions, metav1.Condition{
+			Type:               securityv1alpha1.ModelTestsCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             "AssertionsFailed",
+			Message:            message,
+			ObservedGeneration: store.Generation,
+		})
+		log.Warn().Strs("failures", failures).Msg("Model tests failed, keeping the current authorization model")
+		return subroutines.Pending(modelTestsRetryInterval, "Model tests failed, keeping the current authorization model"), nil
+	}
 
+	meta.SetStatusCondition(&store.Status.Conditions, metav1.Condition{
+		Type:               securityv1alpha1.ModelTestsCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Passed",
+		Message:            fmt.Sprintf("All %d model tests passed", len(tests)),
+		ObservedGeneration: store.Generation,
+	})
 	return subroutines.OK(), nil
 }
 
@@ -229,18 +434,8 @@ func processAPIResourceIntoModel(resource metav1.APIResource, tpl *template.Temp
 		scope = apiextensionsv1.NamespaceScoped
 	}
 
-	group := "core"
-	if resource.Group != "" {
-		group = util.CapGroupToRelationLength(schema.GroupVersionResource{Group: resource.Group, Resource: resource.Name}, 50)
-	}
-
 	var buffer bytes.Buffer
-	err := tpl.Execute(&buffer, modelInput{
-		Name:     resource.Name,
-		Group:    strings.ReplaceAll(group, ".", "_"), // TODO: group name length capping
-		Singular: resource.SingularName,
-		Scope:    string(scope),
-	})
+	err := tpl.Execute(&buffer, newModelInput(resource.Name, util.ModelGroup(resource.Group, resource.Name), resource.SingularName, string(scope)))
 	if err != nil {
 		return buffer, err
 	}
@@ -3,15 +3,18 @@ package subroutine
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
 	"text/template"
 
+	language "github.com/openfga/language/pkg/go/transformer"
 	accountv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
 	"github.com/platform-mesh/golang-commons/logger"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/subroutines"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -21,6 +24,7 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/kcp-dev/logicalcluster/v3"
@@ -58,33 +62,26 @@ type AuthorizationModelGenerationSubroutine struct {
 
 var modelTpl = template.Must(template.New("model").Parse(`module {{ .Name }}
 
-{{ if eq .Scope "Cluster" }}
-extend type core_platform-mesh_io_account
+extend type {{ .Parent }}
 	relations
-		define create_{{ .Group }}_{{ .Name }}: owner
-		define list_{{ .Group }}_{{ .Name }}: member
-		define watch_{{ .Group }}_{{ .Name }}: member
-{{ end }}
-
-{{ if eq .Scope "Namespaced" }}
-extend type core_namespace
-	relations
-		define create_{{ .Group }}_{{ .Name }}: owner
-		define list_{{ .Group }}_{{ .Name }}: member
-		define watch_{{ .Group }}_{{ .Name }}: member
-{{ end }}
+		define create_{{ .Group }}_{{ .Name }}: {{ index .Relations "create" }}
+		define list_{{ .Group }}_{{ .Name }}: {{ index .Relations "list" }}
+		define watch_{{ .Group }}_{{ .Name }}: {{ index .Relations "watch" }}
 
 type {{ .Group }}_{{ .Singular }}
 	relations
-		define parent: [{{ if eq .Scope "Namespaced" }}core_namespace{{ else }}core_platform-mesh_io_account{{ end }}]
+		define parent: [{{ .Parent }}]
 		define member: [role#assignee] or owner or member from parent
 		define owner: [role#assignee] or owner from parent
 		
-		define get: member
-		define update: member
-		define delete: member
-		define patch: member
-		define watch: member
+		define get: {{ index .Relations "get" }}
+		define update: {{ index .Relations "update" }}
+		define delete: {{ index .Relations "delete" }}
+		define patch: {{ index .Relations "patch" }}
+		define watch: {{ index .Relations "watch" }}
+{{- range .CustomVerbs }}
+		define {{ .Name }}: {{ .Relation }}
+{{- end }}
 
 		define manage_iam_roles: owner
 		define get_iam_roles: member
@@ -97,6 +94,142 @@ type modelInput struct {
 	Group    string
 	Singular string
 	Scope    string
+	// Parent is the type the resources are created in.
+	Parent string
+	// Relations maps the Kubernetes verbs to the relations granting them.
+	Relations map[string]string
+	// CustomVerbs are granted in addition to the Kubernetes verbs.
+	CustomVerbs []customVerb
+	// Schema is the APIResourceSchema of the resource. It is not set for
+	// discovered resources.
+	Schema *kcpapisv1alpha1.APIResourceSchema
+}
+
+// ParseModelTemplate parses the template of a ModelTemplate.
+func ParseModelTemplate(modelTemplate *securityv1alpha1.ModelTemplate) (*template.Template, error) {
+	return template.New(modelTemplate.Name).Option("missingkey=error").Parse(modelTemplate.Spec.Template)
+}
+
+// RenderModelTemplate renders the module of the resource of an
+// APIResourceSchema and checks that it only defines what a model template may.
+// Only the default parent types are accepted in its permission profile.
+func RenderModelTemplate(tpl *template.Template, resourceSchema *kcpapisv1alpha1.APIResourceSchema) (string, error) {
+	input, err := newSchemaModelInput(resourceSchema, defaultParentTypes)
+	if err != nil {
+		return "", err
+	}
+	module, err := executeModelTemplate(tpl, input)
+	if err != nil {
+		return "", err
+	}
+	return module, checkTemplateModule(input, module)
+}
+
+// checkTemplateModule checks that a rendered module only defines the type of
+// its resource and only extends the parent type with the relations granting
+// create, list and watch on the resource, so a template cannot change the
+// permissions on any other type of the org's model.
+func checkTemplateModule(input modelInput, module string) error {
+	model, extensions, err := language.TransformModularDSLToProto(module)
+	if err != nil {
+		return fmt.Errorf("invalid module: %w", err)
+	}
+
+	resourceType := fmt.Sprintf("%s_%s", input.Group, input.Singular)
+	parentRelations := []string{
+		fmt.Sprintf("create_%s_%s", input.Group, input.Name),
+		fmt.Sprintf("list_%s_%s", input.Group, input.Name),
+		fmt.Sprintf("watch_%s_%s", input.Group, input.Name),
+	}
+
+	var definesResourceType bool
+	for _, typeDef := range model.GetTypeDefinitions() {
+		if extensions[typeDef.GetType()] == typeDef {
+			if typeDef.GetType() != input.Parent {
+				return fmt.Errorf("module extends type %s, only the parent type %s may be extended", typeDef.GetType(), input.Parent)
+			}
+			for relation := range typeDef.GetRelations() {
+				if !slices.Contains(parentRelations, relation) {
+					return fmt.Errorf("module defines relation %s on the parent type %s, only %s may be defined", relation, input.Parent, strings.Join(parentRelations, ", "))
+				}
+			}
+			continue
+		}
+		if typeDef.GetType() != resourceType || definesResourceType {
+			return fmt.Errorf("module defines type %s, only %s may be defined once", typeDef.GetType(), resourceType)
+		}
+		definesResourceType = true
+	}
+	if !definesResourceType {
+		return fmt.Errorf("module does not define type %s", resourceType)
+	}
+	return nil
+}
+
+// newSchemaModelInput returns the input of the model template of the
+// resource of an APIResourceSchema with its permission profile applied.
+func newSchemaModelInput(resourceSchema *kcpapisv1alpha1.APIResourceSchema, parentTypes map[string][]string) (modelInput, error) {
+	longestRelationName := fmt.Sprintf("create_%s_%s", resourceSchema.Spec.Group, resourceSchema.Spec.Names.Plural)
+
+	group := resourceSchema.Spec.Group
+
+	if len(longestRelationName) > 50 {
+		group = resourceSchema.Spec.Group[len(longestRelationName)-50:]
+	}
+
+	input := newModelInput(resourceSchema.Spec.Names.Plural, strings.ReplaceAll(group, ".", "_"), resourceSchema.Spec.Names.Singular, string(resourceSchema.Spec.Scope))
+	input.Schema = resourceSchema
+	if err := applyPermissionProfile(&input, resourceSchema.Annotations, parentTypes); err != nil {
+		return modelInput{}, fmt.Errorf("invalid permission profile: %w", err)
+	}
+	return input, nil
+}
+
+func executeModelTemplate(tpl *template.Template, input modelInput) (string, error) {
+	var buffer bytes.Buffer
+	if err := tpl.Execute(&buffer, input); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// selectModelTemplate returns the template of the ModelTemplate selecting the
+// APIExport in its workspace, or the built-in template if there is none.
+func selectModelTemplate(ctx context.Context, cl client.Client, apiExport *kcpapisv1alpha2.APIExport) (*template.Template, error) {
+	var modelTemplates securityv1alpha1.ModelTemplateList
+	if err := cl.List(ctx, &modelTemplates); err != nil {
+		return nil, fmt.Errorf("listing ModelTemplates: %w", err)
+	}
+	slices.SortFunc(modelTemplates.Items, func(a, b securityv1alpha1.ModelTemplate) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var selected *securityv1alpha1.ModelTemplate
+	for i, modelTemplate := range modelTemplates.Items {
+		if modelTemplate.Spec.APIExportName == apiExport.Name {
+			selected = &modelTemplates.Items[i]
+			break
+		}
+		if selected != nil || modelTemplate.Spec.APIExportSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(modelTemplate.Spec.APIExportSelector)
+		if err != nil {
+			return nil, fmt.Errorf("parsing APIExport selector of ModelTemplate %s: %w", modelTemplate.Name, err)
+		}
+		if selector.Matches(labels.Set(apiExport.Labels)) {
+			selected = &modelTemplates.Items[i]
+		}
+	}
+	if selected == nil {
+		return modelTpl, nil
+	}
+
+	tpl, err := ParseModelTemplate(selected)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ModelTemplate %s: %w", selected.Name, err)
+	}
+	return tpl, nil
 }
 
 // Finalize implements subroutines.Finalizer.
@@ -156,7 +289,7 @@ func (a *AuthorizationModelGenerationSubroutine) Finalize(ctx context.Context, o
 
 	if bindingCount > 1 {
 		// If there are still other bindings for the same APIExport, we can skip the model deletion.
-		return subroutines.OK(), nil
+		return dryrun.Finalized(), nil
 	}
 
 	apiExportCluster, err := a.mgr.GetCluster(ctx, multicluster.ClusterName(bindingToDelete.Status.APIExportClusterName))
@@ -198,7 +331,7 @@ func (a *AuthorizationModelGenerationSubroutine) Finalize(ctx context.Context, o
 		log.Info().Msg(fmt.Sprintf("authorization model %s has been deleted", authModelName))
 	}
 
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), nil
 }
 
 // Finalizers implements subroutines.Finalizer.
@@ -217,6 +350,7 @@ func (a *AuthorizationModelGenerationSubroutine) GetName() string {
 
 // Process implements subroutines.Processor.
 func (a *AuthorizationModelGenerationSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
 	binding := obj.(*kcpapisv1alpha2.APIBinding)
 
 	internalAPIBindings := []string{"core.platform-mesh.io", "system.platform-mesh.io"}
@@ -248,6 +382,13 @@ func (a *AuthorizationModelGenerationSubroutine) Process(ctx context.Context, ob
 		return subroutines.OK(), fmt.Errorf("getting APIExport: %w", err)
 	}
 
+	tpl, err := selectModelTemplate(ctx, apiExportCluster.GetClient(), &apiExport)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	var profileErrs []error
+	var orgTypes map[string][]string
 	for _, latestResourceSchema := range apiExport.Spec.Resources {
 		var resourceSchema kcpapisv1alpha1.APIResourceSchema
 		err := apiExportCluster.GetClient().Get(ctx, types.NamespacedName{Name: latestResourceSchema.Schema}, &resourceSchema)
@@ -255,34 +396,57 @@ func (a *AuthorizationModelGenerationSubroutine) Process(ctx context.Context, ob
 			return subroutines.OK(), fmt.Errorf("getting APIResourceSchema: %w", err)
 		}
 
-		longestRelationName := fmt.Sprintf("create_%s_%s", resourceSchema.Spec.Group, resourceSchema.Spec.Names.Plural)
+		model := securityv1alpha1.AuthorizationModel{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: toK8sName(resourceSchema.Spec.Group, resourceSchema.Spec.Names.Plural, accountInfo.Spec.Organization.Name),
+			},
+		}
 
-		group := resourceSchema.Spec.Group
+		// The types of the org's model are only read for schemas choosing
+		// their parent.
+		parentTypes := defaultParentTypes
+		if _, ok := resourceSchema.Annotations[securityv1alpha1.PermissionProfileParentAnnotation]; ok {
+			if orgTypes == nil {
+				orgTypes, err = a.getOrgTypes(ctx, accountInfo.Spec.Organization)
+				if err != nil {
+					return subroutines.OK(), err
+				}
+			}
+			parentTypes = orgTypes
+		}
 
-		if len(longestRelationName) > 50 {
-			group = resourceSchema.Spec.Group[len(longestRelationName)-50:]
+		input, profileErr := newSchemaModelInput(&resourceSchema, parentTypes)
+		if profileErr != nil {
+			// The current model is kept, so an invalid profile never changes
+			// the permissions on existing resources.
+			log.Warn().Err(profileErr).Str("schema", resourceSchema.Name).Msg("Invalid permission profile, keeping the current authorization model")
+			err = apiExportCluster.GetClient().Get(ctx, client.ObjectKeyFromObject(&model), &model)
+			if kerrors.IsNotFound(err) {
+				profileErrs = append(profileErrs, fmt.Errorf("APIResourceSchema %s: %w", resourceSchema.Name, profileErr))
+				continue
+			}
+			if err != nil {
+				return subroutines.OK(), fmt.Errorf("getting AuthorizationModel: %w", err)
+			}
+			if err := setPermissionProfileCondition(ctx, apiExportCluster.GetClient(), &model, true, profileErr); err != nil {
+				return subroutines.OK(), err
+			}
+			continue
 		}
 
-		var buffer bytes.Buffer
-		err = modelTpl.Execute(&buffer, modelInput{
-			Name:     resourceSchema.Spec.Names.Plural,
-			Group:    strings.ReplaceAll(group, ".", "_"),
-			Singular: resourceSchema.Spec.Names.Singular,
-			Scope:    string(resourceSchema.Spec.Scope),
-		})
+		module, err := executeModelTemplate(tpl, input)
 		if err != nil {
 			return subroutines.OK(), fmt.Errorf("executing model template: %w", err)
 		}
-
-		model := securityv1alpha1.AuthorizationModel{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: toK8sName(resourceSchema.Spec.Group, resourceSchema.Spec.Names.Plural, accountInfo.Spec.Organization.Name),
-			},
+		// ModelTemplates are checked on admission as well, this guards
+		// against templates created while the webhook was unavailable.
+		if err := checkTemplateModule(input, module); err != nil {
+			return subroutines.OK(), fmt.Errorf("model template for APIResourceSchema %s: %w", resourceSchema.Name, err)
 		}
 
 		_, err = controllerutil.CreateOrUpdate(ctx, apiExportCluster.GetClient(), &model, func() error {
 			model.Spec = securityv1alpha1.AuthorizationModelSpec{
-				Model: buffer.String(),
+				Model: module,
 				StoreRef: securityv1alpha1.WorkspaceStoreRef{
 					Name:    accountInfo.Spec.Organization.Name,
 					Cluster: accountInfo.Spec.Organization.OriginClusterId,
@@ -293,7 +457,71 @@ func (a *AuthorizationModelGenerationSubroutine) Process(ctx context.Context, ob
 		if err != nil {
 			return subroutines.OK(), fmt.Errorf("creating or updating AuthorizationModel: %w", err)
 		}
+		if err := setPermissionProfileCondition(ctx, apiExportCluster.GetClient(), &model, hasPermissionProfile(resourceSchema.Annotations), nil); err != nil {
+			return subroutines.OK(), err
+		}
 	}
 
+	if len(profileErrs) > 0 {
+		return subroutines.OK(), errors.Join(profileErrs...)
+	}
 	return subroutines.OK(), nil
 }
+
+// getOrgTypes returns the types defined in the model of an org with their
+// relations: the default parent types and the types of its core module and of
+// the AuthorizationModels extending it.
+func (a *AuthorizationModelGenerationSubroutine) getOrgTypes(ctx context.Context, org accountv1alpha1.AccountLocation) (map[string][]string, error) {
+	store, err := getReferencedStore(ctx, a.mgr, securityv1alpha1.WorkspaceStoreRef{Name: org.Name, Cluster: org.OriginClusterId})
+	if err != nil {
+		return nil, fmt.Errorf("getting Store %s: %w", org.Name, err)
+	}
+
+	var models securityv1alpha1.AuthorizationModelList
+	if err := a.lister.List(ctx, &models); err != nil {
+		return nil, fmt.Errorf("listing AuthorizationModels: %w", err)
+	}
+
+	modules := []string{store.Spec.CoreModule}
+	for _, model := range models.Items {
+		if model.Spec.StoreRef.Name == org.Name && model.Spec.StoreRef.Cluster == org.OriginClusterId {
+			modules = append(modules, model.Spec.Model)
+		}
+	}
+	return moduleTypes(modules), nil
+}
+
+// setPermissionProfileCondition reports on a generated AuthorizationModel
+// whether the permission profile of its APIResourceSchema is valid. The
+// condition is removed from models of schemas without a profile.
+func setPermissionProfileCondition(ctx context.Context, cl client.Client, model *securityv1alpha1.AuthorizationModel, hasProfile bool, profileErr error) error {
+	var changed bool
+	switch {
+	case profileErr != nil:
+		changed = meta.SetStatusCondition(&model.Status.Conditions, metav1.Condition{
+			Type:               securityv1alpha1.PermissionProfileCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InvalidPermissionProfile",
+			Message:            fmt.Sprintf("%s, keeping the current model", profileErr),
+			ObservedGeneration: model.Generation,
+		})
+	case hasProfile:
+		changed = meta.SetStatusCondition(&model.Status.Conditions, metav1.Condition{
+			Type:               securityv1alpha1.PermissionProfileCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Valid",
+			Message:            "The permission profile is applied",
+			ObservedGeneration: model.Generation,
+		})
+	default:
+		changed = meta.RemoveStatusCondition(&model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+	}
+	// Status is left alone in dry-run mode like by the lifecycles.
+	if !changed || dryrun.Enabled() {
+		return nil
+	}
+	if err := cl.Status().Update(ctx, model); err != nil {
+		return fmt.Errorf("updating status of AuthorizationModel %s: %w", model.Name, err)
+	}
+	return nil
+}
@@ -5,17 +5,24 @@ import (
 	"testing"
 
 	accountv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
 	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
 	kcpapisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
 	kcpapisv1alpha2 "github.com/kcp-dev/sdk/apis/apis/v1alpha2"
@@ -116,6 +123,7 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 					}
 					return nil
 				}).Once()
+				kcpClient.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Once()
 				kcpClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
 					if rs, ok := o.(*kcpapisv1alpha1.APIResourceSchema); ok {
 						rs.Spec.Group = "group"
@@ -155,6 +163,7 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 					}
 					return nil
 				}).Once()
+				kcpClient.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Once()
 				kcpClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
 					if rs, ok := o.(*kcpapisv1alpha1.APIResourceSchema); ok {
 						rs.Spec.Group = "group"
@@ -185,6 +194,7 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 					}
 					return nil
 				}).Once()
+				kcpClient.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Once()
 				kcpClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
 					if rs, ok := o.(*kcpapisv1alpha1.APIResourceSchema); ok {
 						rs.Spec.Group = "group"
@@ -228,6 +238,7 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 					}
 					return nil
 				}).Once()
+				kcpClient.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Once()
 				kcpClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
 			},
 		},
@@ -246,6 +257,7 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 					}
 					return nil
 				}).Once()
+				kcpClient.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Once()
 				kcpClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
 					if rs, ok := o.(*kcpapisv1alpha1.APIResourceSchema); ok {
 						rs.Spec.Group = "veryverylonggroup.platform-mesh.org"
@@ -305,6 +317,292 @@ func TestAuthorizationModelGeneration_Process(t *testing.T) {
 	}
 }
 
+// newGenerationClient returns a client of a workspace holding the APIExport
+// "foo" with a namespaced widgets resource, the AccountInfo of the org "org"
+// and the given objects.
+func newGenerationClient(schemaAnnotations map[string]string, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(accountv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(securityv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(kcpapisv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(kcpapisv1alpha2.AddToScheme(scheme))
+
+	return fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&securityv1alpha1.AuthorizationModel{}).
+		WithObjects(
+			&kcpapisv1alpha2.APIExport{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"team": "widgets"}},
+				Spec: kcpapisv1alpha2.APIExportSpec{
+					Resources: []kcpapisv1alpha2.ResourceSchema{{Schema: "v1.widgets.example.io"}},
+				},
+			},
+			&kcpapisv1alpha1.APIResourceSchema{
+				ObjectMeta: metav1.ObjectMeta{Name: "v1.widgets.example.io", Annotations: schemaAnnotations},
+				Spec: kcpapisv1alpha1.APIResourceSchemaSpec{
+					Group: "example.io",
+					Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Singular: "widget", Kind: "Widget"},
+					Scope: apiextensionsv1.NamespaceScoped,
+					Versions: []kcpapisv1alpha1.APIResourceVersion{
+						{Name: "v1alpha1", Served: true},
+						{Name: "v1", Served: true, Storage: true},
+					},
+				},
+			},
+			&accountv1alpha1.AccountInfo{
+				ObjectMeta: metav1.ObjectMeta{Name: "account"},
+				Spec: accountv1alpha1.AccountInfoSpec{
+					Organization: accountv1alpha1.AccountLocation{Name: "org", OriginClusterId: "origin"},
+				},
+			},
+		).
+		WithObjects(objects...).
+		Build()
+}
+
+func processGeneration(t *testing.T, cl client.Client) error {
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	manager.EXPECT().GetCluster(mock.Anything, mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(cl)
+
+	lister := mocks.NewMockLister(t)
+	lister.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(cl.List).Maybe()
+
+	sub := subroutine.NewAuthorizationModelGenerationSubroutine(manager, lister)
+	_, err := sub.Process(context.Background(), newApiBinding("foo", "bar"))
+	return err
+}
+
+func TestAuthorizationModelGeneration_ProcessModelTemplate(t *testing.T) {
+	byLabel := &securityv1alpha1.ModelTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-by-label"},
+		Spec: securityv1alpha1.ModelTemplateSpec{
+			APIExportSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "widgets"}},
+			Template:          "module {{ .Name }}\n\n# {{ .Schema.Spec.Names.Kind }}{{ range .Schema.Spec.Versions }} {{ .Name }}{{ end }}\ntype {{ .Group }}_{{ .Singular }}\n",
+		},
+	}
+	byName := &securityv1alpha1.ModelTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "b-by-name"},
+		Spec: securityv1alpha1.ModelTemplateSpec{
+			APIExportName: "foo",
+			Template:      "module {{ .Name }}\n\n# {{ .Scope }} {{ .Name }}\ntype {{ .Group }}_{{ .Singular }}\n",
+		},
+	}
+
+	tests := []struct {
+		name          string
+		templates     []client.Object
+		expectedModel string
+		expectError   bool
+	}{
+		{
+			name:          "falls back to the built-in template",
+			templates:     []client.Object{},
+			expectedModel: "define update: member",
+		},
+		{
+			name:          "uses the template selecting the APIExport by label",
+			templates:     []client.Object{byLabel},
+			expectedModel: "# Widget v1alpha1 v1\ntype example_io_widget",
+		},
+		{
+			name:          "prefers the template selecting the APIExport by name",
+			templates:     []client.Object{byLabel, byName},
+			expectedModel: "# Namespaced widgets",
+		},
+		{
+			name: "ignores templates of other APIExports",
+			templates: []client.Object{&securityv1alpha1.ModelTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+				Spec:       securityv1alpha1.ModelTemplateSpec{APIExportName: "bar", Template: "other"},
+			}},
+			expectedModel: "define update: member",
+		},
+		{
+			name: "fails on an invalid template",
+			templates: []client.Object{&securityv1alpha1.ModelTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+				Spec:       securityv1alpha1.ModelTemplateSpec{APIExportName: "foo", Template: "{{ .Name"},
+			}},
+			expectError: true,
+		},
+		{
+			name: "fails on a template defining other types",
+			templates: []client.Object{&securityv1alpha1.ModelTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-types"},
+				Spec:       securityv1alpha1.ModelTemplateSpec{APIExportName: "foo", Template: "module {{ .Name }}\n\ntype {{ .Group }}_{{ .Singular }}\n\ntype role\n"},
+			}},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := newGenerationClient(nil, test.templates...)
+
+			err := processGeneration(t, cl)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var model securityv1alpha1.AuthorizationModel
+			require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Name: "example-io-widgets-org"}, &model))
+			assert.Contains(t, model.Spec.Model, test.expectedModel)
+			assert.Equal(t, "origin", model.Spec.StoreRef.Cluster)
+		})
+	}
+}
+
+func TestAuthorizationModelGeneration_ProcessPermissionProfile(t *testing.T) {
+	modelKey := types.NamespacedName{Name: "example-io-widgets-org"}
+	existingModel := func(conditions ...metav1.Condition) *securityv1alpha1.AuthorizationModel {
+		return &securityv1alpha1.AuthorizationModel{
+			ObjectMeta: metav1.ObjectMeta{Name: modelKey.Name},
+			Spec:       securityv1alpha1.AuthorizationModelSpec{Model: "current"},
+			Status:     securityv1alpha1.AuthorizationModelStatus{Conditions: conditions},
+		}
+	}
+	orgStore := &securityv1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "org"},
+		Spec:       securityv1alpha1.StoreSpec{CoreModule: "module core\n\ntype user\n"},
+	}
+	projects := &securityv1alpha1.AuthorizationModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-io-projects-org"},
+		Spec: securityv1alpha1.AuthorizationModelSpec{
+			Model:    "module projects\n\ntype example_io_project\n  relations\n    define owner: [role#assignee]\n    define member: [role#assignee] or owner\n\ntype example_io_folder\n  relations\n    define member: [role#assignee]\n",
+			StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "org", Cluster: "origin"},
+		},
+	}
+
+	t.Run("applies the permission profile", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileRelationsAnnotation: "update=owner, delete=owner",
+			securityv1alpha1.PermissionProfileVerbsAnnotation:     "reject=owner,approve=owner",
+			securityv1alpha1.PermissionProfileParentAnnotation:    "example_io_project",
+		}, orgStore, projects)
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Contains(t, model.Spec.Model, "extend type example_io_project")
+		assert.Contains(t, model.Spec.Model, "define parent: [example_io_project]")
+		assert.Contains(t, model.Spec.Model, "define get: member")
+		assert.Contains(t, model.Spec.Model, "define update: owner")
+		assert.Contains(t, model.Spec.Model, "define delete: owner")
+		assert.Contains(t, model.Spec.Model, "define approve: owner\n\t\tdefine reject: owner")
+		assert.True(t, meta.IsStatusConditionTrue(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition))
+	})
+
+	t.Run("keeps the current model on unknown relations", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileRelationsAnnotation: "update=admin",
+		}, existingModel())
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Equal(t, "current", model.Spec.Model)
+		condition := meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, "InvalidPermissionProfile", condition.Reason)
+		assert.Contains(t, condition.Message, `unknown relation "admin" for verb "update"`)
+	})
+
+	t.Run("rejects unknown verbs", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileRelationsAnnotation: "approve=owner",
+		}, existingModel())
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		condition := meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+		require.NotNil(t, condition)
+		assert.Contains(t, condition.Message, `unknown verb "approve"`)
+	})
+
+	t.Run("rejects custom verbs redefining relations", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileVerbsAnnotation: "owner=member",
+		}, existingModel())
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		condition := meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+		require.NotNil(t, condition)
+		assert.Contains(t, condition.Message, `verb "owner" is already defined`)
+	})
+
+	t.Run("accepts the default parent types", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileParentAnnotation: "core_platform-mesh_io_account",
+		}, orgStore)
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Contains(t, model.Spec.Model, "define parent: [core_platform-mesh_io_account]")
+	})
+
+	t.Run("rejects parent types the org's model does not define", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileParentAnnotation: "example_io_team",
+		}, orgStore, projects, existingModel())
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Equal(t, "current", model.Spec.Model)
+		condition := meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Contains(t, condition.Message, `unknown type "example_io_team"`)
+	})
+
+	t.Run("rejects parent types without the inherited relations", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileParentAnnotation: "example_io_folder",
+		}, orgStore, projects, existingModel())
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Equal(t, "current", model.Spec.Model)
+		condition := meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition)
+		require.NotNil(t, condition)
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Contains(t, condition.Message, `type "example_io_folder" does not define the relation owner`)
+	})
+
+	t.Run("fails on invalid profiles without a model", func(t *testing.T) {
+		cl := newGenerationClient(map[string]string{
+			securityv1alpha1.PermissionProfileParentAnnotation: "account:root",
+		}, orgStore)
+		assert.ErrorContains(t, processGeneration(t, cl), `invalid type "account:root"`)
+
+		var model securityv1alpha1.AuthorizationModel
+		assert.True(t, kerrors.IsNotFound(cl.Get(context.Background(), modelKey, &model)))
+	})
+
+	t.Run("removes the condition once the profile is removed", func(t *testing.T) {
+		cl := newGenerationClient(nil, existingModel(metav1.Condition{
+			Type:   securityv1alpha1.PermissionProfileCondition,
+			Status: metav1.ConditionFalse,
+			Reason: "InvalidPermissionProfile",
+		}))
+		require.NoError(t, processGeneration(t, cl))
+
+		var model securityv1alpha1.AuthorizationModel
+		require.NoError(t, cl.Get(context.Background(), modelKey, &model))
+		assert.Contains(t, model.Spec.Model, "define update: member")
+		assert.Nil(t, meta.FindStatusCondition(model.Status.Conditions, securityv1alpha1.PermissionProfileCondition))
+	})
+}
+
 func TestAuthorizationModelGeneration_Finalize(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -2,6 +2,7 @@ package subroutine_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -19,9 +20,11 @@ import (
 	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 
 	"github.com/kcp-dev/logicalcluster/v3"
 )
@@ -511,7 +514,7 @@ type core_namespace
 				discoveryMock.EXPECT().ServerResourcesForGroupVersion(mock.Anything).Return(&metav1.APIResourceList{}, nil).Maybe()
 			}
 
-			subroutine := subroutine.NewAuthorizationModelSubroutine(fga, manager, kcpHelper, func(cfg *rest.Config) discovery.DiscoveryInterface { return discoveryMock }, logger.Logger)
+			subroutine := subroutine.NewAuthorizationModelSubroutine(fga, manager, kcpHelper, func(cfg *rest.Config) (discovery.DiscoveryInterface, error) { return discoveryMock, nil }, logger.Logger)
 			ctx := mccontext.WithCluster(context.Background(), multicluster.ClusterName(logicalcluster.Name("path").String()))
 
 			_, err := subroutine.Process(ctx, test.store)
@@ -523,3 +526,231 @@ type core_namespace
 		})
 	}
 }
+
+func TestAuthorizationModelProcessModelTests(t *testing.T) {
+	modelTests := []securityv1alpha1.ModelTest{{
+		Name: "owner",
+		Tuples: []securityv1alpha1.Tuple{
+			{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+		},
+		Check: []securityv1alpha1.CheckAssertion{{
+			User:       "user:alice",
+			Object:     "role:admin",
+			Assertions: map[string]bool{"assignee": true},
+		}},
+	}}
+
+	tests := []struct {
+		name            string
+		allowed         bool
+		expectWrite     bool
+		expectCondition metav1.ConditionStatus
+	}{
+		{
+			name:            "should write the model if the tests pass",
+			allowed:         true,
+			expectWrite:     true,
+			expectCondition: metav1.ConditionTrue,
+		},
+		{
+			name:            "should keep the current model if a test fails",
+			allowed:         false,
+			expectCondition: metav1.ConditionFalse,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{Name: "orgs", UID: "orgs-uid"},
+				Spec: securityv1alpha1.StoreSpec{
+					CoreModule: coreModule,
+					Tests:      modelTests,
+				},
+				Status: securityv1alpha1.StoreStatus{StoreID: "store-id"},
+			}
+
+			fga := mocks.NewMockOpenFGAServiceClient(t)
+			fga.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{}, nil)
+			fga.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "__model-tests-orgs-uid"}).Return(&openfgav1.CreateStoreResponse{Id: "scratch-id"}, nil)
+			fga.EXPECT().WriteAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteAuthorizationModelRequest) bool {
+				return req.StoreId == "scratch-id"
+			})).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "scratch-model-id"}, nil)
+			fga.EXPECT().Write(mock.Anything, mock.Anything).Return(&openfgav1.WriteResponse{}, nil).Twice()
+			fga.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: test.allowed}, nil)
+			fga.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "scratch-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+			if test.expectWrite {
+				fga.EXPECT().WriteAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteAuthorizationModelRequest) bool {
+					return req.StoreId == "store-id"
+				})).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "model-id"}, nil)
+			}
+
+			kcpHelper := mocks.NewMockLister(t)
+			kcpHelper.EXPECT().List(mock.Anything, mock.Anything).Return(nil)
+
+			manager := mocks.NewMockManager(t)
+			ctrlManager := mocks.NewMockCTRLManager(t)
+			manager.EXPECT().GetLocalManager().Return(ctrlManager)
+			ctrlManager.EXPECT().GetConfig().Return(&rest.Config{})
+
+			logger := testlogger.New()
+			subroutine := subroutine.NewAuthorizationModelSubroutine(fga, manager, kcpHelper, nil, logger.Logger)
+			ctx := mccontext.WithCluster(context.Background(), "path")
+
+			res, err := subroutine.Process(ctx, store)
+			assert.NoError(t, err)
+			assert.Equal(t, !test.expectWrite, res.IsPending())
+
+			condition := meta.FindStatusCondition(store.Status.Conditions, securityv1alpha1.ModelTestsCondition)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, test.expectCondition, condition.Status)
+			}
+			if test.expectWrite {
+				assert.Equal(t, "model-id", store.Status.AuthorizationModelID)
+			} else {
+				assert.Empty(t, store.Status.AuthorizationModelID)
+				assert.Contains(t, condition.Message, "owner: check user:alice assignee role:admin: expected true, got false")
+			}
+		})
+	}
+}
+
+func TestAuthorizationModelProcessHistoryAndPinning(t *testing.T) {
+	fullHistory := func() []securityv1alpha1.AuthorizationModelRevision {
+		var history []securityv1alpha1.AuthorizationModelRevision
+		for i := range 10 {
+			history = append(history, securityv1alpha1.AuthorizationModelRevision{ID: fmt.Sprintf("model-%d", i)})
+		}
+		return history
+	}
+
+	tests := []struct {
+		name            string
+		pinned          string
+		status          securityv1alpha1.StoreStatus
+		fgaMocks        func(*mocks.MockOpenFGAServiceClient)
+		expectError     bool
+		expectModelID   string
+		expectHistory   []string
+		expectCondition *metav1.ConditionStatus
+		expectReason    string
+	}{
+		{
+			name:          "should record the written model and drop the oldest one",
+			status:        securityv1alpha1.StoreStatus{StoreID: "store-id", AuthorizationModelID: "model-9", AuthorizationModelHistory: fullHistory()},
+			expectModelID: "model-new",
+			expectHistory: []string{"model-1", "model-2", "model-3", "model-4", "model-5", "model-6", "model-7", "model-8", "model-9", "model-new"},
+		},
+		{
+			name:   "should keep using the pinned model and hold back writes",
+			pinned: "model-0",
+			status: securityv1alpha1.StoreStatus{StoreID: "store-id", AuthorizationModelID: "model-9", AuthorizationModelHistory: fullHistory()},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().ReadAuthorizationModel(mock.Anything, &openfgav1.ReadAuthorizationModelRequest{StoreId: "store-id", Id: "model-9"}).
+					Return(&openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: &openfgav1.AuthorizationModel{SchemaVersion: "1.2"}}, nil)
+			},
+			expectModelID:   "model-0",
+			expectCondition: ptr.To(metav1.ConditionTrue),
+			expectReason:    "WriteHeldBack",
+		},
+		{
+			name:          "should start the history with the current model",
+			status:        securityv1alpha1.StoreStatus{StoreID: "store-id", AuthorizationModelID: "model-9"},
+			expectModelID: "model-new",
+			expectHistory: []string{"model-9", "model-new"},
+		},
+		{
+			name:   "should fail for a pinned model that does not exist",
+			pinned: "unknown",
+			status: securityv1alpha1.StoreStatus{StoreID: "store-id", AuthorizationModelID: "model-9", AuthorizationModelHistory: fullHistory()},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().ReadAuthorizationModel(mock.Anything, &openfgav1.ReadAuthorizationModelRequest{StoreId: "store-id", Id: "unknown"}).
+					Return(nil, errors.New("not found"))
+			},
+			expectError:     true,
+			expectModelID:   "model-9",
+			expectCondition: ptr.To(metav1.ConditionFalse),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{Name: "orgs", Generation: 2},
+				Spec: securityv1alpha1.StoreSpec{
+					CoreModule:                 coreModule,
+					PinnedAuthorizationModelID: test.pinned,
+				},
+				Status: test.status,
+			}
+
+			fga := mocks.NewMockOpenFGAServiceClient(t)
+			if test.fgaMocks != nil {
+				test.fgaMocks(fga)
+			} else {
+				fga.EXPECT().ReadAuthorizationModel(mock.Anything, &openfgav1.ReadAuthorizationModelRequest{StoreId: "store-id", Id: "model-9"}).
+					Return(&openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: &openfgav1.AuthorizationModel{SchemaVersion: "1.2"}}, nil)
+				fga.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).
+					Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "model-new"}, nil)
+			}
+
+			kcpHelper := mocks.NewMockLister(t)
+			kcpHelper.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, ol client.ObjectList, _ ...client.ListOption) error {
+				ol.(*securityv1alpha1.AuthorizationModelList).Items = []securityv1alpha1.AuthorizationModel{{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "extension",
+						Generation:  3,
+						Annotations: map[string]string{"kcp.io/cluster": "provider"},
+					},
+					Spec: securityv1alpha1.AuthorizationModelSpec{
+						Model:    extensionModel,
+						StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "orgs", Cluster: "path"},
+					},
+				}}
+				return nil
+			})
+
+			manager := mocks.NewMockManager(t)
+			ctrlManager := mocks.NewMockCTRLManager(t)
+			manager.EXPECT().GetLocalManager().Return(ctrlManager)
+			ctrlManager.EXPECT().GetConfig().Return(&rest.Config{})
+
+			logger := testlogger.New()
+			subroutine := subroutine.NewAuthorizationModelSubroutine(fga, manager, kcpHelper, nil, logger.Logger)
+			ctx := mccontext.WithCluster(context.Background(), "path")
+
+			_, err := subroutine.Process(ctx, store)
+			if test.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expectModelID, store.Status.AuthorizationModelID)
+			if test.expectHistory != nil {
+				var ids []string
+				for _, revision := range store.Status.AuthorizationModelHistory {
+					ids = append(ids, revision.ID)
+				}
+				assert.Equal(t, test.expectHistory, ids)
+
+				latest := store.Status.AuthorizationModelHistory[len(store.Status.AuthorizationModelHistory)-1]
+				assert.Equal(t, int64(2), latest.StoreGeneration)
+				assert.Equal(t, []securityv1alpha1.AuthorizationModelGeneration{{Cluster: "provider", Name: "extension", Generation: 3}}, latest.Modules)
+			}
+
+			condition := meta.FindStatusCondition(store.Status.Conditions, securityv1alpha1.AuthorizationModelPinnedCondition)
+			if test.expectCondition == nil {
+				assert.Nil(t, condition)
+			} else if assert.NotNil(t, condition) {
+				assert.Equal(t, *test.expectCondition, condition.Status)
+				if test.expectReason != "" {
+					assert.Equal(t, test.expectReason, condition.Reason)
+				}
+			}
+			if test.pinned != "" && !test.expectError {
+				assert.Len(t, store.Status.AuthorizationModelHistory, 10)
+			}
+		})
+	}
+}
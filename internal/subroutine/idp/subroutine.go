@@ -12,6 +12,7 @@ import (
 	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/pkg/clientreg"
 	"github.com/platform-mesh/security-operator/pkg/clientreg/keycloak"
 	"github.com/platform-mesh/subroutines"
@@ -69,6 +70,9 @@ func (s *subroutine) newOIDCClient(realmName string) (clientreg.Client, *keycloa
 		clientreg.WithHTTPClient(httpClient),
 		clientreg.WithTokenProvider(adminClient),
 	)
+	if dryrun.Enabled() {
+		oidcClient = dryrun.ClientRegistration(oidcClient)
+	}
 
 	return oidcClient, adminClient
 }
@@ -114,20 +118,20 @@ func (s *subroutine) Finalize(ctx context.Context, obj client.Object) (subroutin
 			},
 		}
 
-		orgsClient, err := s.kcpClientGetter.NewClientForLogicalCluster(ctx, string(config.MultiProviderName(config.CoreProviderName, config.OrgsClusterPath)))
-		if err != nil {
-			return subroutines.OK(), fmt.Errorf("getting orgs client: %w", err)
-		}
-		if err := orgsClient.Delete(ctx, secretToDelete); err != nil {
+		if err := s.deleteSecret(ctx, secretToDelete); err != nil {
 			return subroutines.OK(), fmt.Errorf("failed to delete client secret: %w", err)
 		}
 	}
 
+	if dryrun.Enabled() {
+		dryrun.Record(ctx, dryrun.SystemKeycloak, "DeleteRealm", realmName, nil)
+		return dryrun.Finalized(), nil
+	}
 	if err := adminClient.DeleteRealm(ctx, realmName); err != nil {
 		return subroutines.OK(), fmt.Errorf("failed to delete realm: %w", err)
 	}
 
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), nil
 }
 
 func (s *subroutine) Finalizers(_ client.Object) []string {
@@ -149,9 +153,14 @@ func (s *subroutine) Process(ctx context.Context, obj client.Object) (subroutine
 	realmName := idpConfig.Name
 	oidcClient, adminClient := s.newOIDCClient(realmName)
 
-	if err := s.ensureRealm(ctx, adminClient, realmName, idpConfig.Spec.RegistrationAllowed, log); err != nil {
+	realmExists, err := s.ensureRealm(ctx, adminClient, realmName, idpConfig.Spec.RegistrationAllowed, log)
+	if err != nil {
 		return subroutines.OK(), err
 	}
+	if !realmExists {
+		log.Info().Str("realm", realmName).Msg("Realm was not created in dry-run mode, skipping its clients")
+		return subroutines.OK(), nil
+	}
 
 	if err := s.deleteRemovedClients(ctx, idpConfig, oidcClient, log); err != nil {
 		return subroutines.OK(), err
@@ -177,6 +186,11 @@ func (s *subroutine) Process(ctx context.Context, obj client.Object) (subroutine
 		}
 	}
 
+	// The managed clients are only recorded once they were registered.
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
+	}
+
 	// Update status
 	original := idpConfig.DeepCopy()
 	idpConfig.Status.ManagedClients = managedClients
@@ -187,7 +201,9 @@ func (s *subroutine) Process(ctx context.Context, obj client.Object) (subroutine
 	return subroutines.OK(), nil
 }
 
-func (s *subroutine) ensureRealm(ctx context.Context, adminClient *keycloak.AdminClient, realmName string, registrationAllowed bool, log *logger.Logger) error {
+// ensureRealm creates or updates the realm and reports whether it exists
+// afterwards, which is not the case for new realms in dry-run mode.
+func (s *subroutine) ensureRealm(ctx context.Context, adminClient *keycloak.AdminClient, realmName string, registrationAllowed bool, log *logger.Logger) (bool, error) {
 	realmConfig := keycloak.RealmConfig{
 		Realm:                       realmName,
 		DisplayName:                 realmName,
@@ -217,9 +233,23 @@ func (s *subroutine) ensureRealm(ctx context.Context, adminClient *keycloak.Admi
 		realmConfig.SMTPServer = smtpConfig
 	}
 
+	if dryrun.Enabled() {
+		exists, err := adminClient.RealmExists(ctx, realmName)
+		if err != nil {
+			return false, fmt.Errorf("failed to check if realm exists: %w", err)
+		}
+		if realmConfig.SMTPServer != nil {
+			smtpConfig := *realmConfig.SMTPServer
+			smtpConfig.Password = ""
+			realmConfig.SMTPServer = &smtpConfig
+		}
+		dryrun.Record(ctx, dryrun.SystemKeycloak, "CreateOrUpdateRealm", realmName, realmConfig)
+		return exists, nil
+	}
+
 	created, err := adminClient.CreateOrUpdateRealm(ctx, realmConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create or update realm: %w", err)
+		return false, fmt.Errorf("failed to create or update realm: %w", err)
 	}
 
 	if created {
@@ -228,7 +258,7 @@ func (s *subroutine) ensureRealm(ctx context.Context, adminClient *keycloak.Admi
 		log.Info().Str("realm", realmName).Msg("Realm updated")
 	}
 
-	return nil
+	return true, nil
 }
 
 func (s *subroutine) deleteRemovedClients(ctx context.Context, idpConfig *v1alpha1.IdentityProviderConfiguration, oidcClient clientreg.Client, log *logger.Logger) error {
@@ -263,11 +293,7 @@ func (s *subroutine) deleteRemovedClients(ctx context.Context, idpConfig *v1alph
 				Namespace: managedClient.SecretRef.Namespace,
 			},
 		}
-		orgsClient, err := s.kcpClientGetter.NewClientForLogicalCluster(ctx, string(config.MultiProviderName(config.CoreProviderName, config.OrgsClusterPath)))
-		if err != nil {
-			return fmt.Errorf("getting orgs client: %w", err)
-		}
-		if err := orgsClient.Delete(ctx, secretToDelete); err != nil {
+		if err := s.deleteSecret(ctx, secretToDelete); err != nil {
 			return fmt.Errorf("failed to delete client secret %s: %w", managedClient.SecretRef.Name, err)
 		}
 	}
@@ -331,6 +357,11 @@ func (s *subroutine) readRegistrationAccessToken(ctx context.Context, secretRef
 }
 
 func (s *subroutine) createOrUpdateSecret(ctx context.Context, clientConfig *v1alpha1.IdentityProviderClientConfig, clientInfo clientreg.ClientInformation, idpName string) error {
+	if dryrun.Enabled() {
+		dryrun.Record(ctx, dryrun.SystemKubernetes, "CreateOrUpdateSecret", clientConfig.SecretRef.Namespace+"/"+clientConfig.SecretRef.Name, nil)
+		return nil
+	}
+
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      clientConfig.SecretRef.Name,
@@ -362,3 +393,16 @@ func (s *subroutine) createOrUpdateSecret(ctx context.Context, clientConfig *v1a
 
 	return err
 }
+
+func (s *subroutine) deleteSecret(ctx context.Context, secret *corev1.Secret) error {
+	if dryrun.Enabled() {
+		dryrun.Record(ctx, dryrun.SystemKubernetes, "DeleteSecret", secret.Namespace+"/"+secret.Name, nil)
+		return nil
+	}
+
+	orgsClient, err := s.kcpClientGetter.NewClientForLogicalCluster(ctx, string(config.MultiProviderName(config.CoreProviderName, config.OrgsClusterPath)))
+	if err != nil {
+		return fmt.Errorf("getting orgs client: %w", err)
+	}
+	return orgsClient.Delete(ctx, secret)
+}
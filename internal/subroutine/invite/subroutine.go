@@ -15,6 +15,7 @@ import (
 	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	"github.com/platform-mesh/security-operator/internal/client"
 	"github.com/platform-mesh/security-operator/internal/config"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/subroutines"
 	"golang.org/x/oauth2/clientcredentials"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -205,6 +206,12 @@ func (s *subroutine) Process(ctx context.Context, obj k8sclient.Object) (subrout
 		}
 	}
 
+	if dryrun.Enabled() {
+		dryrun.Record(ctx, dryrun.SystemKeycloak, "CreateUser", realm+"/"+invite.Spec.Email, map[string]any{"requiredActions": newUser.RequiredActions})
+		dryrun.Record(ctx, dryrun.SystemKeycloak, "ExecuteActionsEmail", realm+"/"+invite.Spec.Email, map[string]any{"clientId": oidcClient.ClientID})
+		return subroutines.OK(), nil
+	}
+
 	var buffer bytes.Buffer
 	if err = json.NewEncoder(&buffer).Encode(&newUser); err != nil {
 		return subroutines.OK(), err
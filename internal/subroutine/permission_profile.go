@@ -0,0 +1,201 @@
+package subroutine
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	language "github.com/openfga/language/pkg/go/transformer"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	accountType   = "core_platform-mesh_io_account"
+	namespaceType = "core_namespace"
+)
+
+// defaultParentTypes are the parent types of generated resources with their
+// relations. They are defined in the model of every org.
+var defaultParentTypes = map[string][]string{
+	accountType:   profileRelations,
+	namespaceType: profileRelations,
+}
+
+// defaultVerbRelations are the relations granting the Kubernetes verbs on
+// generated resources. create, list and watch are granted on the parent of
+// the resources as well.
+var defaultVerbRelations = map[string]string{
+	"get":    "member",
+	"list":   "member",
+	"watch":  "member",
+	"create": "owner",
+	"update": "member",
+	"patch":  "member",
+	"delete": "member",
+}
+
+// profileRelations are the relations verbs can be granted by. Generated
+// types inherit them from their parent, so every parent type defines them.
+var profileRelations = []string{"member", "owner"}
+
+// reservedRelations are defined on every generated type and cannot be used
+// as custom verbs.
+var reservedRelations = []string{"parent", "member", "owner", "manage_iam_roles", "get_iam_roles", "get_iam_users"}
+
+var (
+	customVerbPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	parentTypePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// customVerb is granted on generated resources in addition to the
+// Kubernetes verbs.
+type customVerb struct {
+	Name     string
+	Relation string
+}
+
+// newModelInput returns the input of a model template with the default
+// permissions of a resource.
+func newModelInput(name, group, singular, scope string) modelInput {
+	parent := accountType
+	if scope == string(apiextensionsv1.NamespaceScoped) {
+		parent = namespaceType
+	}
+	return modelInput{
+		Name:      name,
+		Group:     group,
+		Singular:  singular,
+		Scope:     scope,
+		Parent:    parent,
+		Relations: maps.Clone(defaultVerbRelations),
+	}
+}
+
+// hasPermissionProfile returns whether an APIResourceSchema is annotated
+// with a permission profile.
+func hasPermissionProfile(annotations map[string]string) bool {
+	for _, key := range []string{
+		securityv1alpha1.PermissionProfileRelationsAnnotation,
+		securityv1alpha1.PermissionProfileVerbsAnnotation,
+		securityv1alpha1.PermissionProfileParentAnnotation,
+	} {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPermissionProfile applies the permission profile an APIResourceSchema
+// is annotated with to the input of its model template. The parent may only
+// be one of parentTypes defining the profile relations, as a module extending
+// a type the org's model does not define or inheriting relations it lacks
+// breaks the whole model.
+func applyPermissionProfile(input *modelInput, annotations map[string]string, parentTypes map[string][]string) error {
+	if value, ok := annotations[securityv1alpha1.PermissionProfileRelationsAnnotation]; ok {
+		overrides, err := parseVerbRelations(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", securityv1alpha1.PermissionProfileRelationsAnnotation, err)
+		}
+		for _, override := range overrides {
+			if _, ok := defaultVerbRelations[override.Name]; !ok {
+				return fmt.Errorf("%s: unknown verb %q, expected one of %s", securityv1alpha1.PermissionProfileRelationsAnnotation, override.Name, strings.Join(slices.Sorted(maps.Keys(defaultVerbRelations)), ", "))
+			}
+			input.Relations[override.Name] = override.Relation
+		}
+	}
+
+	if value, ok := annotations[securityv1alpha1.PermissionProfileVerbsAnnotation]; ok {
+		verbs, err := parseVerbRelations(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", securityv1alpha1.PermissionProfileVerbsAnnotation, err)
+		}
+		for _, verb := range verbs {
+			_, builtIn := defaultVerbRelations[verb.Name]
+			if builtIn || slices.Contains(reservedRelations, verb.Name) {
+				return fmt.Errorf("%s: verb %q is already defined", securityv1alpha1.PermissionProfileVerbsAnnotation, verb.Name)
+			}
+			if !customVerbPattern.MatchString(verb.Name) {
+				return fmt.Errorf("%s: invalid verb %q", securityv1alpha1.PermissionProfileVerbsAnnotation, verb.Name)
+			}
+			if slices.ContainsFunc(input.CustomVerbs, func(v customVerb) bool { return v.Name == verb.Name }) {
+				return fmt.Errorf("%s: duplicate verb %q", securityv1alpha1.PermissionProfileVerbsAnnotation, verb.Name)
+			}
+			input.CustomVerbs = append(input.CustomVerbs, verb)
+		}
+		slices.SortFunc(input.CustomVerbs, func(a, b customVerb) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+
+	if value, ok := annotations[securityv1alpha1.PermissionProfileParentAnnotation]; ok {
+		parent := strings.TrimSpace(value)
+		if !parentTypePattern.MatchString(parent) {
+			return fmt.Errorf("%s: invalid type %q", securityv1alpha1.PermissionProfileParentAnnotation, value)
+		}
+		relations, ok := parentTypes[parent]
+		if !ok {
+			return fmt.Errorf("%s: unknown type %q, expected %s, %s or a type defined in the org's model", securityv1alpha1.PermissionProfileParentAnnotation, parent, accountType, namespaceType)
+		}
+		for _, relation := range profileRelations {
+			if !slices.Contains(relations, relation) {
+				return fmt.Errorf("%s: type %q does not define the relation %s", securityv1alpha1.PermissionProfileParentAnnotation, parent, relation)
+			}
+		}
+		input.Parent = parent
+	}
+
+	return nil
+}
+
+// parseVerbRelations parses a comma separated list of verb=relation pairs.
+// Relations other than member and owner are rejected.
+func parseVerbRelations(value string) ([]customVerb, error) {
+	var verbs []customVerb
+	for pair := range strings.SplitSeq(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		verb, relation, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q, expected verb=relation", strings.TrimSpace(pair))
+		}
+		verb, relation = strings.TrimSpace(verb), strings.TrimSpace(relation)
+		if !slices.Contains(profileRelations, relation) {
+			return nil, fmt.Errorf("unknown relation %q for verb %q, expected one of %s", relation, verb, strings.Join(profileRelations, ", "))
+		}
+		verbs = append(verbs, customVerb{Name: verb, Relation: relation})
+	}
+	return verbs, nil
+}
+
+// moduleTypes returns the default parent types and the types defined in the
+// given modules with their relations, including the ones added by extensions.
+// Modules that cannot be parsed are skipped, they fail the build of the org's
+// model on their own.
+func moduleTypes(modules []string) map[string][]string {
+	defined := map[string]bool{}
+	relations := map[string][]string{}
+	for _, module := range modules {
+		model, extensions, err := language.TransformModularDSLToProto(module)
+		if err != nil {
+			continue
+		}
+		for _, typeDef := range model.GetTypeDefinitions() {
+			if extensions[typeDef.GetType()] != typeDef {
+				defined[typeDef.GetType()] = true
+			}
+			relations[typeDef.GetType()] = append(relations[typeDef.GetType()], slices.Collect(maps.Keys(typeDef.GetRelations()))...)
+		}
+	}
+
+	types := maps.Clone(defaultParentTypes)
+	for typeName := range defined {
+		types[typeName] = append(slices.Clone(types[typeName]), relations[typeName]...)
+	}
+	return types
+}
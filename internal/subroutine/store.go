@@ -3,15 +3,17 @@ package subroutine
 import (
 	"context"
 	"fmt"
-	"slices"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/platform-mesh/security-operator/api/v1alpha1"
 	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/subroutines"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
 )
@@ -43,7 +45,18 @@ func (s *storeSubroutine) Finalize(ctx context.Context, obj client.Object) (subr
 	store := obj.(*v1alpha1.Store)
 
 	if store.Status.StoreID == "" {
-		return subroutines.OK(), nil
+		return dryrun.Finalized(), nil
+	}
+
+	// Scratch stores of interrupted model tests are deleted whatever the
+	// deletion policy, nobody else can find them.
+	if err := fga.DeleteModelTestsStores(ctx, s.fga, string(store.UID)); err != nil {
+		return subroutines.OK(), err
+	}
+
+	if store.Spec.DeletionPolicy == v1alpha1.StoreDeletionPolicyOrphan {
+		log.Info().Str("storeId", store.Status.StoreID).Msg("Orphaning OpenFGA store")
+		return dryrun.Finalized(), nil
 	}
 
 	authorizationModels, err := getRelatedAuthorizationModels(ctx, s.kcpHelper, store)
@@ -54,40 +67,66 @@ func (s *storeSubroutine) Finalize(ctx context.Context, obj client.Object) (subr
 		return subroutines.OK(), fmt.Errorf("found non-zero count of depending authorization models")
 	}
 
+	if store.Spec.DeletionPolicy == v1alpha1.StoreDeletionPolicyRetain {
+		log.Info().Str("storeId", store.Status.StoreID).Msg("Retaining OpenFGA store")
+		return dryrun.Finalized(), nil
+	}
+
 	_, err = s.fga.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: store.Status.StoreID})
 	if status, ok := status.FromError(err); ok && status.Code() == codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found) {
-		return subroutines.OK(), nil
+		return dryrun.Finalized(), nil
 	}
 	if err != nil {
 		log.Error().Err(err).Msg("unable to delete store")
 		return subroutines.OK(), err
 	}
 
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), nil
 }
 
 func (s *storeSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 	store := obj.(*v1alpha1.Store)
+	name := store.OpenFGAStoreName()
+
+	if fga.IsModelTestsStore(name) {
+		return subroutines.Stop(fmt.Sprintf("OpenFGA store name %q uses the reserved prefix %s", name, fga.ModelTestsStorePrefix)), nil
+	}
+
+	if id := store.Spec.StoreID; id != "" && store.Status.StoreID != id {
+		log.Info().Str("storeId", id).Msg("Adopting OpenFGA store")
+		if _, err := s.fga.GetStore(ctx, &openfgav1.GetStoreRequest{StoreId: id}); err != nil {
+			return subroutines.OK(), fmt.Errorf("getting OpenFGA store %s to adopt: %w", id, err)
+		}
+
+		// The models and tuples recorded so far belong to the previous store.
+		store.Status = v1alpha1.StoreStatus{
+			Conditions: store.Status.Conditions,
+			StoreID:    id,
+		}
+	}
 
 	if store.Status.StoreID == "" {
 		log.Info().Msg("Store ID not set, trying to find store by name")
 
-		list, err := s.fga.ListStores(ctx, &openfgav1.ListStoresRequest{})
+		ids, err := s.findStores(ctx, name)
 		if err != nil {
 			return subroutines.OK(), err
 		}
 
-		storeIdx := slices.IndexFunc(list.GetStores(), func(i *openfgav1.Store) bool { return i.GetName() == store.Name })
-		if storeIdx != -1 {
+		switch len(ids) {
+		case 0:
+		case 1:
 			log.Info().Msg("Store found, updating store ID")
-			store.Status.StoreID = list.GetStores()[storeIdx].GetId()
+			store.Status.StoreID = ids[0]
 			return subroutines.OK(), nil
+		default:
+			return subroutines.OK(), fmt.Errorf("found %d OpenFGA stores named %q, set spec.storeId to adopt one of them", len(ids), name)
 		}
 
 		log.Info().Msg("Store not found, creating new store")
 		res, err := s.fga.CreateStore(ctx, &openfgav1.CreateStoreRequest{
-			Name: store.Name,
+			Name: name,
 		})
 		if err != nil {
 			return subroutines.OK(), err
@@ -102,12 +141,12 @@ func (s *storeSubroutine) Process(ctx context.Context, obj client.Object) (subro
 		return subroutines.OK(), err
 	}
 
-	if fgaStore.GetName() == store.Name {
+	if fgaStore.GetName() == name || (store.Spec.StoreID != "" && store.Spec.StoreName == "") {
 		return subroutines.OK(), nil
 	}
 	_, err = s.fga.UpdateStore(ctx, &openfgav1.UpdateStoreRequest{
 		StoreId: store.Status.StoreID,
-		Name:    store.Name,
+		Name:    name,
 	})
 	if err != nil {
 		return subroutines.OK(), err
@@ -115,3 +154,31 @@ func (s *storeSubroutine) Process(ctx context.Context, obj client.Object) (subro
 
 	return subroutines.OK(), nil
 }
+
+// findStores returns the IDs of all OpenFGA stores with the given name.
+func (s *storeSubroutine) findStores(ctx context.Context, name string) ([]string, error) {
+	var ids []string
+	var continuationToken string
+	for {
+		list, err := s.fga.ListStores(ctx, &openfgav1.ListStoresRequest{
+			PageSize:          wrapperspb.Int32(100),
+			ContinuationToken: continuationToken,
+			Name:              name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Servers that do not support filtering by name return all stores.
+		for _, fgaStore := range list.GetStores() {
+			if fgaStore.GetName() == name {
+				ids = append(ids, fgaStore.GetId())
+			}
+		}
+
+		continuationToken = list.GetContinuationToken()
+		if continuationToken == "" {
+			return ids, nil
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package subroutine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// storeRestoreSubroutine imports a StoreSnapshot into a new OpenFGA store and
+// switches the Store the snapshot was taken of over to it. The previous
+// OpenFGA store is kept unless the restore opts into deleting it after a
+// grace period.
+type storeRestoreSubroutine struct {
+	fga            openfgav1.OpenFGAServiceClient
+	mgr            mcmanager.Manager
+	runtimeClient  client.Client
+	namespace      string
+	writeChunkSize int
+}
+
+func NewStoreRestoreSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, runtimeClient client.Client, namespace string, writeChunkSize int) *storeRestoreSubroutine {
+	return &storeRestoreSubroutine{
+		fga:            fga,
+		mgr:            mgr,
+		runtimeClient:  runtimeClient,
+		namespace:      namespace,
+		writeChunkSize: writeChunkSize,
+	}
+}
+
+var _ subroutines.Processor = &storeRestoreSubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (r *storeRestoreSubroutine) GetName() string { return "StoreRestoreSubroutine" }
+
+// Process implements subroutines.Processor.
+func (r *storeRestoreSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	restore := obj.(*securityv1alpha1.StoreRestore)
+
+	if restore.Status.CompletionTime != nil {
+		return r.deletePreviousStore(ctx, restore)
+	}
+
+	cluster, err := r.mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+
+	var snapshot securityv1alpha1.StoreSnapshot
+	if err := cluster.GetClient().Get(ctx, types.NamespacedName{Name: restore.Spec.SnapshotName}, &snapshot); err != nil {
+		return subroutines.OK(), fmt.Errorf("getting StoreSnapshot %s: %w", restore.Spec.SnapshotName, err)
+	}
+	if snapshot.Status.CompletionTime == nil {
+		return subroutines.Pending(10*time.Second, fmt.Sprintf("StoreSnapshot %s is not completed yet", snapshot.Name)), nil
+	}
+
+	ownStore, err := isOrgStoreRef(ctx, cluster.GetClient(), snapshot.Spec.StoreRef)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+	if !ownStore {
+		return subroutines.Stop(fmt.Sprintf("StoreSnapshot %s references Store %s, which is not the store of this org", snapshot.Name, snapshot.Spec.StoreRef.Name)), nil
+	}
+
+	storeCluster, err := r.mgr.GetCluster(ctx, multicluster.ClusterName(snapshot.Spec.StoreRef.Cluster))
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("unable to get store cluster: %w", err)
+	}
+	storeClient := storeCluster.GetClient()
+
+	var store securityv1alpha1.Store
+	if err := storeClient.Get(ctx, types.NamespacedName{Name: snapshot.Spec.StoreRef.Name}, &store); err != nil {
+		return subroutines.OK(), fmt.Errorf("getting Store %s: %w", snapshot.Spec.StoreRef.Name, err)
+	}
+	if store.Spec.StoreID != "" {
+		return subroutines.OK(), fmt.Errorf("store %s adopts OpenFGA store %s and cannot be restored into a new store", store.Name, store.Spec.StoreID)
+	}
+
+	// The IDs of the restored store are recorded before the Store is
+	// switched over, so a failed update never imports the snapshot twice.
+	if restore.Status.StoreID == "" {
+		archive, err := loadStoreSnapshot(ctx, r.runtimeClient, r.namespace, &snapshot)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+
+		storeID, modelID, err := fga.ImportStore(ctx, r.fga, archive, store.OpenFGAStoreName(), r.writeChunkSize, log)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+		restore.Status.StoreID = storeID
+		restore.Status.AuthorizationModelID = modelID
+		restore.Status.PreviousStoreID = store.Status.StoreID
+		restore.Status.TupleCount = len(archive.Tuples)
+		log.Info().Str("storeID", storeID).Int("tuples", len(archive.Tuples)).Msg("Imported store snapshot")
+	}
+
+	// No store was created in dry-run mode, so the Store keeps its OpenFGA
+	// store and the restore is not completed.
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
+	}
+
+	if previousStoreID := store.Status.StoreID; previousStoreID != restore.Status.StoreID {
+		store.Status.StoreID = restore.Status.StoreID
+		store.Status.AuthorizationModelID = restore.Status.AuthorizationModelID
+		// Models of the previous store cannot be pinned anymore.
+		store.Status.AuthorizationModelHistory = []securityv1alpha1.AuthorizationModelRevision{{
+			ID:        restore.Status.AuthorizationModelID,
+			CreatedAt: metav1.Now(),
+		}}
+		if err := storeClient.Status().Update(ctx, &store); err != nil {
+			return subroutines.OK(), fmt.Errorf("updating status of Store %s: %w", store.Name, err)
+		}
+		log.Info().Str("storeID", restore.Status.StoreID).Str("previousStoreID", previousStoreID).Msg("Switched Store over to the restored store")
+	}
+
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	return r.deletePreviousStore(ctx, restore)
+}
+
+// deletePreviousStore deletes the OpenFGA store the Store referenced before
+// the restore once the grace period the restore opted into has passed.
+func (r *storeRestoreSubroutine) deletePreviousStore(ctx context.Context, restore *securityv1alpha1.StoreRestore) (subroutines.Result, error) {
+	after := restore.Spec.DeletePreviousStoreAfter
+	if after == nil || restore.Status.PreviousStoreID == "" || restore.Status.PreviousStoreDeletionTime != nil {
+		return subroutines.OK(), nil
+	}
+	if wait := time.Until(restore.Status.CompletionTime.Add(after.Duration)); wait > 0 {
+		return subroutines.OKWithRequeue(wait), nil
+	}
+
+	_, err := r.fga.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: restore.Status.PreviousStoreID})
+	if s, ok := status.FromError(err); err != nil && !(ok && s.Code() == codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found)) {
+		return subroutines.OK(), fmt.Errorf("deleting previous OpenFGA store %s: %w", restore.Status.PreviousStoreID, err)
+	}
+	now := metav1.Now()
+	restore.Status.PreviousStoreDeletionTime = &now
+	logger.LoadLoggerFromContext(ctx).Info().Str("storeID", restore.Status.PreviousStoreID).Msg("Deleted previous OpenFGA store")
+	return subroutines.OK(), nil
+}
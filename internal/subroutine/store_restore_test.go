@@ -0,0 +1,242 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// takeSnapshot runs the snapshot subroutine against a store holding the given
+// tuples and returns the completed snapshot and the Secrets it wrote.
+func takeSnapshot(t *testing.T, ctx context.Context, tuples ...*openfgav1.TupleKey) (*securityv1alpha1.StoreSnapshot, map[string]*corev1.Secret) {
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{Id: "model-id", SchemaVersion: "1.2"},
+	}, nil)
+	fga.EXPECT().Read(mock.Anything, mock.Anything).Return(readResponse(tuples...), nil)
+
+	mgr := mocks.NewMockManager(t)
+	expectSnapshotWorkspace(t, mgr)
+	expectStore(t, mgr, "store-id", "model-id")
+
+	secrets := map[string]*corev1.Secret{}
+	runtimeClient := mocks.NewMockClient(t)
+	runtimeClient.EXPECT().DeleteAllOf(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	runtimeClient.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, o client.Object, _ ...client.CreateOption) error {
+		secrets[o.GetName()] = o.(*corev1.Secret)
+		return nil
+	})
+
+	snapshot := &securityv1alpha1.StoreSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapshot"},
+		Spec: securityv1alpha1.StoreSnapshotSpec{
+			StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"},
+		},
+	}
+	_, err := subroutine.NewStoreSnapshotSubroutine(fga, mgr, runtimeClient, "platform-mesh-system").Process(ctx, snapshot)
+	require.NoError(t, err)
+	return snapshot, secrets
+}
+
+func TestStoreRestoreGetName(t *testing.T) {
+	subroutine := subroutine.NewStoreRestoreSubroutine(nil, nil, nil, "", 0)
+	assert.Equal(t, "StoreRestoreSubroutine", subroutine.GetName())
+}
+
+func TestStoreRestoreProcess(t *testing.T) {
+	ctx := mccontext.WithCluster(context.Background(), "snapshot-cluster")
+
+	t.Run("should import the snapshot and switch the store over", func(t *testing.T) {
+		snapshot, secrets := takeSnapshot(t, ctx,
+			&openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+		)
+
+		runtimeClient := mocks.NewMockClient(t)
+		runtimeClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, nn types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+			assert.Equal(t, "platform-mesh-system", nn.Namespace)
+			secrets[nn.Name].DeepCopyInto(o.(*corev1.Secret))
+			return nil
+		})
+
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "snapshot"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+			snapshot.DeepCopyInto(o.(*securityv1alpha1.StoreSnapshot))
+			return nil
+		})
+		expectAccountInfo(cl)
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+		scheme := runtime.NewScheme()
+		utilruntime.Must(securityv1alpha1.AddToScheme(scheme))
+		store := &securityv1alpha1.Store{
+			ObjectMeta: metav1.ObjectMeta{Name: "store"},
+			Status:     securityv1alpha1.StoreStatus{StoreID: "old-store-id", AuthorizationModelID: "old-model-id"},
+		}
+		storeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(store).WithStatusSubresource(store).Build()
+		storeCluster := mocks.NewMockCluster(t)
+		storeCluster.EXPECT().GetClient().Return(storeClient)
+		mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("store-cluster")).Return(storeCluster, nil)
+
+		fga := mocks.NewMockOpenFGAServiceClient(t)
+		fga.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "store"}).Return(&openfgav1.CreateStoreResponse{Id: "new-store-id"}, nil)
+		fga.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "new-model-id"}, nil)
+		fga.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+			assert.Equal(t, "new-store-id", req.StoreId)
+			assert.Len(t, req.GetWrites().GetTupleKeys(), 1)
+			return &openfgav1.WriteResponse{}, nil
+		})
+
+		restore := &securityv1alpha1.StoreRestore{Spec: securityv1alpha1.StoreRestoreSpec{SnapshotName: "snapshot"}}
+		_, err := subroutine.NewStoreRestoreSubroutine(fga, mgr, runtimeClient, "platform-mesh-system", 0).Process(ctx, restore)
+		require.NoError(t, err)
+		assert.Equal(t, "new-store-id", restore.Status.StoreID)
+		assert.Equal(t, "new-model-id", restore.Status.AuthorizationModelID)
+		assert.Equal(t, 1, restore.Status.TupleCount)
+		assert.NotNil(t, restore.Status.CompletionTime)
+		// The previous store is kept without an opt-in.
+		assert.Equal(t, "old-store-id", restore.Status.PreviousStoreID)
+		assert.Nil(t, restore.Status.PreviousStoreDeletionTime)
+
+		require.NoError(t, storeClient.Get(ctx, types.NamespacedName{Name: "store"}, store))
+		assert.Equal(t, "new-store-id", store.Status.StoreID)
+		assert.Equal(t, "new-model-id", store.Status.AuthorizationModelID)
+	})
+
+	t.Run("should wait for the snapshot to complete", func(t *testing.T) {
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+
+		restore := &securityv1alpha1.StoreRestore{Spec: securityv1alpha1.StoreRestoreSpec{SnapshotName: "snapshot"}}
+		res, err := subroutine.NewStoreRestoreSubroutine(nil, mgr, nil, "platform-mesh-system", 0).Process(ctx, restore)
+		require.NoError(t, err)
+		assert.True(t, res.IsPending())
+	})
+
+	t.Run("should not import twice after the store update failed", func(t *testing.T) {
+		now := metav1.Now()
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			Spec: securityv1alpha1.StoreSnapshotSpec{
+				StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"},
+			},
+			Status: securityv1alpha1.StoreSnapshotStatus{CompletionTime: &now},
+		}
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "snapshot"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+			snapshot.DeepCopyInto(o.(*securityv1alpha1.StoreSnapshot))
+			return nil
+		})
+		expectAccountInfo(cl)
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+		expectStore(t, mgr, "new-store-id", "new-model-id")
+
+		restore := &securityv1alpha1.StoreRestore{
+			Spec:   securityv1alpha1.StoreRestoreSpec{SnapshotName: "snapshot"},
+			Status: securityv1alpha1.StoreRestoreStatus{StoreID: "new-store-id", AuthorizationModelID: "new-model-id"},
+		}
+		_, err := subroutine.NewStoreRestoreSubroutine(nil, mgr, nil, "platform-mesh-system", 0).Process(ctx, restore)
+		require.NoError(t, err)
+		assert.NotNil(t, restore.Status.CompletionTime)
+	})
+
+	t.Run("should not restore snapshots of stores of other orgs", func(t *testing.T) {
+		now := metav1.Now()
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			Spec: securityv1alpha1.StoreSnapshotSpec{
+				StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "other", Cluster: "store-cluster"},
+			},
+			Status: securityv1alpha1.StoreSnapshotStatus{CompletionTime: &now},
+		}
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "snapshot"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+			snapshot.DeepCopyInto(o.(*securityv1alpha1.StoreSnapshot))
+			return nil
+		})
+		expectAccountInfo(cl)
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+
+		restore := &securityv1alpha1.StoreRestore{Spec: securityv1alpha1.StoreRestoreSpec{SnapshotName: "snapshot"}}
+		res, err := subroutine.NewStoreRestoreSubroutine(nil, mgr, nil, "platform-mesh-system", 0).Process(ctx, restore)
+		require.NoError(t, err)
+		assert.True(t, res.IsStop())
+		assert.Nil(t, restore.Status.CompletionTime)
+	})
+}
+
+func TestStoreRestoreDeletesPreviousStore(t *testing.T) {
+	completedRestore := func(completedAgo time.Duration) *securityv1alpha1.StoreRestore {
+		completion := metav1.NewTime(time.Now().Add(-completedAgo))
+		return &securityv1alpha1.StoreRestore{
+			Spec: securityv1alpha1.StoreRestoreSpec{
+				SnapshotName:             "snapshot",
+				DeletePreviousStoreAfter: &metav1.Duration{Duration: time.Hour},
+			},
+			Status: securityv1alpha1.StoreRestoreStatus{
+				StoreID:         "new-store-id",
+				PreviousStoreID: "old-store-id",
+				CompletionTime:  &completion,
+			},
+		}
+	}
+
+	t.Run("should keep the previous store during the grace period", func(t *testing.T) {
+		restore := completedRestore(time.Minute)
+		res, err := subroutine.NewStoreRestoreSubroutine(nil, nil, nil, "platform-mesh-system", 0).Process(context.Background(), restore)
+		require.NoError(t, err)
+		assert.Positive(t, res.Requeue())
+		assert.Nil(t, restore.Status.PreviousStoreDeletionTime)
+	})
+
+	t.Run("should delete the previous store after the grace period", func(t *testing.T) {
+		fga := mocks.NewMockOpenFGAServiceClient(t)
+		fga.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "old-store-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil).Once()
+
+		restore := completedRestore(2 * time.Hour)
+		_, err := subroutine.NewStoreRestoreSubroutine(fga, nil, nil, "platform-mesh-system", 0).Process(context.Background(), restore)
+		require.NoError(t, err)
+		assert.NotNil(t, restore.Status.PreviousStoreDeletionTime)
+
+		// The previous store is only deleted once.
+		_, err = subroutine.NewStoreRestoreSubroutine(fga, nil, nil, "platform-mesh-system", 0).Process(context.Background(), restore)
+		require.NoError(t, err)
+	})
+
+	t.Run("should keep the previous store without an opt-in", func(t *testing.T) {
+		restore := completedRestore(2 * time.Hour)
+		restore.Spec.DeletePreviousStoreAfter = nil
+		_, err := subroutine.NewStoreRestoreSubroutine(nil, nil, nil, "platform-mesh-system", 0).Process(context.Background(), restore)
+		require.NoError(t, err)
+		assert.Nil(t, restore.Status.PreviousStoreDeletionTime)
+	})
+}
@@ -0,0 +1,247 @@
+package subroutine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	accountv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	StoreSnapshotFinalizer = "core.platform-mesh.io/store-snapshot-finalizer"
+
+	storeSnapshotLabelKey        = "core.platform-mesh.io/store-snapshot"
+	storeSnapshotClusterLabelKey = "core.platform-mesh.io/store-snapshot-cluster"
+	storeSnapshotDataKey         = "snapshot.json.gz"
+
+	// maxStoreSnapshotSecretSize keeps every Secret of a snapshot well below
+	// the 1MiB object size limit of etcd.
+	maxStoreSnapshotSecretSize = 512 * 1024
+)
+
+// storeSnapshotSubroutine reads the authorization model and all tuples of a
+// Store and writes them gzip compressed into a set of Secrets in the
+// operator's namespace. Snapshots live in kcp workspaces while the Secrets
+// live in the runtime cluster, so they are cleaned up by a finalizer.
+type storeSnapshotSubroutine struct {
+	fga           openfgav1.OpenFGAServiceClient
+	mgr           mcmanager.Manager
+	runtimeClient client.Client
+	namespace     string
+}
+
+func NewStoreSnapshotSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, runtimeClient client.Client, namespace string) *storeSnapshotSubroutine {
+	return &storeSnapshotSubroutine{
+		fga:           fga,
+		mgr:           mgr,
+		runtimeClient: runtimeClient,
+		namespace:     namespace,
+	}
+}
+
+var (
+	_ subroutines.Processor = &storeSnapshotSubroutine{}
+	_ subroutines.Finalizer = &storeSnapshotSubroutine{}
+)
+
+// GetName implements subroutines.Subroutine.
+func (s *storeSnapshotSubroutine) GetName() string { return "StoreSnapshotSubroutine" }
+
+// Finalizers implements subroutines.Finalizer.
+func (s *storeSnapshotSubroutine) Finalizers(_ client.Object) []string {
+	return []string{StoreSnapshotFinalizer}
+}
+
+// Finalize implements subroutines.Finalizer.
+func (s *storeSnapshotSubroutine) Finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	snapshot := obj.(*securityv1alpha1.StoreSnapshot)
+
+	if err := s.deleteSecrets(ctx, snapshot); err != nil {
+		return subroutines.OK(), err
+	}
+	return dryrun.Finalized(), nil
+}
+
+// Process implements subroutines.Processor.
+func (s *storeSnapshotSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	snapshot := obj.(*securityv1alpha1.StoreSnapshot)
+
+	if snapshot.Status.CompletionTime != nil {
+		return subroutines.OK(), nil
+	}
+
+	cluster, err := s.mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+	ownStore, err := isOrgStoreRef(ctx, cluster.GetClient(), snapshot.Spec.StoreRef)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+	if !ownStore {
+		return subroutines.Stop(fmt.Sprintf("Store %s is not the store of this org", snapshot.Spec.StoreRef.Name)), nil
+	}
+
+	store, err := getReferencedStore(ctx, s.mgr, snapshot.Spec.StoreRef)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("getting Store %s: %w", snapshot.Spec.StoreRef.Name, err)
+	}
+	if store.Status.StoreID == "" || store.Status.AuthorizationModelID == "" {
+		return subroutines.Pending(10*time.Second, fmt.Sprintf("Store %s has no authorization model yet", store.Name)), nil
+	}
+
+	archive, err := fga.ExportStore(ctx, s.fga, store.Name, store.Status.StoreID, store.Status.AuthorizationModelID, log)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	data, err := encodeStoreArchive(archive)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	// Secrets of an earlier attempt that failed half-way are replaced as a
+	// whole, so a snapshot never mixes data of two exports.
+	if err := s.deleteSecrets(ctx, snapshot); err != nil {
+		return subroutines.OK(), err
+	}
+
+	labels, err := storeSnapshotLabels(ctx, snapshot)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	var names []string
+	for i := 0; i == 0 || i*maxStoreSnapshotSecretSize < len(data); i++ {
+		part := data[i*maxStoreSnapshotSecretSize : min((i+1)*maxStoreSnapshotSecretSize, len(data))]
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("store-snapshot-%s-%s-%d", labels[storeSnapshotClusterLabelKey], snapshot.Name, i),
+				Namespace: s.namespace,
+				Labels:    labels,
+			},
+			Data: map[string][]byte{storeSnapshotDataKey: part},
+		}
+		if dryrun.Enabled() {
+			dryrun.Record(ctx, dryrun.SystemKubernetes, "CreateSecret", secret.Namespace+"/"+secret.Name, map[string]int{"bytes": len(part)})
+			continue
+		}
+		if err := s.runtimeClient.Create(ctx, secret); err != nil {
+			return subroutines.OK(), fmt.Errorf("creating snapshot secret %s: %w", secret.Name, err)
+		}
+		names = append(names, secret.Name)
+	}
+
+	// The snapshot is not completed without its Secrets.
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
+	}
+
+	now := metav1.Now()
+	snapshot.Status.AuthorizationModelID = store.Status.AuthorizationModelID
+	snapshot.Status.TupleCount = len(archive.Tuples)
+	snapshot.Status.SizeBytes = int64(len(data))
+	snapshot.Status.Secrets = names
+	snapshot.Status.CompletionTime = &now
+	log.Info().Int("tuples", len(archive.Tuples)).Int("bytes", len(data)).Msg("Completed store snapshot")
+
+	return subroutines.OK(), nil
+}
+
+func (s *storeSnapshotSubroutine) deleteSecrets(ctx context.Context, snapshot *securityv1alpha1.StoreSnapshot) error {
+	labels, err := storeSnapshotLabels(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	if dryrun.Enabled() {
+		dryrun.Record(ctx, dryrun.SystemKubernetes, "DeleteSecrets", s.namespace, labels)
+		return nil
+	}
+	if err := s.runtimeClient.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(s.namespace), client.MatchingLabels(labels)); err != nil {
+		return fmt.Errorf("deleting snapshot secrets: %w", err)
+	}
+	return nil
+}
+
+// storeSnapshotLabels returns the labels identifying the Secrets of a
+// snapshot. Snapshots of different workspaces may share a name, so the
+// cluster is part of them.
+func storeSnapshotLabels(ctx context.Context, snapshot *securityv1alpha1.StoreSnapshot) (map[string]string, error) {
+	clusterName, ok := mccontext.ClusterFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unable to get cluster key from context")
+	}
+	return map[string]string{
+		storeSnapshotLabelKey:        snapshot.Name,
+		storeSnapshotClusterLabelKey: clusterName.String(),
+	}, nil
+}
+
+// isOrgStoreRef reports whether ref references the Store of the org the
+// workspace of cl belongs to. Snapshots and restores are created by the
+// members of a workspace, so they must not reach into the stores of other
+// orgs.
+func isOrgStoreRef(ctx context.Context, cl client.Client, ref securityv1alpha1.WorkspaceStoreRef) (bool, error) {
+	var accountInfo accountv1alpha1.AccountInfo
+	if err := cl.Get(ctx, types.NamespacedName{Name: "account"}, &accountInfo); err != nil {
+		return false, fmt.Errorf("getting AccountInfo: %w", err)
+	}
+	org := accountInfo.Spec.Organization
+	return org.Name != "" && ref.Name == org.Name && ref.Cluster == org.OriginClusterId, nil
+}
+
+// encodeStoreArchive returns the gzip compressed JSON of an archive.
+func encodeStoreArchive(archive *fga.StoreArchive) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		return nil, fmt.Errorf("encoding store archive: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("compressing store archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// loadStoreSnapshot reads the Secrets of a completed snapshot in order and
+// decodes the archive they contain.
+func loadStoreSnapshot(ctx context.Context, c client.Client, namespace string, snapshot *securityv1alpha1.StoreSnapshot) (*fga.StoreArchive, error) {
+	var data []byte
+	for _, name := range snapshot.Status.Secrets {
+		var secret corev1.Secret
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &secret); err != nil {
+			return nil, fmt.Errorf("getting snapshot secret %s: %w", name, err)
+		}
+		data = append(data, secret.Data[storeSnapshotDataKey]...)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing store archive: %w", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	var archive fga.StoreArchive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("decoding store archive: %w", err)
+	}
+	return &archive, nil
+}
@@ -0,0 +1,108 @@
+package subroutine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// storeSnapshotScheduleSubroutine creates a StoreSnapshot whenever the
+// interval of a StoreSnapshotSchedule elapsed and prunes the snapshots beyond
+// the ones it is told to keep.
+type storeSnapshotScheduleSubroutine struct {
+	mgr mcmanager.Manager
+}
+
+func NewStoreSnapshotScheduleSubroutine(mgr mcmanager.Manager) *storeSnapshotScheduleSubroutine {
+	return &storeSnapshotScheduleSubroutine{mgr: mgr}
+}
+
+var _ subroutines.Processor = &storeSnapshotScheduleSubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (s *storeSnapshotScheduleSubroutine) GetName() string { return "StoreSnapshotScheduleSubroutine" }
+
+// Process implements subroutines.Processor.
+func (s *storeSnapshotScheduleSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	schedule := obj.(*securityv1alpha1.StoreSnapshotSchedule)
+
+	interval := schedule.Spec.Interval.Duration
+	if interval <= 0 {
+		return subroutines.OK(), fmt.Errorf("interval must be positive, got %s", interval)
+	}
+
+	cluster, err := s.mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+	cl := cluster.GetClient()
+
+	now := time.Now()
+	if last := schedule.Status.LastSnapshotTime; last == nil || !now.Before(last.Add(interval)) {
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s-%d", schedule.Name, now.Unix()),
+				Labels: map[string]string{
+					securityv1alpha1.StoreSnapshotScheduleLabelKey: schedule.Name,
+				},
+			},
+			Spec: securityv1alpha1.StoreSnapshotSpec{
+				StoreRef: schedule.Spec.StoreRef,
+			},
+		}
+		if err := cl.Create(ctx, snapshot); err != nil && !kerrors.IsAlreadyExists(err) {
+			return subroutines.OK(), fmt.Errorf("creating StoreSnapshot %s: %w", snapshot.Name, err)
+		}
+		log.Info().Str("snapshot", snapshot.Name).Msg("Created scheduled store snapshot")
+
+		schedule.Status.LastSnapshotName = snapshot.Name
+		schedule.Status.LastSnapshotTime = &metav1.Time{Time: now}
+	}
+
+	if err := s.prune(ctx, cl, schedule); err != nil {
+		return subroutines.OK(), err
+	}
+
+	return subroutines.OKWithRequeue(schedule.Status.LastSnapshotTime.Add(interval).Sub(now)), nil
+}
+
+// prune deletes the oldest snapshots of a schedule until only spec.keep are
+// left.
+func (s *storeSnapshotScheduleSubroutine) prune(ctx context.Context, cl client.Client, schedule *securityv1alpha1.StoreSnapshotSchedule) error {
+	var snapshots securityv1alpha1.StoreSnapshotList
+	if err := cl.List(ctx, &snapshots, client.MatchingLabels{
+		securityv1alpha1.StoreSnapshotScheduleLabelKey: schedule.Name,
+	}); err != nil {
+		return fmt.Errorf("listing StoreSnapshots: %w", err)
+	}
+
+	keep := max(schedule.Spec.Keep, 1)
+	if len(snapshots.Items) <= keep {
+		return nil
+	}
+
+	slices.SortFunc(snapshots.Items, func(a, b securityv1alpha1.StoreSnapshot) int {
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, snapshot := range snapshots.Items[:len(snapshots.Items)-keep] {
+		if err := cl.Delete(ctx, &snapshot); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting StoreSnapshot %s: %w", snapshot.Name, err)
+		}
+	}
+	return nil
+}
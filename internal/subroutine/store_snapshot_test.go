@@ -0,0 +1,245 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStoreSnapshotGetName(t *testing.T) {
+	s := subroutine.NewStoreSnapshotSubroutine(nil, nil, nil, "")
+	assert.Equal(t, "StoreSnapshotSubroutine", s.GetName())
+	assert.Equal(t, []string{subroutine.StoreSnapshotFinalizer}, s.Finalizers(nil))
+}
+
+// expectAccountInfo lets cl return the AccountInfo of a workspace in the org
+// owning the Store referenced by the tests.
+func expectAccountInfo(cl *mocks.MockClient) {
+	cl.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "account"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+		o.(*accountsv1alpha1.AccountInfo).Spec.Organization = accountsv1alpha1.AccountLocation{Name: "store", OriginClusterId: "store-cluster"}
+		return nil
+	})
+}
+
+// expectSnapshotWorkspace makes the manager return the cluster of the
+// workspace the snapshot lives in from the context.
+func expectSnapshotWorkspace(t *testing.T, mgr *mocks.MockManager) {
+	cl := mocks.NewMockClient(t)
+	expectAccountInfo(cl)
+	cluster := mocks.NewMockCluster(t)
+	cluster.EXPECT().GetClient().Return(cl)
+	mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+}
+
+// expectStore makes the manager return a Store with the given IDs from the
+// store cluster and returns the client of that cluster.
+func expectStore(t *testing.T, mgr *mocks.MockManager, storeID, modelID string) *mocks.MockClient {
+	storeCluster := mocks.NewMockCluster(t)
+	storeClient := mocks.NewMockClient(t)
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("store-cluster")).Return(storeCluster, nil)
+	storeCluster.EXPECT().GetClient().Return(storeClient)
+	storeClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "store"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+		store := o.(*securityv1alpha1.Store)
+		store.Name = "store"
+		store.Status.StoreID = storeID
+		store.Status.AuthorizationModelID = modelID
+		return nil
+	})
+	return storeClient
+}
+
+func TestStoreSnapshotProcess(t *testing.T) {
+	storeRef := securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"}
+	ctx := mccontext.WithCluster(context.Background(), "snapshot-cluster")
+
+	t.Run("should write the snapshot into secrets", func(t *testing.T) {
+		fga := mocks.NewMockOpenFGAServiceClient(t)
+		fga.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+			AuthorizationModel: &openfgav1.AuthorizationModel{Id: "model-id", SchemaVersion: "1.2"},
+		}, nil)
+		fga.EXPECT().Read(mock.Anything, mock.Anything).Return(readResponse(
+			&openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+			&openfgav1.TupleKey{Object: "doc:2", Relation: "viewer", User: "user:bob"},
+		), nil)
+
+		mgr := mocks.NewMockManager(t)
+		expectSnapshotWorkspace(t, mgr)
+		expectStore(t, mgr, "store-id", "model-id")
+
+		var secrets []*corev1.Secret
+		runtimeClient := mocks.NewMockClient(t)
+		runtimeClient.EXPECT().DeleteAllOf(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		runtimeClient.EXPECT().Create(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, o client.Object, _ ...client.CreateOption) error {
+			secrets = append(secrets, o.(*corev1.Secret))
+			return nil
+		})
+
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapshot"},
+			Spec:       securityv1alpha1.StoreSnapshotSpec{StoreRef: storeRef},
+		}
+		_, err := subroutine.NewStoreSnapshotSubroutine(fga, mgr, runtimeClient, "platform-mesh-system").Process(ctx, snapshot)
+		require.NoError(t, err)
+
+		require.Len(t, secrets, 1)
+		assert.Equal(t, "store-snapshot-snapshot-cluster-snapshot-0", secrets[0].Name)
+		assert.Equal(t, "platform-mesh-system", secrets[0].Namespace)
+		assert.Equal(t, []string{secrets[0].Name}, snapshot.Status.Secrets)
+		assert.Equal(t, "model-id", snapshot.Status.AuthorizationModelID)
+		assert.Equal(t, 2, snapshot.Status.TupleCount)
+		assert.Positive(t, snapshot.Status.SizeBytes)
+		assert.NotNil(t, snapshot.Status.CompletionTime)
+	})
+
+	t.Run("should wait for the store to have a model", func(t *testing.T) {
+		mgr := mocks.NewMockManager(t)
+		expectSnapshotWorkspace(t, mgr)
+		expectStore(t, mgr, "store-id", "")
+
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapshot"},
+			Spec:       securityv1alpha1.StoreSnapshotSpec{StoreRef: storeRef},
+		}
+		res, err := subroutine.NewStoreSnapshotSubroutine(nil, mgr, nil, "platform-mesh-system").Process(ctx, snapshot)
+		require.NoError(t, err)
+		assert.True(t, res.IsPending())
+		assert.Nil(t, snapshot.Status.CompletionTime)
+	})
+
+	t.Run("should not take snapshots of stores of other orgs", func(t *testing.T) {
+		mgr := mocks.NewMockManager(t)
+		expectSnapshotWorkspace(t, mgr)
+
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapshot"},
+			Spec: securityv1alpha1.StoreSnapshotSpec{
+				StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "other", Cluster: "store-cluster"},
+			},
+		}
+		res, err := subroutine.NewStoreSnapshotSubroutine(nil, mgr, nil, "platform-mesh-system").Process(ctx, snapshot)
+		require.NoError(t, err)
+		assert.True(t, res.IsStop())
+		assert.Nil(t, snapshot.Status.CompletionTime)
+	})
+
+	t.Run("should not take a completed snapshot again", func(t *testing.T) {
+		now := metav1.Now()
+		snapshot := &securityv1alpha1.StoreSnapshot{
+			Status: securityv1alpha1.StoreSnapshotStatus{CompletionTime: &now},
+		}
+		_, err := subroutine.NewStoreSnapshotSubroutine(nil, nil, nil, "platform-mesh-system").Process(ctx, snapshot)
+		require.NoError(t, err)
+	})
+}
+
+func TestStoreSnapshotFinalize(t *testing.T) {
+	runtimeClient := mocks.NewMockClient(t)
+	runtimeClient.EXPECT().DeleteAllOf(mock.Anything, &corev1.Secret{}, mock.Anything).RunAndReturn(func(_ context.Context, _ client.Object, opts ...client.DeleteAllOfOption) error {
+		var options client.DeleteAllOfOptions
+		options.ApplyOptions(opts)
+		assert.Equal(t, "platform-mesh-system", options.Namespace)
+		assert.Equal(t, "core.platform-mesh.io/store-snapshot=snapshot,core.platform-mesh.io/store-snapshot-cluster=snapshot-cluster", options.LabelSelector.String())
+		return nil
+	})
+
+	ctx := mccontext.WithCluster(context.Background(), "snapshot-cluster")
+	snapshot := &securityv1alpha1.StoreSnapshot{ObjectMeta: metav1.ObjectMeta{Name: "snapshot"}}
+	_, err := subroutine.NewStoreSnapshotSubroutine(nil, nil, runtimeClient, "platform-mesh-system").Finalize(ctx, snapshot)
+	require.NoError(t, err)
+}
+
+func TestStoreSnapshotScheduleProcess(t *testing.T) {
+	storeRef := securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"}
+
+	snapshotAt := func(name string, age time.Duration) securityv1alpha1.StoreSnapshot {
+		return securityv1alpha1.StoreSnapshot{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		}}
+	}
+
+	t.Run("should create a snapshot when due and prune old ones", func(t *testing.T) {
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().Create(mock.Anything, mock.MatchedBy(func(s *securityv1alpha1.StoreSnapshot) bool {
+			return s.Labels[securityv1alpha1.StoreSnapshotScheduleLabelKey] == "schedule" && s.Spec.StoreRef == storeRef
+		})).Return(nil)
+		cl.EXPECT().List(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, l client.ObjectList, _ ...client.ListOption) error {
+			l.(*securityv1alpha1.StoreSnapshotList).Items = []securityv1alpha1.StoreSnapshot{
+				snapshotAt("new", 0),
+				snapshotAt("oldest", 3*time.Hour),
+				snapshotAt("old", 2*time.Hour),
+			}
+			return nil
+		})
+		cl.EXPECT().Delete(mock.Anything, mock.MatchedBy(func(s *securityv1alpha1.StoreSnapshot) bool {
+			return s.Name == "oldest"
+		})).Return(nil)
+
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+
+		last := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		schedule := &securityv1alpha1.StoreSnapshotSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "schedule"},
+			Spec: securityv1alpha1.StoreSnapshotScheduleSpec{
+				StoreRef: storeRef,
+				Interval: metav1.Duration{Duration: time.Hour},
+				Keep:     2,
+			},
+			Status: securityv1alpha1.StoreSnapshotScheduleStatus{LastSnapshotTime: &last},
+		}
+		res, err := subroutine.NewStoreSnapshotScheduleSubroutine(mgr).Process(context.Background(), schedule)
+		require.NoError(t, err)
+		assert.Contains(t, schedule.Status.LastSnapshotName, "schedule-")
+		assert.InDelta(t, time.Hour, res.Requeue(), float64(time.Second))
+	})
+
+	t.Run("should requeue until the next snapshot is due", func(t *testing.T) {
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().List(mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+
+		last := metav1.NewTime(time.Now().Add(-20 * time.Minute))
+		schedule := &securityv1alpha1.StoreSnapshotSchedule{
+			ObjectMeta: metav1.ObjectMeta{Name: "schedule"},
+			Spec: securityv1alpha1.StoreSnapshotScheduleSpec{
+				StoreRef: storeRef,
+				Interval: metav1.Duration{Duration: time.Hour},
+				Keep:     2,
+			},
+			Status: securityv1alpha1.StoreSnapshotScheduleStatus{LastSnapshotName: "previous", LastSnapshotTime: &last},
+		}
+		res, err := subroutine.NewStoreSnapshotScheduleSubroutine(mgr).Process(context.Background(), schedule)
+		require.NoError(t, err)
+		assert.Equal(t, "previous", schedule.Status.LastSnapshotName)
+		assert.InDelta(t, 40*time.Minute, res.Requeue(), float64(time.Second))
+	})
+
+	t.Run("should reject an empty interval", func(t *testing.T) {
+		schedule := &securityv1alpha1.StoreSnapshotSchedule{}
+		_, err := subroutine.NewStoreSnapshotScheduleSubroutine(nil).Process(context.Background(), schedule)
+		assert.Error(t, err)
+	})
+}
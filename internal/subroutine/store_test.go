@@ -34,10 +34,11 @@ func TestFinalizers(t *testing.T) {
 
 func TestProcess(t *testing.T) {
 	tests := []struct {
-		name        string
-		store       *securityv1alpha1.Store
-		fgaMocks    func(*mocks.MockOpenFGAServiceClient)
-		expectError bool
+		name            string
+		store           *securityv1alpha1.Store
+		fgaMocks        func(*mocks.MockOpenFGAServiceClient)
+		expectError     bool
+		expectedStoreID string
 	}{
 		{
 			name: "should try and create the store if it does not exist",
@@ -68,6 +69,114 @@ func TestProcess(t *testing.T) {
 				}, nil)
 			},
 		},
+		{
+			name: "should find the store on a later page",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().ListStores(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListStoresRequest) bool {
+					return req.ContinuationToken == "" && req.Name == "store"
+				})).Return(&openfgav1.ListStoresResponse{
+					Stores:            []*openfgav1.Store{{Name: "other", Id: "other-id"}},
+					ContinuationToken: "next",
+				}, nil)
+				fga.EXPECT().ListStores(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListStoresRequest) bool {
+					return req.ContinuationToken == "next"
+				})).Return(&openfgav1.ListStoresResponse{
+					Stores: []*openfgav1.Store{{Name: "store", Id: "id"}},
+				}, nil)
+			},
+			expectedStoreID: "id",
+		},
+		{
+			name: "should refuse to adopt one of several stores with the same name",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+					Stores: []*openfgav1.Store{{Name: "store", Id: "first"}, {Name: "store", Id: "second"}},
+				}, nil)
+			},
+			expectError: true,
+		},
+		{
+			name: "should create the store with the qualified store name",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					StoreName: "cluster-a-store",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+					Stores: []*openfgav1.Store{{Name: "store", Id: "other-id"}},
+				}, nil)
+				fga.EXPECT().CreateStore(mock.Anything, &openfgav1.CreateStoreRequest{Name: "cluster-a-store"}).Return(&openfgav1.CreateStoreResponse{Id: "id"}, nil)
+			},
+			expectedStoreID: "id",
+		},
+		{
+			name: "should adopt the store of .spec.storeId without renaming it",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					StoreID: "adopted",
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID:              "previous",
+					AuthorizationModelID: "previous-model",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().GetStore(mock.Anything, &openfgav1.GetStoreRequest{StoreId: "adopted"}).Return(&openfgav1.GetStoreResponse{Name: "legacy"}, nil).Twice()
+			},
+			expectedStoreID: "adopted",
+		},
+		{
+			name: "should fail to adopt a store that does not exist",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					StoreID: "adopted",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().GetStore(mock.Anything, &openfgav1.GetStoreRequest{StoreId: "adopted"}).Return(nil, status.Error(codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found), "not found"))
+			},
+			expectError: true,
+		},
+		{
+			name: "should rename an adopted store if a store name is set",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					StoreID:   "adopted",
+					StoreName: "qualified",
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "adopted",
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().GetStore(mock.Anything, &openfgav1.GetStoreRequest{StoreId: "adopted"}).Return(&openfgav1.GetStoreResponse{Name: "legacy"}, nil)
+				fga.EXPECT().UpdateStore(mock.Anything, &openfgav1.UpdateStoreRequest{StoreId: "adopted", Name: "qualified"}).Return(&openfgav1.UpdateStoreResponse{}, nil)
+			},
+			expectedStoreID: "adopted",
+		},
 		{
 			name: "should verify the store if .status.storeId is set",
 			store: &securityv1alpha1.Store{
@@ -172,6 +281,9 @@ func TestProcess(t *testing.T) {
 			} else {
 				assert.Nil(t, err)
 			}
+			if test.expectedStoreID != "" {
+				assert.Equal(t, test.expectedStoreID, test.store.Status.StoreID)
+			}
 
 		})
 	}
@@ -183,6 +295,7 @@ func TestFinalize(t *testing.T) {
 		store          *securityv1alpha1.Store
 		fgaMocks       func(*mocks.MockOpenFGAServiceClient)
 		kcpHelperMocks func(*mocks.MockLister)
+		scratchStores  []*openfgav1.Store
 		expectError    bool
 	}{
 		{
@@ -254,6 +367,56 @@ func TestFinalize(t *testing.T) {
 				fga.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "id"}).Return(nil, nil)
 			},
 		},
+		{
+			name: "should keep the store with the Retain deletion policy",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					DeletionPolicy: securityv1alpha1.StoreDeletionPolicyRetain,
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "id",
+				},
+			},
+			kcpHelperMocks: func(kcpHelper *mocks.MockLister) {
+				kcpHelper.EXPECT().List(mock.Anything, mock.Anything).Return(nil)
+			},
+		},
+		{
+			name: "should release the store with the Orphan deletion policy even if authorizationModels reference it",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					DeletionPolicy: securityv1alpha1.StoreDeletionPolicyOrphan,
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "id",
+				},
+			},
+		},
+		{
+			name: "should delete leftover scratch stores of model tests with any deletion policy",
+			store: &securityv1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "store",
+					UID:  "store-uid",
+				},
+				Spec: securityv1alpha1.StoreSpec{
+					DeletionPolicy: securityv1alpha1.StoreDeletionPolicyOrphan,
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "id",
+				},
+			},
+			scratchStores: []*openfgav1.Store{{Id: "scratch-id", Name: "__model-tests-store-uid"}},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().DeleteStore(mock.Anything, &openfgav1.DeleteStoreRequest{StoreId: "scratch-id"}).Return(&openfgav1.DeleteStoreResponse{}, nil)
+			},
+		},
 		{
 			name: "should reconcile successfully if store is not found with the .status.storeId",
 			store: &securityv1alpha1.Store{
@@ -297,6 +460,9 @@ func TestFinalize(t *testing.T) {
 			if test.fgaMocks != nil {
 				test.fgaMocks(fga)
 			}
+			if test.store.Status.StoreID != "" {
+				fga.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{Stores: test.scratchStores}, nil)
+			}
 
 			manager := mocks.NewMockManager(t)
 			kcpHelper := mocks.NewMockLister(t)
@@ -0,0 +1,47 @@
+package subroutine
+
+import (
+	"context"
+	"fmt"
+
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const tupleChangeEventReason = "TuplesChanged"
+
+// recordTupleChanges records a reconciliation that added or removed tuples
+// in the status of objects that have a LastTupleChange and as an Event.
+// Reconciliations without changes and dry runs leave the last summary in
+// place.
+func recordTupleChanges(ctx context.Context, mgr mcmanager.Manager, obj client.Object, added, removed int) error {
+	if (added == 0 && removed == 0) || dryrun.Enabled() {
+		return nil
+	}
+
+	summary := &securityv1alpha1.TupleChangeSummary{
+		Added:          added,
+		Removed:        removed,
+		LastChangeTime: metav1.Now(),
+	}
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		o.Status.LastTupleChange = summary
+	case *securityv1alpha1.AuthorizationModel:
+		o.Status.LastTupleChange = summary
+	case *securityv1alpha1.APIExportPolicy:
+		o.Status.LastTupleChange = summary
+	}
+
+	cluster, err := mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+	cluster.GetEventRecorder("security-operator").Eventf(obj, nil, corev1.EventTypeNormal, tupleChangeEventReason, "WriteTuples", "Added %d and removed %d tuples", added, removed)
+	return nil
+}
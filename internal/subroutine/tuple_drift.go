@@ -0,0 +1,191 @@
+package subroutine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tupleDriftSubroutine periodically reads the tuples of a Store or
+// AuthorizationModel back from OpenFGA and repairs changes that were made
+// out-of-band, e.g. through the fga CLI or a database restore. Only the
+// objects of managed tuples are read, unless a TupleOwnership requires
+// reading the whole store to find owned tuples that are not managed.
+type tupleDriftSubroutine struct {
+	fga            openfgav1.OpenFGAServiceClient
+	mgr            mcmanager.Manager
+	lister         iclient.Lister
+	interval       time.Duration
+	writeChunkSize int
+}
+
+func NewTupleDriftSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, lister iclient.Lister, interval time.Duration, writeChunkSize int) *tupleDriftSubroutine {
+	return &tupleDriftSubroutine{
+		fga:            fga,
+		mgr:            mgr,
+		lister:         lister,
+		interval:       interval,
+		writeChunkSize: writeChunkSize,
+	}
+}
+
+var _ subroutines.Processor = &tupleDriftSubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (d *tupleDriftSubroutine) GetName() string { return "TupleDriftSubroutine" }
+
+// Process implements subroutines.Processor.
+func (d *tupleDriftSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	var ownership *securityv1alpha1.TupleOwnership
+	var conditions *[]metav1.Condition
+	var lastCheck **metav1.Time
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		ownership = o.Spec.TupleOwnership
+		conditions = &o.Status.Conditions
+		lastCheck = &o.Status.LastDriftCheckTime
+	case *securityv1alpha1.AuthorizationModel:
+		ownership = o.Spec.TupleOwnership
+		conditions = &o.Status.Conditions
+		lastCheck = &o.Status.LastDriftCheckTime
+	}
+
+	// Reading the tuples back is expensive for large stores, so it only
+	// happens once per interval however often the object is reconciled.
+	if *lastCheck != nil {
+		if wait := time.Until((*lastCheck).Add(d.interval)); wait > 0 {
+			return subroutines.OKWithRequeue(wait), nil
+		}
+	}
+
+	store, err := storeForObject(ctx, d.mgr, obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	managedTuples, err := getManagedTuples(obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+	coOwners, err := getTupleCoOwners(ctx, d.lister, obj, store)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	tm := fga.NewTupleManager(d.fga, store.Status.StoreID, store.Status.AuthorizationModelID, log, fga.WithWriteChunkSize(d.writeChunkSize))
+	managedByKey := tuplesByKey(managedTuples)
+	var storedTuples []securityv1alpha1.Tuple
+	if ownership != nil {
+		// Owned tuples that are not managed can only be found by reading the
+		// whole store, which is why ownership is opt-in.
+		storedTuples, err = tm.ListWithFilter(ctx, func(t securityv1alpha1.Tuple) bool {
+			_, managed := managedByKey[keyOf(t)]
+			return managed || ownership.Owns(t)
+		})
+	} else {
+		storedTuples, err = readStoredTuples(ctx, tm, managedByKey)
+	}
+	if err != nil {
+		return subroutines.OK(), fmt.Errorf("reading tuples from store: %w", err)
+	}
+
+	storedByKey := tuplesByKey(storedTuples)
+	var missing []securityv1alpha1.Tuple
+	for _, tuple := range managedTuples {
+		if stored, ok := storedByKey[keyOf(tuple)]; !ok || !stored.Equal(tuple) {
+			missing = append(missing, tuple)
+		}
+	}
+
+	// Stored tuples with the key of a managed tuple but a different condition
+	// have to be deleted before the managed tuple can be written again.
+	// Tuples other objects manage are not touched.
+	var changed, unmanaged []securityv1alpha1.Tuple
+	for _, tuple := range storedTuples {
+		managed, ok := managedByKey[keyOf(tuple)]
+		switch {
+		case ok && managed.Equal(tuple), coOwners.manage(tuple):
+		case ok:
+			changed = append(changed, tuple)
+		default:
+			unmanaged = append(unmanaged, tuple)
+		}
+	}
+
+	if err := tm.Delete(ctx, append(changed, unmanaged...)); err != nil {
+		return subroutines.OK(), fmt.Errorf("deleting drifted tuples: %w", err)
+	}
+	if err := tm.Apply(ctx, missing); err != nil {
+		return subroutines.OK(), fmt.Errorf("re-applying missing tuples: %w", err)
+	}
+
+	condition := metav1.Condition{
+		Type:               securityv1alpha1.DriftedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "InSync",
+		Message:            "Tuples in the store match the managed tuples",
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	if len(missing) > 0 || len(changed) > 0 || len(unmanaged) > 0 {
+		log.Info().Int("missing", len(missing)).Int("changed", len(changed)).Int("unmanaged", len(unmanaged)).Msg("Corrected tuple drift")
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "DriftCorrected"
+		condition.Message = fmt.Sprintf("Re-applied %d missing tuples, replaced %d tuples with a changed condition and deleted %d unmanaged tuples", len(missing)-len(changed), len(changed), len(unmanaged))
+	}
+	meta.SetStatusCondition(conditions, condition)
+	// Tuples shared with co-owners are only reported here, as the tuple
+	// subroutine only looks co-owners up when it removes tuples.
+	setSharedTuples(obj, coOwners, managedTuples)
+	now := metav1.Now()
+	*lastCheck = &now
+
+	return subroutines.OKWithRequeue(d.interval), nil
+}
+
+// readStoredTuples reads the tuples with the key of a managed tuple from the
+// store, object by object.
+func readStoredTuples(ctx context.Context, tm *fga.TupleManager, managedByKey map[tupleKey]securityv1alpha1.Tuple) ([]securityv1alpha1.Tuple, error) {
+	objects := make([]string, 0, len(managedByKey))
+	for key := range managedByKey {
+		objects = append(objects, key.object)
+	}
+	slices.Sort(objects)
+
+	var result []securityv1alpha1.Tuple
+	for _, object := range slices.Compact(objects) {
+		tuples, err := tm.ListWithKey(ctx, &openfgav1.ReadRequestTupleKey{Object: object})
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range tuples {
+			if _, managed := managedByKey[keyOf(tuple)]; managed {
+				result = append(result, tuple)
+			}
+		}
+	}
+	return result, nil
+}
+
+// tuplesByKey indexes tuples by key. OpenFGA stores a single tuple per key.
+func tuplesByKey(tuples []securityv1alpha1.Tuple) map[tupleKey]securityv1alpha1.Tuple {
+	byKey := make(map[tupleKey]securityv1alpha1.Tuple, len(tuples))
+	for _, tuple := range tuples {
+		byKey[keyOf(tuple)] = tuple
+	}
+	return byKey
+}
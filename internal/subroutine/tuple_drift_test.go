@@ -0,0 +1,244 @@
+package subroutine_test
+
+import (
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTupleDriftGetName(t *testing.T) {
+	subroutine := subroutine.NewTupleDriftSubroutine(nil, nil, nil, time.Minute, 0)
+	assert.Equal(t, "TupleDriftSubroutine", subroutine.GetName())
+}
+
+func readResponse(tuples ...*openfgav1.TupleKey) *openfgav1.ReadResponse {
+	resp := &openfgav1.ReadResponse{}
+	for _, key := range tuples {
+		resp.Tuples = append(resp.Tuples, &openfgav1.Tuple{Key: key})
+	}
+	return resp
+}
+
+func TestTupleDriftProcess(t *testing.T) {
+	tests := []struct {
+		name            string
+		store           *securityv1alpha1.Store
+		fgaMocks        func(*mocks.MockOpenFGAServiceClient)
+		expectError     bool
+		expectCondition metav1.ConditionStatus
+		expectMessage   string
+	}{
+		{
+			name: "should report no drift if the store matches the managed tuples",
+			store: &securityv1alpha1.Store{
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1"},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+					return req.GetTupleKey().GetObject() == "foo"
+				})).Return(readResponse(
+					&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user1"},
+					&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user2"},
+				), nil)
+			},
+			expectCondition: metav1.ConditionFalse,
+		},
+		{
+			name: "should re-apply missing tuples",
+			store: &securityv1alpha1.Store{
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1"},
+						{Object: "foo", Relation: "bar", User: "user2"},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Read(mock.Anything, mock.Anything).Return(readResponse(
+					&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user1"},
+				), nil)
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return len(req.Writes.GetTupleKeys()) == 1 && req.Writes.GetTupleKeys()[0].User == "user2"
+				})).Return(&openfgav1.WriteResponse{}, nil)
+			},
+			expectCondition: metav1.ConditionTrue,
+			expectMessage:   "Re-applied 1 missing tuples, replaced 0 tuples with a changed condition and deleted 0 unmanaged tuples",
+		},
+		{
+			name: "should delete unmanaged tuples matching the ownership filter",
+			store: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					TupleOwnership: &securityv1alpha1.TupleOwnership{
+						ObjectTypes: []string{"role"},
+						Relations:   []string{"assignee"},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+					return req.TupleKey == nil
+				})).Return(readResponse(
+					&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+					&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:mallory"},
+					&openfgav1.TupleKey{Object: "role:admin", Relation: "parent", User: "account:foo"},
+					&openfgav1.TupleKey{Object: "account:foo", Relation: "owner", User: "user:bob"},
+				), nil)
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return len(req.Deletes.GetTupleKeys()) == 1 && req.Deletes.GetTupleKeys()[0].User == "user:mallory"
+				})).Return(&openfgav1.WriteResponse{}, nil)
+			},
+			expectCondition: metav1.ConditionTrue,
+			expectMessage:   "Re-applied 0 missing tuples, replaced 0 tuples with a changed condition and deleted 1 unmanaged tuples",
+		},
+		{
+			name: "should rewrite tuples whose condition was changed",
+			store: &securityv1alpha1.Store{
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{
+							Object:   "foo",
+							Relation: "bar",
+							User:     "user1",
+							Condition: &securityv1alpha1.TupleCondition{
+								Name:    "in_range",
+								Context: &runtime.RawExtension{Raw: []byte(`{"cidr":"10.0.0.0/8"}`)},
+							},
+						},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Read(mock.Anything, mock.Anything).Return(readResponse(
+					&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user1"},
+				), nil)
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return len(req.Deletes.GetTupleKeys()) == 1
+				})).Return(&openfgav1.WriteResponse{}, nil).Once()
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return len(req.Writes.GetTupleKeys()) == 1 && req.Writes.GetTupleKeys()[0].GetCondition().GetName() == "in_range"
+				})).Return(&openfgav1.WriteResponse{}, nil).Once()
+			},
+			expectCondition: metav1.ConditionTrue,
+			expectMessage:   "Re-applied 0 missing tuples, replaced 1 tuples with a changed condition and deleted 0 unmanaged tuples",
+		},
+		{
+			name: "should return an error if reading the store fails",
+			store: &securityv1alpha1.Store{
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1"},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fga := mocks.NewMockOpenFGAServiceClient(t)
+			if test.fgaMocks != nil {
+				test.fgaMocks(fga)
+			}
+
+			subroutine := subroutine.NewTupleDriftSubroutine(fga, mocks.NewMockManager(t), noCoOwners(t), time.Minute, 0)
+
+			res, err := subroutine.Process(storeContext(), test.store)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, time.Minute, res.Requeue())
+
+			condition := meta.FindStatusCondition(test.store.Status.Conditions, securityv1alpha1.DriftedCondition)
+			require.NotNil(t, condition)
+			assert.Equal(t, test.expectCondition, condition.Status)
+			if test.expectMessage != "" {
+				assert.Equal(t, test.expectMessage, condition.Message)
+			}
+			assert.NotNil(t, test.store.Status.LastDriftCheckTime)
+		})
+	}
+}
+
+func TestTupleDriftProcessReadsManagedObjects(t *testing.T) {
+	store := &securityv1alpha1.Store{
+		Status: securityv1alpha1.StoreStatus{
+			StoreID: "store-id",
+			ManagedTuples: []securityv1alpha1.Tuple{
+				{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+				{Object: "role:admin", Relation: "viewer", User: "user:bob"},
+				{Object: "role:viewer", Relation: "assignee", User: "user:bob"},
+			},
+		},
+	}
+
+	// Without a TupleOwnership the store is read once per object of the
+	// managed tuples instead of as a whole.
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	for _, object := range []string{"role:admin", "role:viewer"} {
+		fga.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+			return req.GetTupleKey().GetObject() == object
+		})).Return(readResponse(
+			&openfgav1.TupleKey{Object: object, Relation: "assignee", User: "user:alice"},
+			&openfgav1.TupleKey{Object: object, Relation: "assignee", User: "user:bob"},
+			&openfgav1.TupleKey{Object: object, Relation: "viewer", User: "user:bob"},
+		), nil).Once()
+	}
+
+	subroutine := subroutine.NewTupleDriftSubroutine(fga, mocks.NewMockManager(t), noCoOwners(t), time.Minute, 0)
+
+	_, err := subroutine.Process(storeContext(), store)
+	require.NoError(t, err)
+	condition := meta.FindStatusCondition(store.Status.Conditions, securityv1alpha1.DriftedCondition)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+}
+
+func TestTupleDriftProcessSkipsRecentChecks(t *testing.T) {
+	lastCheck := metav1.NewTime(time.Now().Add(-20 * time.Second))
+	store := &securityv1alpha1.Store{
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:            "store-id",
+			LastDriftCheckTime: &lastCheck,
+		},
+	}
+
+	// Neither OpenFGA nor the cluster are called before the interval passed.
+	subroutine := subroutine.NewTupleDriftSubroutine(mocks.NewMockOpenFGAServiceClient(t), mocks.NewMockManager(t), mocks.NewMockLister(t), time.Minute, 0)
+
+	res, err := subroutine.Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.InDelta(t, 40*time.Second, res.Requeue(), float64(time.Second))
+	assert.Equal(t, &lastCheck, store.Status.LastDriftCheckTime)
+	assert.Empty(t, store.Status.Conditions)
+}
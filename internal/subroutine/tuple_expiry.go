@@ -0,0 +1,145 @@
+package subroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const tupleExpiryEventReason = "TuplesExpired"
+
+// tupleExpirySubroutine sweeps expired tuples of a Store or
+// AuthorizationModel out of OpenFGA and requeues the object when the next
+// managed tuple expires.
+type tupleExpirySubroutine struct {
+	fga            openfgav1.OpenFGAServiceClient
+	mgr            mcmanager.Manager
+	lister         iclient.Lister
+	writeChunkSize int
+}
+
+func NewTupleExpirySubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, lister iclient.Lister, writeChunkSize int) *tupleExpirySubroutine {
+	return &tupleExpirySubroutine{
+		fga:            fga,
+		mgr:            mgr,
+		lister:         lister,
+		writeChunkSize: writeChunkSize,
+	}
+}
+
+var _ subroutines.Processor = &tupleExpirySubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (e *tupleExpirySubroutine) GetName() string { return "TupleExpirySubroutine" }
+
+// Process implements subroutines.Processor.
+func (e *tupleExpirySubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	now := time.Now()
+
+	specTuples, err := desiredTuples(ctx, e.mgr, obj)
+	if err != nil {
+		// The tuples written so far are kept until the sources can be read.
+		if errors.Is(err, errTupleSourceClaimNotAccepted) {
+			return subroutines.Pending(tupleSourceClaimRequeue, err.Error()), nil
+		}
+		return subroutines.OK(), err
+	}
+	managedTuples, err := getManagedTuples(obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	// A managed tuple is only swept if the spec does not declare it with a
+	// later expiry in the meantime.
+	var expired, remaining []securityv1alpha1.Tuple
+	for _, tuple := range managedTuples {
+		if tuple.IsExpired(now) && !slices.ContainsFunc(specTuples, func(s securityv1alpha1.Tuple) bool {
+			return s.Equal(tuple) && !s.IsExpired(now)
+		}) {
+			expired = append(expired, tuple)
+			continue
+		}
+		remaining = append(remaining, tuple)
+	}
+
+	if len(expired) > 0 {
+		store, err := storeForObject(ctx, e.mgr, obj)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+		coOwners, err := getTupleCoOwners(ctx, e.lister, obj, store)
+		if err != nil {
+			return subroutines.OK(), err
+		}
+
+		tm := fga.NewTupleManager(e.fga, store.Status.StoreID, store.Status.AuthorizationModelID, log, fga.WithWriteChunkSize(e.writeChunkSize))
+		// Expired tuples that other objects still manage are only forgotten.
+		if err := tm.Delete(ctx, coOwners.exclusive(expired)); err != nil {
+			var partial *fga.PartialWriteError
+			if errors.As(err, &partial) {
+				err = errors.Join(err, setManagedTuples(obj, withoutTuples(managedTuples, partial.Written)))
+			}
+			return subroutines.OK(), fmt.Errorf("deleting expired tuples: %w", err)
+		}
+
+		if dryrun.Enabled() {
+			// Nothing was deleted, so the expired tuples stay managed.
+			remaining = managedTuples
+		} else {
+			log.Info().Int("count", len(expired)).Msg("Deleted expired tuples")
+
+			cluster, err := e.mgr.ClusterFromContext(ctx)
+			if err != nil {
+				return subroutines.OK(), fmt.Errorf("unable to get cluster from context: %w", err)
+			}
+			cluster.GetEventRecorder("security-operator").Eventf(obj, nil, corev1.EventTypeNormal, tupleExpiryEventReason, "DeleteTuples", "Deleted %d expired tuples", len(expired))
+		}
+	}
+
+	nextExpiry := nextTupleExpiry(specTuples, now)
+	if err := setManagedTuples(obj, remaining); err != nil {
+		return subroutines.OK(), err
+	}
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		o.Status.NextTupleExpiry = nextExpiry
+	case *securityv1alpha1.AuthorizationModel:
+		o.Status.NextTupleExpiry = nextExpiry
+	}
+
+	if nextExpiry == nil {
+		return subroutines.OK(), nil
+	}
+	return subroutines.OKWithRequeue(nextExpiry.Sub(now)), nil
+}
+
+// nextTupleExpiry returns the earliest expiry of the given tuples that lies
+// after now or nil if none of them expires.
+func nextTupleExpiry(tuples []securityv1alpha1.Tuple, now time.Time) *metav1.Time {
+	var next *metav1.Time
+	for _, tuple := range tuples {
+		if tuple.ExpiresAt == nil || tuple.IsExpired(now) {
+			continue
+		}
+		if next == nil || tuple.ExpiresAt.Before(next) {
+			next = tuple.ExpiresAt.DeepCopy()
+		}
+	}
+	return next
+}
@@ -0,0 +1,228 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+)
+
+func TestTupleExpiryGetName(t *testing.T) {
+	subroutine := subroutine.NewTupleExpirySubroutine(nil, nil, nil, 0)
+	assert.Equal(t, "TupleExpirySubroutine", subroutine.GetName())
+}
+
+func TestTupleExpiryProcess(t *testing.T) {
+	// Managed tuples are recorded in their serialized form, which keeps
+	// seconds only.
+	now := time.Now().Truncate(time.Second)
+	past := metav1.NewTime(now.Add(-time.Hour))
+	soon := metav1.NewTime(now.Add(time.Hour))
+	later := metav1.NewTime(now.Add(2 * time.Hour))
+
+	tests := []struct {
+		name               string
+		obj                client.Object
+		fgaMocks           func(*mocks.MockOpenFGAServiceClient)
+		mgrMocks           func(*mocks.MockManager, *events.FakeRecorder)
+		expectError        bool
+		expectManaged      []securityv1alpha1.Tuple
+		expectNextExpiry   *metav1.Time
+		expectRequeue      bool
+		expectEventsLogged int
+	}{
+		{
+			name: "should do nothing without expiring tuples",
+			obj: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1"},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1"},
+					},
+				},
+			},
+			expectManaged: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+			},
+		},
+		{
+			name: "should requeue at the next expiry",
+			obj: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &later},
+						{Object: "foo", Relation: "bar", User: "user2", ExpiresAt: &soon},
+					},
+				},
+			},
+			expectNextExpiry: &soon,
+			expectRequeue:    true,
+		},
+		{
+			name: "should delete expired tuples and record an event",
+			obj: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+						{Object: "foo", Relation: "bar", User: "user2"},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID:              "store-id",
+					AuthorizationModelID: "auth-model-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+						{Object: "foo", Relation: "bar", User: "user2"},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return req.StoreId == "store-id" &&
+						len(req.Deletes.GetTupleKeys()) == 1 &&
+						req.Deletes.GetTupleKeys()[0].User == "user1"
+				})).Return(&openfgav1.WriteResponse{}, nil)
+			},
+			mgrMocks: func(mgr *mocks.MockManager, recorder *events.FakeRecorder) {
+				cluster := mocks.NewMockCluster(t)
+				mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+				cluster.EXPECT().GetEventRecorder(mock.Anything).Return(recorder)
+			},
+			expectManaged: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user2"},
+			},
+			expectEventsLogged: 1,
+		},
+		{
+			name: "should keep expired managed tuples that were extended in the spec",
+			obj: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &soon},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+					},
+				},
+			},
+			expectManaged: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+			},
+			expectNextExpiry: &soon,
+			expectRequeue:    true,
+		},
+		{
+			name: "should delete expired tuples of an authorization model from its store",
+			obj: &securityv1alpha1.AuthorizationModel{
+				Spec: securityv1alpha1.AuthorizationModelSpec{
+					StoreRef: securityv1alpha1.WorkspaceStoreRef{
+						Name:    "store",
+						Cluster: "store-cluster",
+					},
+				},
+				Status: securityv1alpha1.AuthorizationModelStatus{
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return req.StoreId == "store-id"
+				})).Return(&openfgav1.WriteResponse{}, nil)
+			},
+			mgrMocks: func(mgr *mocks.MockManager, recorder *events.FakeRecorder) {
+				storeCluster := mocks.NewMockCluster(t)
+				storeClient := mocks.NewMockClient(t)
+				mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("store-cluster")).Return(storeCluster, nil)
+				storeCluster.EXPECT().GetClient().Return(storeClient)
+				storeClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
+					store := o.(*securityv1alpha1.Store)
+					store.Status.StoreID = "store-id"
+					store.Status.AuthorizationModelID = "auth-model-id"
+					return nil
+				})
+
+				cluster := mocks.NewMockCluster(t)
+				mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+				cluster.EXPECT().GetEventRecorder(mock.Anything).Return(recorder)
+			},
+			expectEventsLogged: 1,
+		},
+		{
+			name: "should return an error if deleting expired tuples fails",
+			obj: &securityv1alpha1.Store{
+				Status: securityv1alpha1.StoreStatus{
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Write(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fga := mocks.NewMockOpenFGAServiceClient(t)
+			if test.fgaMocks != nil {
+				test.fgaMocks(fga)
+			}
+
+			recorder := events.NewFakeRecorder(10)
+			manager := mocks.NewMockManager(t)
+			if test.mgrMocks != nil {
+				test.mgrMocks(manager, recorder)
+			}
+
+			subroutine := subroutine.NewTupleExpirySubroutine(fga, manager, noCoOwners(t), 0)
+
+			res, err := subroutine.Process(storeContext(), test.obj)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, recorder.Events, test.expectEventsLogged)
+
+			var managed []securityv1alpha1.Tuple
+			var nextExpiry *metav1.Time
+			switch o := test.obj.(type) {
+			case *securityv1alpha1.Store:
+				managed, nextExpiry = recordedTuples(t, o.Status.ManagedTupleSet), o.Status.NextTupleExpiry
+			case *securityv1alpha1.AuthorizationModel:
+				managed, nextExpiry = recordedTuples(t, o.Status.ManagedTupleSet), o.Status.NextTupleExpiry
+			}
+			assert.Equal(t, test.expectManaged, managed)
+			assert.Equal(t, test.expectNextExpiry, nextExpiry)
+			if test.expectRequeue {
+				assert.Greater(t, res.Requeue(), time.Duration(0))
+				assert.LessOrEqual(t, res.Requeue(), time.Until(test.expectNextExpiry.Time)+time.Second)
+			} else {
+				assert.Zero(t, res.Requeue())
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package subroutine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/subroutines"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	tupleMigrationPageSize = 100
+	// tupleMigrationPagesPerReconcile bounds the pages migrated within one
+	// reconciliation, so that progress is recorded in the status regularly.
+	tupleMigrationPagesPerReconcile = 10
+	tupleMigrationRequeueInterval   = time.Second
+)
+
+// tupleMigrationSubroutine pages through the stores targeted by a
+// TupleMigration and replaces every tuple changed by its rules. The
+// continuation token of each store is kept in the status, so the migration
+// resumes where it stopped. Tuples managed by the Store or its
+// AuthorizationModels are left alone, as they would be written again or the
+// rewritten ones deleted by their owners.
+type tupleMigrationSubroutine struct {
+	fga            openfgav1.OpenFGAServiceClient
+	mgr            mcmanager.Manager
+	lister         iclient.Lister
+	writeChunkSize int
+}
+
+func NewTupleMigrationSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, lister iclient.Lister, writeChunkSize int) *tupleMigrationSubroutine {
+	return &tupleMigrationSubroutine{
+		fga:            fga,
+		mgr:            mgr,
+		lister:         lister,
+		writeChunkSize: writeChunkSize,
+	}
+}
+
+var _ subroutines.Processor = &tupleMigrationSubroutine{}
+
+// GetName implements subroutines.Subroutine.
+func (t *tupleMigrationSubroutine) GetName() string { return "TupleMigrationSubroutine" }
+
+// Process implements subroutines.Processor.
+func (t *tupleMigrationSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	migration := obj.(*securityv1alpha1.TupleMigration)
+
+	if migration.Status.ObservedGeneration != migration.Generation {
+		migration.Status.ObservedGeneration = migration.Generation
+		migration.Status.Stores = nil
+		migration.Status.CompletionTime = nil
+	}
+	if migration.Status.CompletionTime != nil {
+		return subroutines.OK(), nil
+	}
+
+	if err := fga.ValidateRewriteRules(migration.Spec.Rules); err != nil {
+		return subroutines.OK(), fmt.Errorf("invalid rules: %w", err)
+	}
+
+	// The progress of a migration cannot be recorded without migrating, so
+	// migrations are only previewed tuple by tuple with spec.dryRun.
+	if dryrun.Enabled() && !migration.Spec.DryRun {
+		dryrun.Record(ctx, dryrun.SystemOpenFGA, "MigrateTuples", migration.Name, migration.Spec.Rules)
+		return subroutines.OK(), nil
+	}
+
+	stores, err := t.targetStores(ctx, migration)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+
+	completed := true
+	pages := tupleMigrationPagesPerReconcile
+	for _, target := range stores {
+		if target.store.Status.StoreID == "" {
+			log.Info().Str("store", target.store.Name).Msg("Store has no OpenFGA store yet, skipping it for now")
+			completed = false
+			continue
+		}
+
+		progress := migrationProgress(migration, target.cluster, &target.store)
+		if progress.Completed || pages == 0 {
+			completed = completed && progress.Completed
+			continue
+		}
+		owners, err := getStoreTupleOwners(ctx, t.lister, target.cluster, &target.store)
+		if err != nil {
+			return subroutines.OK(), fmt.Errorf("getting managed tuples of store %s: %w", target.store.Name, err)
+		}
+		for ; !progress.Completed && pages > 0; pages-- {
+			if err := t.migratePage(ctx, migration, &target.store, owners, progress); err != nil {
+				return subroutines.OK(), fmt.Errorf("migrating tuples of store %s: %w", target.store.Name, err)
+			}
+		}
+		completed = completed && progress.Completed
+	}
+
+	if !completed {
+		return subroutines.OKWithRequeue(tupleMigrationRequeueInterval), nil
+	}
+
+	now := metav1.Now()
+	migration.Status.CompletionTime = &now
+	log.Info().Int("stores", len(stores)).Bool("dryRun", migration.Spec.DryRun).Msg("Completed tuple migration")
+	return subroutines.OK(), nil
+}
+
+// migrationTarget is a Store migrated by a TupleMigration along with the
+// cluster it lives in.
+type migrationTarget struct {
+	cluster string
+	store   securityv1alpha1.Store
+}
+
+// targetStores returns the Store referenced by the migration or, if it
+// references none, all Stores in the cluster of the migration.
+func (t *tupleMigrationSubroutine) targetStores(ctx context.Context, migration *securityv1alpha1.TupleMigration) ([]migrationTarget, error) {
+	if ref := migration.Spec.StoreRef; ref != nil {
+		store, err := getReferencedStore(ctx, t.mgr, *ref)
+		if err != nil {
+			return nil, fmt.Errorf("getting Store %s: %w", ref.Name, err)
+		}
+		return []migrationTarget{{cluster: ref.Cluster, store: *store}}, nil
+	}
+
+	clusterName, ok := mccontext.ClusterFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unable to get cluster key from context")
+	}
+	cluster, err := t.mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+
+	var stores securityv1alpha1.StoreList
+	if err := cluster.GetClient().List(ctx, &stores); err != nil {
+		return nil, fmt.Errorf("listing Stores: %w", err)
+	}
+
+	targets := make([]migrationTarget, 0, len(stores.Items))
+	for _, store := range stores.Items {
+		targets = append(targets, migrationTarget{cluster: string(clusterName), store: store})
+	}
+	return targets, nil
+}
+
+// migrationProgress returns the progress of the migration for a store. The
+// progress is started over if the Store references a different OpenFGA
+// store than the one it was made in.
+func migrationProgress(migration *securityv1alpha1.TupleMigration, cluster string, store *securityv1alpha1.Store) *securityv1alpha1.TupleMigrationStoreStatus {
+	for i := range migration.Status.Stores {
+		progress := &migration.Status.Stores[i]
+		if progress.Cluster != cluster || progress.Name != store.Name {
+			continue
+		}
+		if progress.StoreID != store.Status.StoreID {
+			*progress = securityv1alpha1.TupleMigrationStoreStatus{Cluster: cluster, Name: store.Name, StoreID: store.Status.StoreID}
+		}
+		return progress
+	}
+
+	migration.Status.Stores = append(migration.Status.Stores, securityv1alpha1.TupleMigrationStoreStatus{
+		Cluster: cluster,
+		Name:    store.Name,
+		StoreID: store.Status.StoreID,
+	})
+	return &migration.Status.Stores[len(migration.Status.Stores)-1]
+}
+
+// migratePage rewrites the tuples of the next page of a store. Rewritten
+// tuples are written before the original ones are deleted, so a page that
+// fails half way is migrated again without losing tuples. Tuples the rules
+// change but owners manage are skipped and have to be renamed in the specs
+// of their owners instead.
+//
+// Rewritten tuples may be read again on a later page, as OpenFGA does not
+// guarantee their order. Rules leave them unchanged, since the target of a
+// rename is not renamed again, but they are counted as scanned twice.
+func (t *tupleMigrationSubroutine) migratePage(ctx context.Context, migration *securityv1alpha1.TupleMigration, store *securityv1alpha1.Store, owners tupleCoOwners, progress *securityv1alpha1.TupleMigrationStoreStatus) error {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	tm := fga.NewTupleManager(t.fga, store.Status.StoreID, store.Status.AuthorizationModelID, log, fga.WithWriteChunkSize(t.writeChunkSize))
+	tuples, continuationToken, err := tm.ListPage(ctx, progress.ContinuationToken, tupleMigrationPageSize)
+	if err != nil {
+		return fmt.Errorf("reading tuples: %w", err)
+	}
+
+	var writes, deletes []securityv1alpha1.Tuple
+	var dropped, skipped int
+	for _, tuple := range tuples {
+		rewritten, keep := fga.RewriteTuple(migration.Spec.Rules, tuple)
+		if (!keep || !rewritten.Equal(tuple)) && owners.manage(tuple) {
+			log.Debug().Str("tuple", tuple.String()).Msg("Skipping tuple managed by a Store or AuthorizationModel")
+			skipped++
+			continue
+		}
+		if !keep {
+			deletes = append(deletes, tuple)
+			dropped++
+			continue
+		}
+		if !rewritten.Equal(tuple) {
+			writes = append(writes, rewritten)
+			deletes = append(deletes, tuple)
+		}
+	}
+
+	if !migration.Spec.DryRun {
+		if err := tm.Apply(ctx, writes); err != nil {
+			return fmt.Errorf("writing rewritten tuples: %w", err)
+		}
+		if err := tm.Delete(ctx, deletes); err != nil {
+			return fmt.Errorf("deleting replaced tuples: %w", err)
+		}
+	}
+
+	progress.Scanned += len(tuples)
+	progress.Rewritten += len(writes)
+	progress.Dropped += dropped
+	progress.Skipped += skipped
+	progress.ContinuationToken = continuationToken
+	progress.Completed = continuationToken == ""
+	return nil
+}
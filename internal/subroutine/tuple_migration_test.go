@@ -0,0 +1,248 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTupleMigrationGetName(t *testing.T) {
+	s := subroutine.NewTupleMigrationSubroutine(nil, nil, nil, 0)
+	assert.Equal(t, "TupleMigrationSubroutine", s.GetName())
+}
+
+func TestTupleMigrationProcess(t *testing.T) {
+	storeRef := &securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"}
+	rules := []securityv1alpha1.TupleRewriteRule{
+		{RenameRelation: &securityv1alpha1.RelationRename{ObjectType: "role", From: "assignee", To: "member"}},
+		{Drop: &securityv1alpha1.TupleMatch{Relation: "legacy"}},
+	}
+	ctx := mccontext.WithCluster(context.Background(), "migration-cluster")
+
+	readPage := func(fga *mocks.MockOpenFGAServiceClient, token, next string, tuples ...*openfgav1.TupleKey) {
+		resp := readResponse(tuples...)
+		resp.ContinuationToken = next
+		fga.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+			return req.StoreId == "store-id" && req.ContinuationToken == token
+		})).Return(resp, nil).Once()
+	}
+
+	tests := []struct {
+		name           string
+		dryRun         bool
+		status         securityv1alpha1.TupleMigrationStatus
+		fgaMocks       func(*mocks.MockOpenFGAServiceClient)
+		expectProgress securityv1alpha1.TupleMigrationStoreStatus
+	}{
+		{
+			name: "should rewrite and drop tuples page by page",
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				readPage(fga, "", "next",
+					&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+					&openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+				)
+				readPage(fga, "next", "",
+					&openfgav1.TupleKey{Object: "doc:1", Relation: "legacy", User: "user:bob"},
+				)
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					keys := req.GetWrites().GetTupleKeys()
+					return len(keys) == 1 && keys[0].Relation == "member"
+				})).Return(&openfgav1.WriteResponse{}, nil).Once()
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					keys := req.GetDeletes().GetTupleKeys()
+					return len(keys) == 1 && keys[0].Relation == "assignee"
+				})).Return(&openfgav1.WriteResponse{}, nil).Once()
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					keys := req.GetDeletes().GetTupleKeys()
+					return len(keys) == 1 && keys[0].Relation == "legacy"
+				})).Return(&openfgav1.WriteResponse{}, nil).Once()
+			},
+			expectProgress: securityv1alpha1.TupleMigrationStoreStatus{
+				Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+				Scanned: 3, Rewritten: 1, Dropped: 1, Completed: true,
+			},
+		},
+		{
+			name:   "should only count tuples in dry-run mode",
+			dryRun: true,
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				readPage(fga, "", "",
+					&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+					&openfgav1.TupleKey{Object: "doc:1", Relation: "legacy", User: "user:bob"},
+				)
+			},
+			expectProgress: securityv1alpha1.TupleMigrationStoreStatus{
+				Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+				Scanned: 2, Rewritten: 1, Dropped: 1, Completed: true,
+			},
+		},
+		{
+			name: "should resume at the recorded continuation token",
+			status: securityv1alpha1.TupleMigrationStatus{
+				ObservedGeneration: 1,
+				Stores: []securityv1alpha1.TupleMigrationStoreStatus{{
+					Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+					ContinuationToken: "next", Scanned: 100,
+				}},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				readPage(fga, "next", "",
+					&openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+				)
+			},
+			expectProgress: securityv1alpha1.TupleMigrationStoreStatus{
+				Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+				Scanned: 101, Completed: true,
+			},
+		},
+		{
+			name: "should start over once the Store references another OpenFGA store",
+			status: securityv1alpha1.TupleMigrationStatus{
+				ObservedGeneration: 1,
+				Stores: []securityv1alpha1.TupleMigrationStoreStatus{{
+					Cluster: "store-cluster", Name: "store", StoreID: "old-store-id",
+					ContinuationToken: "next", Scanned: 100,
+				}},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				readPage(fga, "", "",
+					&openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"},
+				)
+			},
+			expectProgress: securityv1alpha1.TupleMigrationStoreStatus{
+				Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+				Scanned: 1, Completed: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fga := mocks.NewMockOpenFGAServiceClient(t)
+			test.fgaMocks(fga)
+			mgr := mocks.NewMockManager(t)
+			expectStore(t, mgr, "store-id", "model-id")
+
+			migration := &securityv1alpha1.TupleMigration{
+				ObjectMeta: metav1.ObjectMeta{Name: "rename", Generation: 1},
+				Spec:       securityv1alpha1.TupleMigrationSpec{StoreRef: storeRef, Rules: rules, DryRun: test.dryRun},
+				Status:     test.status,
+			}
+
+			res, err := subroutine.NewTupleMigrationSubroutine(fga, mgr, noCoOwners(t), 0).Process(ctx, migration)
+			require.NoError(t, err)
+			assert.Zero(t, res.Requeue())
+			assert.NotNil(t, migration.Status.CompletionTime)
+			assert.Equal(t, []securityv1alpha1.TupleMigrationStoreStatus{test.expectProgress}, migration.Status.Stores)
+		})
+	}
+
+	t.Run("should migrate all stores of the workspace", func(t *testing.T) {
+		fga := mocks.NewMockOpenFGAServiceClient(t)
+		readPage(fga, "", "", &openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:alice"})
+
+		cl := mocks.NewMockClient(t)
+		cl.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.StoreList")).RunAndReturn(func(_ context.Context, ol client.ObjectList, _ ...client.ListOption) error {
+			ol.(*securityv1alpha1.StoreList).Items = []securityv1alpha1.Store{
+				{ObjectMeta: metav1.ObjectMeta{Name: "acme"}, Status: securityv1alpha1.StoreStatus{StoreID: "store-id"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "new"}},
+			}
+			return nil
+		})
+		cluster := mocks.NewMockCluster(t)
+		cluster.EXPECT().GetClient().Return(cl)
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+
+		migration := &securityv1alpha1.TupleMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: "rename", Generation: 1},
+			Spec:       securityv1alpha1.TupleMigrationSpec{Rules: rules},
+		}
+
+		res, err := subroutine.NewTupleMigrationSubroutine(fga, mgr, noCoOwners(t), 0).Process(ctx, migration)
+		require.NoError(t, err)
+		assert.NotZero(t, res.Requeue())
+		assert.Nil(t, migration.Status.CompletionTime)
+		assert.Equal(t, []securityv1alpha1.TupleMigrationStoreStatus{{
+			Cluster: "migration-cluster", Name: "acme", StoreID: "store-id", Scanned: 1, Completed: true,
+		}}, migration.Status.Stores)
+	})
+
+	t.Run("should skip tuples managed by AuthorizationModels", func(t *testing.T) {
+		fga := mocks.NewMockOpenFGAServiceClient(t)
+		readPage(fga, "", "",
+			&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+			&openfgav1.TupleKey{Object: "role:admin", Relation: "assignee", User: "user:bob"},
+		)
+		fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+			keys := req.GetWrites().GetTupleKeys()
+			return len(keys) == 1 && keys[0].User == "user:bob" && keys[0].Relation == "member"
+		})).Return(&openfgav1.WriteResponse{}, nil).Once()
+		fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+			keys := req.GetDeletes().GetTupleKeys()
+			return len(keys) == 1 && keys[0].User == "user:bob" && keys[0].Relation == "assignee"
+		})).Return(&openfgav1.WriteResponse{}, nil).Once()
+		mgr := mocks.NewMockManager(t)
+		expectStore(t, mgr, "store-id", "model-id")
+
+		lister := mocks.NewMockLister(t)
+		lister.EXPECT().List(mock.Anything, mock.AnythingOfType("*v1alpha1.AuthorizationModelList")).RunAndReturn(func(_ context.Context, ol client.ObjectList, _ ...client.ListOption) error {
+			ol.(*securityv1alpha1.AuthorizationModelList).Items = []securityv1alpha1.AuthorizationModel{{
+				ObjectMeta: metav1.ObjectMeta{Name: "roles"},
+				Spec:       securityv1alpha1.AuthorizationModelSpec{StoreRef: *storeRef},
+				Status: securityv1alpha1.AuthorizationModelStatus{ManagedTuples: []securityv1alpha1.Tuple{
+					{Object: "role:admin", Relation: "assignee", User: "user:alice"},
+				}},
+			}}
+			return nil
+		})
+
+		migration := &securityv1alpha1.TupleMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: "rename", Generation: 1},
+			Spec:       securityv1alpha1.TupleMigrationSpec{StoreRef: storeRef, Rules: rules},
+		}
+
+		_, err := subroutine.NewTupleMigrationSubroutine(fga, mgr, lister, 0).Process(ctx, migration)
+		require.NoError(t, err)
+		assert.Equal(t, []securityv1alpha1.TupleMigrationStoreStatus{{
+			Cluster: "store-cluster", Name: "store", StoreID: "store-id",
+			Scanned: 2, Rewritten: 1, Skipped: 1, Completed: true,
+		}}, migration.Status.Stores)
+	})
+
+	t.Run("should not run a completed migration again", func(t *testing.T) {
+		now := metav1.Now()
+		migration := &securityv1alpha1.TupleMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: "rename", Generation: 1},
+			Spec:       securityv1alpha1.TupleMigrationSpec{StoreRef: storeRef, Rules: rules},
+			Status:     securityv1alpha1.TupleMigrationStatus{ObservedGeneration: 1, CompletionTime: &now},
+		}
+
+		_, err := subroutine.NewTupleMigrationSubroutine(mocks.NewMockOpenFGAServiceClient(t), mocks.NewMockManager(t), mocks.NewMockLister(t), 0).Process(ctx, migration)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject chained renames", func(t *testing.T) {
+		migration := &securityv1alpha1.TupleMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: "rename", Generation: 1},
+			Spec: securityv1alpha1.TupleMigrationSpec{StoreRef: storeRef, Rules: []securityv1alpha1.TupleRewriteRule{
+				{RenameObjectType: &securityv1alpha1.TypeRename{From: "a", To: "b"}},
+				{RenameObjectType: &securityv1alpha1.TypeRename{From: "b", To: "c"}},
+			}},
+		}
+
+		_, err := subroutine.NewTupleMigrationSubroutine(nil, nil, nil, 0).Process(ctx, migration)
+		assert.ErrorContains(t, err, "invalid rules")
+	})
+}
@@ -0,0 +1,187 @@
+package subroutine
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// tupleKey identifies a tuple in OpenFGA regardless of its condition.
+type tupleKey struct {
+	object, relation, user string
+}
+
+func keyOf(t securityv1alpha1.Tuple) tupleKey {
+	return tupleKey{object: t.Object, relation: t.Relation, user: t.User}
+}
+
+// tupleCoOwners indexes the tuples of the other objects writing tuples to the
+// store of a Store or AuthorizationModel by key. A tuple is only deleted from
+// OpenFGA if none of them manages a tuple with the same key, as deleting it
+// would revoke it for them as well.
+type tupleCoOwners map[tupleKey][]securityv1alpha1.TupleOwnerReference
+
+// add records the tuples a co-owner manages.
+func (c tupleCoOwners) add(ref securityv1alpha1.TupleOwnerReference, tuples []securityv1alpha1.Tuple) {
+	for _, tuple := range tuples {
+		key := keyOf(tuple)
+		if refs := c[key]; len(refs) > 0 && refs[len(refs)-1] == ref {
+			continue
+		}
+		c[key] = append(c[key], ref)
+	}
+}
+
+// getTupleCoOwners returns the Store and AuthorizationModels sharing the given
+// store with obj. Objects that are being deleted are not considered, so that
+// owners deleted at the same time do not keep each other's tuples alive.
+func getTupleCoOwners(ctx context.Context, lister iclient.Lister, obj client.Object, store *securityv1alpha1.Store) (tupleCoOwners, error) {
+	storeName := store.Name
+	var storeCluster string
+	owners := tupleCoOwners{}
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		cluster, ok := mccontext.ClusterFrom(ctx)
+		if !ok {
+			return nil, errors.New("unable to get cluster key from context")
+		}
+		storeCluster = string(cluster)
+	case *securityv1alpha1.AuthorizationModel:
+		storeName, storeCluster = o.Spec.StoreRef.Name, o.Spec.StoreRef.Cluster
+		if store.DeletionTimestamp.IsZero() {
+			tuples, err := getManagedTuples(store)
+			if err != nil {
+				return nil, err
+			}
+			owners.add(securityv1alpha1.TupleOwnerReference{Kind: "Store", Cluster: storeCluster, Name: storeName}, tuples)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+
+	var models securityv1alpha1.AuthorizationModelList
+	if err := lister.List(ctx, &models); err != nil {
+		return nil, fmt.Errorf("listing AuthorizationModels: %w", err)
+	}
+	for _, model := range models.Items {
+		if model.Spec.StoreRef.Name != storeName || model.Spec.StoreRef.Cluster != storeCluster || !model.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if _, ok := obj.(*securityv1alpha1.AuthorizationModel); ok && model.Name == obj.GetName() && logicalcluster.From(&model) == logicalcluster.From(obj) {
+			continue
+		}
+
+		tuples, err := getManagedTuples(&model)
+		if err != nil {
+			return nil, err
+		}
+		owners.add(securityv1alpha1.TupleOwnerReference{Kind: "AuthorizationModel", Cluster: logicalcluster.From(&model).String(), Name: model.Name}, tuples)
+	}
+	return owners, nil
+}
+
+// getStoreTupleOwners returns the Store in the given cluster and all
+// AuthorizationModels referencing it along with the tuples they manage.
+func getStoreTupleOwners(ctx context.Context, lister iclient.Lister, cluster string, store *securityv1alpha1.Store) (tupleCoOwners, error) {
+	owners := tupleCoOwners{}
+	tuples, err := getManagedTuples(store)
+	if err != nil {
+		return nil, err
+	}
+	owners.add(securityv1alpha1.TupleOwnerReference{Kind: "Store", Cluster: cluster, Name: store.Name}, tuples)
+
+	var models securityv1alpha1.AuthorizationModelList
+	if err := lister.List(ctx, &models); err != nil {
+		return nil, fmt.Errorf("listing AuthorizationModels: %w", err)
+	}
+	for _, model := range models.Items {
+		if model.Spec.StoreRef.Name != store.Name || model.Spec.StoreRef.Cluster != cluster {
+			continue
+		}
+		tuples, err := getManagedTuples(&model)
+		if err != nil {
+			return nil, err
+		}
+		owners.add(securityv1alpha1.TupleOwnerReference{Kind: "AuthorizationModel", Cluster: logicalcluster.From(&model).String(), Name: model.Name}, tuples)
+	}
+	return owners, nil
+}
+
+// manage reports whether any co-owner manages a tuple with the same key.
+func (c tupleCoOwners) manage(t securityv1alpha1.Tuple) bool {
+	return len(c[keyOf(t)]) > 0
+}
+
+// exclusive returns the tuples no co-owner manages, i.e. the ones that can be
+// deleted from OpenFGA.
+func (c tupleCoOwners) exclusive(tuples []securityv1alpha1.Tuple) []securityv1alpha1.Tuple {
+	var result []securityv1alpha1.Tuple
+	for _, tuple := range tuples {
+		if !c.manage(tuple) {
+			result = append(result, tuple)
+		}
+	}
+	return result
+}
+
+// shared returns the given tuples that co-owners manage as well, with the
+// co-owners of each.
+func (c tupleCoOwners) shared(tuples []securityv1alpha1.Tuple) []securityv1alpha1.SharedTuple {
+	var result []securityv1alpha1.SharedTuple
+	for _, tuple := range tuples {
+		coOwners := slices.Clone(c[keyOf(tuple)])
+		if len(coOwners) == 0 {
+			continue
+		}
+		slices.SortFunc(coOwners, func(a, b securityv1alpha1.TupleOwnerReference) int {
+			return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Cluster, b.Cluster), cmp.Compare(a.Name, b.Name))
+		})
+		result = append(result, securityv1alpha1.SharedTuple{Tuple: tuple.String(), CoOwners: coOwners})
+	}
+	slices.SortFunc(result, func(a, b securityv1alpha1.SharedTuple) int {
+		return cmp.Compare(a.Tuple, b.Tuple)
+	})
+	return result
+}
+
+// setSharedTuples reports the managed tuples of a Store or AuthorizationModel
+// that co-owners manage as well in its status.
+func setSharedTuples(obj client.Object, owners tupleCoOwners, managed []securityv1alpha1.Tuple) {
+	shared := owners.shared(managed)
+
+	condition := metav1.Condition{
+		Type:               securityv1alpha1.TuplesSharedCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Exclusive",
+		Message:            "No managed tuple is managed by another object",
+		ObservedGeneration: obj.GetGeneration(),
+	}
+	if len(shared) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Shared"
+		condition.Message = fmt.Sprintf("%d managed tuples are managed by other objects as well and are kept until none of them manages them", len(shared))
+	}
+	if len(shared) > securityv1alpha1.MaxReportedSharedTuples {
+		shared = shared[:securityv1alpha1.MaxReportedSharedTuples]
+	}
+
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		o.Status.SharedTuples = shared
+		meta.SetStatusCondition(&o.Status.Conditions, condition)
+	case *securityv1alpha1.AuthorizationModel:
+		o.Status.SharedTuples = shared
+		meta.SetStatusCondition(&o.Status.Conditions, condition)
+	}
+}
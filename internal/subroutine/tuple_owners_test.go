@@ -0,0 +1,242 @@
+package subroutine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+)
+
+// coOwningModel returns an AuthorizationModel in the given cluster managing
+// tuples of the Store "store" in the cluster "cluster".
+func coOwningModel(t *testing.T, cluster, name string, tuples ...securityv1alpha1.Tuple) securityv1alpha1.AuthorizationModel {
+	set, err := securityv1alpha1.NewManagedTupleSet(tuples)
+	require.NoError(t, err)
+	return securityv1alpha1.AuthorizationModel{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{"kcp.io/cluster": cluster}},
+		Spec:       securityv1alpha1.AuthorizationModelSpec{StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "cluster"}},
+		Status:     securityv1alpha1.AuthorizationModelStatus{ManagedTupleSet: set},
+	}
+}
+
+func listerOf(t *testing.T, models ...securityv1alpha1.AuthorizationModel) *mocks.MockLister {
+	lister := mocks.NewMockLister(t)
+	lister.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+		list.(*securityv1alpha1.AuthorizationModelList).Items = models
+		return nil
+	})
+	return lister
+}
+
+func writeDeletes(users ...string) func(*openfgav1.WriteRequest) bool {
+	return func(req *openfgav1.WriteRequest) bool {
+		keys := req.GetDeletes().GetTupleKeys()
+		if len(keys) != len(users) || len(req.GetWrites().GetTupleKeys()) > 0 {
+			return false
+		}
+		for i, key := range keys {
+			if key.GetUser() != users[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestTupleFinalizeKeepsSharedTuples(t *testing.T) {
+	shared := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user1"}
+	exclusive := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user2"}
+	set, err := securityv1alpha1.NewManagedTupleSet([]securityv1alpha1.Tuple{shared, exclusive})
+	require.NoError(t, err)
+
+	store := &securityv1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "store"},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTupleSet:      set,
+		},
+	}
+
+	deleting := coOwningModel(t, "other", "deleting", exclusive)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	otherStore := coOwningModel(t, "other", "other-store", exclusive)
+	otherStore.Spec.StoreRef.Name = "other"
+	lister := listerOf(t,
+		// Co-owns the shared tuple regardless of its condition.
+		coOwningModel(t, "other", "model", securityv1alpha1.Tuple{
+			Object: "foo", Relation: "bar", User: "user1",
+			Condition: &securityv1alpha1.TupleCondition{Name: "in_network"},
+		}),
+		deleting,
+		otherStore,
+	)
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(writeDeletes("user2"))).Return(&openfgav1.WriteResponse{}, nil)
+
+	_, err = subroutine.NewTupleSubroutine(fga, mocks.NewMockManager(t), lister, 0).Finalize(storeContext(), store)
+	require.NoError(t, err)
+	assert.Nil(t, store.Status.ManagedTupleSet)
+}
+
+func TestTupleFinalizeOfAuthorizationModelKeepsTuplesOfStore(t *testing.T) {
+	shared := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user1"}
+	exclusive := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user2"}
+
+	model := coOwningModel(t, "model-cluster", "model", shared, exclusive)
+	storeSet, err := securityv1alpha1.NewManagedTupleSet([]securityv1alpha1.Tuple{shared})
+	require.NoError(t, err)
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	storeClient := mocks.NewMockClient(t)
+	manager.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("cluster")).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(storeClient)
+	storeClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "store"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+		*obj.(*securityv1alpha1.Store) = securityv1alpha1.Store{
+			ObjectMeta: metav1.ObjectMeta{Name: "store"},
+			Status: securityv1alpha1.StoreStatus{
+				StoreID:              "store-id",
+				AuthorizationModelID: "auth-model-id",
+				ManagedTupleSet:      storeSet,
+			},
+		}
+		return nil
+	})
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(writeDeletes("user2"))).Return(&openfgav1.WriteResponse{}, nil)
+
+	// The lister returns the finalized model itself, which is no co-owner.
+	_, err = subroutine.NewTupleSubroutine(fga, manager, listerOf(t, model), 0).Finalize(context.Background(), &model)
+	require.NoError(t, err)
+}
+
+func TestTupleDriftReportsSharedTuples(t *testing.T) {
+	tuples := []securityv1alpha1.Tuple{
+		{Object: "foo", Relation: "bar", User: "user1"},
+		{Object: "foo", Relation: "bar", User: "user2"},
+	}
+	set, err := securityv1alpha1.NewManagedTupleSet(tuples)
+	require.NoError(t, err)
+
+	store := &securityv1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "store"},
+		Spec:       securityv1alpha1.StoreSpec{Tuples: tuples},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTupleSet:      set,
+		},
+	}
+	lister := listerOf(t,
+		coOwningModel(t, "b", "model", tuples[0]),
+		coOwningModel(t, "a", "model", tuples[0]),
+	)
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Read(mock.Anything, mock.Anything).Return(readResponse(
+		&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user1"},
+		&openfgav1.TupleKey{Object: "foo", Relation: "bar", User: "user2"},
+	), nil)
+
+	_, err = subroutine.NewTupleDriftSubroutine(fga, mocks.NewMockManager(t), lister, time.Minute, 0).Process(storeContext(), store)
+	require.NoError(t, err)
+
+	assert.Equal(t, []securityv1alpha1.SharedTuple{{
+		Tuple: "foo#bar@user1",
+		CoOwners: []securityv1alpha1.TupleOwnerReference{
+			{Kind: "AuthorizationModel", Cluster: "a", Name: "model"},
+			{Kind: "AuthorizationModel", Cluster: "b", Name: "model"},
+		},
+	}}, store.Status.SharedTuples)
+	assert.True(t, meta.IsStatusConditionTrue(store.Status.Conditions, securityv1alpha1.TuplesSharedCondition))
+}
+
+func TestTupleProcessReportsSharedTuplesOfAddedTuples(t *testing.T) {
+	kept := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user1"}
+	added := securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user2"}
+	set, err := securityv1alpha1.NewManagedTupleSet([]securityv1alpha1.Tuple{kept})
+	require.NoError(t, err)
+
+	store := &securityv1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "store"},
+		Spec:       securityv1alpha1.StoreSpec{Tuples: []securityv1alpha1.Tuple{kept, added}},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTupleSet:      set,
+		},
+	}
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.GetWrites().GetTupleKeys()) == 1 && req.GetWrites().GetTupleKeys()[0].GetUser() == "user2"
+	})).Return(&openfgav1.WriteResponse{}, nil)
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetEventRecorder(mock.Anything).Return(events.NewFakeRecorder(1))
+
+	lister := listerOf(t, coOwningModel(t, "a", "model", added))
+	_, err = subroutine.NewTupleSubroutine(fga, manager, lister, 0).Process(storeContext(), store)
+	require.NoError(t, err)
+
+	assert.Equal(t, []securityv1alpha1.SharedTuple{{
+		Tuple:    "foo#bar@user2",
+		CoOwners: []securityv1alpha1.TupleOwnerReference{{Kind: "AuthorizationModel", Cluster: "a", Name: "model"}},
+	}}, store.Status.SharedTuples)
+	assert.True(t, meta.IsStatusConditionTrue(store.Status.Conditions, securityv1alpha1.TuplesSharedCondition))
+
+	// Co-owners are not listed again as long as the tuples do not change.
+	_, err = subroutine.NewTupleSubroutine(fga, manager, mocks.NewMockLister(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+}
+
+func TestTupleExpiryKeepsSharedTuples(t *testing.T) {
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	expired := []securityv1alpha1.Tuple{
+		{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+		{Object: "foo", Relation: "bar", User: "user2", ExpiresAt: &past},
+	}
+	set, err := securityv1alpha1.NewManagedTupleSet(expired)
+	require.NoError(t, err)
+
+	store := &securityv1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "store"},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTupleSet:      set,
+		},
+	}
+	lister := listerOf(t, coOwningModel(t, "other", "model", securityv1alpha1.Tuple{Object: "foo", Relation: "bar", User: "user1"}))
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(writeDeletes("user2"))).Return(&openfgav1.WriteResponse{}, nil)
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetEventRecorder(mock.Anything).Return(events.NewFakeRecorder(1))
+
+	_, err = subroutine.NewTupleExpirySubroutine(fga, manager, lister, 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.Nil(t, store.Status.ManagedTupleSet)
+}
@@ -0,0 +1,233 @@
+package subroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	kcpapisv1alpha2 "github.com/kcp-dev/sdk/apis/apis/v1alpha2"
+)
+
+// tupleSourceClaimRequeue is how often tuple sources whose permission claim
+// is not accepted are checked again.
+const tupleSourceClaimRequeue = 5 * time.Minute
+
+// errTupleSourceClaimNotAccepted is returned for tuple sources that cannot be
+// read because no APIBinding of the workspace accepted the permission claim
+// on their resource.
+var errTupleSourceClaimNotAccepted = errors.New("permission claim is not accepted")
+
+// desiredTuples returns the tuples a Store or AuthorizationModel declares,
+// both inline and in the ConfigMaps and Secrets referenced by tuplesFrom.
+func desiredTuples(ctx context.Context, mgr mcmanager.Manager, obj client.Object) ([]securityv1alpha1.Tuple, error) {
+	var tuples []securityv1alpha1.Tuple
+	var sources []securityv1alpha1.TupleSource
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		tuples, sources = o.Spec.Tuples, o.Spec.TuplesFrom
+	case *securityv1alpha1.AuthorizationModel:
+		tuples, sources = o.Spec.Tuples, o.Spec.TuplesFrom
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+	if len(sources) == 0 {
+		return tuples, nil
+	}
+
+	cluster, err := mgr.ClusterFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cluster from context: %w", err)
+	}
+
+	tuples = slices.Clone(tuples)
+	for _, source := range sources {
+		sourceTuples, err := tuplesFromSource(ctx, cluster.GetClient(), source)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, sourceTuples...)
+	}
+	return tuples, nil
+}
+
+// tuplesFromSource reads the tuples of all or the selected key of a ConfigMap
+// or Secret.
+func tuplesFromSource(ctx context.Context, cl client.Client, source securityv1alpha1.TupleSource) ([]securityv1alpha1.Tuple, error) {
+	var ref securityv1alpha1.TupleSourceRef
+	var kind string
+	data := map[string][]byte{}
+	switch {
+	case source.ConfigMapRef != nil:
+		ref, kind = *source.ConfigMapRef, "ConfigMap"
+		var cm corev1.ConfigMap
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &cm); err != nil {
+			return nil, tupleSourceGetError(ctx, cl, kind, "configmaps", ref, err)
+		}
+		if err := checkTupleSourceLabel(kind, &cm); err != nil {
+			return nil, err
+		}
+		for key, value := range cm.Data {
+			data[key] = []byte(value)
+		}
+		maps.Copy(data, cm.BinaryData)
+	case source.SecretRef != nil:
+		ref, kind = *source.SecretRef, "Secret"
+		var secret corev1.Secret
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, &secret); err != nil {
+			return nil, tupleSourceGetError(ctx, cl, kind, "secrets", ref, err)
+		}
+		if err := checkTupleSourceLabel(kind, &secret); err != nil {
+			return nil, err
+		}
+		maps.Copy(data, secret.Data)
+	default:
+		return nil, errors.New("tuple source references neither a ConfigMap nor a Secret")
+	}
+
+	keys := slices.Sorted(maps.Keys(data))
+	if ref.Key != "" {
+		if _, ok := data[ref.Key]; !ok {
+			if ref.Optional {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%s %s/%s has no key %s", kind, ref.Namespace, ref.Name, ref.Key)
+		}
+		keys = []string{ref.Key}
+	}
+
+	var tuples []securityv1alpha1.Tuple
+	for _, key := range keys {
+		keyTuples, err := fga.ParseTupleFile(key, data[key])
+		if err != nil {
+			return nil, fmt.Errorf("reading tuples from %s %s/%s: %w", kind, ref.Namespace, ref.Name, err)
+		}
+		tuples = append(tuples, keyTuples...)
+	}
+	return tuples, nil
+}
+
+// tupleSourceGetError returns the error for a tuple source that could not be
+// read. Sources hidden because the permission claim on their resource was not
+// accepted are reported as such, even if they are optional, so their tuples
+// are not removed from the store. Other optional sources that do not exist
+// hold no tuples.
+func tupleSourceGetError(ctx context.Context, cl client.Client, kind, resource string, ref securityv1alpha1.TupleSourceRef, err error) error {
+	if !kerrors.IsNotFound(err) && !kerrors.IsForbidden(err) {
+		return fmt.Errorf("getting %s %s/%s: %w", kind, ref.Namespace, ref.Name, err)
+	}
+
+	accepted, claimErr := tupleSourceClaimAccepted(ctx, cl, resource)
+	if claimErr != nil {
+		return claimErr
+	}
+	if !accepted {
+		return fmt.Errorf("reading %s %s/%s: %w on %s, the APIBinding to core.platform-mesh.io must accept it", kind, ref.Namespace, ref.Name, errTupleSourceClaimNotAccepted, resource)
+	}
+	if kerrors.IsNotFound(err) && ref.Optional {
+		return nil
+	}
+	return fmt.Errorf("getting %s %s/%s: %w", kind, ref.Namespace, ref.Name, err)
+}
+
+// tupleSourceClaimAccepted reports whether an APIBinding of the workspace of
+// cl accepted the permission claim on the given core resource.
+func tupleSourceClaimAccepted(ctx context.Context, cl client.Client, resource string) (bool, error) {
+	var bindings kcpapisv1alpha2.APIBindingList
+	if err := cl.List(ctx, &bindings); err != nil {
+		return false, fmt.Errorf("listing APIBindings: %w", err)
+	}
+	for _, binding := range bindings.Items {
+		for _, claim := range binding.Spec.PermissionClaims {
+			if claim.Group == "" && claim.Resource == resource && claim.State == kcpapisv1alpha2.ClaimAccepted {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// checkTupleSourceLabel rejects ConfigMaps and Secrets that are not labelled
+// as tuple sources, which bindings accepting all objects of the claimed
+// resource would expose as well.
+func checkTupleSourceLabel(kind string, obj client.Object) error {
+	if obj.GetLabels()[securityv1alpha1.TupleSourceLabelKey] != securityv1alpha1.TupleSourceLabelValue {
+		return fmt.Errorf("%s %s/%s is not labelled %s=%s", kind, obj.GetNamespace(), obj.GetName(), securityv1alpha1.TupleSourceLabelKey, securityv1alpha1.TupleSourceLabelValue)
+	}
+	return nil
+}
+
+// getManagedTuples returns the tuples recorded as written for a Store or
+// AuthorizationModel. Objects that were last reconciled before tuples were
+// recorded as a ManagedTupleSet still have them in the deprecated
+// ManagedTuples field.
+func getManagedTuples(obj client.Object) ([]securityv1alpha1.Tuple, error) {
+	var set *securityv1alpha1.ManagedTupleSet
+	var legacy []securityv1alpha1.Tuple
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		set, legacy = o.Status.ManagedTupleSet, o.Status.ManagedTuples
+	case *securityv1alpha1.AuthorizationModel:
+		set, legacy = o.Status.ManagedTupleSet, o.Status.ManagedTuples
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+	if set == nil {
+		return legacy, nil
+	}
+
+	tuples, err := set.Tuples()
+	if err != nil {
+		return nil, fmt.Errorf("reading managed tuples: %w", err)
+	}
+	return tuples, nil
+}
+
+// managedTuplesHash returns the hash of the recorded managed tuples or an
+// empty string if they are still recorded in the deprecated field.
+func managedTuplesHash(obj client.Object) string {
+	var set *securityv1alpha1.ManagedTupleSet
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		if len(o.Status.ManagedTuples) > 0 {
+			return ""
+		}
+		set = o.Status.ManagedTupleSet
+	case *securityv1alpha1.AuthorizationModel:
+		if len(o.Status.ManagedTuples) > 0 {
+			return ""
+		}
+		set = o.Status.ManagedTupleSet
+	}
+	if set == nil {
+		return ""
+	}
+	return set.Hash
+}
+
+// setManagedTuples records the tuples written for a Store or
+// AuthorizationModel in its status.
+func setManagedTuples(obj client.Object, tuples []securityv1alpha1.Tuple) error {
+	set, err := securityv1alpha1.NewManagedTupleSet(tuples)
+	if err != nil {
+		return fmt.Errorf("recording managed tuples: %w", err)
+	}
+	switch o := obj.(type) {
+	case *securityv1alpha1.Store:
+		o.Status.ManagedTupleSet, o.Status.ManagedTuples = set, nil
+	case *securityv1alpha1.AuthorizationModel:
+		o.Status.ManagedTupleSet, o.Status.ManagedTuples = set, nil
+	}
+	return nil
+}
@@ -2,12 +2,16 @@ package subroutine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/logger"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/fga"
 	"github.com/platform-mesh/subroutines"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,56 +22,51 @@ import (
 )
 
 type tupleSubroutine struct {
-	fga openfgav1.OpenFGAServiceClient
-	mgr mcmanager.Manager
+	fga            openfgav1.OpenFGAServiceClient
+	mgr            mcmanager.Manager
+	lister         iclient.Lister
+	writeChunkSize int
 }
 
 // Finalize implements subroutines.Finalizer.
 func (t *tupleSubroutine) Finalize(ctx context.Context, obj client.Object) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 
-	var storeID string
-	var authorizationModelID string
-	var managedTuples []securityv1alpha1.Tuple
-
-	switch o := obj.(type) {
-	case *securityv1alpha1.Store:
-		storeID = o.Status.StoreID
-		authorizationModelID = o.Status.AuthorizationModelID
-		managedTuples = o.Status.ManagedTuples
-	case *securityv1alpha1.AuthorizationModel:
-		managedTuples = o.Status.ManagedTuples
-
-		storeCluster, err := t.mgr.GetCluster(ctx, multicluster.ClusterName(o.Spec.StoreRef.Cluster))
-		if err != nil {
-			return subroutines.OK(), fmt.Errorf("unable to get store cluster: %w", err)
-		}
-
-		var store securityv1alpha1.Store
-		err = storeCluster.GetClient().Get(ctx, types.NamespacedName{
-			Name: o.Spec.StoreRef.Name,
-		}, &store)
-		if err != nil {
-			return subroutines.OK(), err
-		}
+	store, err := storeForObject(ctx, t.mgr, obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
 
-		storeID = store.Status.StoreID
-		authorizationModelID = store.Status.AuthorizationModelID
+	// Stores that are retained or orphaned keep their tuples. The policy only
+	// applies to the Store being deleted, AuthorizationModels deleted on
+	// their own still remove their tuples.
+	_, isStore := obj.(*securityv1alpha1.Store)
+	if policy := store.Spec.DeletionPolicy; isStore && policy != "" && policy != securityv1alpha1.StoreDeletionPolicyDelete {
+		log.Info().Str("deletionPolicy", string(policy)).Msg("Keeping tuples in OpenFGA store")
+		return dryrun.Finalized(), setManagedTuples(obj, nil)
 	}
 
-	tm := fga.NewTupleManager(t.fga, storeID, authorizationModelID, log)
-	if err := tm.Delete(ctx, managedTuples); err != nil {
+	managedTuples, err := getManagedTuples(obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
+	coOwners, err := getTupleCoOwners(ctx, t.lister, obj, store)
+	if err != nil {
 		return subroutines.OK(), err
 	}
 
-	switch o := obj.(type) {
-	case *securityv1alpha1.Store:
-		o.Status.ManagedTuples = nil
-	case *securityv1alpha1.AuthorizationModel:
-		o.Status.ManagedTuples = nil
+	// Tuples managed by other objects as well are left in OpenFGA and only
+	// forgotten.
+	tm := t.tupleManager(store, log)
+	if err := tm.Delete(ctx, coOwners.exclusive(managedTuples)); err != nil {
+		var partial *fga.PartialWriteError
+		if errors.As(err, &partial) {
+			err = errors.Join(err, setManagedTuples(obj, withoutTuples(managedTuples, partial.Written)))
+		}
+		return subroutines.OK(), err
 	}
 
-	return subroutines.OK(), nil
+	return dryrun.Finalized(), setManagedTuples(obj, nil)
 }
 
 // Finalizers implements subroutines.Finalizer.
@@ -82,70 +81,140 @@ func (t *tupleSubroutine) GetName() string { return "TupleSubroutine" }
 func (t *tupleSubroutine) Process(ctx context.Context, obj client.Object) (subroutines.Result, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 
-	var storeID string
-	var authorizationModelID string
-	var specTuples []securityv1alpha1.Tuple
-	var managedTuples []securityv1alpha1.Tuple
+	store, err := storeForObject(ctx, t.mgr, obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
 
-	switch o := obj.(type) {
-	case *securityv1alpha1.Store:
-		storeID = o.Status.StoreID
-		authorizationModelID = o.Status.AuthorizationModelID
+	specTuples, err := desiredTuples(ctx, t.mgr, obj)
+	if err != nil {
+		// The tuples written so far are kept until the sources can be read.
+		if errors.Is(err, errTupleSourceClaimNotAccepted) {
+			return subroutines.Pending(tupleSourceClaimRequeue, err.Error()), nil
+		}
+		return subroutines.OK(), err
+	}
 
-		specTuples = o.Spec.Tuples
-		managedTuples = o.Status.ManagedTuples
-	case *securityv1alpha1.AuthorizationModel:
-		specTuples = o.Spec.Tuples
-		managedTuples = o.Status.ManagedTuples
+	// Expired tuples are left to the expiry subroutine and are never written
+	// again.
+	specTuples = slices.DeleteFunc(slices.Clone(specTuples), func(tuple securityv1alpha1.Tuple) bool {
+		return tuple.IsExpired(time.Now())
+	})
+
+	// Nothing changed since the tuples were last written. Tuples changed in
+	// OpenFGA in the meantime and tuples other objects started or stopped
+	// sharing are left to drift detection.
+	if hash := managedTuplesHash(obj); hash != "" && hash == securityv1alpha1.HashTuples(specTuples) {
+		return subroutines.OK(), nil
+	}
 
-		storeCluster, err := t.mgr.GetCluster(ctx, multicluster.ClusterName(o.Spec.StoreRef.Cluster))
-		if err != nil {
-			return subroutines.OK(), fmt.Errorf("unable to get store cluster: %w", err)
-		}
+	managedTuples, err := getManagedTuples(obj)
+	if err != nil {
+		return subroutines.OK(), err
+	}
 
-		var store securityv1alpha1.Store
-		err = storeCluster.GetClient().Get(ctx, types.NamespacedName{
-			Name: o.Spec.StoreRef.Name,
-		}, &store)
+	// Only the difference to the managed tuples is sent to OpenFGA. Managed
+	// tuples whose condition changed are deleted and written again, as OpenFGA
+	// refuses to overwrite an existing tuple with a different condition.
+	diff := fga.DiffTuples(managedTuples, specTuples)
+
+	// Co-owners are only looked up if tuples change, as listing them reads
+	// every AuthorizationModel of the store. They are needed to keep tuples
+	// other objects manage as well and to refresh the shared tuples.
+	var coOwners tupleCoOwners
+	if len(diff.Add) > 0 || len(diff.Remove) > 0 {
+		coOwners, err = getTupleCoOwners(ctx, t.lister, obj, store)
 		if err != nil {
 			return subroutines.OK(), err
 		}
-
-		storeID = store.Status.StoreID
-		authorizationModelID = store.Status.AuthorizationModelID
 	}
+	tuplesToDelete := coOwners.exclusive(diff.Remove)
 
-	tm := fga.NewTupleManager(t.fga, storeID, authorizationModelID, log)
-	if err := tm.Apply(ctx, specTuples); err != nil {
+	// Writes are chunked on a best-effort basis. Partial progress is recorded
+	// in the managed tuples so that nothing written is lost track of. Tuples
+	// managed by other objects as well are only forgotten.
+	tm := t.tupleManager(store, log)
+	if err := tm.Delete(ctx, tuplesToDelete); err != nil {
+		var partial *fga.PartialWriteError
+		if errors.As(err, &partial) {
+			err = errors.Join(err, setManagedTuples(obj, withoutTuples(managedTuples, partial.Written)))
+		}
 		return subroutines.OK(), err
 	}
-
-	var tuplesToDelete []securityv1alpha1.Tuple
-	for _, tuple := range managedTuples {
-		if slices.IndexFunc(specTuples, func(s securityv1alpha1.Tuple) bool {
-			return s.Object == tuple.Object && s.Relation == tuple.Relation && s.User == tuple.User
-		}) == -1 {
-			tuplesToDelete = append(tuplesToDelete, tuple)
+	managedTuples = withoutTuples(managedTuples, diff.Remove)
+	if err := tm.Apply(ctx, diff.Add); err != nil {
+		var partial *fga.PartialWriteError
+		if errors.As(err, &partial) {
+			err = errors.Join(err, setManagedTuples(obj, append(managedTuples, withoutTuples(partial.Written, managedTuples)...)))
 		}
+		return subroutines.OK(), err
 	}
-	if err := tm.Delete(ctx, tuplesToDelete); err != nil {
+
+	// Nothing was written in dry-run mode, so the managed tuples stay as
+	// they are and the changes are recorded again next time.
+	if dryrun.Enabled() {
+		return subroutines.OK(), nil
+	}
+
+	if coOwners != nil {
+		setSharedTuples(obj, coOwners, specTuples)
+	}
+	if err := setManagedTuples(obj, specTuples); err != nil {
 		return subroutines.OK(), err
 	}
+	return subroutines.OK(), recordTupleChanges(ctx, t.mgr, obj, len(diff.Add), len(tuplesToDelete))
+}
 
+func (t *tupleSubroutine) tupleManager(store *securityv1alpha1.Store, log *logger.Logger) *fga.TupleManager {
+	return fga.NewTupleManager(t.fga, store.Status.StoreID, store.Status.AuthorizationModelID, log, fga.WithWriteChunkSize(t.writeChunkSize))
+}
+
+// withoutTuples returns the tuples that are not Equal to any of the removed
+// ones.
+func withoutTuples(tuples, removed []securityv1alpha1.Tuple) []securityv1alpha1.Tuple {
+	var result []securityv1alpha1.Tuple
+	for _, tuple := range tuples {
+		if !slices.ContainsFunc(removed, tuple.Equal) {
+			result = append(result, tuple)
+		}
+	}
+	return result
+}
+
+// storeForObject returns the Store the tuples of a Store or AuthorizationModel
+// are written to.
+func storeForObject(ctx context.Context, mgr mcmanager.Manager, obj client.Object) (*securityv1alpha1.Store, error) {
 	switch o := obj.(type) {
 	case *securityv1alpha1.Store:
-		o.Status.ManagedTuples = specTuples
+		return o, nil
 	case *securityv1alpha1.AuthorizationModel:
-		o.Status.ManagedTuples = specTuples
+		return getReferencedStore(ctx, mgr, o.Spec.StoreRef)
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", obj)
+	}
+}
+
+// getReferencedStore fetches the Store a WorkspaceStoreRef points to from its
+// cluster.
+func getReferencedStore(ctx context.Context, mgr mcmanager.Manager, ref securityv1alpha1.WorkspaceStoreRef) (*securityv1alpha1.Store, error) {
+	storeCluster, err := mgr.GetCluster(ctx, multicluster.ClusterName(ref.Cluster))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get store cluster: %w", err)
 	}
 
-	return subroutines.OK(), nil
+	var store securityv1alpha1.Store
+	if err := storeCluster.GetClient().Get(ctx, types.NamespacedName{Name: ref.Name}, &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
 }
 
-func NewTupleSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager) *tupleSubroutine {
+func NewTupleSubroutine(fga openfgav1.OpenFGAServiceClient, mgr mcmanager.Manager, lister iclient.Lister, writeChunkSize int) *tupleSubroutine {
 	return &tupleSubroutine{
-		fga: fga,
-		mgr: mgr,
+		fga:            fga,
+		mgr:            mgr,
+		lister:         lister,
+		writeChunkSize: writeChunkSize,
 	}
 }
 
@@ -3,27 +3,41 @@ package subroutine_test
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/dryrun"
 	"github.com/platform-mesh/security-operator/internal/subroutine"
 	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	mccontext "sigs.k8s.io/multicluster-runtime/pkg/context"
 	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+
+	kcpapisv1alpha2 "github.com/kcp-dev/sdk/apis/apis/v1alpha2"
 )
 
 func TestTupleGetName(t *testing.T) {
-	subroutine := subroutine.NewTupleSubroutine(nil, nil)
+	subroutine := subroutine.NewTupleSubroutine(nil, nil, nil, 0)
 	assert.Equal(t, "TupleSubroutine", subroutine.GetName())
 }
 
 func TestTupleFinalizers(t *testing.T) {
-	subroutine := subroutine.NewTupleSubroutine(nil, nil)
+	subroutine := subroutine.NewTupleSubroutine(nil, nil, nil, 0)
 	assert.Equal(t, []string{"core.platform-mesh.io/fga-tuples"}, subroutine.Finalizers(nil))
 }
 
@@ -113,6 +127,81 @@ func TestTupleProcessWithStore(t *testing.T) {
 				fga.EXPECT().Write(mock.Anything, mock.Anything).Return(nil, nil).Twice()
 			},
 		},
+		{
+			name: "should rewrite tuples whose condition changed",
+			store: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{
+							Object:   "foo",
+							Relation: "bar",
+							User:     "user1",
+							Condition: &securityv1alpha1.TupleCondition{
+								Name:    "in_time_window",
+								Context: &runtime.RawExtension{Raw: []byte(`{"end": "2026-12-31T00:00:00Z"}`)},
+							},
+						},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID:              "store-id",
+					AuthorizationModelID: "auth-model-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{
+							Object:   "foo",
+							Relation: "bar",
+							User:     "user1",
+							Condition: &securityv1alpha1.TupleCondition{
+								Name:    "in_time_window",
+								Context: &runtime.RawExtension{Raw: []byte(`{"end":"2026-06-30T00:00:00Z"}`)},
+							},
+						},
+					},
+				},
+			},
+			fgaMocks: func(fga *mocks.MockOpenFGAServiceClient) {
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return req.Deletes != nil && len(req.Deletes.TupleKeys) == 1
+				})).Return(nil, nil).Once()
+				fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+					return req.Writes != nil && req.Writes.TupleKeys[0].Condition.GetName() == "in_time_window"
+				})).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "should not rewrite tuples whose condition context only differs in formatting",
+			store: &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{
+					Tuples: []securityv1alpha1.Tuple{
+						{
+							Object:   "foo",
+							Relation: "bar",
+							User:     "user1",
+							Condition: &securityv1alpha1.TupleCondition{
+								Name:    "in_time_window",
+								Context: &runtime.RawExtension{Raw: []byte(`{"end": "2026-12-31T00:00:00Z"}`)},
+							},
+						},
+					},
+				},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID:              "store-id",
+					AuthorizationModelID: "auth-model-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{
+							Object:   "foo",
+							Relation: "bar",
+							User:     "user1",
+							Condition: &securityv1alpha1.TupleCondition{
+								Name:    "in_time_window",
+								Context: &runtime.RawExtension{Raw: []byte(`{"end":"2026-12-31T00:00:00Z"}`)},
+							},
+						},
+					},
+				},
+			},
+			// No calls to OpenFGA are expected.
+		},
 		{
 			name: "should stop processing if an error occurs",
 			store: &securityv1alpha1.Store{
@@ -164,15 +253,17 @@ func TestTupleProcessWithStore(t *testing.T) {
 			if test.mgrMocks != nil {
 				test.mgrMocks(manager)
 			}
+			recordsTupleChanges(t, manager)
 
-			subroutine := subroutine.NewTupleSubroutine(fga, manager)
+			subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
 
-			_, err := subroutine.Process(context.Background(), test.store)
+			_, err := subroutine.Process(storeContext(), test.store)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.Nil(t, err)
-				assert.Equal(t, test.store.Status.ManagedTuples, test.store.Spec.Tuples)
+				assert.Empty(t, test.store.Status.ManagedTuples)
+				assertRecordedTuples(t, test.store.Spec.Tuples, test.store.Status.ManagedTupleSet)
 			}
 
 		})
@@ -323,17 +414,19 @@ func TestTupleProcessWithAuthorizationModel(t *testing.T) {
 			if test.k8sMocks != nil {
 				test.k8sMocks(mocks.NewMockClient(t))
 			}
+			recordsTupleChanges(t, manager)
 
-			subroutine := subroutine.NewTupleSubroutine(fga, manager)
+			subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
 
-			ctx := context.Background()
+			ctx := storeContext()
 
 			_, err := subroutine.Process(ctx, test.store)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.Nil(t, err)
-				assert.Equal(t, test.store.Status.ManagedTuples, test.store.Spec.Tuples)
+				assert.Empty(t, test.store.Status.ManagedTuples)
+				assertRecordedTuples(t, test.store.Spec.Tuples, test.store.Status.ManagedTupleSet)
 			}
 
 		})
@@ -413,9 +506,9 @@ func TestTupleFinalizationWithAuthorizationModel(t *testing.T) {
 				test.k8sMocks(mocks.NewMockClient(t))
 			}
 
-			subroutine := subroutine.NewTupleSubroutine(fga, manager)
+			subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
 
-			ctx := context.Background()
+			ctx := storeContext()
 
 			_, err := subroutine.Finalize(ctx, test.store)
 			if test.expectError {
@@ -423,6 +516,7 @@ func TestTupleFinalizationWithAuthorizationModel(t *testing.T) {
 			} else {
 				assert.Nil(t, err)
 				assert.Empty(t, test.store.Status.ManagedTuples)
+				assert.Nil(t, test.store.Status.ManagedTupleSet)
 			}
 
 		})
@@ -488,16 +582,425 @@ func TestTupleFinalizationWithStore(t *testing.T) {
 				test.mgrMocks(manager)
 			}
 
-			subroutine := subroutine.NewTupleSubroutine(fga, manager)
+			subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
 
-			_, err := subroutine.Finalize(context.Background(), test.store)
+			_, err := subroutine.Finalize(storeContext(), test.store)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.Nil(t, err)
 				assert.Empty(t, test.store.Status.ManagedTuples)
+				assert.Nil(t, test.store.Status.ManagedTupleSet)
+			}
+
+		})
+	}
+}
+
+func TestTupleFinalizationKeepsTuplesOfRetainedStores(t *testing.T) {
+	for _, policy := range []securityv1alpha1.StoreDeletionPolicy{
+		securityv1alpha1.StoreDeletionPolicyRetain,
+		securityv1alpha1.StoreDeletionPolicyOrphan,
+	} {
+		t.Run(string(policy), func(t *testing.T) {
+			store := &securityv1alpha1.Store{
+				Spec: securityv1alpha1.StoreSpec{DeletionPolicy: policy},
+				Status: securityv1alpha1.StoreStatus{
+					StoreID: "store-id",
+					ManagedTuples: []securityv1alpha1.Tuple{
+						{Object: "foo", Relation: "bar", User: "user4"},
+					},
+				},
 			}
 
+			// No Write is expected on the OpenFGA client.
+			subroutine := subroutine.NewTupleSubroutine(mocks.NewMockOpenFGAServiceClient(t), mocks.NewMockManager(t), mocks.NewMockLister(t), 0)
+
+			_, err := subroutine.Finalize(storeContext(), store)
+			assert.NoError(t, err)
+			assert.Empty(t, store.Status.ManagedTuples)
+			assert.Nil(t, store.Status.ManagedTupleSet)
+		})
+	}
+}
+
+func TestTupleFinalizationDeletesModelTuplesOfRetainedStores(t *testing.T) {
+	model := &securityv1alpha1.AuthorizationModel{
+		Spec: securityv1alpha1.AuthorizationModelSpec{
+			StoreRef: securityv1alpha1.WorkspaceStoreRef{Name: "store", Cluster: "store-cluster"},
+		},
+		Status: securityv1alpha1.AuthorizationModelStatus{
+			ManagedTuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user4"},
+			},
+		},
+	}
+
+	manager := mocks.NewMockManager(t)
+	storeCluster := mocks.NewMockCluster(t)
+	storeClient := mocks.NewMockClient(t)
+	manager.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("store-cluster")).Return(storeCluster, nil)
+	storeCluster.EXPECT().GetClient().Return(storeClient)
+	storeClient.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, nn types.NamespacedName, o client.Object, opts ...client.GetOption) error {
+		*o.(*securityv1alpha1.Store) = securityv1alpha1.Store{
+			Spec:   securityv1alpha1.StoreSpec{DeletionPolicy: securityv1alpha1.StoreDeletionPolicyRetain},
+			Status: securityv1alpha1.StoreStatus{StoreID: "store-id", AuthorizationModelID: "auth-model-id"},
+		}
+		return nil
+	})
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Deletes.GetTupleKeys()) == 1 && req.Deletes.GetTupleKeys()[0].User == "user4"
+	})).Return(&openfgav1.WriteResponse{}, nil)
+
+	subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
+
+	_, err := subroutine.Finalize(storeContext(), model)
+	assert.NoError(t, err)
+	assert.Empty(t, model.Status.ManagedTuples)
+	assert.Nil(t, model.Status.ManagedTupleSet)
+}
+
+func TestTupleProcessSkipsExpiredTuples(t *testing.T) {
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			Tuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1", ExpiresAt: &past},
+				{Object: "foo", Relation: "bar", User: "user2"},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+		},
+	}
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.GetTupleKeys()) == 1 && req.Writes.GetTupleKeys()[0].User == "user2"
+	})).Return(&openfgav1.WriteResponse{}, nil)
+
+	manager := mocks.NewMockManager(t)
+	recordsTupleChanges(t, manager)
+	subroutine := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0)
+
+	_, err := subroutine.Process(storeContext(), store)
+	assert.NoError(t, err)
+	assert.Equal(t, []securityv1alpha1.Tuple{{Object: "foo", Relation: "bar", User: "user2"}}, recordedTuples(t, store.Status.ManagedTupleSet))
+}
+
+func TestTupleProcessRecordsPartialProgress(t *testing.T) {
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			Tuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+				{Object: "foo", Relation: "bar", User: "user2"},
+				{Object: "foo", Relation: "bar", User: "user3"},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+		},
+	}
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.GetTupleKeys()) == 2
+	})).Return(nil, errors.New("write failed"))
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.GetTupleKeys()) == 1
+	})).Return(&openfgav1.WriteResponse{}, nil)
+
+	subroutine := subroutine.NewTupleSubroutine(fga, mocks.NewMockManager(t), noCoOwners(t), 2)
+
+	_, err := subroutine.Process(storeContext(), store)
+	assert.Error(t, err)
+	assert.Equal(t, []securityv1alpha1.Tuple{{Object: "foo", Relation: "bar", User: "user3"}}, recordedTuples(t, store.Status.ManagedTupleSet))
+}
+
+// assertRecordedTuples asserts that a managed tuple set records tuples Equal
+// to the expected ones in any order.
+func assertRecordedTuples(t *testing.T, expected []securityv1alpha1.Tuple, set *securityv1alpha1.ManagedTupleSet) {
+	t.Helper()
+	recorded := recordedTuples(t, set)
+	assert.Len(t, recorded, len(expected))
+	for _, tuple := range expected {
+		assert.True(t, slices.ContainsFunc(recorded, tuple.Equal), "tuple %s is not recorded", tuple)
+	}
+}
+
+// recordedTuples decodes the tuples recorded in a managed tuple set.
+func recordedTuples(t *testing.T, set *securityv1alpha1.ManagedTupleSet) []securityv1alpha1.Tuple {
+	t.Helper()
+	tuples, err := set.Tuples()
+	require.NoError(t, err)
+	return tuples
+}
+
+// tupleSourceClient returns a client of a workspace holding the given
+// objects, whose APIBinding accepted the permission claims on the given
+// resources.
+func tupleSourceClient(acceptedClaims []string, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(kcpapisv1alpha2.AddToScheme(scheme))
+
+	binding := &kcpapisv1alpha2.APIBinding{ObjectMeta: metav1.ObjectMeta{Name: "core.platform-mesh.io"}}
+	for _, resource := range acceptedClaims {
+		binding.Spec.PermissionClaims = append(binding.Spec.PermissionClaims, kcpapisv1alpha2.AcceptablePermissionClaim{
+			ScopedPermissionClaim: kcpapisv1alpha2.ScopedPermissionClaim{
+				PermissionClaim: kcpapisv1alpha2.PermissionClaim{GroupResource: kcpapisv1alpha2.GroupResource{Resource: resource}},
+			},
+			State: kcpapisv1alpha2.ClaimAccepted,
 		})
 	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objects, binding)...).Build()
+}
+
+var tupleSourceLabels = map[string]string{securityv1alpha1.TupleSourceLabelKey: securityv1alpha1.TupleSourceLabelValue}
+
+func TestTupleProcessReadsTuplesFromSources(t *testing.T) {
+	cl := tupleSourceClient([]string{"configmaps", "secrets"},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tuples", Labels: tupleSourceLabels},
+			Data: map[string]string{
+				"tuples.yaml": "- {object: foo, relation: bar, user: user2}\n",
+				"ignored":     "not a tuple",
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tuples", Labels: tupleSourceLabels},
+			Data:       map[string][]byte{"tuples": []byte("foo#bar@user3\n")},
+		},
+	)
+
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			Tuples: []securityv1alpha1.Tuple{{Object: "foo", Relation: "bar", User: "user1"}},
+			TuplesFrom: []securityv1alpha1.TupleSource{
+				{ConfigMapRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "tuples", Key: "tuples.yaml"}},
+				{SecretRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "tuples"}},
+				{ConfigMapRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "missing", Optional: true}},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTuples:        []securityv1alpha1.Tuple{{Object: "foo", Relation: "bar", User: "user1"}},
+		},
+	}
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(cl)
+	cluster.EXPECT().GetEventRecorder(mock.Anything).Return(events.NewFakeRecorder(1))
+
+	// Only the tuples that are not managed yet are written.
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.GetTupleKeys()) == 2
+	})).Return(&openfgav1.WriteResponse{}, nil)
+
+	_, err := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.Empty(t, store.Status.ManagedTuples, "the deprecated field is cleared once the tuples are recorded in a set")
+	assertRecordedTuples(t, []securityv1alpha1.Tuple{
+		{Object: "foo", Relation: "bar", User: "user1"},
+		{Object: "foo", Relation: "bar", User: "user2"},
+		{Object: "foo", Relation: "bar", User: "user3"},
+	}, store.Status.ManagedTupleSet)
+}
+
+func TestTupleProcessFailsOnMissingSource(t *testing.T) {
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			TuplesFrom: []securityv1alpha1.TupleSource{
+				{ConfigMapRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "missing"}},
+			},
+		},
+	}
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(tupleSourceClient([]string{"configmaps"}))
+
+	_, err := subroutine.NewTupleSubroutine(mocks.NewMockOpenFGAServiceClient(t), manager, noCoOwners(t), 0).Process(storeContext(), store)
+	assert.Error(t, err)
+}
+
+func TestTupleProcessRejectsUnlabelledSources(t *testing.T) {
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			TuplesFrom: []securityv1alpha1.TupleSource{
+				{ConfigMapRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "tuples"}},
+			},
+		},
+	}
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(tupleSourceClient([]string{"configmaps"}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tuples"},
+		Data:       map[string]string{"tuples": "foo#bar@user1\n"},
+	}))
+
+	_, err := subroutine.NewTupleSubroutine(mocks.NewMockOpenFGAServiceClient(t), manager, noCoOwners(t), 0).Process(storeContext(), store)
+	assert.ErrorContains(t, err, "is not labelled "+securityv1alpha1.TupleSourceLabelKey)
+}
+
+func TestTupleProcessWaitsForAcceptedClaims(t *testing.T) {
+	managed := []securityv1alpha1.Tuple{{Object: "foo", Relation: "bar", User: "user1"}}
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			TuplesFrom: []securityv1alpha1.TupleSource{
+				{SecretRef: &securityv1alpha1.TupleSourceRef{Namespace: "default", Name: "tuples", Optional: true}},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTuples:        managed,
+		},
+	}
+
+	manager := mocks.NewMockManager(t)
+	cluster := mocks.NewMockCluster(t)
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil)
+	cluster.EXPECT().GetClient().Return(tupleSourceClient([]string{"configmaps"}))
+
+	// The tuples of the hidden Secret are neither written nor removed.
+	res, err := subroutine.NewTupleSubroutine(mocks.NewMockOpenFGAServiceClient(t), manager, noCoOwners(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.True(t, res.IsPending())
+	assert.Contains(t, res.Message(), "secrets")
+	assert.Equal(t, managed, store.Status.ManagedTuples)
+}
+
+func TestTupleProcessSkipsUnchangedTuples(t *testing.T) {
+	tuples := []securityv1alpha1.Tuple{
+		{Object: "foo", Relation: "bar", User: "user1"},
+		{Object: "foo", Relation: "bar", User: "user2"},
+	}
+	recorded := slices.Clone(tuples)
+	slices.Reverse(recorded)
+	set, err := securityv1alpha1.NewManagedTupleSet(recorded)
+	require.NoError(t, err)
+
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{Tuples: tuples},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTupleSet:      set,
+		},
+	}
+
+	// No calls to OpenFGA are expected.
+	_, err = subroutine.NewTupleSubroutine(mocks.NewMockOpenFGAServiceClient(t), mocks.NewMockManager(t), noCoOwners(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.Equal(t, set, store.Status.ManagedTupleSet)
+}
+
+func TestTupleProcessRecordsTupleChanges(t *testing.T) {
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			Tuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+				{Object: "foo", Relation: "bar", User: "user2"},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+				{Object: "foo", Relation: "bar", User: "user3"},
+			},
+		},
+	}
+
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.Writes == nil && len(req.Deletes.GetTupleKeys()) == 1 && req.Deletes.GetTupleKeys()[0].User == "user3"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+	fga.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.Deletes == nil && len(req.Writes.GetTupleKeys()) == 1 && req.Writes.GetTupleKeys()[0].User == "user2"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	manager := mocks.NewMockManager(t)
+	recorder := recordsTupleChanges(t, manager)
+
+	_, err := subroutine.NewTupleSubroutine(fga, manager, noCoOwners(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	require.NotNil(t, store.Status.LastTupleChange)
+	assert.Equal(t, 1, store.Status.LastTupleChange.Added)
+	assert.Equal(t, 1, store.Status.LastTupleChange.Removed)
+	assert.False(t, store.Status.LastTupleChange.LastChangeTime.IsZero())
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal TuplesChanged Added 1 and removed 1 tuples", <-recorder.Events)
+}
+
+func TestTupleProcessDryRun(t *testing.T) {
+	dryrun.Enable(dryrun.NewRecorder(dryrun.DefaultReportSize))
+	t.Cleanup(func() { dryrun.Enable(nil) })
+
+	store := &securityv1alpha1.Store{
+		Spec: securityv1alpha1.StoreSpec{
+			Tuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+				{Object: "foo", Relation: "bar", User: "user2"},
+			},
+		},
+		Status: securityv1alpha1.StoreStatus{
+			StoreID:              "store-id",
+			AuthorizationModelID: "auth-model-id",
+			ManagedTuples: []securityv1alpha1.Tuple{
+				{Object: "foo", Relation: "bar", User: "user1"},
+			},
+		},
+	}
+	original := store.DeepCopy()
+
+	// The writes are recorded by the OpenFGA connection in dry-run mode.
+	fga := mocks.NewMockOpenFGAServiceClient(t)
+	fga.EXPECT().Write(mock.Anything, mock.Anything).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	_, err := subroutine.NewTupleSubroutine(fga, mocks.NewMockManager(t), noCoOwners(t), 0).Process(storeContext(), store)
+	require.NoError(t, err)
+	assert.Equal(t, original.Status, store.Status)
+}
+
+// recordsTupleChanges lets the manager hand out an event recorder for the
+// Events of tuple changes.
+func recordsTupleChanges(t *testing.T, manager *mocks.MockManager) *events.FakeRecorder {
+	recorder := events.NewFakeRecorder(10)
+	cluster := mocks.NewMockCluster(t)
+	cluster.EXPECT().GetEventRecorder(mock.Anything).Return(recorder).Maybe()
+	manager.EXPECT().ClusterFromContext(mock.Anything).Return(cluster, nil).Maybe()
+	return recorder
+}
+
+// storeHoldsNoTuples lets reads of tuples not set up otherwise find nothing
+// in the store.
+func storeHoldsNoTuples(fga *mocks.MockOpenFGAServiceClient) {
+	fga.EXPECT().Read(mock.Anything, mock.Anything).Return(&openfgav1.ReadResponse{}, nil).Maybe()
+}
+
+// storeContext returns a context of the cluster the Stores of the tests live
+// in.
+func storeContext() context.Context {
+	return mccontext.WithCluster(context.Background(), "cluster")
+}
+
+// noCoOwners returns a lister without AuthorizationModels sharing a store.
+func noCoOwners(t *testing.T) *mocks.MockLister {
+	lister := mocks.NewMockLister(t)
+	lister.EXPECT().List(mock.Anything, mock.Anything).Return(nil).Maybe()
+	return lister
 }
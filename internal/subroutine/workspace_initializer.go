@@ -146,8 +146,12 @@ func (w *workspaceInitializer) reconcile(ctx context.Context, obj client.Object)
 	}
 
 	// Check if Store applied tuple changes
+	managedTuples, err := getManagedTuples(&store)
+	if err != nil {
+		return subroutines.OK(), err
+	}
 	for _, t := range tuples {
-		if !slices.Contains(store.Status.ManagedTuples, t) {
+		if !slices.ContainsFunc(managedTuples, t.Equal) {
 			return subroutines.StopWithRequeue(5*time.Second, "store does not yet contain all specified tuples, requeueing"), nil
 		}
 	}
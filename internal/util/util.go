@@ -2,10 +2,15 @@ package util
 
 import (
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// MaxRelationLength is the length the relations generated for an API resource
+// are capped to.
+const MaxRelationLength = 50
+
 func CapGroupToRelationLength(gvr schema.GroupVersionResource, maxLength int) string {
 
 	maxRelation := fmt.Sprintf("create_%s_%s", gvr.Group, gvr.Resource)
@@ -21,3 +26,14 @@ func CapGroupToRelationLength(gvr schema.GroupVersionResource, maxLength int) st
 
 	return group
 }
+
+// ModelGroup returns the group an API resource's types and relations are
+// named with in generated models, i.e. "core" for the core group or the group
+// capped to MaxRelationLength with dots replaced by underscores.
+func ModelGroup(group, resource string) string {
+	if group == "" {
+		return "core"
+	}
+	capped := CapGroupToRelationLength(schema.GroupVersionResource{Group: group, Resource: resource}, MaxRelationLength)
+	return strings.ReplaceAll(capped, ".", "_")
+}
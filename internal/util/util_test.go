@@ -40,3 +40,10 @@ func TestCapGroupToRelationLength(t *testing.T) {
 		})
 	}
 }
+
+func TestModelGroup(t *testing.T) {
+	assert.Equal(t, "core", ModelGroup("", "pods"))
+	assert.Equal(t, "apps", ModelGroup("apps", "deployments"))
+	assert.Equal(t, "core_platform-mesh_io", ModelGroup("core.platform-mesh.io", "accounts"))
+	assert.Equal(t, "e_example_com", ModelGroup("a-very-long-group-name.example.com", "averyveryverylongresourcename"))
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	language "github.com/openfga/language/pkg/go/transformer"
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	iclient "github.com/platform-mesh/security-operator/internal/client"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	mcruntime "sigs.k8s.io/multicluster-runtime"
+	mcmanager "sigs.k8s.io/multicluster-runtime/pkg/manager"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// SetupAuthorizationModelValidatingWebhooksWithManager registers validating webhooks that reject
+// `AuthorizationModel` and `Store` objects whose modules do not combine into a valid model with the
+// other modules of their store, or whose tuples do not match that model.
+func SetupAuthorizationModelValidatingWebhooksWithManager(mgr mcmanager.Manager, lister iclient.Lister) error {
+	models := &storeModelBuilder{
+		mgr:                    mgr,
+		lister:                 lister,
+		newDiscoveryClientFunc: subroutine.NewDiscoveryClient,
+	}
+
+	if err := mcruntime.NewWebhookManagedBy(mgr.GetLocalManager()).
+		For(&v1alpha1.AuthorizationModel{}).
+		WithValidator(&authorizationModelValidator{models: models}).
+		Complete(); err != nil {
+		return err
+	}
+
+	return mcruntime.NewWebhookManagedBy(mgr.GetLocalManager()).
+		For(&v1alpha1.Store{}).
+		WithValidator(&storeValidator{models: models}).
+		Complete()
+}
+
+// unvalidatedError reports that an object could not be validated because
+// reading what it is validated against failed, not because it is invalid.
+type unvalidatedError struct {
+	err error
+}
+
+func (e *unvalidatedError) Error() string { return e.err.Error() }
+
+func (e *unvalidatedError) Unwrap() error { return e.err }
+
+// admitUnvalidated admits objects that could not be validated with a warning
+// instead of denying them, so an unavailable dependency does not block
+// changes. The reconcilers validate them again.
+func admitUnvalidated(warnings admission.Warnings, err error) (admission.Warnings, error) {
+	var unvalidated *unvalidatedError
+	if errors.As(err, &unvalidated) {
+		return append(warnings, fmt.Sprintf("not validated: %s, the object is validated once it is reconciled", unvalidated.err)), nil
+	}
+	return warnings, err
+}
+
+// storeModelBuilder combines the modules of a store the same way the Store
+// reconciler does.
+type storeModelBuilder struct {
+	mgr                    mcmanager.Manager
+	lister                 iclient.Lister
+	newDiscoveryClientFunc subroutine.NewDiscoveryClientFunc
+}
+
+// build returns the model of a store in the given cluster with the candidate
+// AuthorizationModel in place of its stored version, if any.
+func (b *storeModelBuilder) build(ctx context.Context, store *v1alpha1.Store, storeCluster string, candidate *v1alpha1.AuthorizationModel) (*openfgav1.AuthorizationModel, error) {
+	var allModels v1alpha1.AuthorizationModelList
+	if err := b.lister.List(ctx, &allModels); err != nil {
+		return nil, &unvalidatedError{fmt.Errorf("failed to list authorization models: %w", err)}
+	}
+
+	var modules []v1alpha1.AuthorizationModel
+	for _, model := range allModels.Items {
+		if model.Spec.StoreRef.Name != store.Name || model.Spec.StoreRef.Cluster != storeCluster {
+			continue
+		}
+		if candidate != nil && model.Name == candidate.Name && logicalcluster.From(&model) == logicalcluster.From(candidate) {
+			continue
+		}
+		modules = append(modules, model)
+	}
+	if candidate != nil {
+		modules = append(modules, *candidate)
+	}
+
+	moduleFiles, err := subroutine.BuildStoreModuleFiles(b.mgr.GetLocalManager().GetConfig(), b.newDiscoveryClientFunc, store, modules)
+	if err != nil {
+		return nil, &unvalidatedError{fmt.Errorf("failed to build module files: %w", err)}
+	}
+
+	model, err := language.TransformModuleFilesToModel(moduleFiles, "1.2")
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization model: %w", err)
+	}
+	if err := fga.ValidateModelReferences(model); err != nil {
+		return nil, fmt.Errorf("invalid authorization model: %w", err)
+	}
+	return model, nil
+}
+
+var _ webhook.CustomValidator = (*authorizationModelValidator)(nil) // nolint:staticcheck
+
+type authorizationModelValidator struct {
+	models *storeModelBuilder
+}
+
+func (v *authorizationModelValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return admitUnvalidated(v.validate(ctx, obj.(*v1alpha1.AuthorizationModel)))
+}
+
+func (v *authorizationModelValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldModel, newModel := oldObj.(*v1alpha1.AuthorizationModel), newObj.(*v1alpha1.AuthorizationModel)
+	// Only spec changes are validated, so finalizers can always be removed.
+	if newModel.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldModel.Spec, newModel.Spec) {
+		return nil, nil
+	}
+	return admitUnvalidated(v.validate(ctx, newModel))
+}
+
+func (v *authorizationModelValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *authorizationModelValidator) validate(ctx context.Context, model *v1alpha1.AuthorizationModel) (admission.Warnings, error) {
+	storeCluster, err := v.models.mgr.GetCluster(ctx, multicluster.ClusterName(model.Spec.StoreRef.Cluster))
+	if err != nil {
+		return nil, &unvalidatedError{fmt.Errorf("failed to get store cluster %s: %w", model.Spec.StoreRef.Cluster, err)}
+	}
+
+	var store v1alpha1.Store
+	if err := storeCluster.GetClient().Get(ctx, types.NamespacedName{Name: model.Spec.StoreRef.Name}, &store); err != nil {
+		if kerrors.IsNotFound(err) {
+			return admission.Warnings{fmt.Sprintf("store %s does not exist yet, the model is validated once it is reconciled", model.Spec.StoreRef.Name)}, nil
+		}
+		return nil, &unvalidatedError{fmt.Errorf("failed to get store %s: %w", model.Spec.StoreRef.Name, err)}
+	}
+
+	combined, err := v.models.build(ctx, &store, model.Spec.StoreRef.Cluster, model)
+	if err != nil {
+		return nil, err
+	}
+	if err := fga.ValidateTuples(combined, model.Spec.Tuples); err != nil {
+		return nil, fmt.Errorf("invalid tuples: %w", err)
+	}
+	return nil, nil
+}
@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/multicluster-runtime/pkg/multicluster"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const testCoreModule = `module core
+
+type user
+
+type role
+  relations
+    define assignee: [user]
+
+type core_platform-mesh_io_account
+  relations
+    define owner: [role#assignee]
+    define member: [role#assignee] or owner
+`
+
+const testExistingModule = `module existing
+
+extend type core_platform-mesh_io_account
+  relations
+    define view_things: member
+`
+
+// newTestModelBuilder returns a builder for the "orgs" store, whose model is
+// not extended with discovered modules, referenced by one existing module.
+func newTestModelBuilder(t *testing.T, store *v1alpha1.Store) *storeModelBuilder {
+	storeClient := mocks.NewMockClient(t)
+	storeClient.EXPECT().Get(mock.Anything, types.NamespacedName{Name: "orgs"}, mock.Anything).RunAndReturn(func(_ context.Context, _ types.NamespacedName, o client.Object, _ ...client.GetOption) error {
+		if store == nil {
+			return kerrors.NewNotFound(schema.GroupResource{Resource: "stores"}, "orgs")
+		}
+		store.DeepCopyInto(o.(*v1alpha1.Store))
+		return nil
+	}).Maybe()
+	storeCluster := mocks.NewMockCluster(t)
+	storeCluster.EXPECT().GetClient().Return(storeClient).Maybe()
+
+	ctrlManager := mocks.NewMockCTRLManager(t)
+	ctrlManager.EXPECT().GetConfig().Return(&rest.Config{}).Maybe()
+	mgr := mocks.NewMockManager(t)
+	mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root-orgs")).Return(storeCluster, nil).Maybe()
+	mgr.EXPECT().GetLocalManager().Return(ctrlManager).Maybe()
+
+	lister := mocks.NewMockLister(t)
+	lister.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, l client.ObjectList, _ ...client.ListOption) error {
+		l.(*v1alpha1.AuthorizationModelList).Items = []v1alpha1.AuthorizationModel{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "existing"},
+				Spec: v1alpha1.AuthorizationModelSpec{
+					StoreRef: v1alpha1.WorkspaceStoreRef{Name: "orgs", Cluster: "root-orgs"},
+					Model:    testExistingModule,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-store"},
+				Spec: v1alpha1.AuthorizationModelSpec{
+					StoreRef: v1alpha1.WorkspaceStoreRef{Name: "other", Cluster: "root-orgs"},
+					Model:    "not a module",
+				},
+			},
+		}
+		return nil
+	}).Maybe()
+
+	return &storeModelBuilder{mgr: mgr, lister: lister}
+}
+
+func TestAuthorizationModelValidator_ValidateCreate(t *testing.T) {
+	store := &v1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "orgs"},
+		Spec:       v1alpha1.StoreSpec{CoreModule: testCoreModule},
+	}
+
+	tests := []struct {
+		name            string
+		store           *v1alpha1.Store
+		model           string
+		tuples          []v1alpha1.Tuple
+		wantErrContains string
+		wantWarning     bool
+	}{
+		{
+			name: "valid extension is allowed",
+			model: `module foo
+
+extend type core_platform-mesh_io_account
+  relations
+    define create_foo: owner
+
+type foo
+  relations
+    define parent: [core_platform-mesh_io_account]
+    define owner: owner from parent
+`,
+			tuples: []v1alpha1.Tuple{
+				{Object: "foo:1", Relation: "parent", User: "core_platform-mesh_io_account:acme"},
+			},
+		},
+		{
+			name:            "invalid DSL is denied",
+			model:           "module foo\n\ntype",
+			wantErrContains: "invalid authorization model",
+		},
+		{
+			name: "conflicting extension is denied",
+			model: `module foo
+
+extend type core_platform-mesh_io_account
+  relations
+    define view_things: owner
+`,
+			wantErrContains: "relation view_things already exists",
+		},
+		{
+			name: "extension of an unknown type is denied",
+			model: `module foo
+
+extend type bar
+  relations
+    define view: [user]
+`,
+			wantErrContains: "extended type bar does not exist",
+		},
+		{
+			name: "reference to an unknown type is denied",
+			model: `module foo
+
+type foo
+  relations
+    define viewer: [group#member]
+`,
+			wantErrContains: "references unknown type group",
+		},
+		{
+			name:            "tuples with unknown relations are denied",
+			model:           "module foo\n\ntype foo\n",
+			tuples:          []v1alpha1.Tuple{{Object: "foo:1", Relation: "viewer", User: "user:alice"}},
+			wantErrContains: "type foo has no relation viewer",
+		},
+		{
+			name:        "missing store is allowed with a warning",
+			store:       nil,
+			model:       "module foo\n\ntype",
+			wantWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store
+			if tt.wantWarning {
+				s = tt.store
+			}
+			v := &authorizationModelValidator{models: newTestModelBuilder(t, s)}
+
+			warnings, err := v.ValidateCreate(t.Context(), &v1alpha1.AuthorizationModel{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec: v1alpha1.AuthorizationModelSpec{
+					StoreRef: v1alpha1.WorkspaceStoreRef{Name: "orgs", Cluster: "root-orgs"},
+					Model:    tt.model,
+					Tuples:   tt.tuples,
+				},
+			})
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWarning, len(warnings) > 0)
+		})
+	}
+}
+
+func TestAuthorizationModelValidator_AdmitsUnvalidatedWithWarning(t *testing.T) {
+	model := &v1alpha1.AuthorizationModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: v1alpha1.AuthorizationModelSpec{
+			StoreRef: v1alpha1.WorkspaceStoreRef{Name: "orgs", Cluster: "root-orgs"},
+			Model:    "module foo\n\ntype",
+		},
+	}
+
+	t.Run("store cluster is unavailable", func(t *testing.T) {
+		mgr := mocks.NewMockManager(t)
+		mgr.EXPECT().GetCluster(mock.Anything, multicluster.ClusterName("root-orgs")).Return(nil, assert.AnError)
+		v := &authorizationModelValidator{models: &storeModelBuilder{mgr: mgr}}
+
+		warnings, err := v.ValidateCreate(t.Context(), model)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "failed to get store cluster root-orgs")
+	})
+
+	t.Run("authorization models cannot be listed", func(t *testing.T) {
+		models := newTestModelBuilder(t, &v1alpha1.Store{
+			ObjectMeta: metav1.ObjectMeta{Name: "orgs"},
+			Spec:       v1alpha1.StoreSpec{CoreModule: testCoreModule},
+		})
+		lister := mocks.NewMockLister(t)
+		lister.EXPECT().List(mock.Anything, mock.Anything).Return(assert.AnError)
+		models.lister = lister
+		v := &authorizationModelValidator{models: models}
+
+		warnings, err := v.ValidateCreate(t.Context(), model)
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "failed to list authorization models")
+	})
+}
+
+func TestAuthorizationModelValidator_ValidateUpdateReplacesStoredVersion(t *testing.T) {
+	store := &v1alpha1.Store{
+		ObjectMeta: metav1.ObjectMeta{Name: "orgs"},
+		Spec:       v1alpha1.StoreSpec{CoreModule: testCoreModule},
+	}
+	v := &authorizationModelValidator{models: newTestModelBuilder(t, store)}
+
+	existing := &v1alpha1.AuthorizationModel{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing"},
+		Spec: v1alpha1.AuthorizationModelSpec{
+			StoreRef: v1alpha1.WorkspaceStoreRef{Name: "orgs", Cluster: "root-orgs"},
+			Model:    testExistingModule,
+		},
+	}
+	updated := existing.DeepCopy()
+	updated.Spec.Model = testExistingModule + "    define edit_things: owner\n"
+
+	_, err := v.ValidateUpdate(t.Context(), existing, updated)
+	assert.NoError(t, err)
+}
+
+func TestAuthorizationModelValidator_ValidateUpdateIgnoresUnchangedSpec(t *testing.T) {
+	v := &authorizationModelValidator{}
+	model := &v1alpha1.AuthorizationModel{Spec: v1alpha1.AuthorizationModelSpec{Model: "not a module"}}
+
+	_, err := v.ValidateUpdate(t.Context(), model, model.DeepCopy())
+	assert.NoError(t, err)
+}
+
+func TestStoreValidator_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name            string
+		coreModule      string
+		tuples          []v1alpha1.Tuple
+		wantErrContains string
+	}{
+		{
+			name:       "valid store is allowed",
+			coreModule: testCoreModule,
+			tuples:     []v1alpha1.Tuple{{Object: "role:admin", Relation: "assignee", User: "user:alice"}},
+		},
+		{
+			name:            "core module breaking an extension is denied",
+			coreModule:      "module core\n\ntype user\n",
+			wantErrContains: "extended type core_platform-mesh_io_account does not exist",
+		},
+		{
+			name:            "tuples with unknown types are denied",
+			coreModule:      testCoreModule,
+			tuples:          []v1alpha1.Tuple{{Object: "doc:1", Relation: "viewer", User: "user:alice"}},
+			wantErrContains: "unknown object type doc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &storeValidator{models: newTestModelBuilder(t, nil)}
+
+			_, err := v.ValidateCreate(t.Context(), &v1alpha1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "orgs",
+					Annotations: map[string]string{"kcp.io/cluster": "root-orgs"},
+				},
+				Spec: v1alpha1.StoreSpec{CoreModule: tt.coreModule, Tuples: tt.tuples},
+			})
+			if tt.wantErrContains != "" {
+				assert.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	mcruntime "sigs.k8s.io/multicluster-runtime"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	kcpapisv1alpha1 "github.com/kcp-dev/sdk/apis/apis/v1alpha1"
+)
+
+// SetupModelTemplateValidatingWebhookWithManager registers a validating
+// webhook that rejects `ModelTemplate` objects whose template does not render
+// a valid OpenFGA module, or renders one that defines other types than the
+// resource's or extends its parent with other relations than create, list
+// and watch.
+func SetupModelTemplateValidatingWebhookWithManager(mgr manager.Manager) error {
+	return mcruntime.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.ModelTemplate{}).
+		WithValidator(&modelTemplateValidator{}).
+		Complete()
+}
+
+// sampleResourceSchemas are rendered by templates at admission, one for each
+// scope.
+var sampleResourceSchemas = []kcpapisv1alpha1.APIResourceSchema{
+	sampleResourceSchema(apiextensionsv1.ClusterScoped),
+	sampleResourceSchema(apiextensionsv1.NamespaceScoped),
+}
+
+func sampleResourceSchema(scope apiextensionsv1.ResourceScope) kcpapisv1alpha1.APIResourceSchema {
+	return kcpapisv1alpha1.APIResourceSchema{
+		ObjectMeta: metav1.ObjectMeta{Name: "v000000-sample.samples.example.platform-mesh.io"},
+		Spec: kcpapisv1alpha1.APIResourceSchemaSpec{
+			Group: "example.platform-mesh.io",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   "samples",
+				Singular: "sample",
+				Kind:     "Sample",
+				ListKind: "SampleList",
+			},
+			Scope: scope,
+			Versions: []kcpapisv1alpha1.APIResourceVersion{
+				{Name: "v1alpha1", Served: true, Storage: true},
+			},
+		},
+	}
+}
+
+var _ webhook.CustomValidator = (*modelTemplateValidator)(nil) // nolint:staticcheck
+
+type modelTemplateValidator struct{}
+
+func (v *modelTemplateValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, v.validate(obj.(*v1alpha1.ModelTemplate))
+}
+
+func (v *modelTemplateValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldTemplate, newTemplate := oldObj.(*v1alpha1.ModelTemplate), newObj.(*v1alpha1.ModelTemplate)
+	if newTemplate.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldTemplate.Spec, newTemplate.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(newTemplate)
+}
+
+func (v *modelTemplateValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *modelTemplateValidator) validate(modelTemplate *v1alpha1.ModelTemplate) error {
+	if modelTemplate.Spec.APIExportSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(modelTemplate.Spec.APIExportSelector); err != nil {
+			return fmt.Errorf("invalid APIExport selector: %w", err)
+		}
+	}
+
+	tpl, err := subroutine.ParseModelTemplate(modelTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	for _, resourceSchema := range sampleResourceSchemas {
+		if _, err := subroutine.RenderModelTemplate(tpl, &resourceSchema); err != nil {
+			return fmt.Errorf("rendering template for a %s resource: %w", resourceSchema.Spec.Scope, err)
+		}
+	}
+	return nil
+}
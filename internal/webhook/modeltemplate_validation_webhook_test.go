@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const validModelTemplate = `module {{ .Name }}
+
+type {{ .Group }}_{{ .Singular }}
+	relations
+		define parent: [{{ if eq .Scope "Namespaced" }}core_namespace{{ else }}core_platform-mesh_io_account{{ end }}]
+		define owner: [role#assignee] or owner from parent
+		define member: [role#assignee] or owner or member from parent
+
+		define get: member
+		define update: owner
+		define delete: owner
+		{{- range .Schema.Spec.Versions }}
+		define get_{{ .Name }}: member
+		{{- end }}
+`
+
+const parentExtension = `module {{ .Name }}
+
+extend type {{ .Parent }}
+	relations
+		define create_{{ .Group }}_{{ .Name }}: owner
+		define list_{{ .Group }}_{{ .Name }}: member
+		define watch_{{ .Group }}_{{ .Name }}: member
+`
+
+func TestModelTemplateValidator_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            v1alpha1.ModelTemplateSpec
+		wantErrContains string
+	}{
+		{
+			name: "valid template",
+			spec: v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: validModelTemplate},
+		},
+		{
+			name:            "template that does not parse",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: "module {{ .Name"},
+			wantErrContains: "invalid template",
+		},
+		{
+			name:            "template referencing unknown fields",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: "module {{ .Schema.Spec.Unknown }}"},
+			wantErrContains: "rendering template",
+		},
+		{
+			name:            "template rendering an invalid module",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: "type {{ .Name }}\n\trelations\n\t\tdefine"},
+			wantErrContains: "invalid module",
+		},
+		{
+			name: "template extending the parent with the resource's relations",
+			spec: v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: parentExtension + validModelTemplate[len("module {{ .Name }}\n"):]},
+		},
+		{
+			name:            "template defining other types",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: validModelTemplate + "\ntype role\n"},
+			wantErrContains: "module defines type role",
+		},
+		{
+			name:            "template defining the resource type twice",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: validModelTemplate + "\ntype {{ .Group }}_{{ .Singular }}\n"},
+			wantErrContains: "only example_platform-mesh_io_sample may be defined once",
+		},
+		{
+			name:            "template not defining the resource type",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: "module {{ .Name }}\n"},
+			wantErrContains: "module does not define type example_platform-mesh_io_sample",
+		},
+		{
+			name:            "template extending other types",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: validModelTemplate + "\nextend type role\n\trelations\n\t\tdefine assignee: [user]\n"},
+			wantErrContains: "module extends type role",
+		},
+		{
+			name:            "template defining other relations on the parent",
+			spec:            v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: parentExtension + "\t\tdefine delete_all: owner\n" + validModelTemplate[len("module {{ .Name }}\n"):]},
+			wantErrContains: "module defines relation delete_all on the parent type",
+		},
+		{
+			name: "invalid selector",
+			spec: v1alpha1.ModelTemplateSpec{
+				APIExportSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Unknown"},
+				}},
+				Template: validModelTemplate,
+			},
+			wantErrContains: "invalid APIExport selector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &modelTemplateValidator{}
+			_, err := v.ValidateCreate(context.Background(), &v1alpha1.ModelTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "template"},
+				Spec:       tt.spec,
+			})
+			if tt.wantErrContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErrContains)
+		})
+	}
+}
+
+func TestModelTemplateValidator_ValidateUpdate(t *testing.T) {
+	v := &modelTemplateValidator{}
+	invalid := &v1alpha1.ModelTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "template"},
+		Spec:       v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: "module {{ .Name"},
+	}
+
+	// Unchanged specs are not validated again.
+	_, err := v.ValidateUpdate(context.Background(), invalid, invalid.DeepCopy())
+	assert.NoError(t, err)
+
+	updated := invalid.DeepCopy()
+	updated.Spec.Template = "module {{ .Name }"
+	_, err = v.ValidateUpdate(context.Background(), invalid, updated)
+	assert.ErrorContains(t, err, "invalid template")
+}
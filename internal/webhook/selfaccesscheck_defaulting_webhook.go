@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	mcruntime "sigs.k8s.io/multicluster-runtime"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SetupSelfAccessCheckDefaultingWebhookWithManager registers a defaulting
+// webhook that records the user creating a `SelfAccessCheck` in its spec and
+// signs it with signingKey.
+func SetupSelfAccessCheckDefaultingWebhookWithManager(mgr manager.Manager, signingKey []byte) error {
+	return mcruntime.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.SelfAccessCheck{}).
+		WithDefaulter(&selfAccessCheckDefaulter{signingKey: signingKey}).
+		Complete()
+}
+
+var _ webhook.CustomDefaulter = (*selfAccessCheckDefaulter)(nil) // nolint:staticcheck
+
+type selfAccessCheckDefaulter struct {
+	signingKey []byte
+}
+
+// Default sets and signs the user of a SelfAccessCheck on creation, whatever
+// it was set to by the requester. Its spec is immutable afterwards, so the
+// signature stays valid.
+func (d *selfAccessCheckDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("getting admission request: %w", err)
+	}
+	if req.Operation != admissionv1.Create {
+		return nil
+	}
+
+	check := obj.(*v1alpha1.SelfAccessCheck)
+	check.Spec.User = req.UserInfo.Username
+	if check.Annotations == nil {
+		check.Annotations = map[string]string{}
+	}
+	check.Annotations[v1alpha1.SelfAccessCheckSignatureAnnotation] = subroutine.SignSelfAccessCheck(d.signingKey, check)
+	return nil
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/subroutine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func admissionContext(operation admissionv1.Operation, user string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			UserInfo:  authenticationv1.UserInfo{Username: user},
+		},
+	})
+}
+
+func TestSelfAccessCheckDefaulter_Default(t *testing.T) {
+	key := []byte("signing-key")
+	d := &selfAccessCheckDefaulter{signingKey: key}
+
+	t.Run("sets and signs the requesting user on creation", func(t *testing.T) {
+		check := &v1alpha1.SelfAccessCheck{Spec: v1alpha1.SelfAccessCheckSpec{
+			User:                  "bob@acme.corp",
+			AccessCheckAttributes: v1alpha1.AccessCheckAttributes{Relation: "member", Object: "account:root:orgs:acme"},
+		}}
+		require.NoError(t, d.Default(admissionContext(admissionv1.Create, "alice@acme.corp"), check))
+		assert.Equal(t, "alice@acme.corp", check.Spec.User)
+		assert.True(t, subroutine.VerifySelfAccessCheck(key, check))
+		assert.False(t, subroutine.VerifySelfAccessCheck([]byte("other-key"), check))
+	})
+
+	t.Run("leaves updates alone", func(t *testing.T) {
+		check := &v1alpha1.SelfAccessCheck{Spec: v1alpha1.SelfAccessCheckSpec{User: "bob@acme.corp"}}
+		require.NoError(t, d.Default(admissionContext(admissionv1.Update, "alice@acme.corp"), check))
+		assert.Equal(t, "bob@acme.corp", check.Spec.User)
+		assert.Empty(t, check.Annotations)
+	})
+
+	t.Run("fails without an admission request", func(t *testing.T) {
+		assert.Error(t, d.Default(context.Background(), &v1alpha1.SelfAccessCheck{}))
+	})
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform-mesh/security-operator/api/v1alpha1"
+	"github.com/platform-mesh/security-operator/internal/fga"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+var _ webhook.CustomValidator = (*storeValidator)(nil) // nolint:staticcheck
+
+// storeValidator rejects Stores whose core module does not combine with the
+// AuthorizationModels referencing them, or whose tuples do not match the
+// combined model.
+type storeValidator struct {
+	models *storeModelBuilder
+}
+
+func (v *storeValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return admitUnvalidated(v.validate(ctx, obj.(*v1alpha1.Store)))
+}
+
+func (v *storeValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldStore, newStore := oldObj.(*v1alpha1.Store), newObj.(*v1alpha1.Store)
+	// Only spec changes are validated, so finalizers can always be removed.
+	if newStore.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldStore.Spec, newStore.Spec) {
+		return nil, nil
+	}
+	return admitUnvalidated(v.validate(ctx, newStore))
+}
+
+func (v *storeValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *storeValidator) validate(ctx context.Context, store *v1alpha1.Store) (admission.Warnings, error) {
+	if fga.IsModelTestsStore(store.OpenFGAStoreName()) {
+		return nil, fmt.Errorf("spec.storeName must not start with the reserved prefix %s", fga.ModelTestsStorePrefix)
+	}
+	combined, err := v.models.build(ctx, store, logicalcluster.From(store).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := fga.ValidateTuples(combined, store.Spec.Tuples); err != nil {
+		return nil, fmt.Errorf("invalid tuples: %w", err)
+	}
+	return nil, nil
+}
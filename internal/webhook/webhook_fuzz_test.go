@@ -35,6 +35,23 @@ func FuzzIdentityProviderConfigurationValidateCreate(f *testing.F) {
 	})
 }
 
+func FuzzModelTemplateValidateCreate(f *testing.F) {
+	f.Add(validModelTemplate)
+	f.Add("module {{ .Name")
+	f.Add("{{ range .Schema.Spec.Versions }}{{ .Name }}{{ end }}")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, tpl string) {
+		modelTemplate := &v1alpha1.ModelTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "template"},
+			Spec:       v1alpha1.ModelTemplateSpec{APIExportName: "widgets", Template: tpl},
+		}
+
+		// Must not panic — validation errors are expected
+		_, _ = (&modelTemplateValidator{}).ValidateCreate(context.Background(), modelTemplate)
+	})
+}
+
 func splitCSV(s string) []string {
 	var result []string
 	start := 0